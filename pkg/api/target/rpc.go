@@ -0,0 +1,38 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package target
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+)
+
+var errNoConn = errors.New("target gRPC connection is not established")
+
+// Invoke sends a unary RPC other than gNMI (e.g gNOI) over the target gRPC connection.
+// The target credentials and metadata are added to the request.
+// The connection must be created using CreateGNMIClient.
+func (t *Target) Invoke(ctx context.Context, method string, req, rsp any) error {
+	if t.conn == nil {
+		return errNoConn
+	}
+	return t.conn.Invoke(t.appendRequestMetadata(ctx), method, req, rsp, t.callOpts()...)
+}
+
+// NewStream opens a streaming RPC other than gNMI (e.g gNOI) over the target gRPC connection.
+// The target credentials and metadata are added to the stream.
+// The connection must be created using CreateGNMIClient.
+func (t *Target) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string) (grpc.ClientStream, error) {
+	if t.conn == nil {
+		return nil, errNoConn
+	}
+	return t.conn.NewStream(t.appendRequestMetadata(ctx), desc, method, t.callOpts()...)
+}
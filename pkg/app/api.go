@@ -48,12 +48,23 @@ func (a *App) newAPIServer() (*http.Server, error) {
 		}
 	}
 
+	if a.Config.APIServer.Auth != nil {
+		a.apiAuth, err = a.newAPIAuth()
+		if err != nil {
+			return nil, err
+		}
+		a.router.Use(a.authMiddleware)
+	}
+
 	if a.Config.APIServer.EnableMetrics {
 		a.router.Handle("/metrics", promhttp.HandlerFor(a.reg, promhttp.HandlerOpts{}))
 		a.reg.MustRegister(collectors.NewGoCollector())
 		a.reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 		a.reg.MustRegister(subscribeResponseReceivedCounter)
 		a.reg.MustRegister(subscribeResponseFailedCounter)
+		a.reg.MustRegister(configReloadCounter)
+		a.reg.MustRegister(configLastReloadSuccess)
+		a.reg.MustRegister(configLastReloadSuccessTimestamp)
 		a.registerTargetMetrics()
 		go a.startClusterMetrics()
 	}
@@ -0,0 +1,460 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+const (
+	apiHealthzPath = "/api/v1/healthz"
+	// minimum duration between 2 JWKS refreshes triggered by an unknown key ID.
+	jwksMinRefreshInterval = time.Minute
+	clusterTokenPrincipal  = "cluster"
+)
+
+// API roles, ordered by privilege.
+const (
+	apiRoleNone = iota
+	apiRoleRead
+	apiRoleWrite
+	apiRoleAdmin
+)
+
+var apiRoles = map[string]int{
+	config.APIRoleRead:  apiRoleRead,
+	config.APIRoleWrite: apiRoleWrite,
+	config.APIRoleAdmin: apiRoleAdmin,
+}
+
+var jwtValidMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// apiAuth authenticates the API server requests and checks
+// the authenticated principal role against the endpoint required role.
+type apiAuth struct {
+	cfg   *config.APIAuth
+	users map[string]*config.APIUser
+	jwt   *jwtValidator
+	// nil if the audit log is disabled
+	audit  *log.Logger
+	logger *log.Logger
+}
+
+type apiPrincipal struct {
+	name string
+	// basic, bearer, jwt or anonymous
+	method string
+	role   int
+}
+
+func (a *App) newAPIAuth() (*apiAuth, error) {
+	cfg := a.Config.APIServer.Auth
+	aa := &apiAuth{
+		cfg:    cfg,
+		users:  make(map[string]*config.APIUser, len(cfg.Users)),
+		logger: a.Logger,
+	}
+	for _, u := range cfg.Users {
+		aa.users[u.Username] = u
+	}
+	if cfg.JWT != nil {
+		var err error
+		aa.jwt, err = newJWTValidator(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		err = aa.jwt.refresh(a.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWKS: %w", err)
+		}
+		go aa.jwt.start(a.ctx, a.Logger)
+	}
+	if cfg.AuditLog == nil || !cfg.AuditLog.Disable {
+		aa.audit = log.New(a.Logger.Writer(), "[api-audit] ", a.Logger.Flags())
+		if cfg.AuditLog != nil && cfg.AuditLog.File != "" {
+			f, err := os.OpenFile(cfg.AuditLog.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open audit log file: %w", err)
+			}
+			aa.audit = log.New(f, "", 0)
+		}
+	}
+	if a.Config.Clustering != nil && cfg.ClusterToken == "" {
+		a.Logger.Printf("api-server auth is enabled without a cluster-token, cluster members will not be able to call each other's API")
+	}
+	return aa, nil
+}
+
+func (a *App) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := apiRequiredRole(r)
+		if required == apiRoleNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, err := a.apiAuth.authenticate(r)
+		if err != nil {
+			a.apiAuth.auditRecord(r, p, http.StatusUnauthorized, required)
+			a.apiAuth.unauthorized(w, err)
+			return
+		}
+		if p.role < required {
+			a.apiAuth.auditRecord(r, p, http.StatusForbidden, required)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("%q is not allowed to %s %s", p.name, r.Method, r.URL.Path)}})
+			return
+		}
+		if required < apiRoleWrite {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		a.apiAuth.auditRecord(r, p, sw.status, required)
+	})
+}
+
+// apiRequiredRole returns the minimum role needed to call the request endpoint.
+func apiRequiredRole(r *http.Request) int {
+	path := r.URL.Path
+	switch {
+	case path == apiHealthzPath:
+		return apiRoleNone
+	case strings.HasPrefix(path, "/api/v1/admin/"):
+		return apiRoleAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return apiRoleRead
+	case strings.HasPrefix(path, "/api/v1/cluster/"):
+		return apiRoleAdmin
+	default:
+		return apiRoleWrite
+	}
+}
+
+// authenticate returns the principal identified by the request credentials.
+// the returned principal is never nil, it is the anonymous principal if
+// an error is returned.
+func (aa *apiAuth) authenticate(r *http.Request) (*apiPrincipal, error) {
+	anonymous := &apiPrincipal{name: "anonymous", method: "anonymous", role: apiRoles[aa.cfg.AnonymousRole]}
+	authz := r.Header.Get("Authorization")
+	if authz == "" {
+		if aa.cfg.AnonymousRole != "" {
+			return anonymous, nil
+		}
+		return anonymous, errors.New("missing credentials")
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		u, ok := aa.users[username]
+		if !ok || !checkPassword(u, password) {
+			return &apiPrincipal{name: username, method: "basic"}, errors.New("invalid username or password")
+		}
+		return &apiPrincipal{name: username, method: "basic", role: apiRoles[u.Role]}, nil
+	}
+	scheme, token, ok := strings.Cut(authz, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return anonymous, errors.New("unsupported authorization scheme")
+	}
+	if aa.cfg.ClusterToken != "" && constantTimeEqual(token, aa.cfg.ClusterToken) {
+		return &apiPrincipal{name: clusterTokenPrincipal, method: "bearer", role: apiRoleAdmin}, nil
+	}
+	for _, t := range aa.cfg.Tokens {
+		if constantTimeEqual(token, t.Token) {
+			return &apiPrincipal{name: t.Name, method: "bearer", role: apiRoles[t.Role]}, nil
+		}
+	}
+	if aa.jwt == nil {
+		return anonymous, errors.New("invalid token")
+	}
+	name, role, err := aa.jwt.validate(token)
+	if err != nil {
+		return &apiPrincipal{name: name, method: "jwt"}, fmt.Errorf("invalid token: %w", err)
+	}
+	return &apiPrincipal{name: name, method: "jwt", role: role}, nil
+}
+
+func checkPassword(u *config.APIUser, password string) bool {
+	if u.Password != "" {
+		return constantTimeEqual(password, u.Password)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (aa *apiAuth) unauthorized(w http.ResponseWriter, err error) {
+	if len(aa.users) > 0 {
+		w.Header().Add("WWW-Authenticate", `Basic realm="gnmic"`)
+	}
+	if len(aa.cfg.Tokens) > 0 || aa.jwt != nil {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="gnmic"`)
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+}
+
+type apiAuditRecord struct {
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal,omitempty"`
+	AuthMethod string    `json:"auth-method,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote-addr"`
+	Status     int       `json:"status"`
+}
+
+// auditRecord logs the mutating requests, including the denied ones.
+func (aa *apiAuth) auditRecord(r *http.Request, p *apiPrincipal, status, required int) {
+	if aa.audit == nil || required < apiRoleWrite {
+		return
+	}
+	rec := &apiAuditRecord{
+		Time:       time.Now(),
+		Principal:  p.name,
+		AuthMethod: p.method,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		Status:     status,
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		aa.logger.Printf("failed to marshal audit record: %v", err)
+		return
+	}
+	aa.audit.Println(string(b))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// jwtValidator validates JWTs using the keys from a JWKS file or URL.
+type jwtValidator struct {
+	cfg    *config.APIJWT
+	client *http.Client
+
+	m           sync.RWMutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+}
+
+func newJWTValidator(cfg *config.APIJWT) (*jwtValidator, error) {
+	v := &jwtValidator{
+		cfg:  cfg,
+		keys: make(map[string]interface{}),
+	}
+	if cfg.JWKSURL == "" {
+		return v, nil
+	}
+	var tlsCfg *tls.Config
+	if cfg.TLS != nil {
+		var err error
+		tlsCfg, err = utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v.client = &http.Client{
+		Timeout:   defaultHTTPClientTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+	}
+	return v, nil
+}
+
+// start refreshes the JWKS periodically until ctx is done.
+func (v *jwtValidator) start(ctx context.Context, logger *log.Logger) {
+	ticker := time.NewTicker(v.cfg.JWKSRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := v.refresh(ctx)
+			if err != nil {
+				logger.Printf("failed to refresh JWKS: %v", err)
+			}
+		}
+	}
+}
+
+func (v *jwtValidator) refresh(ctx context.Context) error {
+	b, err := v.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	jwks := new(jose.JSONWebKeySet)
+	err = json.Unmarshal(b, jwks)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if !k.IsPublic() || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		keys[k.KeyID] = k.Key
+	}
+	if len(keys) == 0 {
+		return errors.New("no public signing key found")
+	}
+	v.m.Lock()
+	v.keys = keys
+	v.lastRefresh = time.Now()
+	v.m.Unlock()
+	return nil
+}
+
+func (v *jwtValidator) fetch(ctx context.Context) ([]byte, error) {
+	if v.cfg.JWKSFile != "" {
+		return os.ReadFile(v.cfg.JWKSFile)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", rsp.StatusCode)
+	}
+	return io.ReadAll(rsp.Body)
+}
+
+func (v *jwtValidator) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	v.m.RLock()
+	key, ok := v.keys[kid]
+	lastRefresh := v.lastRefresh
+	v.m.RUnlock()
+	if ok {
+		return key, nil
+	}
+	// unknown key ID, the keys might have been rotated
+	if time.Since(lastRefresh) < jwksMinRefreshInterval {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPClientTimeout)
+	defer cancel()
+	err := v.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.m.RLock()
+	defer v.m.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key ID %q", kid)
+}
+
+// validate validates the token and returns the principal name and role.
+func (v *jwtValidator) validate(token string) (string, int, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtValidMethods),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, v.keyFunc, opts...)
+	if err != nil {
+		return "", apiRoleNone, err
+	}
+	name, _ := claims[v.cfg.UsernameClaim].(string)
+	return name, v.role(claims[v.cfg.RoleClaim]), nil
+}
+
+// role returns the highest role found in the role claim value,
+// which can be a string or a list of strings.
+func (v *jwtValidator) role(claim interface{}) int {
+	var values []string
+	switch claim := claim.(type) {
+	case string:
+		values = strings.Fields(claim)
+	case []interface{}:
+		for _, c := range claim {
+			if s, ok := c.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	role := apiRoleNone
+	for _, val := range values {
+		if mapped, ok := v.cfg.RoleMapping[val]; ok {
+			val = mapped
+		}
+		if r := apiRoles[val]; r > role {
+			role = r
+		}
+	}
+	if role == apiRoleNone {
+		return apiRoles[v.cfg.DefaultRole]
+	}
+	return role
+}
+
+// bearerTransport adds a bearer token to the requests sent by
+// the clustering client.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,224 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+func newTestAuthApp(t *testing.T, auth *config.APIAuth) (*App, *bytes.Buffer) {
+	t.Helper()
+	a := New()
+	buf := new(bytes.Buffer)
+	a.Logger = log.New(buf, "", 0)
+	a.Config.APIServer = &config.APIServer{Auth: auth}
+	var err error
+	a.apiAuth, err = a.newAPIAuth()
+	if err != nil {
+		t.Fatalf("failed to create API auth: %v", err)
+	}
+	return a, buf
+}
+
+func TestAPIAuthMiddleware(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hashed-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, audit := newTestAuthApp(t, &config.APIAuth{
+		Users: []*config.APIUser{
+			{Username: "reader", Password: "reader-pass", Role: config.APIRoleRead},
+			{Username: "admin", PasswordHash: string(hash), Role: config.APIRoleAdmin},
+		},
+		Tokens: []*config.APIToken{
+			{Name: "ci", Token: "ci-token", Role: config.APIRoleWrite},
+		},
+		ClusterToken: "cluster-token",
+	})
+	h := a.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	type req struct {
+		method, path string
+		user, pass   string
+		token        string
+		want         int
+	}
+	tests := map[string]req{
+		"healthz_no_auth":      {method: http.MethodGet, path: "/api/v1/healthz", want: http.StatusOK},
+		"no_credentials":       {method: http.MethodGet, path: "/api/v1/targets", want: http.StatusUnauthorized},
+		"reader_get":           {method: http.MethodGet, path: "/api/v1/targets", user: "reader", pass: "reader-pass", want: http.StatusOK},
+		"reader_bad_pass":      {method: http.MethodGet, path: "/api/v1/targets", user: "reader", pass: "x", want: http.StatusUnauthorized},
+		"unknown_user":         {method: http.MethodGet, path: "/api/v1/targets", user: "nobody", pass: "x", want: http.StatusUnauthorized},
+		"reader_post":          {method: http.MethodPost, path: "/api/v1/config/targets", user: "reader", pass: "reader-pass", want: http.StatusForbidden},
+		"token_post":           {method: http.MethodPost, path: "/api/v1/config/targets", token: "ci-token", want: http.StatusOK},
+		"token_drain":          {method: http.MethodPost, path: "/api/v1/cluster/members/m1/drain", token: "ci-token", want: http.StatusForbidden},
+		"token_cluster_get":    {method: http.MethodGet, path: "/api/v1/cluster", token: "ci-token", want: http.StatusOK},
+		"bad_token":            {method: http.MethodGet, path: "/api/v1/targets", token: "nope", want: http.StatusUnauthorized},
+		"admin_hash_shutdown":  {method: http.MethodPost, path: "/api/v1/admin/shutdown", user: "admin", pass: "hashed-pass", want: http.StatusOK},
+		"token_shutdown":       {method: http.MethodPost, path: "/api/v1/admin/shutdown", token: "ci-token", want: http.StatusForbidden},
+		"cluster_token_delete": {method: http.MethodDelete, path: "/api/v1/targets/t1", token: "cluster-token", want: http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+	// audit log
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	var records []apiAuditRecord
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "[api-audit] ") {
+			continue
+		}
+		rec := apiAuditRecord{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(l, "[api-audit] ")), &rec); err != nil {
+			t.Fatalf("failed to parse audit record %q: %v", l, err)
+		}
+		records = append(records, rec)
+	}
+	// one record per mutating request
+	if len(records) != 6 {
+		t.Errorf("got %d audit records, want 6: %s", len(records), audit.String())
+	}
+	for _, rec := range records {
+		if rec.Method == http.MethodGet {
+			t.Errorf("unexpected audit record for a GET request: %+v", rec)
+		}
+	}
+}
+
+func TestAPIAuthAnonymousRole(t *testing.T) {
+	a, _ := newTestAuthApp(t, &config.APIAuth{AnonymousRole: config.APIRoleRead})
+	h := a.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for method, want := range map[string]int{
+		http.MethodGet:  http.StatusOK,
+		http.MethodPost: http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/config/targets", nil))
+		if w.Code != want {
+			t.Errorf("%s: got status %d, want %d", method, w.Code, want)
+		}
+	}
+}
+
+func TestAPIAuthJWT(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: key.Public(), KeyID: "k1", Algorithm: "ES256", Use: "sig"},
+	}}
+	b, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := newTestAuthApp(t, &config.APIAuth{
+		JWT: &config.APIJWT{
+			JWKSFile:            jwksFile,
+			JWKSRefreshInterval: time.Hour,
+			Issuer:              "idp",
+			Audience:            "gnmic",
+			UsernameClaim:       "sub",
+			RoleClaim:           "groups",
+			RoleMapping:         map[string]string{"net-ops": config.APIRoleWrite},
+		},
+	})
+	h := a.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	sign := func(kid string, claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		tok.Header["kid"] = kid
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := map[string]struct {
+		token string
+		want  int
+	}{
+		"valid_mapped_role": {
+			token: sign("k1", jwt.MapClaims{"sub": "alice", "iss": "idp", "aud": "gnmic", "exp": exp, "groups": []string{"net-ops"}}),
+			want:  http.StatusOK,
+		},
+		"no_role": {
+			token: sign("k1", jwt.MapClaims{"sub": "bob", "iss": "idp", "aud": "gnmic", "exp": exp}),
+			want:  http.StatusForbidden,
+		},
+		"read_role": {
+			token: sign("k1", jwt.MapClaims{"sub": "bob", "iss": "idp", "aud": "gnmic", "exp": exp, "groups": "read"}),
+			want:  http.StatusForbidden,
+		},
+		"wrong_audience": {
+			token: sign("k1", jwt.MapClaims{"sub": "alice", "iss": "idp", "aud": "other", "exp": exp, "groups": "admin"}),
+			want:  http.StatusUnauthorized,
+		},
+		"expired": {
+			token: sign("k1", jwt.MapClaims{"sub": "alice", "iss": "idp", "aud": "gnmic", "exp": time.Now().Add(-time.Hour).Unix(), "groups": "admin"}),
+			want:  http.StatusUnauthorized,
+		},
+		"missing_exp": {
+			token: sign("k1", jwt.MapClaims{"sub": "alice", "iss": "idp", "aud": "gnmic", "groups": "admin"}),
+			want:  http.StatusUnauthorized,
+		},
+		"unknown_kid": {
+			token: sign("k2", jwt.MapClaims{"sub": "alice", "iss": "idp", "aud": "gnmic", "exp": exp, "groups": "admin"}),
+			want:  http.StatusUnauthorized,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/config/targets", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,660 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// apiReplicatedHeader is set on config changes forwarded to the other
+// cluster members, its value is the name of the originating instance.
+const apiReplicatedHeader = "X-Gnmic-Replicated"
+
+const apiReplicationTimeout = 30 * time.Second
+
+var (
+	errConfigExists   = errors.New("already exists")
+	errConfigNotFound = errors.New("not found")
+	errConfigInUse    = errors.New("in use")
+)
+
+// subscriptions
+
+func (a *App) handleConfigSubscriptionsGet(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.configLock.RLock()
+	sub, ok := a.Config.Subscriptions[id]
+	a.configLock.RUnlock()
+	if !ok {
+		apiConfigError(w, fmt.Errorf("subscription %q %w", id, errConfigNotFound))
+		return
+	}
+	a.handlerCommonGet(w, sub)
+}
+
+func (a *App) handleConfigSubscriptionsPost(w http.ResponseWriter, r *http.Request) {
+	body, cfgs, ok := readConfigBody(w, r)
+	if !ok {
+		return
+	}
+	a.setSubscriptions(w, r, body, cfgs)
+}
+
+func (a *App) handleConfigSubscriptionsPut(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	body, cfg, ok := readConfigBodyNamed(w, r)
+	if !ok {
+		return
+	}
+	a.setSubscriptions(w, r, body, map[string]map[string]interface{}{id: cfg})
+}
+
+func (a *App) setSubscriptions(w http.ResponseWriter, r *http.Request, body []byte, cfgs map[string]map[string]interface{}) {
+	subs := make(map[string]*types.SubscriptionConfig, len(cfgs))
+	for n, cfg := range cfgs {
+		sub, err := a.Config.DecodeSubscriptionConfig(n, cfg)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("subscription %q: %v", n, err)}})
+			return
+		}
+		subs[n] = sub
+	}
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+	err := a.SetSubscriptionsConfig(subs, replaceConfig(r))
+	if err != nil {
+		apiConfigError(w, err)
+		return
+	}
+	a.replicateConfigRequest(r, body)
+}
+
+func (a *App) handleConfigSubscriptionsDelete(w http.ResponseWriter, r *http.Request) {
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+	err := a.DeleteSubscriptionConfig(mux.Vars(r)["id"])
+	if err != nil {
+		apiConfigError(w, err)
+		return
+	}
+	a.replicateConfigRequest(r, nil)
+}
+
+// outputs
+
+func (a *App) handleConfigOutputsGet(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.configLock.RLock()
+	out, ok := a.Config.Outputs[id]
+	a.configLock.RUnlock()
+	if !ok {
+		apiConfigError(w, fmt.Errorf("output %q %w", id, errConfigNotFound))
+		return
+	}
+	a.handlerCommonGet(w, out)
+}
+
+func (a *App) handleConfigOutputsPost(w http.ResponseWriter, r *http.Request) {
+	body, cfgs, ok := readConfigBody(w, r)
+	if !ok {
+		return
+	}
+	a.setOutputs(w, r, body, cfgs)
+}
+
+func (a *App) handleConfigOutputsPut(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	body, cfg, ok := readConfigBodyNamed(w, r)
+	if !ok {
+		return
+	}
+	a.setOutputs(w, r, body, map[string]map[string]interface{}{id: cfg})
+}
+
+func (a *App) setOutputs(w http.ResponseWriter, r *http.Request, body []byte, cfgs map[string]map[string]interface{}) {
+	for n, cfg := range cfgs {
+		outCfg, err := a.Config.DecodeOutputConfig(cfg)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("output %q: %v", n, err)}})
+			return
+		}
+		cfgs[n] = outCfg
+	}
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+	// validate all the outputs before applying any of them
+	a.configLock.RLock()
+	for n, cfg := range cfgs {
+		err := a.checkOutputConfig(n, cfg, replaceConfig(r))
+		if err != nil {
+			a.configLock.RUnlock()
+			apiConfigError(w, err)
+			return
+		}
+	}
+	a.configLock.RUnlock()
+	for n, cfg := range cfgs {
+		err := a.SetOutputConfig(n, cfg, replaceConfig(r))
+		if err != nil {
+			apiConfigError(w, err)
+			return
+		}
+	}
+	a.replicateConfigRequest(r, body)
+}
+
+func (a *App) handleConfigOutputsDelete(w http.ResponseWriter, r *http.Request) {
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+	err := a.DeleteOutputConfig(mux.Vars(r)["id"])
+	if err != nil {
+		apiConfigError(w, err)
+		return
+	}
+	a.replicateConfigRequest(r, nil)
+}
+
+// processors
+
+func (a *App) handleConfigProcessorsGet(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.configLock.RLock()
+	p, ok := a.Config.Processors[id]
+	a.configLock.RUnlock()
+	if !ok {
+		apiConfigError(w, fmt.Errorf("processor %q %w", id, errConfigNotFound))
+		return
+	}
+	a.handlerCommonGet(w, p)
+}
+
+func (a *App) handleConfigProcessorsPost(w http.ResponseWriter, r *http.Request) {
+	body, cfgs, ok := readConfigBody(w, r)
+	if !ok {
+		return
+	}
+	a.setProcessors(w, r, body, cfgs)
+}
+
+func (a *App) handleConfigProcessorsPut(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	body, cfg, ok := readConfigBodyNamed(w, r)
+	if !ok {
+		return
+	}
+	a.setProcessors(w, r, body, map[string]map[string]interface{}{id: cfg})
+}
+
+func (a *App) setProcessors(w http.ResponseWriter, r *http.Request, body []byte, cfgs map[string]map[string]interface{}) {
+	for n, cfg := range cfgs {
+		pCfg, err := a.Config.DecodeProcessorConfig(cfg)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("processor %q: %v", n, err)}})
+			return
+		}
+		cfgs[n] = pCfg
+	}
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+	// validate all the processors before applying any of them
+	a.configLock.RLock()
+	for n := range cfgs {
+		err := a.checkProcessorConfig(n, replaceConfig(r))
+		if err != nil {
+			a.configLock.RUnlock()
+			apiConfigError(w, err)
+			return
+		}
+	}
+	a.configLock.RUnlock()
+	for n, cfg := range cfgs {
+		err := a.SetProcessorConfig(n, cfg, replaceConfig(r))
+		if err != nil {
+			apiConfigError(w, err)
+			return
+		}
+	}
+	a.replicateConfigRequest(r, body)
+}
+
+func (a *App) handleConfigProcessorsDelete(w http.ResponseWriter, r *http.Request) {
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+	err := a.DeleteProcessorConfig(mux.Vars(r)["id"])
+	if err != nil {
+		apiConfigError(w, err)
+		return
+	}
+	a.replicateConfigRequest(r, nil)
+}
+
+// SetSubscriptionsConfig adds the subscriptions subs to the configuration,
+// existing subscriptions are replaced only if replace is true.
+// The running targets using one of the subscriptions are restarted.
+func (a *App) SetSubscriptionsConfig(subs map[string]*types.SubscriptionConfig, replace bool) error {
+	a.configLock.Lock()
+	allSubs := make(map[string]*types.SubscriptionConfig, len(a.Config.Subscriptions)+len(subs))
+	for n, sub := range a.Config.Subscriptions {
+		allSubs[n] = sub
+	}
+	for n, sub := range subs {
+		if _, ok := allSubs[n]; ok && !replace {
+			a.configLock.Unlock()
+			return fmt.Errorf("subscription %q %w", n, errConfigExists)
+		}
+		allSubs[n] = sub
+	}
+	err := config.ValidateSubscriptionsConfig(allSubs)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	a.Config.Subscriptions = allSubs
+	a.configLock.Unlock()
+
+	names := make([]string, 0, len(subs))
+	for n := range subs {
+		names = append(names, n)
+	}
+	a.restartSubscriptionTargets(names)
+	return nil
+}
+
+// DeleteSubscriptionConfig removes subscription name from the configuration
+// if no target references it.
+func (a *App) DeleteSubscriptionConfig(name string) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q %w", name, errConfigNotFound)
+	}
+	for tn, tc := range a.Config.Targets {
+		if strInList(name, tc.Subscriptions) {
+			a.configLock.Unlock()
+			return fmt.Errorf("subscription %q %w by target %q", name, errConfigInUse, tn)
+		}
+	}
+	delete(a.Config.Subscriptions, name)
+	a.configLock.Unlock()
+
+	a.restartSubscriptionTargets([]string{name})
+	return nil
+}
+
+// SetOutputConfig adds output name to the configuration and starts it.
+// If the output exists and replace is true, it is closed and restarted with the new config.
+// The inputs writing to the output are restarted to use the new instance.
+func (a *App) SetOutputConfig(name string, cfg map[string]interface{}, replace bool) error {
+	a.configLock.Lock()
+	err := a.checkOutputConfig(name, cfg, replace)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	_, exists := a.Config.Outputs[name]
+	a.Config.Outputs[name] = cfg
+	a.configLock.Unlock()
+
+	if exists {
+		a.Logger.Printf("restarting output %q", name)
+		a.DeleteOutput(name)
+	}
+	a.InitOutput(a.ctx, name, a.Config.Targets)
+	a.restartOutputsInputs([]string{name})
+	return nil
+}
+
+// checkOutputConfig returns an error if output name cannot be set to cfg.
+// Must be called with the configLock held.
+func (a *App) checkOutputConfig(name string, cfg map[string]interface{}, replace bool) error {
+	if _, ok := a.Config.Outputs[name]; ok && !replace {
+		return fmt.Errorf("output %q %w", name, errConfigExists)
+	}
+	for _, pn := range configRefs(cfg, "event-processors") {
+		if _, ok := a.Config.Processors[pn]; !ok {
+			return fmt.Errorf("output %q: unknown event processor %q", name, pn)
+		}
+	}
+	return nil
+}
+
+// DeleteOutputConfig closes output name and removes it from the configuration
+// if no target, subscription or input references it.
+func (a *App) DeleteOutputConfig(name string) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Outputs[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("output %q %w", name, errConfigNotFound)
+	}
+	for tn, tc := range a.Config.Targets {
+		if strInList(name, tc.Outputs) {
+			a.configLock.Unlock()
+			return fmt.Errorf("output %q %w by target %q", name, errConfigInUse, tn)
+		}
+	}
+	for sn, sc := range a.Config.Subscriptions {
+		if strInList(name, sc.Outputs) {
+			a.configLock.Unlock()
+			return fmt.Errorf("output %q %w by subscription %q", name, errConfigInUse, sn)
+		}
+	}
+	for in, icfg := range a.Config.Inputs {
+		if strInList(name, configRefs(icfg, "outputs")) {
+			a.configLock.Unlock()
+			return fmt.Errorf("output %q %w by input %q", name, errConfigInUse, in)
+		}
+	}
+	delete(a.Config.Outputs, name)
+	a.configLock.Unlock()
+
+	a.DeleteOutput(name)
+	return nil
+}
+
+// SetProcessorConfig adds event processor name to the configuration.
+// If the processor exists and replace is true, the outputs and inputs
+// using it are restarted with the new config.
+func (a *App) SetProcessorConfig(name string, cfg map[string]interface{}, replace bool) error {
+	a.configLock.Lock()
+	err := a.checkProcessorConfig(name, replace)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	_, exists := a.Config.Processors[name]
+	a.Config.Processors[name] = cfg
+	outs, ins := a.processorUsers(name)
+	// the inputs writing to a restarted output are restarted as well
+	for _, n := range a.outputsInputs(outs) {
+		if !strInList(n, ins) {
+			ins = append(ins, n)
+		}
+	}
+	a.configLock.Unlock()
+	if !exists {
+		return nil
+	}
+	for _, n := range outs {
+		a.Logger.Printf("restarting output %q after processor %q update", n, name)
+		a.DeleteOutput(n)
+		a.InitOutput(a.ctx, n, a.Config.Targets)
+	}
+	for _, n := range ins {
+		a.Logger.Printf("restarting input %q after processor %q update", n, name)
+		a.restartInput(n)
+	}
+	return nil
+}
+
+// checkProcessorConfig returns an error if processor name cannot be set.
+// Must be called with the configLock held.
+func (a *App) checkProcessorConfig(name string, replace bool) error {
+	if _, ok := a.Config.Processors[name]; ok && !replace {
+		return fmt.Errorf("processor %q %w", name, errConfigExists)
+	}
+	return nil
+}
+
+// DeleteProcessorConfig removes event processor name from the configuration
+// if no output or input references it.
+func (a *App) DeleteProcessorConfig(name string) error {
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	if _, ok := a.Config.Processors[name]; !ok {
+		return fmt.Errorf("processor %q %w", name, errConfigNotFound)
+	}
+	outs, ins := a.processorUsers(name)
+	if len(outs) > 0 {
+		return fmt.Errorf("processor %q %w by output %q", name, errConfigInUse, outs[0])
+	}
+	if len(ins) > 0 {
+		return fmt.Errorf("processor %q %w by input %q", name, errConfigInUse, ins[0])
+	}
+	delete(a.Config.Processors, name)
+	return nil
+}
+
+// restartSubscriptionTargets restarts the running targets
+// subscribed to one of the subscriptions subs.
+// Targets without an explicit subscriptions list use all the
+// configured subscriptions so they are always restarted.
+func (a *App) restartSubscriptionTargets(subs []string) {
+	tcs := make([]*types.TargetConfig, 0)
+	a.operLock.RLock()
+	for _, t := range a.Targets {
+		if len(t.Config.Subscriptions) == 0 {
+			tcs = append(tcs, t.Config)
+			continue
+		}
+		for _, sn := range subs {
+			if strInList(sn, t.Config.Subscriptions) {
+				tcs = append(tcs, t.Config)
+				break
+			}
+		}
+	}
+	a.operLock.RUnlock()
+
+	for _, tc := range tcs {
+		a.Logger.Printf("restarting target %q after subscriptions update", tc.Name)
+		if err := a.stopTarget(a.ctx, tc.Name); err != nil {
+			a.Logger.Printf("failed to stop target %q: %v", tc.Name, err)
+			continue
+		}
+		go a.TargetSubscribeStream(a.ctx, tc)
+	}
+}
+
+// restartInput closes input name and starts it again with the current config.
+func (a *App) restartInput(name string) {
+	a.operLock.Lock()
+	if in, ok := a.Inputs[name]; ok {
+		err := in.Close()
+		if err != nil {
+			a.Logger.Printf("failed to close input %q: %v", name, err)
+		}
+		delete(a.Inputs, name)
+	}
+	a.operLock.Unlock()
+	a.InitInput(a.ctx, name, a.Config.Targets)
+}
+
+// restartOutputsInputs restarts the running inputs writing to one of the outputs outs,
+// inputs copy the output instances when they start so they must be restarted
+// after an output is added or replaced.
+func (a *App) restartOutputsInputs(outs []string) {
+	a.configLock.RLock()
+	ins := a.outputsInputs(outs)
+	a.configLock.RUnlock()
+	for _, n := range ins {
+		a.Logger.Printf("restarting input %q after outputs update", n)
+		a.restartInput(n)
+	}
+}
+
+// outputsInputs returns the names of the inputs writing to one of the outputs outs.
+// Inputs without an explicit outputs list write to all the outputs.
+// Must be called with the configLock held.
+func (a *App) outputsInputs(outs []string) []string {
+	ins := make([]string, 0)
+	for n, cfg := range a.Config.Inputs {
+		if inputUsesOutputs(cfg, outs) {
+			ins = append(ins, n)
+		}
+	}
+	return ins
+}
+
+// inputUsesOutputs returns true if the input config cfg writes to one of the outputs outs.
+func inputUsesOutputs(cfg map[string]interface{}, outs []string) bool {
+	if len(outs) == 0 {
+		return false
+	}
+	refs := configRefs(cfg, "outputs")
+	if len(refs) == 0 {
+		return true
+	}
+	for _, on := range outs {
+		if strInList(on, refs) {
+			return true
+		}
+	}
+	return false
+}
+
+// processorUsers returns the names of the outputs and inputs using event processor name.
+// Must be called with the configLock held.
+func (a *App) processorUsers(name string) ([]string, []string) {
+	outs := make([]string, 0)
+	for n, cfg := range a.Config.Outputs {
+		if strInList(name, configRefs(cfg, "event-processors")) {
+			outs = append(outs, n)
+		}
+	}
+	ins := make([]string, 0)
+	for n, cfg := range a.Config.Inputs {
+		if strInList(name, configRefs(cfg, "event-processors")) {
+			ins = append(ins, n)
+		}
+	}
+	return outs, ins
+}
+
+// replicateConfigRequest forwards a config change applied locally to the other cluster members.
+// Requests already replicated by another member are not forwarded again.
+func (a *App) replicateConfigRequest(r *http.Request, body []byte) {
+	if a.Config.Clustering == nil || a.locker == nil {
+		return
+	}
+	if r.Header.Get(apiReplicatedHeader) != "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, apiReplicationTimeout)
+	defer cancel()
+	err := a.replicateConfigChange(ctx, r.Method, r.URL.Path, body)
+	if err != nil {
+		a.Logger.Printf("failed to replicate %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+// replaceConfig returns true if the request is allowed to
+// replace an existing config.
+// Replicated requests always replace the existing config so that
+// the cluster members converge to the same config.
+func replaceConfig(r *http.Request) bool {
+	return r.Method == http.MethodPut || r.Header.Get(apiReplicatedHeader) != ""
+}
+
+func apiConfigError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errConfigNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errConfigExists), errors.Is(err, errConfigInUse):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+}
+
+// readConfigBody reads a request body formatted as a map of
+// config names to configs, similar to the config file sections.
+func readConfigBody(w http.ResponseWriter, r *http.Request) ([]byte, map[string]map[string]interface{}, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return nil, nil, false
+	}
+	defer r.Body.Close()
+	cfgs := make(map[string]map[string]interface{})
+	err = json.Unmarshal(body, &cfgs)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return nil, nil, false
+	}
+	if len(cfgs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"empty config"}})
+		return nil, nil, false
+	}
+	for n, cfg := range cfgs {
+		if cfg == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("empty config %q", n)}})
+			return nil, nil, false
+		}
+	}
+	return body, cfgs, true
+}
+
+// readConfigBodyNamed reads a request body containing a single config.
+func readConfigBodyNamed(w http.ResponseWriter, r *http.Request) ([]byte, map[string]interface{}, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return nil, nil, false
+	}
+	defer r.Body.Close()
+	cfg := make(map[string]interface{})
+	err = json.Unmarshal(body, &cfg)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return nil, nil, false
+	}
+	if len(cfg) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"empty config"}})
+		return nil, nil, false
+	}
+	return body, cfg, true
+}
+
+// configRefs returns the list of names found under key in cfg.
+func configRefs(cfg map[string]interface{}, key string) []string {
+	switch v := cfg[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		refs := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				refs = append(refs, s)
+			}
+		}
+		return refs
+	}
+	return nil
+}
+
+func strInList(s string, ls []string) bool {
+	for _, item := range ls {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
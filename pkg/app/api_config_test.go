@@ -0,0 +1,91 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+func TestAPIConfigCRUD(t *testing.T) {
+	a := New()
+	a.Config.APIServer = &config.APIServer{}
+	a.routes()
+
+	type step struct {
+		name   string
+		setup  func()
+		method string
+		path   string
+		body   string
+		want   int
+	}
+	steps := []step{
+		{name: "create_processor", method: http.MethodPost, path: "/api/v1/config/processors",
+			body: `{"p1": {"event-drop": {"condition": ".tags.x == \"y\""}}}`, want: http.StatusOK},
+		{name: "create_existing_processor", method: http.MethodPost, path: "/api/v1/config/processors",
+			body: `{"p1": {"event-drop": {}}}`, want: http.StatusConflict},
+		{name: "replace_processor", method: http.MethodPut, path: "/api/v1/config/processors/p1",
+			body: `{"event-drop": {}}`, want: http.StatusOK},
+		{name: "unknown_processor_type", method: http.MethodPut, path: "/api/v1/config/processors/p2",
+			body: `{"event-unknown": {}}`, want: http.StatusBadRequest},
+		{name: "get_processor", method: http.MethodGet, path: "/api/v1/config/processors/p1", want: http.StatusOK},
+		{name: "get_unknown_processor", method: http.MethodGet, path: "/api/v1/config/processors/p2", want: http.StatusNotFound},
+		{name: "delete_used_processor", method: http.MethodDelete, path: "/api/v1/config/processors/p1",
+			setup: func() {
+				a.Config.Outputs["o1"] = map[string]interface{}{"type": "file", "event-processors": []interface{}{"p1"}}
+			},
+			want: http.StatusConflict},
+		{name: "delete_unknown_processor", method: http.MethodDelete, path: "/api/v1/config/processors/p2", want: http.StatusNotFound},
+		{name: "unknown_output_type", method: http.MethodPost, path: "/api/v1/config/outputs",
+			body: `{"o2": {"type": "unknown"}}`, want: http.StatusBadRequest},
+		{name: "output_unknown_processor", method: http.MethodPut, path: "/api/v1/config/outputs/o2",
+			body: `{"type": "file", "event-processors": ["p2"]}`, want: http.StatusBadRequest},
+		{name: "output_batch_with_invalid_entry", method: http.MethodPost, path: "/api/v1/config/outputs",
+			body: `{"o3": {"type": "file"}, "o4": {"type": "file", "event-processors": ["p2"]}}`, want: http.StatusBadRequest},
+		{name: "get_output_from_failed_batch", method: http.MethodGet, path: "/api/v1/config/outputs/o3", want: http.StatusNotFound},
+		{name: "create_subscription", method: http.MethodPost, path: "/api/v1/config/subscriptions",
+			body: `{"s1": {"paths": ["/interfaces"], "mode": "stream"}}`, want: http.StatusOK},
+		{name: "subscription_missing_paths", method: http.MethodPut, path: "/api/v1/config/subscriptions/s2",
+			body: `{"mode": "stream"}`, want: http.StatusBadRequest},
+		{name: "mixed_poll_subscription", method: http.MethodPut, path: "/api/v1/config/subscriptions/s2",
+			body: `{"paths": ["/system"], "mode": "poll"}`, want: http.StatusBadRequest},
+		{name: "delete_used_subscription", method: http.MethodDelete, path: "/api/v1/config/subscriptions/s1",
+			setup: func() {
+				a.Config.Targets["t1"] = &types.TargetConfig{Name: "t1", Subscriptions: []string{"s1"}, Outputs: []string{"o1"}}
+			},
+			want: http.StatusConflict},
+		{name: "delete_used_output", method: http.MethodDelete, path: "/api/v1/config/outputs/o1", want: http.StatusConflict},
+		{name: "delete_target_references", method: http.MethodDelete, path: "/api/v1/config/subscriptions/s1",
+			setup: func() {
+				delete(a.Config.Targets, "t1")
+			},
+			want: http.StatusOK},
+		{name: "delete_output", method: http.MethodDelete, path: "/api/v1/config/outputs/o1", want: http.StatusOK},
+		{name: "delete_processor", method: http.MethodDelete, path: "/api/v1/config/processors/p1", want: http.StatusOK},
+	}
+	for _, s := range steps {
+		if s.setup != nil {
+			s.setup()
+		}
+		w := httptest.NewRecorder()
+		a.router.ServeHTTP(w, httptest.NewRequest(s.method, s.path, strings.NewReader(s.body)))
+		if w.Code != s.want {
+			t.Fatalf("%s: got status %d, want %d: %s", s.name, w.Code, s.want, w.Body.String())
+		}
+	}
+	if len(a.Config.Subscriptions) != 0 || len(a.Config.Outputs) != 0 || len(a.Config.Processors) != 0 {
+		t.Errorf("unexpected leftover config: %v, %v, %v", a.Config.Subscriptions, a.Config.Outputs, a.Config.Processors)
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/fullstorydev/grpcurl"
 	"github.com/gorilla/mux"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
@@ -82,8 +81,15 @@ type App struct {
 	clusteringClient *http.Client
 	// api
 	apiServices  map[string]*lockers.Service
+	apiAuth      *apiAuth
 	isLeader     bool
 	dispatchLock *sync.Mutex
+	// serializes the config changes made through the API
+	// so that a request is validated and applied as a whole.
+	apiConfigLock *sync.Mutex
+	// config reload
+	reloadCmd  *cobra.Command
+	lastReload *configReloadResult
 	// prometheus registry
 	reg *prometheus.Registry
 	//
@@ -132,9 +138,10 @@ func New() *App {
 		activeTargets: make(map[string]struct{}),
 		targetsLockFn: make(map[string]context.CancelFunc),
 		//
-		router:       mux.NewRouter(),
-		apiServices:  make(map[string]*lockers.Service),
-		dispatchLock: new(sync.Mutex),
+		router:        mux.NewRouter(),
+		apiServices:   make(map[string]*lockers.Service),
+		dispatchLock:  new(sync.Mutex),
+		apiConfigLock: new(sync.Mutex),
 
 		Logger:        log.New(io.Discard, "[gnmic] ", log.LstdFlags|log.Lmsgprefix),
 		out:           os.Stdout,
@@ -352,100 +359,6 @@ func (a *App) createCollectorDialOpts() {
 	a.dialOpts = opts
 }
 
-func (a *App) watchConfig() {
-	a.Logger.Printf("watching config...")
-	a.Config.FileConfig.OnConfigChange(a.loadTargets)
-	a.Config.FileConfig.WatchConfig()
-}
-
-func (a *App) loadTargets(e fsnotify.Event) {
-	a.Logger.Printf("got config change notification: %v", e)
-	ctx, cancel := context.WithCancel(a.ctx)
-	defer cancel()
-	err := a.sem.Acquire(ctx, 1)
-	if err != nil {
-		a.Logger.Printf("failed to acquire target loading semaphore: %v", err)
-		return
-	}
-	defer a.sem.Release(1)
-	switch e.Op {
-	case fsnotify.Write, fsnotify.Create:
-		newTargets, err := a.Config.GetTargets()
-		if err != nil && !errors.Is(err, config.ErrNoTargetsFound) {
-			a.Logger.Printf("failed getting targets from new config: %v", err)
-			return
-		}
-		if !a.inCluster() {
-			currentTargets := a.Targets
-			// delete targets
-			for n := range currentTargets {
-				if _, ok := newTargets[n]; !ok {
-					if a.Config.Debug {
-						a.Logger.Printf("target %q deleted from config", n)
-					}
-					err = a.DeleteTarget(a.ctx, n)
-					if err != nil {
-						a.Logger.Printf("failed to delete target %q: %v", n, err)
-					}
-				}
-			}
-			// add targets
-			var limiter *time.Ticker
-			if a.Config.LocalFlags.SubscribeBackoff > 0 {
-				limiter = time.NewTicker(a.Config.LocalFlags.SubscribeBackoff)
-			}
-			for n, tc := range newTargets {
-				if _, ok := currentTargets[n]; !ok {
-					if a.Config.Debug {
-						a.Logger.Printf("target %q added to config", n)
-					}
-					a.AddTargetConfig(tc)
-					a.wg.Add(1)
-					go a.TargetSubscribeStream(a.ctx, tc)
-					if limiter != nil {
-						<-limiter.C
-					}
-				}
-			}
-			if limiter != nil {
-				limiter.Stop()
-			}
-			return
-		}
-		// in a cluster
-		if !a.isLeader {
-			return
-		}
-		// in cluster && leader
-		dist, err := a.getTargetToInstanceMapping(a.ctx)
-		if err != nil {
-			a.Logger.Printf("failed to get target to instance mapping: %v", err)
-			return
-		}
-		// delete targets
-		for t := range dist {
-			if _, ok := newTargets[t]; !ok {
-				err = a.deleteTarget(ctx, t)
-				if err != nil {
-					a.Logger.Printf("failed to delete target %q: %v", t, err)
-					continue
-				}
-			}
-		}
-		// add new targets to cluster
-		a.configLock.Lock()
-		for _, tc := range newTargets {
-			if _, ok := dist[tc.Name]; !ok {
-				err = a.dispatchTarget(a.ctx, tc)
-				if err != nil {
-					a.Logger.Printf("failed to add target %q: %v", tc.Name, err)
-				}
-			}
-		}
-		a.configLock.Unlock()
-	}
-}
-
 func (a *App) startAPIServer() {
 	if a.Config.APIServer == nil {
 		return
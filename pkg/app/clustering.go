@@ -713,6 +713,7 @@ func (a *App) createAPIClient() error {
 				},
 			},
 		}
+		a.setClusteringClientAuth()
 		return nil
 	}
 	// with certs
@@ -731,9 +732,22 @@ func (a *App) createAPIClient() error {
 			TLSClientConfig: tlsConfig,
 		},
 	}
+	a.setClusteringClientAuth()
 	return nil
 }
 
+// setClusteringClientAuth makes the clustering client send the cluster token
+// if the API server authentication is enabled.
+func (a *App) setClusteringClientAuth() {
+	if a.Config.APIServer == nil || a.Config.APIServer.Auth == nil || a.Config.APIServer.Auth.ClusterToken == "" {
+		return
+	}
+	a.clusteringClient.Transport = &bearerTransport{
+		token: a.Config.APIServer.Auth.ClusterToken,
+		base:  a.clusteringClient.Transport,
+	}
+}
+
 func (a *App) clusterRebalanceTargets() error {
 	a.dispatchLock.Lock()
 	defer a.dispatchLock.Unlock()
@@ -789,3 +803,45 @@ func (a *App) clusterRebalanceTargets() error {
 		rebalanceCount++
 	}
 }
+
+// replicateConfigChange sends a config change request to all the cluster members
+// except the local instance.
+func (a *App) replicateConfigChange(ctx context.Context, method, path string, body []byte) error {
+	err := a.createAPIClient()
+	if err != nil {
+		return err
+	}
+	services, err := a.locker.GetServices(ctx, fmt.Sprintf("%s-%s", a.Config.Clustering.ClusterName, apiServiceName), nil)
+	if err != nil {
+		return err
+	}
+	localID := a.Config.Clustering.InstanceName + "-api"
+	numErrs := 0
+	for _, s := range services {
+		if s.ID == localID {
+			continue
+		}
+		url := fmt.Sprintf("%s://%s%s", a.getServiceScheme(s), s.Address, path)
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(apiReplicatedHeader, a.Config.Clustering.InstanceName)
+		rsp, err := a.clusteringClient.Do(req)
+		if err != nil {
+			a.Logger.Printf("failed to replicate %s %s to %q: %v", method, path, s.ID, err)
+			numErrs++
+			continue
+		}
+		rsp.Body.Close()
+		a.Logger.Printf("received response code=%d, for %s %s", rsp.StatusCode, method, url)
+		if rsp.StatusCode >= http.StatusMultipleChoices {
+			numErrs++
+		}
+	}
+	if numErrs == 0 {
+		return nil
+	}
+	return fmt.Errorf("there was %d error(s) while replicating the change", numErrs)
+}
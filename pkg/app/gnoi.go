@@ -0,0 +1,258 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openconfig/grpctunnel/tunnel"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/gnoi"
+)
+
+const defaultGNOIChunkSize = 64 * 1024
+
+// GNOIPreRunE returns the PreRunE function of a gnoi sub command,
+// the local flags are read from the config file keys `<prefix>-<flag name>`
+func (a *App) GNOIPreRunE(prefix string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		a.Config.SetLocalFlagsFromFileWithPrefix(cmd, prefix)
+		a.createCollectorDialOpts()
+		return a.initTunnelServer(tunnel.ServerConfig{
+			AddTargetHandler:    a.tunServerAddTargetHandler,
+			DeleteTargetHandler: a.tunServerDeleteTargetHandler,
+			RegisterHandler:     a.tunServerRegisterHandler,
+			Handler:             a.tunServerHandler,
+		})
+	}
+}
+
+// gnoiRun runs fn against all the configured targets concurrently.
+func (a *App) gnoiRun(fn func(ctx context.Context, t *target.Target) error) error {
+	if a.Config.Format == formatEvent {
+		return errors.New("format event not supported for gNOI RPCs")
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+
+	targetsConfig, err := a.GetTargets()
+	if err != nil {
+		return fmt.Errorf("failed getting targets config: %v", err)
+	}
+	if a.PromptMode {
+		// prompt mode
+		for _, tc := range targetsConfig {
+			a.AddTargetConfig(tc)
+		}
+	}
+	numTargets := len(a.Config.Targets)
+	a.errCh = make(chan error, numTargets*2)
+	a.wg.Add(numTargets)
+	for _, tc := range a.Config.Targets {
+		go func(tc *types.TargetConfig) {
+			defer a.wg.Done()
+			t, err := a.gnoiTarget(ctx, tc)
+			if err != nil {
+				a.logError(fmt.Errorf("target %q: %v", tc.Name, err))
+				return
+			}
+			err = fn(ctx, t)
+			if err != nil {
+				a.logError(fmt.Errorf("target %q: %v", tc.Name, err))
+			}
+		}(tc)
+	}
+	a.wg.Wait()
+	return a.checkErrors()
+}
+
+// gnoiTarget initializes the target and its gRPC connection.
+func (a *App) gnoiTarget(ctx context.Context, tc *types.TargetConfig) (*target.Target, error) {
+	a.operLock.Lock()
+	t, err := a.initTarget(tc)
+	a.operLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// acquire reader lock
+	a.operLock.RLock()
+	err = a.CreateGNMIClient(ctx, t)
+	a.operLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// gnoiUnary sends a unary gNOI RPC to the target using the target timeout,
+// it prints the request if print-request is set.
+func (a *App) gnoiUnary(ctx context.Context, t *target.Target, rpcName string, fields map[string]interface{}) (*dynamicpb.Message, error) {
+	rpc, err := gnoi.NewRPC(rpcName)
+	if err != nil {
+		return nil, err
+	}
+	req, err := rpc.NewRequest(fields)
+	if err != nil {
+		return nil, err
+	}
+	if a.Config.PrintRequest {
+		err = a.printGNOIMsg(t.Config.Name, fmt.Sprintf("%s Request:", rpcName), req)
+		if err != nil {
+			a.logError(fmt.Errorf("target %q: %v", t.Config.Name, err))
+		}
+	}
+	a.Logger.Printf("sending gNOI %s to %s", rpc.Method(), t.Config.Name)
+	ctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
+	defer cancel()
+	rsp := rpc.NewResponse()
+	err = t.Invoke(ctx, rpc.Method(), req, rsp)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v", rpc.Method(), err)
+	}
+	return rsp, nil
+}
+
+// gnoiStream opens a streaming gNOI RPC,
+// it returns the stream and the RPC to build requests and responses.
+func (a *App) gnoiStream(ctx context.Context, t *target.Target, rpcName string) (*gnoi.RPC, grpcClientStream, error) {
+	rpc, err := gnoi.NewRPC(rpcName)
+	if err != nil {
+		return nil, nil, err
+	}
+	a.Logger.Printf("opening gNOI stream %s to %s", rpc.Method(), t.Config.Name)
+	stream, err := t.NewStream(ctx, rpc.StreamDesc(), rpc.Method())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s failed: %v", rpc.Method(), err)
+	}
+	return rpc, stream, nil
+}
+
+// grpcClientStream is the subset of grpc.ClientStream used by the gNOI commands.
+type grpcClientStream interface {
+	SendMsg(m any) error
+	RecvMsg(m any) error
+	CloseSend() error
+}
+
+// recvGNOI receives a single message from a gNOI stream.
+func recvGNOI(rpc *gnoi.RPC, stream grpcClientStream) (*dynamicpb.Message, error) {
+	rsp := rpc.NewResponse()
+	err := stream.RecvMsg(rsp)
+	if err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (a *App) printGNOIMsg(name, msgName string, msg proto.Message) error {
+	a.printLock.Lock()
+	defer a.printLock.Unlock()
+	if a.Config.PrintRequest {
+		fmt.Fprintln(os.Stderr, msgName)
+	}
+	printPrefix := ""
+	if len(a.Config.TargetsList()) > 1 && !a.Config.NoPrefix {
+		printPrefix = fmt.Sprintf("[%s] ", name)
+	}
+	var b []byte
+	var err error
+	switch a.Config.Format {
+	case "prototext":
+		b, err = prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+	case "proto":
+		b, err = proto.Marshal(msg)
+	default: // json, protojson
+		b, err = protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}.Marshal(msg)
+	}
+	if err != nil {
+		a.Logger.Printf("error marshaling message: %v", err)
+		return err
+	}
+	fmt.Fprintf(a.out, "%s\n", indent(printPrefix, string(b)))
+	return nil
+}
+
+// gnoiPaths converts a list of xpaths into gnoi.types.Path in their protoJSON form.
+func gnoiPaths(xpaths []string) ([]interface{}, error) {
+	res := make([]interface{}, 0, len(xpaths))
+	for _, xp := range xpaths {
+		p, err := path.ParsePath(xp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse path %q: %v", xp, err)
+		}
+		res = append(res, gnoi.Path(p))
+	}
+	return res, nil
+}
+
+func newHash(method string) (hash.Hash, error) {
+	switch strings.ToUpper(method) {
+	case "MD5":
+		return md5.New(), nil
+	case "SHA256":
+		return sha256.New(), nil
+	case "SHA512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash method %q", method)
+	}
+}
+
+// sendChunks reads r and sends its content in chunks of size chunkSize
+// as the bytes field called name of the RPC request.
+func sendChunks(r io.Reader, chunkSize uint64, rpc *gnoi.RPC, name string, stream grpcClientStream) (uint64, error) {
+	if chunkSize == 0 {
+		chunkSize = defaultGNOIChunkSize
+	}
+	buf := make([]byte, chunkSize)
+	var total uint64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			req, rerr := rpc.NewBytesRequest(name, buf[:n])
+			if rerr != nil {
+				return total, rerr
+			}
+			if serr := stream.SendMsg(req); serr != nil {
+				return total, serr
+			}
+			total += uint64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func (a *App) bindGNOIFlags(cmd *cobra.Command, prefix string) {
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", prefix, flag.Name), flag)
+	})
+}
@@ -0,0 +1,341 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/gnoi"
+)
+
+const defaultCertValidity = 365 * 24 * time.Hour
+
+// cert get
+
+func (a *App) GNOICertGetRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOICertGetFlags(cmd)
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.certificate.CertificateManagement.GetCertificates", nil)
+		if err != nil {
+			return err
+		}
+		if a.Config.GnoiCertID == "" {
+			return a.printGNOIMsg(t.Config.Name, "GetCertificates Response:", rsp)
+		}
+		infos := gnoi.Get(rsp, "certificate_info")
+		if !infos.IsValid() {
+			return fmt.Errorf("certificate %q not found", a.Config.GnoiCertID)
+		}
+		for i := 0; i < infos.List().Len(); i++ {
+			info := infos.List().Get(i).Message()
+			if gnoi.Get(info, "certificate_id").String() == a.Config.GnoiCertID {
+				return a.printGNOIMsg(t.Config.Name, "GetCertificates Response:", info.Interface())
+			}
+		}
+		return fmt.Errorf("certificate %q not found", a.Config.GnoiCertID)
+	})
+}
+
+func (a *App) InitGNOICertGetFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiCertID, "id", "", "", "only show the certificate with this ID")
+	a.bindGNOIFlags(cmd, "gnoi-cert-get")
+}
+
+// cert install
+
+func (a *App) GNOICertInstallRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOICertInstallFlags(cmd)
+	return a.gnoiCertLoad(false)
+}
+
+func (a *App) InitGNOICertInstallFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	a.initGNOICertLoadFlags(cmd)
+	a.bindGNOIFlags(cmd, "gnoi-cert-install")
+}
+
+// cert rotate
+
+func (a *App) GNOICertRotateRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOICertRotateFlags(cmd)
+	return a.gnoiCertLoad(true)
+}
+
+func (a *App) InitGNOICertRotateFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	a.initGNOICertLoadFlags(cmd)
+	a.bindGNOIFlags(cmd, "gnoi-cert-rotate")
+}
+
+func (a *App) initGNOICertLoadFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&a.Config.GnoiCertID, "id", "", "", "certificate ID")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertCert, "cert", "", "", "certificate file to load, if not set a CSR is generated by the target and signed using --ca-cert and --ca-key")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertKey, "key", "", "", "private key file of the certificate to load")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertCACert, "ca-cert", "", "", "CA certificate file, sent to the target as CA bundle")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertCAKey, "ca-key", "", "", "CA private key file, used to sign the CSR generated by the target")
+	cmd.Flags().DurationVarP(&a.Config.GnoiCertValidity, "validity", "", defaultCertValidity, "validity of the certificate signed by gnmic")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertKeyType, "key-type", "", "KT_RSA", "CSR key type")
+	cmd.Flags().Uint32VarP(&a.Config.GnoiCertKeySize, "key-size", "", 2048, "CSR minimum key size")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertCommonName, "common-name", "", "", "CSR common name, defaults to the target name")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertCountry, "country", "", "", "CSR country")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertState, "state", "", "", "CSR state")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertCity, "city", "", "", "CSR city")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertOrg, "org", "", "", "CSR organization")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertOrgUnit, "org-unit", "", "", "CSR organizational unit")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertIPAddress, "ip-address", "", "", "CSR IP address, defaults to the target address IP")
+	cmd.Flags().StringVarP(&a.Config.GnoiCertEmailID, "email-id", "", "", "CSR email ID")
+}
+
+// gnoiCertLoad runs the Install or Rotate RPCs.
+// If a certificate and a key are provided, they are loaded as is,
+// otherwise a CSR is generated by the target, signed using the CA cert and key
+// then loaded back to the target.
+func (a *App) gnoiCertLoad(rotate bool) error {
+	if a.Config.GnoiCertID == "" {
+		return errors.New("missing --id")
+	}
+	var certPEM, keyPEM, caPEM []byte
+	var err error
+	if a.Config.GnoiCertCACert != "" {
+		caPEM, err = os.ReadFile(a.Config.GnoiCertCACert)
+		if err != nil {
+			return err
+		}
+	}
+	var ca *tls.Certificate
+	switch {
+	case a.Config.GnoiCertCert != "" && a.Config.GnoiCertKey != "":
+		certPEM, err = os.ReadFile(a.Config.GnoiCertCert)
+		if err != nil {
+			return err
+		}
+		keyPEM, err = os.ReadFile(a.Config.GnoiCertKey)
+		if err != nil {
+			return err
+		}
+	case a.Config.GnoiCertCert != "" || a.Config.GnoiCertKey != "":
+		return errors.New("--cert and --key must be set together")
+	case a.Config.GnoiCertCACert != "" && a.Config.GnoiCertCAKey != "":
+		c, err := tls.LoadX509KeyPair(a.Config.GnoiCertCACert, a.Config.GnoiCertCAKey)
+		if err != nil {
+			return fmt.Errorf("failed to load CA key pair: %v", err)
+		}
+		c.Leaf, err = x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("failed to parse CA certificate: %v", err)
+		}
+		ca = &c
+	default:
+		return errors.New("either --cert and --key or --ca-cert and --ca-key must be set")
+	}
+	rpcName := "gnoi.certificate.CertificateManagement.Install"
+	rspOneof := "install_response"
+	if rotate {
+		rpcName = "gnoi.certificate.CertificateManagement.Rotate"
+		rspOneof = "rotate_response"
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rpc, stream, err := a.gnoiStream(ctx, t, rpcName)
+		if err != nil {
+			return err
+		}
+		send := func(fields map[string]interface{}) error {
+			req, err := rpc.NewRequest(fields)
+			if err != nil {
+				return err
+			}
+			if a.Config.PrintRequest {
+				err = a.printGNOIMsg(t.Config.Name, fmt.Sprintf("%s Request:", rpcName), req)
+				if err != nil {
+					a.logError(fmt.Errorf("target %q: %v", t.Config.Name, err))
+				}
+			}
+			return stream.SendMsg(req)
+		}
+		tCertPEM := certPEM
+		if ca != nil {
+			err = send(map[string]interface{}{"generate_csr": a.gnoiCSRRequest(t)})
+			if err != nil {
+				return err
+			}
+			rsp, err := recvGNOI(rpc, stream)
+			if err != nil {
+				return err
+			}
+			if gnoi.WhichOneof(rsp, rspOneof) != "generated_csr" {
+				return fmt.Errorf("unexpected response, expecting generated_csr")
+			}
+			csr := gnoi.Get(rsp, "generated_csr", "csr", "csr")
+			if !csr.IsValid() {
+				return errors.New("target returned an empty CSR")
+			}
+			tCertPEM, err = signCSR(csr.Bytes(), ca, a.Config.GnoiCertValidity)
+			if err != nil {
+				return fmt.Errorf("failed to sign CSR: %v", err)
+			}
+		}
+		load := map[string]interface{}{
+			"certificate_id": a.Config.GnoiCertID,
+			"certificate": map[string]interface{}{
+				"type":        "CT_X509",
+				"certificate": tCertPEM,
+			},
+		}
+		if keyPEM != nil {
+			pubPEM, err := publicKeyPEM(tCertPEM)
+			if err != nil {
+				return err
+			}
+			load["key_pair"] = map[string]interface{}{
+				"private_key": keyPEM,
+				"public_key":  pubPEM,
+			}
+		}
+		if caPEM != nil {
+			load["ca_certificates"] = []interface{}{
+				map[string]interface{}{
+					"type":        "CT_X509",
+					"certificate": caPEM,
+				},
+			}
+		}
+		err = send(map[string]interface{}{"load_certificate": load})
+		if err != nil {
+			return err
+		}
+		rsp, err := recvGNOI(rpc, stream)
+		if err != nil {
+			return err
+		}
+		if gnoi.WhichOneof(rsp, rspOneof) != "load_certificate" {
+			return fmt.Errorf("unexpected response, expecting load_certificate")
+		}
+		if rotate {
+			err = send(map[string]interface{}{"finalize_rotation": map[string]interface{}{}})
+			if err != nil {
+				return err
+			}
+		}
+		err = stream.CloseSend()
+		if err != nil {
+			return err
+		}
+		action := "installed"
+		if rotate {
+			action = "rotated"
+		}
+		a.printGNOIText(t.Config.Name, fmt.Sprintf("certificate %q %s", a.Config.GnoiCertID, action))
+		// drain the stream
+		_, err = recvGNOI(rpc, stream)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (a *App) gnoiCSRRequest(t *target.Target) map[string]interface{} {
+	commonName := a.Config.GnoiCertCommonName
+	if commonName == "" {
+		commonName = t.Config.Name
+	}
+	ipAddress := a.Config.GnoiCertIPAddress
+	if ipAddress == "" {
+		ipAddress = strings.Split(t.Config.Address, ":")[0]
+	}
+	return map[string]interface{}{
+		"certificate_id": a.Config.GnoiCertID,
+		"csr_params": map[string]interface{}{
+			"type":                "CT_X509",
+			"min_key_size":        a.Config.GnoiCertKeySize,
+			"key_type":            strings.ToUpper(a.Config.GnoiCertKeyType),
+			"common_name":         commonName,
+			"country":             a.Config.GnoiCertCountry,
+			"state":               a.Config.GnoiCertState,
+			"city":                a.Config.GnoiCertCity,
+			"organization":        a.Config.GnoiCertOrg,
+			"organizational_unit": a.Config.GnoiCertOrgUnit,
+			"ip_address":          ipAddress,
+			"email_id":            a.Config.GnoiCertEmailID,
+		},
+	}
+}
+
+// signCSR signs a PEM encoded CSR using the CA, it returns the PEM encoded certificate.
+func signCSR(csrPEM []byte, ca *tls.Certificate, validity time.Duration) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		return nil, errors.New("failed to decode CSR PEM")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	err = csr.CheckSignature()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	if validity <= 0 {
+		validity = defaultCertValidity
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        csr.Subject,
+		DNSNames:       csr.DNSNames,
+		IPAddresses:    csr.IPAddresses,
+		EmailAddresses: csr.EmailAddresses,
+		URIs:           csr.URIs,
+		NotBefore:      now,
+		NotAfter:       now.Add(validity),
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Leaf, csr.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// publicKeyPEM returns the PEM encoded public key of a PEM encoded certificate.
+func publicKeyPEM(certPEM []byte) ([]byte, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("failed to decode certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
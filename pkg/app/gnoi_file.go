@@ -0,0 +1,274 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/gnoi"
+)
+
+// file get
+
+func (a *App) GNOIFileGetRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIFileGetFlags(cmd)
+	if len(a.Config.GnoiFileGetFile) == 0 {
+		return errors.New("missing --file")
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		for _, file := range a.Config.GnoiFileGetFile {
+			dst := filepath.Join(a.Config.GnoiFileGetDst, t.Config.Name, file)
+			n, err := a.gnoiFileGet(ctx, t, file, dst)
+			if err != nil {
+				return fmt.Errorf("failed to get file %q: %v", file, err)
+			}
+			a.printGNOIText(t.Config.Name, fmt.Sprintf("file %q saved to %q (%d bytes)", file, dst, n))
+		}
+		return nil
+	})
+}
+
+func (a *App) gnoiFileGet(ctx context.Context, t *target.Target, file, dst string) (int64, error) {
+	rpc, stream, err := a.gnoiStream(ctx, t, "gnoi.file.File.Get")
+	if err != nil {
+		return 0, err
+	}
+	req, err := rpc.NewRequest(map[string]interface{}{"remote_file": file})
+	if err != nil {
+		return 0, err
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return 0, err
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return 0, err
+	}
+	// the content is written to a temporary file and hashed on the fly
+	// using all the supported methods since the one used by the target
+	// is only known at the end of the stream.
+	// The file is renamed to dst once the hash is verified.
+	err = os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	hashes := make(map[string]hash.Hash, 3)
+	writers := []io.Writer{f}
+	for _, m := range []string{"MD5", "SHA256", "SHA512"} {
+		hashes[m], _ = newHash(m)
+		writers = append(writers, hashes[m])
+	}
+	w := io.MultiWriter(writers...)
+	var n int64
+	for {
+		rsp, err := recvGNOI(rpc, stream)
+		if err == io.EOF {
+			return 0, errors.New("stream ended without a hash")
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch gnoi.WhichOneof(rsp, "response") {
+		case "contents":
+			b := gnoi.Get(rsp, "contents").Bytes()
+			_, err = w.Write(b)
+			if err != nil {
+				return 0, err
+			}
+			n += int64(len(b))
+		case "hash":
+			ht := gnoi.Get(rsp, "hash").Message()
+			method := gnoi.EnumName(ht, "method")
+			h, ok := hashes[method]
+			if !ok {
+				return 0, fmt.Errorf("unsupported hash method %q", method)
+			}
+			if sum := gnoi.Get(ht, "hash"); !sum.IsValid() || !bytes.Equal(h.Sum(nil), sum.Bytes()) {
+				return 0, errors.New("hash mismatch")
+			}
+			err = f.Close()
+			if err != nil {
+				return 0, err
+			}
+			return n, os.Rename(f.Name(), dst)
+		}
+	}
+}
+
+func (a *App) InitGNOIFileGetFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringArrayVarP(&a.Config.GnoiFileGetFile, "file", "", []string{}, "path of the remote file to get")
+	cmd.Flags().StringVarP(&a.Config.GnoiFileGetDst, "dst", "", ".", "local directory to save the files in, the files are saved under <dst>/<target name>/<remote file>")
+	a.bindGNOIFlags(cmd, "gnoi-file-get")
+}
+
+// file put
+
+func (a *App) GNOIFilePutRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIFilePutFlags(cmd)
+	if a.Config.GnoiFilePutFile == "" {
+		return errors.New("missing --file")
+	}
+	if a.Config.GnoiFilePutDst == "" {
+		return errors.New("missing --dst")
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		n, err := a.gnoiFilePut(ctx, t)
+		if err != nil {
+			return fmt.Errorf("failed to put file %q: %v", a.Config.GnoiFilePutFile, err)
+		}
+		a.printGNOIText(t.Config.Name, fmt.Sprintf("file %q written to %q (%d bytes)", a.Config.GnoiFilePutFile, a.Config.GnoiFilePutDst, n))
+		return nil
+	})
+}
+
+func (a *App) gnoiFilePut(ctx context.Context, t *target.Target) (uint64, error) {
+	h, err := newHash(a.Config.GnoiFilePutHashMethod)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(a.Config.GnoiFilePutFile)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	rpc, stream, err := a.gnoiStream(ctx, t, "gnoi.file.File.Put")
+	if err != nil {
+		return 0, err
+	}
+	req, err := rpc.NewRequest(map[string]interface{}{
+		"open": map[string]interface{}{
+			"remote_file": a.Config.GnoiFilePutDst,
+			"permissions": a.Config.GnoiFilePutPermissions,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return 0, err
+	}
+	n, err := sendChunks(io.TeeReader(f, h), a.Config.GnoiFilePutChunkSize, rpc, "contents", stream)
+	if err != nil {
+		return n, err
+	}
+	req, err = rpc.NewRequest(map[string]interface{}{
+		"hash": map[string]interface{}{
+			"method": strings.ToUpper(a.Config.GnoiFilePutHashMethod),
+			"hash":   h.Sum(nil),
+		},
+	})
+	if err != nil {
+		return n, err
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return n, err
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return n, err
+	}
+	_, err = recvGNOI(rpc, stream)
+	return n, err
+}
+
+func (a *App) InitGNOIFilePutFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiFilePutFile, "file", "", "", "path of the local file to put")
+	cmd.Flags().StringVarP(&a.Config.GnoiFilePutDst, "dst", "", "", "path of the remote file")
+	cmd.Flags().Uint32VarP(&a.Config.GnoiFilePutPermissions, "permissions", "", 644, "remote file permissions, in UNIX octal notation, e.g: 644")
+	cmd.Flags().Uint64VarP(&a.Config.GnoiFilePutChunkSize, "chunk-size", "", defaultGNOIChunkSize, "size of the chunks the file is sent in")
+	cmd.Flags().StringVarP(&a.Config.GnoiFilePutHashMethod, "hash-method", "", "MD5", "hash method, one of: MD5, SHA256, SHA512")
+	a.bindGNOIFlags(cmd, "gnoi-file-put")
+}
+
+// file stat
+
+func (a *App) GNOIFileStatRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIFileStatFlags(cmd)
+	if len(a.Config.GnoiFileStatPath) == 0 {
+		return errors.New("missing --path")
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		for _, p := range a.Config.GnoiFileStatPath {
+			rsp, err := a.gnoiUnary(ctx, t, "gnoi.file.File.Stat", map[string]interface{}{"path": p})
+			if err != nil {
+				return err
+			}
+			err = a.printGNOIMsg(t.Config.Name, "Stat Response:", rsp)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (a *App) InitGNOIFileStatFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringArrayVarP(&a.Config.GnoiFileStatPath, "path", "", []string{}, "path of the remote file or directory")
+	a.bindGNOIFlags(cmd, "gnoi-file-stat")
+}
+
+// file remove
+
+func (a *App) GNOIFileRemoveRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIFileRemoveFlags(cmd)
+	if len(a.Config.GnoiFileRemovePath) == 0 {
+		return errors.New("missing --path")
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		for _, p := range a.Config.GnoiFileRemovePath {
+			_, err := a.gnoiUnary(ctx, t, "gnoi.file.File.Remove", map[string]interface{}{"remote_file": p})
+			if err != nil {
+				return err
+			}
+			a.printGNOIText(t.Config.Name, fmt.Sprintf("file %q removed", p))
+		}
+		return nil
+	})
+}
+
+func (a *App) InitGNOIFileRemoveFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringArrayVarP(&a.Config.GnoiFileRemovePath, "path", "", []string{}, "path of the remote file to remove")
+	a.bindGNOIFlags(cmd, "gnoi-file-remove")
+}
+
+// printGNOIText prints a line of text prefixed with the target name if
+// there is more than one target.
+func (a *App) printGNOIText(name, s string) {
+	a.printLock.Lock()
+	defer a.printLock.Unlock()
+	if len(a.Config.TargetsList()) > 1 && !a.Config.NoPrefix {
+		s = fmt.Sprintf("[%s] %s", name, s)
+	}
+	fmt.Fprintln(a.out, s)
+}
@@ -0,0 +1,79 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/gnoi"
+)
+
+// healthz get
+
+func (a *App) GNOIHealthzGetRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIHealthzGetFlags(cmd)
+	if a.Config.GnoiHealthzPath == "" {
+		return errors.New("missing --path")
+	}
+	p, err := path.ParsePath(a.Config.GnoiHealthzPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse path %q: %v", a.Config.GnoiHealthzPath, err)
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.healthz.Healthz.Get",
+			map[string]interface{}{"path": gnoi.Path(p)})
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "Healthz Get Response:", rsp)
+	})
+}
+
+func (a *App) InitGNOIHealthzGetFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiHealthzPath, "path", "", "", "path of the component, in xpath format")
+	a.bindGNOIFlags(cmd, "gnoi-healthz-get")
+}
+
+// healthz list
+
+func (a *App) GNOIHealthzListRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIHealthzListFlags(cmd)
+	if a.Config.GnoiHealthzPath == "" {
+		return errors.New("missing --path")
+	}
+	p, err := path.ParsePath(a.Config.GnoiHealthzPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse path %q: %v", a.Config.GnoiHealthzPath, err)
+	}
+	fields := map[string]interface{}{
+		"path":                 gnoi.Path(p),
+		"include_acknowledged": a.Config.GnoiHealthzIncludeAcknowledged,
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.healthz.Healthz.List", fields)
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "Healthz List Response:", rsp)
+	})
+}
+
+func (a *App) InitGNOIHealthzListFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiHealthzPath, "path", "", "", "path of the component, in xpath format")
+	cmd.Flags().BoolVarP(&a.Config.GnoiHealthzIncludeAcknowledged, "include-acknowledged", "", false, "include the acknowledged events")
+	a.bindGNOIFlags(cmd, "gnoi-healthz-list")
+}
@@ -0,0 +1,179 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/gnoi"
+)
+
+// os install
+
+func (a *App) GNOIOSInstallRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIOSInstallFlags(cmd)
+	if a.Config.GnoiOSVersion == "" {
+		return errors.New("missing --version")
+	}
+	if a.Config.GnoiOSPackage == "" {
+		return errors.New("missing --pkg")
+	}
+	return a.gnoiRun(a.gnoiOSInstall)
+}
+
+func (a *App) gnoiOSInstall(ctx context.Context, t *target.Target) error {
+	rpc, stream, err := a.gnoiStream(ctx, t, "gnoi.os.OS.Install")
+	if err != nil {
+		return err
+	}
+	req, err := rpc.NewRequest(map[string]interface{}{
+		"transfer_request": map[string]interface{}{
+			"version":            a.Config.GnoiOSVersion,
+			"standby_supervisor": a.Config.GnoiOSStandby,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return err
+	}
+	rsp, err := recvGNOI(rpc, stream)
+	if err != nil {
+		return err
+	}
+	switch gnoi.WhichOneof(rsp, "response") {
+	case "transfer_ready":
+	case "validated":
+		// the target already has the requested version
+		stream.CloseSend()
+		return a.printGNOIMsg(t.Config.Name, "Install Response:", rsp)
+	case "install_error":
+		stream.CloseSend()
+		return installError(rsp)
+	default:
+		stream.CloseSend()
+		return fmt.Errorf("unexpected response: %v", rsp)
+	}
+	// the target is ready to receive the package,
+	// the responses (progress, validated or error) are read while the package is sent.
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		for {
+			rsp, err := recvGNOI(rpc, stream)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			switch gnoi.WhichOneof(rsp, "response") {
+			case "transfer_progress", "sync_progress":
+				if a.Config.Debug {
+					a.Logger.Printf("target %q: install progress: %v", t.Config.Name, rsp)
+				}
+			case "validated":
+				errCh <- a.printGNOIMsg(t.Config.Name, "Install Response:", rsp)
+				return
+			case "install_error":
+				errCh <- installError(rsp)
+				return
+			}
+		}
+	}()
+	f, err := os.Open(a.Config.GnoiOSPackage)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := sendChunks(f, a.Config.GnoiOSChunkSize, rpc, "transfer_content", stream)
+	if err != nil {
+		return fmt.Errorf("failed to send package after %d bytes: %v", n, err)
+	}
+	a.Logger.Printf("target %q: sent %d bytes", t.Config.Name, n)
+	req, err = rpc.NewRequest(map[string]interface{}{"transfer_end": map[string]interface{}{}})
+	if err != nil {
+		return err
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return err
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return err
+	}
+	return <-errCh
+}
+
+func installError(rsp *dynamicpb.Message) error {
+	ie := gnoi.Get(rsp, "install_error").Message()
+	return fmt.Errorf("install error %s: %s", gnoi.EnumName(ie, "type"), gnoi.Get(ie, "detail").String())
+}
+
+func (a *App) InitGNOIOSInstallFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiOSVersion, "version", "", "", "version of the OS package")
+	cmd.Flags().StringVarP(&a.Config.GnoiOSPackage, "pkg", "", "", "path to the OS package file")
+	cmd.Flags().BoolVarP(&a.Config.GnoiOSStandby, "standby", "", false, "install the package on the standby supervisor")
+	cmd.Flags().Uint64VarP(&a.Config.GnoiOSChunkSize, "chunk-size", "", defaultGNOIChunkSize, "size of the chunks the package is sent in")
+	a.bindGNOIFlags(cmd, "gnoi-os-install")
+}
+
+// os activate
+
+func (a *App) GNOIOSActivateRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIOSActivateFlags(cmd)
+	if a.Config.GnoiOSVersion == "" {
+		return errors.New("missing --version")
+	}
+	fields := map[string]interface{}{
+		"version":            a.Config.GnoiOSVersion,
+		"standby_supervisor": a.Config.GnoiOSStandby,
+		"no_reboot":          a.Config.GnoiOSNoReboot,
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.os.OS.Activate", fields)
+		if err != nil {
+			return err
+		}
+		if gnoi.WhichOneof(rsp, "response") == "activate_error" {
+			ae := gnoi.Get(rsp, "activate_error").Message()
+			return fmt.Errorf("activate error %s: %s", gnoi.EnumName(ae, "type"), gnoi.Get(ae, "detail").String())
+		}
+		return a.printGNOIMsg(t.Config.Name, "Activate Response:", rsp)
+	})
+}
+
+func (a *App) InitGNOIOSActivateFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiOSVersion, "version", "", "", "version of the OS package to activate")
+	cmd.Flags().BoolVarP(&a.Config.GnoiOSStandby, "standby", "", false, "activate the package on the standby supervisor")
+	cmd.Flags().BoolVarP(&a.Config.GnoiOSNoReboot, "no-reboot", "", false, "do not reboot after activation")
+	a.bindGNOIFlags(cmd, "gnoi-os-activate")
+}
+
+// os verify
+
+func (a *App) GNOIOSVerifyRunE(cmd *cobra.Command, args []string) error {
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.os.OS.Verify", nil)
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "Verify Response:", rsp)
+	})
+}
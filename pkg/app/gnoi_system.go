@@ -0,0 +1,242 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+)
+
+// time
+
+func (a *App) GNOITimeRunE(cmd *cobra.Command, args []string) error {
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.system.System.Time", nil)
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "Time Response:", rsp)
+	})
+}
+
+// ping
+
+func (a *App) GNOIPingRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIPingFlags(cmd)
+	if a.Config.GnoiPingDestination == "" {
+		return errors.New("missing --destination")
+	}
+	fields := map[string]interface{}{
+		"destination":      a.Config.GnoiPingDestination,
+		"source":           a.Config.GnoiPingSource,
+		"count":            a.Config.GnoiPingCount,
+		"interval":         a.Config.GnoiPingInterval.Nanoseconds(),
+		"wait":             a.Config.GnoiPingWait.Nanoseconds(),
+		"size":             a.Config.GnoiPingSize,
+		"do_not_fragment":  a.Config.GnoiPingDoNotFragment,
+		"do_not_resolve":   a.Config.GnoiPingDoNotResolve,
+		"l3protocol":       strings.ToUpper(a.Config.GnoiPingL3Protocol),
+		"network_instance": a.Config.GnoiPingNetworkInstance,
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		return a.gnoiServerStream(ctx, t, "gnoi.system.System.Ping", fields, "Ping Response:")
+	})
+}
+
+func (a *App) InitGNOIPingFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiPingDestination, "destination", "", "", "destination address to ping")
+	cmd.Flags().StringVarP(&a.Config.GnoiPingSource, "source", "", "", "source address to ping from")
+	cmd.Flags().Int32VarP(&a.Config.GnoiPingCount, "count", "", 0, "number of packets")
+	cmd.Flags().DurationVarP(&a.Config.GnoiPingInterval, "interval", "", 0, "duration between ping requests")
+	cmd.Flags().DurationVarP(&a.Config.GnoiPingWait, "wait", "", 0, "duration to wait for a response")
+	cmd.Flags().Int32VarP(&a.Config.GnoiPingSize, "size", "", 0, "size of the ping request")
+	cmd.Flags().BoolVarP(&a.Config.GnoiPingDoNotFragment, "do-not-fragment", "", false, "set the do not fragment bit (IPv4)")
+	cmd.Flags().BoolVarP(&a.Config.GnoiPingDoNotResolve, "do-not-resolve", "", false, "do not try to resolve the address returned")
+	cmd.Flags().StringVarP(&a.Config.GnoiPingL3Protocol, "l3protocol", "", "", "layer 3 protocol, one of: IPV4, IPV6")
+	cmd.Flags().StringVarP(&a.Config.GnoiPingNetworkInstance, "network-instance", "", "", "network instance to ping from")
+	a.bindGNOIFlags(cmd, "gnoi-system-ping")
+}
+
+// traceroute
+
+func (a *App) GNOITracerouteRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOITracerouteFlags(cmd)
+	if a.Config.GnoiTracerouteDestination == "" {
+		return errors.New("missing --destination")
+	}
+	fields := map[string]interface{}{
+		"destination":       a.Config.GnoiTracerouteDestination,
+		"source":            a.Config.GnoiTracerouteSource,
+		"initial_ttl":       a.Config.GnoiTracerouteInitialTTL,
+		"max_ttl":           a.Config.GnoiTracerouteMaxTTL,
+		"wait":              a.Config.GnoiTracerouteWait.Nanoseconds(),
+		"do_not_fragment":   a.Config.GnoiTracerouteDoNotFragment,
+		"do_not_resolve":    a.Config.GnoiTracerouteDoNotResolve,
+		"l3protocol":        strings.ToUpper(a.Config.GnoiTracerouteL3Protocol),
+		"l4protocol":        strings.ToUpper(a.Config.GnoiTracerouteL4Protocol),
+		"do_not_lookup_asn": a.Config.GnoiTracerouteDoNotLookupASN,
+		"network_instance":  a.Config.GnoiTracerouteNetworkInstance,
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		return a.gnoiServerStream(ctx, t, "gnoi.system.System.Traceroute", fields, "Traceroute Response:")
+	})
+}
+
+func (a *App) InitGNOITracerouteFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiTracerouteDestination, "destination", "", "", "traceroute destination address")
+	cmd.Flags().StringVarP(&a.Config.GnoiTracerouteSource, "source", "", "", "traceroute source address")
+	cmd.Flags().Uint32VarP(&a.Config.GnoiTracerouteInitialTTL, "initial-ttl", "", 0, "initial TTL")
+	cmd.Flags().Int32VarP(&a.Config.GnoiTracerouteMaxTTL, "max-ttl", "", 0, "maximum number of hops")
+	cmd.Flags().DurationVarP(&a.Config.GnoiTracerouteWait, "wait", "", 0, "duration to wait for a response")
+	cmd.Flags().BoolVarP(&a.Config.GnoiTracerouteDoNotFragment, "do-not-fragment", "", false, "set the do not fragment bit (IPv4)")
+	cmd.Flags().BoolVarP(&a.Config.GnoiTracerouteDoNotResolve, "do-not-resolve", "", false, "do not try to resolve the addresses returned")
+	cmd.Flags().StringVarP(&a.Config.GnoiTracerouteL3Protocol, "l3protocol", "", "", "layer 3 protocol, one of: IPV4, IPV6")
+	cmd.Flags().StringVarP(&a.Config.GnoiTracerouteL4Protocol, "l4protocol", "", "ICMP", "layer 4 protocol, one of: ICMP, TCP, UDP")
+	cmd.Flags().BoolVarP(&a.Config.GnoiTracerouteDoNotLookupASN, "do-not-lookup-asn", "", false, "do not try to lookup ASN")
+	cmd.Flags().StringVarP(&a.Config.GnoiTracerouteNetworkInstance, "network-instance", "", "", "network instance to run the traceroute from")
+	a.bindGNOIFlags(cmd, "gnoi-system-traceroute")
+}
+
+// reboot
+
+func (a *App) GNOIRebootRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIRebootFlags(cmd)
+	subcomponents, err := gnoiPaths(a.Config.GnoiRebootSubcomponent)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{
+		"method":        strings.ToUpper(a.Config.GnoiRebootMethod),
+		"delay":         uint64(a.Config.GnoiRebootDelay.Nanoseconds()),
+		"message":       a.Config.GnoiRebootMessage,
+		"subcomponents": subcomponents,
+		"force":         a.Config.GnoiRebootForce,
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.system.System.Reboot", fields)
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "Reboot Response:", rsp)
+	})
+}
+
+func (a *App) InitGNOIRebootFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiRebootMethod, "method", "", "COLD", "reboot method, one of: COLD, POWERDOWN, HALT, WARM, NSF, POWERUP")
+	cmd.Flags().DurationVarP(&a.Config.GnoiRebootDelay, "delay", "", 0, "delay before rebooting")
+	cmd.Flags().StringVarP(&a.Config.GnoiRebootMessage, "message", "", "", "informational reason for the reboot")
+	cmd.Flags().StringArrayVarP(&a.Config.GnoiRebootSubcomponent, "subcomponent", "", []string{}, "path of a subcomponent to reboot, in xpath format")
+	cmd.Flags().BoolVarP(&a.Config.GnoiRebootForce, "force", "", false, "force reboot if sanity checks fail")
+	a.bindGNOIFlags(cmd, "gnoi-system-reboot")
+}
+
+// reboot-status
+
+func (a *App) GNOIRebootStatusRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOIRebootStatusFlags(cmd)
+	subcomponents, err := gnoiPaths(a.Config.GnoiRebootSubcomponent)
+	if err != nil {
+		return err
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.system.System.RebootStatus",
+			map[string]interface{}{"subcomponents": subcomponents})
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "RebootStatus Response:", rsp)
+	})
+}
+
+func (a *App) InitGNOIRebootStatusFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringArrayVarP(&a.Config.GnoiRebootSubcomponent, "subcomponent", "", []string{}, "path of a subcomponent, in xpath format")
+	a.bindGNOIFlags(cmd, "gnoi-system-reboot-status")
+}
+
+// cancel-reboot
+
+func (a *App) GNOICancelRebootRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitGNOICancelRebootFlags(cmd)
+	subcomponents, err := gnoiPaths(a.Config.GnoiRebootSubcomponent)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{
+		"message":       a.Config.GnoiRebootMessage,
+		"subcomponents": subcomponents,
+	}
+	return a.gnoiRun(func(ctx context.Context, t *target.Target) error {
+		rsp, err := a.gnoiUnary(ctx, t, "gnoi.system.System.CancelReboot", fields)
+		if err != nil {
+			return err
+		}
+		return a.printGNOIMsg(t.Config.Name, "CancelReboot Response:", rsp)
+	})
+}
+
+func (a *App) InitGNOICancelRebootFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	cmd.Flags().StringVarP(&a.Config.GnoiRebootMessage, "message", "", "", "informational reason for the cancellation")
+	cmd.Flags().StringArrayVarP(&a.Config.GnoiRebootSubcomponent, "subcomponent", "", []string{}, "path of a subcomponent, in xpath format")
+	a.bindGNOIFlags(cmd, "gnoi-system-cancel-reboot")
+}
+
+// gnoiServerStream sends a server streaming RPC request and prints the
+// received responses until the target closes the stream.
+func (a *App) gnoiServerStream(ctx context.Context, t *target.Target, rpcName string, fields map[string]interface{}, msgName string) error {
+	rpc, stream, err := a.gnoiStream(ctx, t, rpcName)
+	if err != nil {
+		return err
+	}
+	req, err := rpc.NewRequest(fields)
+	if err != nil {
+		return err
+	}
+	if a.Config.PrintRequest {
+		err = a.printGNOIMsg(t.Config.Name, fmt.Sprintf("%s Request:", rpcName), req)
+		if err != nil {
+			a.logError(fmt.Errorf("target %q: %v", t.Config.Name, err))
+		}
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return err
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	for {
+		rsp, err := recvGNOI(rpc, stream)
+		if err == io.EOF {
+			a.Logger.Printf("target %q: %s done after %s", t.Config.Name, rpc.Method(), time.Since(start))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = a.printGNOIMsg(t.Config.Name, msgName, rsp)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -58,6 +58,28 @@ var clusterIsLeader = prometheus.NewGauge(prometheus.GaugeOpts{
 	Help:      "Has value 1 if this gnmic instance is the cluster leader, 0 otherwise",
 })
 
+// config reload
+var configReloadCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "config",
+	Name:      "reloads_total",
+	Help:      "Total number of config reload attempts",
+}, []string{"result"})
+
+var configLastReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "config",
+	Name:      "last_reload_successful",
+	Help:      "Has value 1 if the last config reload attempt succeeded, 0 otherwise",
+})
+
+var configLastReloadSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "config",
+	Name:      "last_reload_success_timestamp_seconds",
+	Help:      "Timestamp of the last successful config reload",
+})
+
 func (a *App) registerTargetMetrics() {
 	err := a.reg.Register(targetUPMetric)
 	if err != nil {
@@ -28,7 +28,11 @@ func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types
 		if outType, ok := cfg["type"]; ok {
 			a.Logger.Printf("starting output type %s", outType)
 			if initializer, ok := outputs.Outputs[outType.(string)]; ok {
-				out := initializer()
+				out, err := outputs.WithBuffer(initializer(), cfg)
+				if err != nil {
+					a.Logger.Printf("failed to init output %q: %v", name, err)
+					return
+				}
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
@@ -0,0 +1,484 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+const (
+	reloadTriggerFile   = "file"
+	reloadTriggerSignal = "signal"
+	reloadTriggerAPI    = "api"
+
+	configWatchDebounce = time.Second
+)
+
+type configReloadResult struct {
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	Trigger   string    `json:"trigger,omitempty"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Changes   []string  `json:"changes,omitempty"`
+}
+
+// watchConfig reloads the configuration when the config file changes.
+// The config file directory is watched rather than the file itself
+// to catch editors replacing the file and Kubernetes ConfigMap updates.
+func (a *App) watchConfig() {
+	cfgFile := filepath.Clean(a.Config.FileConfig.ConfigFileUsed())
+	if cfgFile == "." {
+		a.Logger.Printf("no config file to watch")
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		a.Logger.Printf("failed to create config file watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+	err = watcher.Add(filepath.Dir(cfgFile))
+	if err != nil {
+		a.Logger.Printf("failed to watch config file directory: %v", err)
+		return
+	}
+	a.Logger.Printf("watching config file %q...", cfgFile)
+	realCfgFile, _ := filepath.EvalSymlinks(cfgFile)
+
+	var timer *time.Timer
+	for {
+		select {
+		case <-a.ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case e, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			currentCfgFile, _ := filepath.EvalSymlinks(cfgFile)
+			fileChanged := filepath.Clean(e.Name) == cfgFile && e.Op&(fsnotify.Write|fsnotify.Create) != 0
+			linkChanged := currentCfgFile != "" && currentCfgFile != realCfgFile
+			if !fileChanged && !linkChanged {
+				continue
+			}
+			realCfgFile = currentCfgFile
+			if a.Config.Debug {
+				a.Logger.Printf("got config change notification: %v", e)
+			}
+			// editors usually write a file in several steps,
+			// wait for the changes to settle before reloading.
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(configWatchDebounce, func() {
+				a.reloadConfig(reloadTriggerFile)
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			a.Logger.Printf("config file watcher error: %v", err)
+		}
+	}
+}
+
+// handleReloadSignal reloads the configuration when a SIGHUP is received.
+func (a *App) handleReloadSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-sigCh:
+			a.Logger.Printf("received SIGHUP")
+			a.reloadConfig(reloadTriggerSignal)
+		}
+	}
+}
+
+// reloadConfig reads the config file again and applies the changed
+// targets, subscriptions, outputs, inputs and processors.
+// If the new config is not valid, nothing is applied.
+// The reload is serialized with the config changes made through the API.
+func (a *App) reloadConfig(trigger string) *configReloadResult {
+	res := &configReloadResult{
+		Timestamp: time.Now(),
+		Trigger:   trigger,
+	}
+	err := a.sem.Acquire(a.ctx, 1)
+	if err != nil {
+		a.Logger.Printf("failed to acquire config reload semaphore: %v", err)
+		res.Error = err.Error()
+		return res
+	}
+	defer a.sem.Release(1)
+	a.apiConfigLock.Lock()
+	defer a.apiConfigLock.Unlock()
+
+	a.Logger.Printf("reloading config, trigger=%s", trigger)
+	err = a.reloadConfigFile(res)
+	if err != nil {
+		res.Error = err.Error()
+		configReloadCounter.WithLabelValues("failure").Inc()
+		configLastReloadSuccess.Set(0)
+		a.Logger.Printf("config reload failed, config unchanged: %v", err)
+	} else {
+		res.Success = true
+		configReloadCounter.WithLabelValues("success").Inc()
+		configLastReloadSuccess.Set(1)
+		configLastReloadSuccessTimestamp.SetToCurrentTime()
+		if len(res.Changes) == 0 {
+			a.Logger.Printf("config reloaded, no changes")
+		}
+		for _, c := range res.Changes {
+			a.Logger.Printf("config reloaded: %s", c)
+		}
+	}
+	a.configLock.Lock()
+	a.lastReload = res
+	a.configLock.Unlock()
+	return res
+}
+
+func (a *App) reloadConfigFile(res *configReloadResult) error {
+	nc, err := a.Config.Reload(a.ctx, a.reloadCmd)
+	if err != nil {
+		return err
+	}
+	for n, sc := range nc.Subscriptions {
+		if strings.ToUpper(sc.Mode) == "POLL" {
+			return fmt.Errorf("subscription %q: poll subscriptions cannot be loaded by a config reload", n)
+		}
+	}
+
+	changedProcessors := diffConfigMaps(a.Config.Processors, nc.Processors)
+	actionsChanged := !reflect.DeepEqual(a.Config.Actions, nc.Actions)
+
+	a.configLock.Lock()
+	a.Config.Processors = nc.Processors
+	a.Config.Actions = nc.Actions
+	a.configLock.Unlock()
+	for _, n := range sortedKeys(changedProcessors) {
+		res.Changes = append(res.Changes, fmt.Sprintf("processor %q %s", n, changedProcessors[n]))
+	}
+	if actionsChanged {
+		res.Changes = append(res.Changes, "actions changed")
+	}
+	// an output or input is restarted if its config changed
+	// or if one of its processors changed.
+	usesChangedProcessor := func(cfg map[string]interface{}) bool {
+		pns := configRefs(cfg, "event-processors")
+		if actionsChanged && len(pns) > 0 {
+			return true
+		}
+		for _, pn := range pns {
+			if _, ok := changedProcessors[pn]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	outChanges, changedOutputs := a.reloadOutputs(nc.Outputs, usesChangedProcessor)
+	res.Changes = append(res.Changes, outChanges...)
+	res.Changes = append(res.Changes, a.reloadInputs(nc.Inputs, usesChangedProcessor, changedOutputs)...)
+
+	changedSubscriptions := diffSubscriptions(a.Config.Subscriptions, nc.Subscriptions)
+	for _, n := range sortedKeys(changedSubscriptions) {
+		res.Changes = append(res.Changes, fmt.Sprintf("subscription %q %s", n, changedSubscriptions[n]))
+	}
+	a.configLock.Lock()
+	a.Config.Subscriptions = nc.Subscriptions
+	a.configLock.Unlock()
+
+	res.Changes = append(res.Changes, a.reloadTargets(nc.Targets, changedSubscriptions)...)
+	return nil
+}
+
+// reloadOutputs applies the new outputs config, only the added, deleted
+// or changed outputs are (re)started or stopped.
+// It returns the changes and the names of the added, deleted or changed outputs.
+func (a *App) reloadOutputs(newOutputs map[string]map[string]interface{}, restart func(map[string]interface{}) bool) ([]string, []string) {
+	changes := make([]string, 0)
+	a.configLock.Lock()
+	diff := diffConfigMaps(a.Config.Outputs, newOutputs)
+	for n, cfg := range newOutputs {
+		if _, ok := diff[n]; !ok && restart(cfg) {
+			diff[n] = "processors changed"
+		}
+	}
+	a.Config.Outputs = newOutputs
+	a.configLock.Unlock()
+
+	for _, n := range sortedKeys(diff) {
+		changes = append(changes, fmt.Sprintf("output %q %s", n, diff[n]))
+		if diff[n] != "added" {
+			a.DeleteOutput(n)
+		}
+		if diff[n] != "deleted" {
+			a.InitOutput(a.ctx, n, a.Config.Targets)
+		}
+	}
+	return changes, sortedKeys(diff)
+}
+
+// reloadInputs applies the new inputs config, only the added, deleted
+// or changed inputs are (re)started or stopped.
+// Unchanged inputs writing to one of the changed outputs are restarted
+// since they hold the instances of the outputs they started with.
+func (a *App) reloadInputs(newInputs map[string]map[string]interface{}, restart func(map[string]interface{}) bool, changedOutputs []string) []string {
+	changes := make([]string, 0)
+	a.configLock.Lock()
+	diff := diffConfigMaps(a.Config.Inputs, newInputs)
+	for n, cfg := range newInputs {
+		if _, ok := diff[n]; ok {
+			continue
+		}
+		switch {
+		case restart(cfg):
+			diff[n] = "processors changed"
+		case inputUsesOutputs(cfg, changedOutputs):
+			diff[n] = "outputs changed"
+		}
+	}
+	a.Config.Inputs = newInputs
+	a.configLock.Unlock()
+
+	for _, n := range sortedKeys(diff) {
+		changes = append(changes, fmt.Sprintf("input %q %s", n, diff[n]))
+		// restartInput closes the input and starts it if it is still configured.
+		a.restartInput(n)
+	}
+	return changes
+}
+
+// reloadTargets applies the new targets config.
+// Unchanged targets are restarted only if they use a changed subscription.
+func (a *App) reloadTargets(newTargets map[string]*types.TargetConfig, changedSubscriptions map[string]string) []string {
+	changes := make([]string, 0)
+	subs := sortedKeys(changedSubscriptions)
+	// targets are managed by a loader or the tunnel server,
+	// or distributed by the cluster leader.
+	if len(a.Config.FileConfig.GetStringMap("loader")) > 0 || a.Config.UseTunnelServer {
+		if len(subs) > 0 {
+			a.restartSubscriptionTargets(subs)
+		}
+		return changes
+	}
+	if a.inCluster() {
+		if len(subs) > 0 {
+			a.restartSubscriptionTargets(subs)
+		}
+		if a.isLeader {
+			changes = append(changes, a.reloadClusterTargets(newTargets)...)
+		}
+		return changes
+	}
+
+	a.configLock.RLock()
+	currentTargets := make(map[string]*types.TargetConfig, len(a.Config.Targets))
+	for n, tc := range a.Config.Targets {
+		currentTargets[n] = tc
+	}
+	a.configLock.RUnlock()
+	// deleted targets
+	for _, n := range sortedKeys(currentTargets) {
+		if _, ok := newTargets[n]; ok {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("target %q deleted", n))
+		err := a.DeleteTarget(a.ctx, n)
+		if err != nil {
+			a.Logger.Printf("failed to delete target %q: %v", n, err)
+		}
+	}
+	// added and changed targets
+	var limiter *time.Ticker
+	if a.Config.LocalFlags.SubscribeBackoff > 0 {
+		limiter = time.NewTicker(a.Config.LocalFlags.SubscribeBackoff)
+		defer limiter.Stop()
+	}
+	for _, n := range sortedKeys(newTargets) {
+		tc := newTargets[n]
+		ctc, ok := currentTargets[n]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("target %q added", n))
+			a.AddTargetConfig(tc)
+		case !reflect.DeepEqual(ctc, tc):
+			changes = append(changes, fmt.Sprintf("target %q changed", n))
+			a.configLock.Lock()
+			a.Config.Targets[n] = tc
+			a.configLock.Unlock()
+			err := a.stopTarget(a.ctx, n)
+			if err != nil {
+				a.Logger.Printf("failed to stop target %q: %v", n, err)
+			}
+		case targetUsesSubscriptions(ctc, subs):
+			changes = append(changes, fmt.Sprintf("target %q restarted", n))
+			err := a.stopTarget(a.ctx, n)
+			if err != nil {
+				a.Logger.Printf("failed to stop target %q: %v", n, err)
+			}
+			tc = ctc
+		default:
+			continue
+		}
+		go a.TargetSubscribeStream(a.ctx, tc)
+		if limiter != nil {
+			<-limiter.C
+		}
+	}
+	return changes
+}
+
+// reloadClusterTargets dispatches the new targets and removes the deleted ones
+// from the cluster. Must be called by the cluster leader.
+func (a *App) reloadClusterTargets(newTargets map[string]*types.TargetConfig) []string {
+	changes := make([]string, 0)
+	dist, err := a.getTargetToInstanceMapping(a.ctx)
+	if err != nil {
+		a.Logger.Printf("failed to get target to instance mapping: %v", err)
+		return changes
+	}
+	// delete targets
+	for t := range dist {
+		if _, ok := newTargets[t]; !ok {
+			changes = append(changes, fmt.Sprintf("target %q deleted", t))
+			err = a.deleteTarget(a.ctx, t)
+			if err != nil {
+				a.Logger.Printf("failed to delete target %q: %v", t, err)
+				continue
+			}
+		}
+	}
+	// add new targets to cluster
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	for _, tc := range newTargets {
+		if _, ok := dist[tc.Name]; !ok {
+			changes = append(changes, fmt.Sprintf("target %q added", tc.Name))
+			a.Config.Targets[tc.Name] = tc
+			err = a.dispatchTarget(a.ctx, tc)
+			if err != nil {
+				a.Logger.Printf("failed to add target %q: %v", tc.Name, err)
+			}
+		}
+	}
+	return changes
+}
+
+func targetUsesSubscriptions(tc *types.TargetConfig, subs []string) bool {
+	if len(subs) == 0 {
+		return false
+	}
+	if len(tc.Subscriptions) == 0 {
+		return true
+	}
+	for _, sn := range subs {
+		if strInList(sn, tc.Subscriptions) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffConfigMaps returns the names of the added, deleted and changed configs.
+func diffConfigMaps(old, new map[string]map[string]interface{}) map[string]string {
+	diff := make(map[string]string)
+	for n, cfg := range old {
+		ncfg, ok := new[n]
+		if !ok {
+			diff[n] = "deleted"
+			continue
+		}
+		if !reflect.DeepEqual(cfg, ncfg) {
+			diff[n] = "changed"
+		}
+	}
+	for n := range new {
+		if _, ok := old[n]; !ok {
+			diff[n] = "added"
+		}
+	}
+	return diff
+}
+
+func diffSubscriptions(old, new map[string]*types.SubscriptionConfig) map[string]string {
+	diff := make(map[string]string)
+	for n, sc := range old {
+		nsc, ok := new[n]
+		if !ok {
+			diff[n] = "deleted"
+			continue
+		}
+		if !reflect.DeepEqual(sc, nsc) {
+			diff[n] = "changed"
+		}
+	}
+	for n := range new {
+		if _, ok := old[n]; !ok {
+			diff[n] = "added"
+		}
+	}
+	return diff
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (a *App) handleConfigReloadGet(w http.ResponseWriter, r *http.Request) {
+	a.configLock.RLock()
+	res := a.lastReload
+	a.configLock.RUnlock()
+	if res == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"no config reload attempted"}})
+		return
+	}
+	a.handlerCommonGet(w, res)
+}
+
+func (a *App) handleConfigReloadPost(w http.ResponseWriter, r *http.Request) {
+	if a.reloadCmd == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"config reload is only available with stream subscriptions"}})
+		return
+	}
+	res := a.reloadConfig(reloadTriggerAPI)
+	if !res.Success {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{res.Error}})
+		return
+	}
+	a.handlerCommonGet(w, res)
+}
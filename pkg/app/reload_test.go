@@ -0,0 +1,41 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReloadInputsChangedOutputs(t *testing.T) {
+	a := New()
+	inputs := func() map[string]map[string]interface{} {
+		return map[string]map[string]interface{}{
+			"all-outputs": {"type": "unknown"},
+			"o1-input":    {"type": "unknown", "outputs": []interface{}{"o1"}},
+			"o2-input":    {"type": "unknown", "outputs": []interface{}{"o2"}},
+		}
+	}
+	a.Config.Inputs = inputs()
+	noProcessorChange := func(map[string]interface{}) bool { return false }
+
+	changes := a.reloadInputs(inputs(), noProcessorChange, []string{"o1"})
+	want := []string{
+		`input "all-outputs" outputs changed`,
+		`input "o1-input" outputs changed`,
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got %q, want %q", changes, want)
+	}
+
+	changes = a.reloadInputs(inputs(), noProcessorChange, nil)
+	if len(changes) != 0 {
+		t.Errorf("unexpected changes: %q", changes)
+	}
+}
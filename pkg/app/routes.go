@@ -35,6 +35,9 @@ func (a *App) clusterRoutes(r *mux.Router) {
 func (a *App) configRoutes(r *mux.Router) {
 	// config
 	r.HandleFunc("/config", a.handleConfig).Methods(http.MethodGet)
+	// config/reload
+	r.HandleFunc("/config/reload", a.handleConfigReloadGet).Methods(http.MethodGet)
+	r.HandleFunc("/config/reload", a.handleConfigReloadPost).Methods(http.MethodPost)
 	// config/targets
 	r.HandleFunc("/config/targets", a.handleConfigTargetsGet).Methods(http.MethodGet)
 	r.HandleFunc("/config/targets/{id}", a.handleConfigTargetsGet).Methods(http.MethodGet)
@@ -43,12 +46,24 @@ func (a *App) configRoutes(r *mux.Router) {
 	r.HandleFunc("/config/targets/{id}/subscriptions", a.handleConfigTargetsSubscriptions).Methods(http.MethodPatch)
 	// config/subscriptions
 	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsGet).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptionsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsDelete).Methods(http.MethodDelete)
 	// config/outputs
 	r.HandleFunc("/config/outputs", a.handleConfigOutputs).Methods(http.MethodGet)
+	r.HandleFunc("/config/outputs/{id}", a.handleConfigOutputsGet).Methods(http.MethodGet)
+	r.HandleFunc("/config/outputs", a.handleConfigOutputsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/outputs/{id}", a.handleConfigOutputsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/outputs/{id}", a.handleConfigOutputsDelete).Methods(http.MethodDelete)
 	// config/inputs
 	r.HandleFunc("/config/inputs", a.handleConfigInputs).Methods(http.MethodGet)
 	// config/processors
 	r.HandleFunc("/config/processors", a.handleConfigProcessors).Methods(http.MethodGet)
+	r.HandleFunc("/config/processors/{id}", a.handleConfigProcessorsGet).Methods(http.MethodGet)
+	r.HandleFunc("/config/processors", a.handleConfigProcessorsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/processors/{id}", a.handleConfigProcessorsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/processors/{id}", a.handleConfigProcessorsDelete).Methods(http.MethodDelete)
 	// config/clustering
 	r.HandleFunc("/config/clustering", a.handleConfigClustering).Methods(http.MethodGet)
 	// config/api-server
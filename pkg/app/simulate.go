@@ -0,0 +1,451 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/ygot/gnmidiff/gnmiparse"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/server"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/cache"
+	"github.com/openconfig/gnmic/pkg/config"
+	gfile "github.com/openconfig/gnmic/pkg/file"
+)
+
+const (
+	defaultSimulateName           = "simulator"
+	defaultSimulateRandomInterval = 10 * time.Second
+	defaultSimulateListSize       = 2
+	// cache subscription name the simulated data is written under.
+	simulateCacheName = "simulate"
+)
+
+func (a *App) SimulatePreRunE(cmd *cobra.Command, _ []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	a.Config.LocalFlags.SimulateTree = config.SanitizeArrayFlagValue(a.Config.LocalFlags.SimulateTree)
+	a.Config.LocalFlags.SimulateRandomPath = config.SanitizeArrayFlagValue(a.Config.LocalFlags.SimulateRandomPath)
+	if a.Config.LocalFlags.SimulateName == "" {
+		a.Config.LocalFlags.SimulateName = defaultSimulateName
+	}
+	if a.Config.LocalFlags.SimulateListSize <= 0 {
+		a.Config.LocalFlags.SimulateListSize = defaultSimulateListSize
+	}
+	if a.Config.LocalFlags.SimulateRandomInterval <= 0 {
+		a.Config.LocalFlags.SimulateRandomInterval = defaultSimulateRandomInterval
+	}
+	if len(a.Config.Address) > 1 {
+		fmt.Fprintf(os.Stderr, "multiple addresses specified, listening only on %s\n", a.Config.Address[0])
+	}
+	return a.yangFilesPreProcessing()
+}
+
+func (a *App) SimulateRunE(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	err := a.Config.GetGNMIServerWithDefaults()
+	if err != nil {
+		return err
+	}
+	if len(a.Config.Address) > 0 {
+		a.Config.GnmiServer.Address = a.Config.Address[0]
+	}
+	if a.Config.GnmiServer.TLS == nil && !a.Config.Insecure {
+		// self signed certificate if no cert and key are set
+		a.Config.GnmiServer.TLS = &types.TLSConfig{
+			CaFile:   a.Config.TLSCa,
+			CertFile: a.Config.TLSCert,
+			KeyFile:  a.Config.TLSKey,
+		}
+		if a.Config.TLSCa != "" {
+			a.Config.GnmiServer.TLS.ClientAuth = "verify-if-given"
+		}
+	}
+
+	err = a.generateYangSchema(a.Config.GlobalFlags.File, a.Config.GlobalFlags.Exclude)
+	if err != nil {
+		return err
+	}
+	a.c, err = cache.New(nil, cache.WithLogger(a.Logger))
+	if err != nil {
+		return err
+	}
+	defer a.c.Stop()
+
+	// static tree
+	for _, f := range a.Config.LocalFlags.SimulateTree {
+		upds, err := a.readSimulateTree(ctx, f)
+		if err != nil {
+			return fmt.Errorf("failed to read tree file %q: %v", f, err)
+		}
+		a.Logger.Printf("loaded %d leaves from %q", len(upds), f)
+		a.simulateWrite(ctx, &gnmi.Notification{Update: upds})
+	}
+	// YANG based random values
+	if len(a.SchemaTree.Dir) > 0 {
+		gen, err := a.newSimulateGenerator()
+		if err != nil {
+			return err
+		}
+		upds := gen.updates(a.SchemaTree, false)
+		a.Logger.Printf("generated %d leaves from YANG schema", len(upds))
+		a.simulateWrite(ctx, &gnmi.Notification{Update: upds})
+		go a.simulateRandomValues(ctx, gen)
+	}
+	// recorded notifications
+	if a.Config.LocalFlags.SimulateReplay != "" {
+		notifs, err := gnmiparse.NotifsFromFile(a.Config.LocalFlags.SimulateReplay)
+		if err != nil {
+			return err
+		}
+		a.Logger.Printf("loaded %d notifications from %q", len(notifs), a.Config.LocalFlags.SimulateReplay)
+		go a.simulateReplay(ctx, notifs)
+	}
+
+	s, err := server.New(server.Config{
+		Address:              a.Config.GnmiServer.Address,
+		MaxUnaryRPC:          a.Config.GnmiServer.MaxUnaryRPC,
+		MaxStreamingRPC:      a.Config.GnmiServer.MaxSubscriptions,
+		MaxRecvMsgSize:       a.Config.GnmiServer.MaxRecvMsgSize,
+		MaxSendMsgSize:       a.Config.GnmiServer.MaxSendMsgSize,
+		MaxConcurrentStreams: a.Config.GnmiServer.MaxConcurrentStreams,
+		TCPKeepalive:         a.Config.GnmiServer.TCPKeepalive,
+		Keepalive:            a.Config.GnmiServer.GRPCKeepalive.Convert(),
+		RateLimit:            a.Config.GnmiServer.RateLimit,
+		HealthEnabled:        true,
+		TLS:                  a.Config.GnmiServer.TLS,
+	}, server.WithLogger(a.Logger),
+		server.WithCapabilitiesHandler(a.simulateCapabilitiesHandler),
+		server.WithGetHandler(a.simulateGetHandler),
+		server.WithSetHandler(a.simulateSetHandler),
+		server.WithSubscribeHandler(a.simulateSubscribeHandler),
+	)
+	if err != nil {
+		return err
+	}
+	a.Logger.Printf("simulating target %q on %s", a.Config.LocalFlags.SimulateName, a.Config.GnmiServer.Address)
+	return s.Start(ctx)
+}
+
+// InitSimulateFlags used to init or reset simulateCmd flags for gnmic-prompt mode
+func (a *App) InitSimulateFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.SimulateName, "name", "", defaultSimulateName, "simulated target name, used as the notifications prefix target")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SimulateTree, "tree", "", []string{}, "JSON or YAML file with the simulated target data tree")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.SimulateReplay, "replay", "", "", "textproto file with a recorded SubscribeResponse stream or GetResponse to replay")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SimulateReplayInterval, "replay-interval", "", 0, "interval between replayed notifications, defaults to the recorded timestamps intervals")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SimulateReplayLoop, "replay-loop", "", false, "replay the recorded notifications in a loop")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SimulateRandomPath, "random-path", "", []string{}, "generate random values only under these paths, defaults to the whole YANG schema")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SimulateRandomInterval, "random-interval", "", defaultSimulateRandomInterval, "interval between generated values updates")
+	cmd.Flags().IntVarP(&a.Config.LocalFlags.SimulateListSize, "list-size", "", defaultSimulateListSize, "number of entries generated for each YANG list and leaf-list")
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func (a *App) readSimulateTree(ctx context.Context, name string) ([]*gnmi.Update, error) {
+	b, err := gfile.ReadFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &v)
+	default:
+		err = decodeJSON(b, &v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return treeUpdates(a.SchemaTree, nil, v)
+}
+
+func (a *App) newSimulateGenerator() (*simGenerator, error) {
+	paths := make([][]*gnmi.PathElem, 0, len(a.Config.LocalFlags.SimulateRandomPath))
+	for _, p := range a.Config.LocalFlags.SimulateRandomPath {
+		gp, err := path.ParsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid random path %q: %v", p, err)
+		}
+		paths = append(paths, gp.GetElem())
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return newSimGenerator(rnd, a.Config.LocalFlags.SimulateListSize, paths), nil
+}
+
+func (a *App) simulateRandomValues(ctx context.Context, gen *simGenerator) {
+	ticker := time.NewTicker(a.Config.LocalFlags.SimulateRandomInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			upds := gen.updates(a.SchemaTree, true)
+			if len(upds) == 0 {
+				continue
+			}
+			a.simulateWrite(ctx, &gnmi.Notification{Update: upds})
+		}
+	}
+}
+
+// simulateReplay writes the recorded notifications to the cache
+// using the configured interval or the recorded timestamps intervals.
+func (a *App) simulateReplay(ctx context.Context, notifs []*gnmi.Notification) {
+	if len(notifs) == 0 {
+		return
+	}
+	for {
+		for i, n := range notifs {
+			var wait time.Duration
+			if i > 0 {
+				wait = a.Config.LocalFlags.SimulateReplayInterval
+				if wait <= 0 {
+					wait = time.Duration(n.GetTimestamp() - notifs[i-1].GetTimestamp())
+				}
+			}
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+			n = proto.Clone(n).(*gnmi.Notification)
+			prefix := n.GetPrefix()
+			n.Prefix = nil
+			for _, upd := range n.GetUpdate() {
+				upd.Path = &gnmi.Path{Elem: path.PathElems(prefix, upd.GetPath())}
+			}
+			for i, del := range n.GetDelete() {
+				n.Delete[i] = &gnmi.Path{Elem: path.PathElems(prefix, del)}
+			}
+			a.simulateWrite(ctx, n)
+		}
+		if !a.Config.LocalFlags.SimulateReplayLoop {
+			a.Logger.Printf("replay of %q done", a.Config.LocalFlags.SimulateReplay)
+			return
+		}
+		wait := a.Config.LocalFlags.SimulateReplayInterval
+		if wait <= 0 {
+			wait = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// simulateWrite writes a notification to the simulated target cache.
+// The notification paths must not have an origin nor a target.
+func (a *App) simulateWrite(ctx context.Context, n *gnmi.Notification) {
+	n.Timestamp = time.Now().UnixNano()
+	n.Prefix = &gnmi.Path{Target: a.Config.LocalFlags.SimulateName}
+	a.c.Write(ctx, simulateCacheName, &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{Update: n},
+	})
+}
+
+func (a *App) checkSimulateTarget(prefix *gnmi.Path) error {
+	switch prefix.GetTarget() {
+	case "", "*", a.Config.LocalFlags.SimulateName:
+		return nil
+	}
+	return status.Errorf(codes.NotFound, "unknown target %q", prefix.GetTarget())
+}
+
+func (a *App) simulateCapabilitiesHandler(ctx context.Context, req *gnmi.CapabilityRequest) (*gnmi.CapabilityResponse, error) {
+	rsp := &gnmi.CapabilityResponse{
+		GNMIVersion: "0.10.0",
+		SupportedEncodings: []gnmi.Encoding{
+			gnmi.Encoding_JSON,
+			gnmi.Encoding_JSON_IETF,
+			gnmi.Encoding_PROTO,
+		},
+	}
+	if a.modules == nil || len(a.SchemaTree.Dir) == 0 {
+		return rsp, nil
+	}
+	for _, e := range sortedEntries(a.SchemaTree) {
+		m, ok := a.modules.Modules[e.Name]
+		if !ok {
+			continue
+		}
+		md := &gnmi.ModelData{Name: m.Name}
+		if m.Organization != nil {
+			md.Organization = m.Organization.Name
+		}
+		if len(m.Revision) > 0 {
+			md.Version = m.Revision[0].Name
+		}
+		rsp.SupportedModels = append(rsp.SupportedModels, md)
+	}
+	return rsp, nil
+}
+
+func (a *App) simulateGetHandler(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	if err := a.checkSimulateTarget(req.GetPrefix()); err != nil {
+		return nil, err
+	}
+	pr, _ := peer.FromContext(ctx)
+	a.Logger.Printf("received Get request from %q", pr.Addr)
+
+	paths := req.GetPath()
+	if len(paths) == 0 {
+		paths = []*gnmi.Path{{}}
+	}
+	rsp := &gnmi.GetResponse{
+		Notification: make([]*gnmi.Notification, 0, len(paths)),
+	}
+	for _, p := range paths {
+		elems := path.PathElems(req.GetPrefix(), p)
+		n := &gnmi.Notification{
+			Prefix: &gnmi.Path{Target: a.Config.LocalFlags.SimulateName},
+		}
+		ro := &cache.ReadOpts{
+			Target: a.Config.LocalFlags.SimulateName,
+			Paths: []*gnmi.Path{{
+				Target: a.Config.LocalFlags.SimulateName,
+				Elem:   elems,
+			}},
+			Mode: cache.ReadMode_Once,
+		}
+		for cn := range a.c.Subscribe(ctx, ro) {
+			if cn.Err != nil {
+				return nil, status.Errorf(codes.Internal, "%v", cn.Err)
+			}
+			if cn.Notification.GetTimestamp() > n.Timestamp {
+				n.Timestamp = cn.Notification.GetTimestamp()
+			}
+			for _, upd := range cn.Notification.GetUpdate() {
+				n.Update = append(n.Update, &gnmi.Update{
+					Path: &gnmi.Path{Elem: path.PathElems(cn.Notification.GetPrefix(), upd.GetPath())},
+					Val:  upd.GetVal(),
+				})
+			}
+		}
+		if len(n.Update) == 0 {
+			return nil, status.Errorf(codes.NotFound, "path %q not found", "/"+path.GnmiPathToXPath(&gnmi.Path{Elem: elems}, false))
+		}
+		rsp.Notification = append(rsp.Notification, n)
+	}
+	return rsp, nil
+}
+
+func (a *App) simulateSetHandler(ctx context.Context, req *gnmi.SetRequest) (*gnmi.SetResponse, error) {
+	if err := a.checkSimulateTarget(req.GetPrefix()); err != nil {
+		return nil, err
+	}
+	pr, _ := peer.FromContext(ctx)
+	a.Logger.Printf("received Set request from %q", pr.Addr)
+
+	n := new(gnmi.Notification)
+	rsp := &gnmi.SetResponse{
+		Prefix: req.GetPrefix(),
+	}
+	for _, p := range req.GetDelete() {
+		n.Delete = append(n.Delete, &gnmi.Path{Elem: path.PathElems(req.GetPrefix(), p)})
+		rsp.Response = append(rsp.Response, &gnmi.UpdateResult{Path: p, Op: gnmi.UpdateResult_DELETE})
+	}
+	for _, upd := range req.GetReplace() {
+		elems := path.PathElems(req.GetPrefix(), upd.GetPath())
+		n.Delete = append(n.Delete, &gnmi.Path{Elem: elems})
+		upds, err := a.simulateSetUpdates(elems, upd.GetVal())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		n.Update = append(n.Update, upds...)
+		rsp.Response = append(rsp.Response, &gnmi.UpdateResult{Path: upd.GetPath(), Op: gnmi.UpdateResult_REPLACE})
+	}
+	for _, upd := range req.GetUpdate() {
+		elems := path.PathElems(req.GetPrefix(), upd.GetPath())
+		upds, err := a.simulateSetUpdates(elems, upd.GetVal())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		n.Update = append(n.Update, upds...)
+		rsp.Response = append(rsp.Response, &gnmi.UpdateResult{Path: upd.GetPath(), Op: gnmi.UpdateResult_UPDATE})
+	}
+	a.simulateWrite(ctx, n)
+	rsp.Timestamp = n.GetTimestamp()
+	return rsp, nil
+}
+
+// simulateSetUpdates converts a Set request value into leaf updates,
+// JSON values are flattened.
+func (a *App) simulateSetUpdates(elems []*gnmi.PathElem, tv *gnmi.TypedValue) ([]*gnmi.Update, error) {
+	var b []byte
+	switch v := tv.GetValue().(type) {
+	case *gnmi.TypedValue_JsonVal:
+		b = v.JsonVal
+	case *gnmi.TypedValue_JsonIetfVal:
+		b = v.JsonIetfVal
+	default:
+		if len(elems) == 0 {
+			return nil, errors.New("cannot set a value at the root path")
+		}
+		return []*gnmi.Update{{Path: &gnmi.Path{Elem: elems}, Val: tv}}, nil
+	}
+	var v interface{}
+	err := decodeJSON(b, &v)
+	if err != nil {
+		return nil, err
+	}
+	return treeUpdates(a.SchemaTree, elems, v)
+}
+
+func (a *App) simulateSubscribeHandler(req *gnmi.SubscribeRequest, stream gnmi.GNMI_SubscribeServer) error {
+	if err := a.checkSimulateTarget(req.GetSubscribe().GetPrefix()); err != nil {
+		return err
+	}
+	// the simulated data is stored without origin.
+	req = proto.Clone(req).(*gnmi.SubscribeRequest)
+	sub := req.GetSubscribe()
+	sub.Prefix = &gnmi.Path{
+		Target: a.Config.LocalFlags.SimulateName,
+		Elem:   sub.GetPrefix().GetElem(),
+	}
+	for _, s := range sub.GetSubscription() {
+		if s.GetPath() != nil {
+			s.Path.Origin = ""
+		}
+	}
+	return a.serverSubscribeHandler(req, stream)
+}
+
+func decodeJSON(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
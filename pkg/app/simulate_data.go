@@ -0,0 +1,531 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+// defaultSimulateListKeys are the fields used as list keys
+// when a tree list has no matching YANG schema entry.
+var defaultSimulateListKeys = []string{"name", "id", "index"}
+
+// treeUpdates flattens a JSON/YAML tree into leaf updates.
+// Map keys can be path elements with keys (`interface[name=eth0]`)
+// or relative xpaths (`interfaces/interface[name=eth0]`).
+// Arrays of objects are lists, their keys are taken from the schema if set,
+// otherwise the first of `name`, `id` or `index` present in each entry is used.
+// Arrays of scalars are leaf-lists.
+func treeUpdates(schema *yang.Entry, elems []*gnmi.PathElem, v interface{}) ([]*gnmi.Update, error) {
+	upds := make([]*gnmi.Update, 0)
+	err := flattenTree(schema, elems, v, &upds)
+	if err != nil {
+		return nil, err
+	}
+	return upds, nil
+}
+
+func flattenTree(schema *yang.Entry, elems []*gnmi.PathElem, v interface{}, upds *[]*gnmi.Update) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			m[fmt.Sprint(k)] = vv
+		}
+		return flattenTree(schema, elems, m, upds)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p, err := path.ParsePath("/" + strings.TrimPrefix(k, "/"))
+			if err != nil {
+				return fmt.Errorf("invalid tree element %q: %v", k, err)
+			}
+			celems := copyPathElems(elems)
+			for _, pe := range p.GetElem() {
+				pe.Name = stripModulePrefix(pe.Name)
+				celems = append(celems, pe)
+			}
+			err = flattenTree(schema, celems, v[k], upds)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		if !isObjectList(v) {
+			tvs := make([]*gnmi.TypedValue, 0, len(v))
+			for _, item := range v {
+				tv, err := scalarTypedValue(item)
+				if err != nil {
+					return err
+				}
+				tvs = append(tvs, tv)
+			}
+			return appendTreeUpdate(elems, &gnmi.TypedValue{
+				Value: &gnmi.TypedValue_LeaflistVal{
+					LeaflistVal: &gnmi.ScalarArray{Element: tvs},
+				},
+			}, upds)
+		}
+		if len(elems) == 0 {
+			return fmt.Errorf("a list must have a name")
+		}
+		var listKeys []string
+		if e := findSchemaEntry(schema, elems); e != nil && e.IsList() {
+			listKeys = strings.Fields(e.Key)
+		}
+		for i, item := range v {
+			m := toStringMap(item)
+			keys := listKeys
+			if len(keys) == 0 {
+				for _, k := range defaultSimulateListKeys {
+					if _, ok := m[k]; ok {
+						keys = []string{k}
+						break
+					}
+				}
+			}
+			if len(keys) == 0 {
+				return fmt.Errorf("list %q entry %d: could not determine the list keys", elems[len(elems)-1].GetName(), i)
+			}
+			celems := copyPathElems(elems)
+			last := celems[len(celems)-1]
+			last.Key = make(map[string]string, len(keys))
+			for _, k := range keys {
+				kv, ok := m[k]
+				if !ok {
+					return fmt.Errorf("list %q entry %d: missing key %q", last.GetName(), i, k)
+				}
+				last.Key[k] = fmt.Sprint(kv)
+			}
+			err := flattenTree(schema, celems, m, upds)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		tv, err := scalarTypedValue(v)
+		if err != nil {
+			return err
+		}
+		return appendTreeUpdate(elems, tv, upds)
+	}
+}
+
+func appendTreeUpdate(elems []*gnmi.PathElem, tv *gnmi.TypedValue, upds *[]*gnmi.Update) error {
+	if len(elems) == 0 {
+		return fmt.Errorf("cannot set a value at the root path")
+	}
+	*upds = append(*upds, &gnmi.Update{
+		Path: &gnmi.Path{Elem: elems},
+		Val:  tv,
+	})
+	return nil
+}
+
+func isObjectList(l []interface{}) bool {
+	for _, item := range l {
+		switch item.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func toStringMap(i interface{}) map[string]interface{} {
+	switch i := i.(type) {
+	case map[string]interface{}:
+		return i
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(i))
+		for k, v := range i {
+			m[fmt.Sprint(k)] = v
+		}
+		return m
+	}
+	return nil
+}
+
+// scalarTypedValue converts a decoded JSON or YAML scalar into a gNMI TypedValue.
+func scalarTypedValue(v interface{}) (*gnmi.TypedValue, error) {
+	switch v := v.(type) {
+	case string:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: v}}, nil
+	case bool:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: v}}, nil
+	case int:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(v)}}, nil
+	case int64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: v}}, nil
+	case uint64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: v}}, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < math.MaxInt64 {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(v)}}, nil
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: v}}, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: i}}, nil
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: u}}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: f}}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+func copyPathElems(elems []*gnmi.PathElem) []*gnmi.PathElem {
+	r := make([]*gnmi.PathElem, 0, len(elems)+1)
+	for _, pe := range elems {
+		npe := &gnmi.PathElem{Name: pe.GetName()}
+		if len(pe.GetKey()) > 0 {
+			npe.Key = make(map[string]string, len(pe.GetKey()))
+			for k, v := range pe.GetKey() {
+				npe.Key[k] = v
+			}
+		}
+		r = append(r, npe)
+	}
+	return r
+}
+
+func stripModulePrefix(name string) string {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// findSchemaEntry returns the schema entry matching the path elements names,
+// choice and case nodes are traversed transparently.
+// The root entry children are the YANG modules.
+func findSchemaEntry(root *yang.Entry, elems []*gnmi.PathElem) *yang.Entry {
+	if root == nil || len(elems) == 0 {
+		return nil
+	}
+	for _, m := range sortedEntries(root) {
+		if e := findEntry(m, elems); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func findEntry(e *yang.Entry, elems []*gnmi.PathElem) *yang.Entry {
+	for _, pe := range elems {
+		if e == nil {
+			return nil
+		}
+		e = schemaChild(e, pe.GetName())
+	}
+	return e
+}
+
+func schemaChild(e *yang.Entry, name string) *yang.Entry {
+	if c, ok := e.Dir[name]; ok && !c.IsChoice() && !c.IsCase() {
+		return c
+	}
+	for _, c := range e.Dir {
+		if c.IsChoice() || c.IsCase() {
+			if r := schemaChild(c, name); r != nil {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// simGenerator generates random values for the leaves of a YANG schema.
+type simGenerator struct {
+	rnd      *rand.Rand
+	listSize int
+	// paths the values are generated for,
+	// all the schema leaves if empty.
+	paths [][]*gnmi.PathElem
+	// current counters values, indexed by xpath.
+	counters map[string]uint64
+}
+
+func newSimGenerator(rnd *rand.Rand, listSize int, paths [][]*gnmi.PathElem) *simGenerator {
+	if listSize <= 0 {
+		listSize = 1
+	}
+	return &simGenerator{
+		rnd:      rnd,
+		listSize: listSize,
+		paths:    paths,
+		counters: make(map[string]uint64),
+	}
+}
+
+// updates returns updates with generated values for the leaves
+// of the modules under the schema root. If stateOnly is true, only read-only
+// leaves are generated.
+func (g *simGenerator) updates(root *yang.Entry, stateOnly bool) []*gnmi.Update {
+	upds := make([]*gnmi.Update, 0)
+	for _, m := range sortedEntries(root) {
+		for _, e := range sortedEntries(m) {
+			g.walk(e, nil, nil, stateOnly, &upds)
+		}
+	}
+	return upds
+}
+
+func (g *simGenerator) walk(e *yang.Entry, elems []*gnmi.PathElem, keys map[string]*gnmi.TypedValue, stateOnly bool, upds *[]*gnmi.Update) {
+	switch {
+	case e.IsChoice():
+		// generate the first case only
+		cases := sortedEntries(e)
+		if len(cases) > 0 {
+			g.walk(cases[0], elems, keys, stateOnly, upds)
+		}
+		return
+	case e.IsCase():
+		for _, c := range sortedEntries(e) {
+			g.walk(c, elems, keys, stateOnly, upds)
+		}
+		return
+	}
+	celems := append(copyPathElems(elems), &gnmi.PathElem{Name: e.Name})
+	match, under := g.match(celems)
+	if !match {
+		return
+	}
+	switch {
+	case e.IsLeaf():
+		if !under || (stateOnly && !e.ReadOnly()) {
+			return
+		}
+		tv, ok := keys[e.Name]
+		if !ok {
+			tv = g.value(e, e.Type, celems)
+		}
+		if tv != nil {
+			*upds = append(*upds, &gnmi.Update{Path: &gnmi.Path{Elem: celems}, Val: tv})
+		}
+	case e.IsLeafList():
+		if !under || (stateOnly && !e.ReadOnly()) {
+			return
+		}
+		tvs := make([]*gnmi.TypedValue, 0, g.listSize)
+		for i := 0; i < g.listSize; i++ {
+			if tv := g.value(e, e.Type, celems); tv != nil {
+				tvs = append(tvs, tv)
+			}
+		}
+		if len(tvs) == 0 {
+			return
+		}
+		*upds = append(*upds, &gnmi.Update{
+			Path: &gnmi.Path{Elem: celems},
+			Val: &gnmi.TypedValue{
+				Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: &gnmi.ScalarArray{Element: tvs}},
+			},
+		})
+	case e.IsList():
+		listKeys := strings.Fields(e.Key)
+		for i := 0; i < g.listSize; i++ {
+			lelems := copyPathElems(celems)
+			last := lelems[len(lelems)-1]
+			last.Key = make(map[string]string, len(listKeys))
+			// the key values are used for all the leaves named
+			// after a key in the list entry subtree.
+			ckeys := make(map[string]*gnmi.TypedValue, len(keys)+len(listKeys))
+			for k, v := range keys {
+				ckeys[k] = v
+			}
+			for _, k := range listKeys {
+				tv := g.keyValue(e.Name, e.Dir[k], i)
+				last.Key[k] = typedValueString(tv)
+				ckeys[k] = tv
+			}
+			if m, _ := g.match(lelems); !m {
+				continue
+			}
+			for _, c := range sortedEntries(e) {
+				g.walk(c, lelems, ckeys, stateOnly, upds)
+			}
+		}
+	case e.IsDir():
+		for _, c := range sortedEntries(e) {
+			g.walk(c, celems, keys, stateOnly, upds)
+		}
+	}
+}
+
+// match checks the path elements against the generator paths.
+// It returns true if the elements are a prefix of one of the paths
+// or under one of them, under is true in the latter case.
+func (g *simGenerator) match(elems []*gnmi.PathElem) (bool, bool) {
+	if len(g.paths) == 0 {
+		return true, true
+	}
+	matched := false
+	for _, p := range g.paths {
+		n := len(elems)
+		if len(p) < n {
+			n = len(p)
+		}
+		ok := true
+		for i := 0; i < n; i++ {
+			if !pathElemMatch(elems[i], p[i]) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if len(elems) >= len(p) {
+			return true, true
+		}
+		matched = true
+	}
+	return matched, false
+}
+
+func pathElemMatch(pe, filter *gnmi.PathElem) bool {
+	if filter.GetName() != "*" && filter.GetName() != pe.GetName() {
+		return false
+	}
+	for k, v := range filter.GetKey() {
+		if v == "*" {
+			continue
+		}
+		// keys are not set yet while walking down to the list entries.
+		if pv, ok := pe.GetKey()[k]; ok && pv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *simGenerator) keyValue(listName string, e *yang.Entry, i int) *gnmi.TypedValue {
+	if e != nil && e.Type != nil {
+		switch e.Type.Kind {
+		case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(i + 1)}}
+		case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(i + 1)}}
+		case yang.Yenum:
+			names := e.Type.Enum.Names()
+			if len(names) > 0 {
+				return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: names[i%len(names)]}}
+			}
+		}
+	}
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: fmt.Sprintf("%s-%d", listName, i+1)}}
+}
+
+func (g *simGenerator) value(e *yang.Entry, t *yang.YangType, elems []*gnmi.PathElem) *gnmi.TypedValue {
+	if t == nil {
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: e.Name}}
+	}
+	switch t.Kind {
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: g.rnd.Int63n(100)}}
+	case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		if isCounter(e, t) {
+			xp := path.GnmiPathToXPath(&gnmi.Path{Elem: elems}, false)
+			g.counters[xp] += uint64(g.rnd.Int63n(1000))
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: g.counters[xp]}}
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(g.rnd.Int63n(100))}}
+	case yang.Ydecimal64:
+		f := math.Pow10(t.FractionDigits)
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: math.Round(g.rnd.Float64()*100*f) / f}}
+	case yang.Ybool:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: g.rnd.Intn(2) == 1}}
+	case yang.Yenum:
+		if names := t.Enum.Names(); len(names) > 0 {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: names[g.rnd.Intn(len(names))]}}
+		}
+	case yang.Ybits:
+		if names := t.Bit.Names(); len(names) > 0 {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: names[g.rnd.Intn(len(names))]}}
+		}
+	case yang.Yidentityref:
+		if t.IdentityBase != nil && len(t.IdentityBase.Values) > 0 {
+			id := t.IdentityBase.Values[g.rnd.Intn(len(t.IdentityBase.Values))]
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: id.Name}}
+		}
+	case yang.Yunion:
+		if len(t.Type) > 0 {
+			return g.value(e, t.Type[0], elems)
+		}
+	case yang.Ybinary:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BytesVal{BytesVal: []byte(e.Name)}}
+	case yang.Yempty:
+		return nil
+	}
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: e.Name}}
+}
+
+// isCounter returns true if the leaf is a counter,
+// i.e its type is a counter or its parent is a `counters` container.
+func isCounter(e *yang.Entry, t *yang.YangType) bool {
+	if strings.HasPrefix(t.Name, "counter") {
+		return true
+	}
+	return e.Parent != nil && e.Parent.Name == "counters"
+}
+
+func sortedEntries(e *yang.Entry) []*yang.Entry {
+	names := make([]string, 0, len(e.Dir))
+	for n := range e.Dir {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	entries := make([]*yang.Entry, 0, len(names))
+	for _, n := range names {
+		entries = append(entries, e.Dir[n])
+	}
+	return entries
+}
+
+func typedValueString(tv *gnmi.TypedValue) string {
+	switch v := tv.GetValue().(type) {
+	case *gnmi.TypedValue_StringVal:
+		return v.StringVal
+	case *gnmi.TypedValue_IntVal:
+		return strconv.FormatInt(v.IntVal, 10)
+	case *gnmi.TypedValue_UintVal:
+		return strconv.FormatUint(v.UintVal, 10)
+	}
+	return tv.String()
+}
@@ -0,0 +1,209 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+	"gopkg.in/yaml.v2"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+func updatesToMap(upds []*gnmi.Update) map[string]string {
+	m := make(map[string]string, len(upds))
+	for _, upd := range upds {
+		m["/"+path.GnmiPathToXPath(upd.GetPath(), false)] = upd.GetVal().String()
+	}
+	return m
+}
+
+func TestTreeUpdates(t *testing.T) {
+	tests := map[string]struct {
+		in      string
+		want    map[string]*gnmi.TypedValue
+		wantErr bool
+	}{
+		"containers": {
+			in: `
+system:
+  config:
+    hostname: sim1
+    enabled: true
+    mtu: 1500
+    ratio: 1.5
+`,
+			want: map[string]*gnmi.TypedValue{
+				"/system/config/hostname": {Value: &gnmi.TypedValue_StringVal{StringVal: "sim1"}},
+				"/system/config/enabled":  {Value: &gnmi.TypedValue_BoolVal{BoolVal: true}},
+				"/system/config/mtu":      {Value: &gnmi.TypedValue_IntVal{IntVal: 1500}},
+				"/system/config/ratio":    {Value: &gnmi.TypedValue_DoubleVal{DoubleVal: 1.5}},
+			},
+		},
+		"list_and_leaf_list": {
+			in: `
+openconfig-interfaces:interfaces:
+  interface:
+    - name: eth0
+      state:
+        oper-status: UP
+  vlans: [1, 2]
+`,
+			want: map[string]*gnmi.TypedValue{
+				"/interfaces/interface[name=eth0]/name":              {Value: &gnmi.TypedValue_StringVal{StringVal: "eth0"}},
+				"/interfaces/interface[name=eth0]/state/oper-status": {Value: &gnmi.TypedValue_StringVal{StringVal: "UP"}},
+				"/interfaces/vlans": {Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: &gnmi.ScalarArray{
+					Element: []*gnmi.TypedValue{
+						{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+						{Value: &gnmi.TypedValue_IntVal{IntVal: 2}},
+					},
+				}}},
+			},
+		},
+		"path_keys": {
+			in: `
+interfaces/interface[name=eth0]:
+  state/oper-status: DOWN
+`,
+			want: map[string]*gnmi.TypedValue{
+				"/interfaces/interface[name=eth0]/state/oper-status": {Value: &gnmi.TypedValue_StringVal{StringVal: "DOWN"}},
+			},
+		},
+		"list_without_keys": {
+			in: `
+interfaces:
+  interface:
+    - description: eth0
+`,
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var v interface{}
+			if err := yaml.Unmarshal([]byte(tt.in), &v); err != nil {
+				t.Fatal(err)
+			}
+			upds, err := treeUpdates(nil, nil, v)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := updatesToMap(upds)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d updates, want %d: %v", len(got), len(tt.want), got)
+			}
+			for p, tv := range tt.want {
+				if got[p] != tv.String() {
+					t.Errorf("%s: got %q, want %q", p, got[p], tv.String())
+				}
+			}
+		})
+	}
+}
+
+func testSimulateSchema() *yang.Entry {
+	root := buildRootEntry()
+	mod := &yang.Entry{Name: "test", Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}}
+	root.Dir["test"] = mod
+	things := &yang.Entry{Name: "things", Kind: yang.DirectoryEntry, Parent: mod, Dir: map[string]*yang.Entry{}}
+	mod.Dir["things"] = things
+	thing := &yang.Entry{Name: "thing", Kind: yang.DirectoryEntry, Parent: things, Key: "id",
+		ListAttr: &yang.ListAttr{}, Dir: map[string]*yang.Entry{}}
+	things.Dir["thing"] = thing
+	thing.Dir["id"] = &yang.Entry{Name: "id", Kind: yang.LeafEntry, Parent: thing,
+		Type: &yang.YangType{Kind: yang.Yuint32}}
+	state := &yang.Entry{Name: "state", Kind: yang.DirectoryEntry, Parent: thing,
+		Config: yang.TSFalse, Dir: map[string]*yang.Entry{}}
+	thing.Dir["state"] = state
+	state.Dir["id"] = &yang.Entry{Name: "id", Kind: yang.LeafEntry, Parent: state,
+		Type: &yang.YangType{Kind: yang.Yuint32}}
+	state.Dir["in-pkts"] = &yang.Entry{Name: "in-pkts", Kind: yang.LeafEntry, Parent: state,
+		Type: &yang.YangType{Name: "counter64", Kind: yang.Yuint64}}
+	state.Dir["up"] = &yang.Entry{Name: "up", Kind: yang.LeafEntry, Parent: state,
+		Type: &yang.YangType{Kind: yang.Ybool}}
+	thing.Dir["description"] = &yang.Entry{Name: "description", Kind: yang.LeafEntry, Parent: thing,
+		Type: &yang.YangType{Kind: yang.Ystring}}
+	return root
+}
+
+func TestSimGenerator(t *testing.T) {
+	schema := testSimulateSchema()
+	gen := newSimGenerator(rand.New(rand.NewSource(1)), 2, nil)
+
+	got := updatesToMap(gen.updates(schema, false))
+	if len(got) != 10 {
+		t.Fatalf("got %d updates, want 10: %v", len(got), got)
+	}
+	for _, p := range []string{
+		"/things/thing[id=1]/id",
+		"/things/thing[id=2]/state/id",
+		"/things/thing[id=2]/description",
+	} {
+		if _, ok := got[p]; !ok {
+			t.Errorf("missing path %s", p)
+		}
+	}
+	keyVal := (&gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 2}}).String()
+	if v := got["/things/thing[id=2]/state/id"]; v != keyVal {
+		t.Errorf("got key leaf value %q, want %q", v, keyVal)
+	}
+
+	// state only updates, counters never decrease.
+	p := &gnmi.Path{Elem: []*gnmi.PathElem{
+		{Name: "things"},
+		{Name: "thing", Key: map[string]string{"id": "1"}},
+		{Name: "state"},
+		{Name: "in-pkts"},
+	}}
+	xp := path.GnmiPathToXPath(p, false)
+	var last uint64
+	for i := 0; i < 5; i++ {
+		upds := gen.updates(schema, true)
+		if len(upds) != 6 {
+			t.Fatalf("got %d state updates, want 6", len(upds))
+		}
+		for _, upd := range upds {
+			if path.GnmiPathToXPath(upd.GetPath(), false) != xp {
+				continue
+			}
+			v := upd.GetVal().GetUintVal()
+			if v < last {
+				t.Fatalf("counter decreased from %d to %d", last, v)
+			}
+			last = v
+		}
+	}
+}
+
+func TestSimGeneratorPaths(t *testing.T) {
+	schema := testSimulateSchema()
+	paths := make([][]*gnmi.PathElem, 0, 1)
+	gp, err := path.ParsePath("/things/thing[id=2]/state/up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, gp.GetElem())
+	gen := newSimGenerator(rand.New(rand.NewSource(1)), 3, paths)
+	got := updatesToMap(gen.updates(schema, false))
+	if len(got) != 1 {
+		t.Fatalf("got %d updates, want 1: %v", len(got), got)
+	}
+	if _, ok := got["/things/thing[id=2]/state/up"]; !ok {
+		t.Errorf("unexpected updates: %v", got)
+	}
+}
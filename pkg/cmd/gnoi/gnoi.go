@@ -0,0 +1,114 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gnoi
+
+import (
+	"github.com/openconfig/gnmic/pkg/app"
+	"github.com/spf13/cobra"
+)
+
+// New creates the gnoi command tree.
+func New(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gnoi",
+		Short: "run gNOI RPCs",
+	}
+	cmd.AddCommand(newSystemCmd(gApp))
+	cmd.AddCommand(newFileCmd(gApp))
+	cmd.AddCommand(newCertCmd(gApp))
+	cmd.AddCommand(newOSCmd(gApp))
+	cmd.AddCommand(newHealthzCmd(gApp))
+	return cmd
+}
+
+// newGNOICmd creates a gnoi leaf command,
+// its local flags can be set in the config file using the keys `gnoi-<service>-<rpc>-<flag>`.
+func newGNOICmd(gApp *app.App, service, use, short string,
+	runE func(cmd *cobra.Command, args []string) error,
+	initFlags func(cmd *cobra.Command)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          use,
+		Short:        short,
+		PreRunE:      gApp.GNOIPreRunE("gnoi-" + service + "-" + use),
+		RunE:         runE,
+		SilenceUsage: true,
+	}
+	if initFlags != nil {
+		initFlags(cmd)
+	}
+	return cmd
+}
+
+func newSystemCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "system",
+		Short: "run gNOI System RPCs",
+	}
+	cmd.AddCommand(
+		newGNOICmd(gApp, "system", "time", "get the target's current time", gApp.GNOITimeRunE, nil),
+		newGNOICmd(gApp, "system", "ping", "ping a destination from the target", gApp.GNOIPingRunE, gApp.InitGNOIPingFlags),
+		newGNOICmd(gApp, "system", "traceroute", "run a traceroute from the target", gApp.GNOITracerouteRunE, gApp.InitGNOITracerouteFlags),
+		newGNOICmd(gApp, "system", "reboot", "reboot the target or some of its subcomponents", gApp.GNOIRebootRunE, gApp.InitGNOIRebootFlags),
+		newGNOICmd(gApp, "system", "reboot-status", "get the status of a reboot", gApp.GNOIRebootStatusRunE, gApp.InitGNOIRebootStatusFlags),
+		newGNOICmd(gApp, "system", "cancel-reboot", "cancel a pending reboot", gApp.GNOICancelRebootRunE, gApp.InitGNOICancelRebootFlags),
+	)
+	return cmd
+}
+
+func newFileCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "file",
+		Short: "run gNOI File RPCs",
+	}
+	cmd.AddCommand(
+		newGNOICmd(gApp, "file", "get", "get files from the target", gApp.GNOIFileGetRunE, gApp.InitGNOIFileGetFlags),
+		newGNOICmd(gApp, "file", "put", "put a file on the target", gApp.GNOIFilePutRunE, gApp.InitGNOIFilePutFlags),
+		newGNOICmd(gApp, "file", "stat", "get files or directories information", gApp.GNOIFileStatRunE, gApp.InitGNOIFileStatFlags),
+		newGNOICmd(gApp, "file", "remove", "remove files from the target", gApp.GNOIFileRemoveRunE, gApp.InitGNOIFileRemoveFlags),
+	)
+	return cmd
+}
+
+func newCertCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cert",
+		Short: "run gNOI CertificateManagement RPCs",
+	}
+	cmd.AddCommand(
+		newGNOICmd(gApp, "cert", "get", "get the certificates installed on the target", gApp.GNOICertGetRunE, gApp.InitGNOICertGetFlags),
+		newGNOICmd(gApp, "cert", "install", "install a new certificate on the target", gApp.GNOICertInstallRunE, gApp.InitGNOICertInstallFlags),
+		newGNOICmd(gApp, "cert", "rotate", "rotate an existing certificate on the target", gApp.GNOICertRotateRunE, gApp.InitGNOICertRotateFlags),
+	)
+	return cmd
+}
+
+func newOSCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "os",
+		Short: "run gNOI OS RPCs",
+	}
+	cmd.AddCommand(
+		newGNOICmd(gApp, "os", "install", "transfer an OS package to the target", gApp.GNOIOSInstallRunE, gApp.InitGNOIOSInstallFlags),
+		newGNOICmd(gApp, "os", "activate", "activate an OS version on the target", gApp.GNOIOSActivateRunE, gApp.InitGNOIOSActivateFlags),
+		newGNOICmd(gApp, "os", "verify", "verify the running OS version", gApp.GNOIOSVerifyRunE, nil),
+	)
+	return cmd
+}
+
+func newHealthzCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "healthz",
+		Short: "run gNOI Healthz RPCs",
+	}
+	cmd.AddCommand(
+		newGNOICmd(gApp, "healthz", "get", "get the health of a component", gApp.GNOIHealthzGetRunE, gApp.InitGNOIHealthzGetFlags),
+		newGNOICmd(gApp, "healthz", "list", "list the health events of a component", gApp.GNOIHealthzListRunE, gApp.InitGNOIHealthzListFlags),
+	)
+	return cmd
+}
@@ -24,11 +24,13 @@ import (
 	"github.com/openconfig/gnmic/pkg/cmd/generate"
 	"github.com/openconfig/gnmic/pkg/cmd/get"
 	"github.com/openconfig/gnmic/pkg/cmd/getset"
+	"github.com/openconfig/gnmic/pkg/cmd/gnoi"
 	"github.com/openconfig/gnmic/pkg/cmd/listener"
 	"github.com/openconfig/gnmic/pkg/cmd/path"
 	"github.com/openconfig/gnmic/pkg/cmd/processor"
 	"github.com/openconfig/gnmic/pkg/cmd/proxy"
 	"github.com/openconfig/gnmic/pkg/cmd/set"
+	"github.com/openconfig/gnmic/pkg/cmd/simulate"
 	"github.com/openconfig/gnmic/pkg/cmd/subscribe"
 	"github.com/openconfig/gnmic/pkg/cmd/version"
 )
@@ -79,6 +81,8 @@ func newRootCmd() *cobra.Command {
 	gApp.RootCmd.AddCommand(version.New(gApp))
 	gApp.RootCmd.AddCommand(proxy.New(gApp))
 	gApp.RootCmd.AddCommand(processor.New(gApp))
+	gApp.RootCmd.AddCommand(gnoi.New(gApp))
+	gApp.RootCmd.AddCommand(simulate.New(gApp))
 	return gApp.RootCmd
 }
 
@@ -0,0 +1,28 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package simulate
+
+import (
+	"github.com/openconfig/gnmic/pkg/app"
+	"github.com/spf13/cobra"
+)
+
+// New returns the simulate command.
+func New(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "simulate",
+		Aliases:      []string{"sim"},
+		Short:        "run a simulated gNMI target serving data from a tree file, a recorded stream or YANG models",
+		PreRunE:      gApp.SimulatePreRunE,
+		RunE:         gApp.SimulateRunE,
+		SilenceUsage: true,
+	}
+	gApp.InitSimulateFlags(cmd)
+	return cmd
+}
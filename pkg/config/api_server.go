@@ -9,26 +9,95 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
+
 	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
 )
 
 const (
 	defaultAPIServerAddress = ":7890"
 	defaultAPIServerTimeout = 10 * time.Second
 	trueString              = "true"
+
+	defaultJWTUsernameClaim    = "sub"
+	defaultJWTRoleClaim        = "role"
+	defaultJWKSRefreshInterval = time.Hour
+)
+
+// API server roles, each role includes the permissions of the previous one.
+const (
+	// APIRoleRead allows the read-only (GET) endpoints.
+	APIRoleRead = "read"
+	// APIRoleWrite allows the endpoints changing the targets and configuration.
+	APIRoleWrite = "write"
+	// APIRoleAdmin allows the admin and cluster management endpoints.
+	APIRoleAdmin = "admin"
 )
 
 type APIServer struct {
-	Address       string           `mapstructure:"address,omitempty" json:"address,omitempty"`
-	Timeout       time.Duration    `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
-	TLS           *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
-	EnableMetrics bool             `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
-	Debug         bool             `mapstructure:"debug,omitempty" json:"debug,omitempty"`
-	HealthzDisableLogging bool     	   `mapstructure:"healthz-disable-logging,omitempty" json:"healthz-disable-logging,omitempty"`
+	Address               string           `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Timeout               time.Duration    `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	TLS                   *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	EnableMetrics         bool             `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug                 bool             `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	HealthzDisableLogging bool             `mapstructure:"healthz-disable-logging,omitempty" json:"healthz-disable-logging,omitempty"`
+	Auth                  *APIAuth         `mapstructure:"auth,omitempty" json:"auth,omitempty"`
+}
+
+// APIAuth is the API server authentication and authorization config.
+// secrets are not marshaled to JSON to avoid exposing them via the config API endpoints.
+type APIAuth struct {
+	Users  []*APIUser  `mapstructure:"users,omitempty" json:"users,omitempty"`
+	Tokens []*APIToken `mapstructure:"tokens,omitempty" json:"tokens,omitempty"`
+	JWT    *APIJWT     `mapstructure:"jwt,omitempty" json:"jwt,omitempty"`
+	// role given to requests without credentials, if empty they are rejected.
+	AnonymousRole string `mapstructure:"anonymous-role,omitempty" json:"anonymous-role,omitempty"`
+	// token with the admin role, used by the cluster members to call each other's API.
+	ClusterToken string       `mapstructure:"cluster-token,omitempty" json:"-"`
+	AuditLog     *APIAuditLog `mapstructure:"audit-log,omitempty" json:"audit-log,omitempty"`
+}
+
+// APIUser is a basic auth user.
+type APIUser struct {
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"-"`
+	// bcrypt hash of the password, used if password is not set.
+	PasswordHash string `mapstructure:"password-hash,omitempty" json:"-"`
+	Role         string `mapstructure:"role,omitempty" json:"role,omitempty"`
+}
+
+// APIToken is a static bearer token.
+type APIToken struct {
+	Name  string `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Token string `mapstructure:"token,omitempty" json:"-"`
+	Role  string `mapstructure:"role,omitempty" json:"role,omitempty"`
+}
+
+// APIJWT configures the validation of JWT bearer tokens.
+type APIJWT struct {
+	JWKSFile            string            `mapstructure:"jwks-file,omitempty" json:"jwks-file,omitempty"`
+	JWKSURL             string            `mapstructure:"jwks-url,omitempty" json:"jwks-url,omitempty"`
+	JWKSRefreshInterval time.Duration     `mapstructure:"jwks-refresh-interval,omitempty" json:"jwks-refresh-interval,omitempty"`
+	TLS                 *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Issuer              string            `mapstructure:"issuer,omitempty" json:"issuer,omitempty"`
+	Audience            string            `mapstructure:"audience,omitempty" json:"audience,omitempty"`
+	UsernameClaim       string            `mapstructure:"username-claim,omitempty" json:"username-claim,omitempty"`
+	RoleClaim           string            `mapstructure:"role-claim,omitempty" json:"role-claim,omitempty"`
+	RoleMapping         map[string]string `mapstructure:"role-mapping,omitempty" json:"role-mapping,omitempty"`
+	DefaultRole         string            `mapstructure:"default-role,omitempty" json:"default-role,omitempty"`
+}
+
+// APIAuditLog configures the audit log of the mutating API calls.
+type APIAuditLog struct {
+	// file the audit records are appended to, if empty they are written to the gnmic log.
+	File    string `mapstructure:"file,omitempty" json:"file,omitempty"`
+	Disable bool   `mapstructure:"disable,omitempty" json:"disable,omitempty"`
 }
 
 func (c *Config) GetAPIServer() error {
@@ -55,10 +124,108 @@ func (c *Config) GetAPIServer() error {
 	c.APIServer.EnableMetrics = os.ExpandEnv(c.FileConfig.GetString("api-server/enable-metrics")) == trueString
 	c.APIServer.Debug = os.ExpandEnv(c.FileConfig.GetString("api-server/debug")) == trueString
 	c.APIServer.HealthzDisableLogging = os.ExpandEnv(c.FileConfig.GetString("api-server/healthz-disable-logging")) == trueString
+	if c.FileConfig.IsSet("api-server/auth") {
+		err := c.getAPIServerAuth()
+		if err != nil {
+			return fmt.Errorf("api-server auth config error: %w", err)
+		}
+	}
 	c.setAPIServerDefaults()
 	return nil
 }
 
+func (c *Config) getAPIServerAuth() error {
+	c.APIServer.Auth = new(APIAuth)
+	decoder, err := mapstructure.NewDecoder(
+		&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+			Result:     c.APIServer.Auth,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = decoder.Decode(utils.Convert(c.FileConfig.Get("api-server/auth")))
+	if err != nil {
+		return err
+	}
+	auth := c.APIServer.Auth
+	auth.ClusterToken = os.ExpandEnv(auth.ClusterToken)
+	if auth.AnonymousRole != "" && !validAPIRole(auth.AnonymousRole) {
+		return fmt.Errorf("unknown anonymous-role %q", auth.AnonymousRole)
+	}
+	for i, u := range auth.Users {
+		if u == nil || u.Username == "" {
+			return fmt.Errorf("user %d: missing username", i)
+		}
+		u.Password = os.ExpandEnv(u.Password)
+		u.PasswordHash = os.ExpandEnv(u.PasswordHash)
+		if u.Password == "" && u.PasswordHash == "" {
+			return fmt.Errorf("user %q: missing password or password-hash", u.Username)
+		}
+		if !validAPIRole(u.Role) {
+			return fmt.Errorf("user %q: unknown role %q", u.Username, u.Role)
+		}
+	}
+	for i, t := range auth.Tokens {
+		if t == nil {
+			return fmt.Errorf("token %d: empty config", i)
+		}
+		t.Token = os.ExpandEnv(t.Token)
+		if t.Token == "" {
+			return fmt.Errorf("token %d %q: missing token value", i, t.Name)
+		}
+		if !validAPIRole(t.Role) {
+			return fmt.Errorf("token %d %q: unknown role %q", i, t.Name, t.Role)
+		}
+	}
+	if auth.JWT != nil {
+		jwt := auth.JWT
+		jwt.JWKSFile = os.ExpandEnv(jwt.JWKSFile)
+		jwt.JWKSURL = os.ExpandEnv(jwt.JWKSURL)
+		if jwt.JWKSFile == "" && jwt.JWKSURL == "" {
+			return errors.New("jwt: one of jwks-file or jwks-url must be set")
+		}
+		if jwt.JWKSFile != "" && jwt.JWKSURL != "" {
+			return errors.New("jwt: only one of jwks-file or jwks-url can be set")
+		}
+		if jwt.TLS != nil {
+			if err := jwt.TLS.Validate(); err != nil {
+				return fmt.Errorf("jwt: TLS config error: %w", err)
+			}
+		}
+		if jwt.JWKSRefreshInterval <= 0 {
+			jwt.JWKSRefreshInterval = defaultJWKSRefreshInterval
+		}
+		if jwt.UsernameClaim == "" {
+			jwt.UsernameClaim = defaultJWTUsernameClaim
+		}
+		if jwt.RoleClaim == "" {
+			jwt.RoleClaim = defaultJWTRoleClaim
+		}
+		for k, r := range jwt.RoleMapping {
+			if !validAPIRole(r) {
+				return fmt.Errorf("jwt: role-mapping %q: unknown role %q", k, r)
+			}
+		}
+		if jwt.DefaultRole != "" && !validAPIRole(jwt.DefaultRole) {
+			return fmt.Errorf("jwt: unknown default-role %q", jwt.DefaultRole)
+		}
+	}
+	if auth.AuditLog != nil {
+		auth.AuditLog.File = os.ExpandEnv(auth.AuditLog.File)
+	}
+	return nil
+}
+
+func validAPIRole(r string) bool {
+	switch r {
+	case APIRoleRead, APIRoleWrite, APIRoleAdmin:
+		return true
+	}
+	return false
+}
+
 func (c *Config) setAPIServerDefaults() {
 	if c.APIServer.Address == "" {
 		c.APIServer.Address = defaultAPIServerAddress
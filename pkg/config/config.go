@@ -269,6 +269,74 @@ type LocalFlags struct {
 	ProcessorInputDelimiter string   `mapstructure:"processor-input-delimiter,omitempty" yaml:"processor-input-delimiter,omitempty" json:"processor-input-delimiter,omitempty"`
 	ProcessorName           []string `mapstructure:"processor-name,omitempty" yaml:"processor-name,omitempty" json:"processor-name,omitempty"`
 	ProcessorOutput         string   `mapstructure:"processor-output,omitempty" yaml:"processor-output,omitempty" json:"processor-output,omitempty"`
+	// gNOI
+	GnoiPingDestination            string        `mapstructure:"gnoi-system-ping-destination,omitempty" yaml:"gnoi-system-ping-destination,omitempty" json:"gnoi-system-ping-destination,omitempty"`
+	GnoiPingSource                 string        `mapstructure:"gnoi-system-ping-source,omitempty" yaml:"gnoi-system-ping-source,omitempty" json:"gnoi-system-ping-source,omitempty"`
+	GnoiPingCount                  int32         `mapstructure:"gnoi-system-ping-count,omitempty" yaml:"gnoi-system-ping-count,omitempty" json:"gnoi-system-ping-count,omitempty"`
+	GnoiPingInterval               time.Duration `mapstructure:"gnoi-system-ping-interval,omitempty" yaml:"gnoi-system-ping-interval,omitempty" json:"gnoi-system-ping-interval,omitempty"`
+	GnoiPingWait                   time.Duration `mapstructure:"gnoi-system-ping-wait,omitempty" yaml:"gnoi-system-ping-wait,omitempty" json:"gnoi-system-ping-wait,omitempty"`
+	GnoiPingSize                   int32         `mapstructure:"gnoi-system-ping-size,omitempty" yaml:"gnoi-system-ping-size,omitempty" json:"gnoi-system-ping-size,omitempty"`
+	GnoiPingDoNotFragment          bool          `mapstructure:"gnoi-system-ping-do-not-fragment,omitempty" yaml:"gnoi-system-ping-do-not-fragment,omitempty" json:"gnoi-system-ping-do-not-fragment,omitempty"`
+	GnoiPingDoNotResolve           bool          `mapstructure:"gnoi-system-ping-do-not-resolve,omitempty" yaml:"gnoi-system-ping-do-not-resolve,omitempty" json:"gnoi-system-ping-do-not-resolve,omitempty"`
+	GnoiPingL3Protocol             string        `mapstructure:"gnoi-system-ping-l3protocol,omitempty" yaml:"gnoi-system-ping-l3protocol,omitempty" json:"gnoi-system-ping-l3protocol,omitempty"`
+	GnoiPingNetworkInstance        string        `mapstructure:"gnoi-system-ping-network-instance,omitempty" yaml:"gnoi-system-ping-network-instance,omitempty" json:"gnoi-system-ping-network-instance,omitempty"`
+	GnoiTracerouteDestination      string        `mapstructure:"gnoi-system-traceroute-destination,omitempty" yaml:"gnoi-system-traceroute-destination,omitempty" json:"gnoi-system-traceroute-destination,omitempty"`
+	GnoiTracerouteSource           string        `mapstructure:"gnoi-system-traceroute-source,omitempty" yaml:"gnoi-system-traceroute-source,omitempty" json:"gnoi-system-traceroute-source,omitempty"`
+	GnoiTracerouteInitialTTL       uint32        `mapstructure:"gnoi-system-traceroute-initial-ttl,omitempty" yaml:"gnoi-system-traceroute-initial-ttl,omitempty" json:"gnoi-system-traceroute-initial-ttl,omitempty"`
+	GnoiTracerouteMaxTTL           int32         `mapstructure:"gnoi-system-traceroute-max-ttl,omitempty" yaml:"gnoi-system-traceroute-max-ttl,omitempty" json:"gnoi-system-traceroute-max-ttl,omitempty"`
+	GnoiTracerouteWait             time.Duration `mapstructure:"gnoi-system-traceroute-wait,omitempty" yaml:"gnoi-system-traceroute-wait,omitempty" json:"gnoi-system-traceroute-wait,omitempty"`
+	GnoiTracerouteDoNotFragment    bool          `mapstructure:"gnoi-system-traceroute-do-not-fragment,omitempty" yaml:"gnoi-system-traceroute-do-not-fragment,omitempty" json:"gnoi-system-traceroute-do-not-fragment,omitempty"`
+	GnoiTracerouteDoNotResolve     bool          `mapstructure:"gnoi-system-traceroute-do-not-resolve,omitempty" yaml:"gnoi-system-traceroute-do-not-resolve,omitempty" json:"gnoi-system-traceroute-do-not-resolve,omitempty"`
+	GnoiTracerouteL3Protocol       string        `mapstructure:"gnoi-system-traceroute-l3protocol,omitempty" yaml:"gnoi-system-traceroute-l3protocol,omitempty" json:"gnoi-system-traceroute-l3protocol,omitempty"`
+	GnoiTracerouteL4Protocol       string        `mapstructure:"gnoi-system-traceroute-l4protocol,omitempty" yaml:"gnoi-system-traceroute-l4protocol,omitempty" json:"gnoi-system-traceroute-l4protocol,omitempty"`
+	GnoiTracerouteDoNotLookupASN   bool          `mapstructure:"gnoi-system-traceroute-do-not-lookup-asn,omitempty" yaml:"gnoi-system-traceroute-do-not-lookup-asn,omitempty" json:"gnoi-system-traceroute-do-not-lookup-asn,omitempty"`
+	GnoiTracerouteNetworkInstance  string        `mapstructure:"gnoi-system-traceroute-network-instance,omitempty" yaml:"gnoi-system-traceroute-network-instance,omitempty" json:"gnoi-system-traceroute-network-instance,omitempty"`
+	GnoiRebootMethod               string        `mapstructure:"gnoi-system-reboot-method,omitempty" yaml:"gnoi-system-reboot-method,omitempty" json:"gnoi-system-reboot-method,omitempty"`
+	GnoiRebootDelay                time.Duration `mapstructure:"gnoi-system-reboot-delay,omitempty" yaml:"gnoi-system-reboot-delay,omitempty" json:"gnoi-system-reboot-delay,omitempty"`
+	GnoiRebootMessage              string        `mapstructure:"gnoi-system-reboot-message,omitempty" yaml:"gnoi-system-reboot-message,omitempty" json:"gnoi-system-reboot-message,omitempty"`
+	GnoiRebootSubcomponent         []string      `mapstructure:"gnoi-system-reboot-subcomponent,omitempty" yaml:"gnoi-system-reboot-subcomponent,omitempty" json:"gnoi-system-reboot-subcomponent,omitempty"`
+	GnoiRebootForce                bool          `mapstructure:"gnoi-system-reboot-force,omitempty" yaml:"gnoi-system-reboot-force,omitempty" json:"gnoi-system-reboot-force,omitempty"`
+	GnoiFileGetFile                []string      `mapstructure:"gnoi-file-get-file,omitempty" yaml:"gnoi-file-get-file,omitempty" json:"gnoi-file-get-file,omitempty"`
+	GnoiFileGetDst                 string        `mapstructure:"gnoi-file-get-dst,omitempty" yaml:"gnoi-file-get-dst,omitempty" json:"gnoi-file-get-dst,omitempty"`
+	GnoiFilePutFile                string        `mapstructure:"gnoi-file-put-file,omitempty" yaml:"gnoi-file-put-file,omitempty" json:"gnoi-file-put-file,omitempty"`
+	GnoiFilePutDst                 string        `mapstructure:"gnoi-file-put-dst,omitempty" yaml:"gnoi-file-put-dst,omitempty" json:"gnoi-file-put-dst,omitempty"`
+	GnoiFilePutPermissions         uint32        `mapstructure:"gnoi-file-put-permissions,omitempty" yaml:"gnoi-file-put-permissions,omitempty" json:"gnoi-file-put-permissions,omitempty"`
+	GnoiFilePutChunkSize           uint64        `mapstructure:"gnoi-file-put-chunk-size,omitempty" yaml:"gnoi-file-put-chunk-size,omitempty" json:"gnoi-file-put-chunk-size,omitempty"`
+	GnoiFilePutHashMethod          string        `mapstructure:"gnoi-file-put-hash-method,omitempty" yaml:"gnoi-file-put-hash-method,omitempty" json:"gnoi-file-put-hash-method,omitempty"`
+	GnoiFileStatPath               []string      `mapstructure:"gnoi-file-stat-path,omitempty" yaml:"gnoi-file-stat-path,omitempty" json:"gnoi-file-stat-path,omitempty"`
+	GnoiFileRemovePath             []string      `mapstructure:"gnoi-file-remove-path,omitempty" yaml:"gnoi-file-remove-path,omitempty" json:"gnoi-file-remove-path,omitempty"`
+	GnoiCertID                     string        `mapstructure:"gnoi-cert-id,omitempty" yaml:"gnoi-cert-id,omitempty" json:"gnoi-cert-id,omitempty"`
+	GnoiCertCert                   string        `mapstructure:"gnoi-cert-cert,omitempty" yaml:"gnoi-cert-cert,omitempty" json:"gnoi-cert-cert,omitempty"`
+	GnoiCertKey                    string        `mapstructure:"gnoi-cert-key,omitempty" yaml:"gnoi-cert-key,omitempty" json:"gnoi-cert-key,omitempty"`
+	GnoiCertCACert                 string        `mapstructure:"gnoi-cert-ca-cert,omitempty" yaml:"gnoi-cert-ca-cert,omitempty" json:"gnoi-cert-ca-cert,omitempty"`
+	GnoiCertCAKey                  string        `mapstructure:"gnoi-cert-ca-key,omitempty" yaml:"gnoi-cert-ca-key,omitempty" json:"gnoi-cert-ca-key,omitempty"`
+	GnoiCertValidity               time.Duration `mapstructure:"gnoi-cert-validity,omitempty" yaml:"gnoi-cert-validity,omitempty" json:"gnoi-cert-validity,omitempty"`
+	GnoiCertKeyType                string        `mapstructure:"gnoi-cert-key-type,omitempty" yaml:"gnoi-cert-key-type,omitempty" json:"gnoi-cert-key-type,omitempty"`
+	GnoiCertKeySize                uint32        `mapstructure:"gnoi-cert-key-size,omitempty" yaml:"gnoi-cert-key-size,omitempty" json:"gnoi-cert-key-size,omitempty"`
+	GnoiCertCommonName             string        `mapstructure:"gnoi-cert-common-name,omitempty" yaml:"gnoi-cert-common-name,omitempty" json:"gnoi-cert-common-name,omitempty"`
+	GnoiCertCountry                string        `mapstructure:"gnoi-cert-country,omitempty" yaml:"gnoi-cert-country,omitempty" json:"gnoi-cert-country,omitempty"`
+	GnoiCertState                  string        `mapstructure:"gnoi-cert-state,omitempty" yaml:"gnoi-cert-state,omitempty" json:"gnoi-cert-state,omitempty"`
+	GnoiCertCity                   string        `mapstructure:"gnoi-cert-city,omitempty" yaml:"gnoi-cert-city,omitempty" json:"gnoi-cert-city,omitempty"`
+	GnoiCertOrg                    string        `mapstructure:"gnoi-cert-org,omitempty" yaml:"gnoi-cert-org,omitempty" json:"gnoi-cert-org,omitempty"`
+	GnoiCertOrgUnit                string        `mapstructure:"gnoi-cert-org-unit,omitempty" yaml:"gnoi-cert-org-unit,omitempty" json:"gnoi-cert-org-unit,omitempty"`
+	GnoiCertIPAddress              string        `mapstructure:"gnoi-cert-ip-address,omitempty" yaml:"gnoi-cert-ip-address,omitempty" json:"gnoi-cert-ip-address,omitempty"`
+	GnoiCertEmailID                string        `mapstructure:"gnoi-cert-email-id,omitempty" yaml:"gnoi-cert-email-id,omitempty" json:"gnoi-cert-email-id,omitempty"`
+	GnoiOSVersion                  string        `mapstructure:"gnoi-os-version,omitempty" yaml:"gnoi-os-version,omitempty" json:"gnoi-os-version,omitempty"`
+	GnoiOSPackage                  string        `mapstructure:"gnoi-os-pkg,omitempty" yaml:"gnoi-os-pkg,omitempty" json:"gnoi-os-pkg,omitempty"`
+	GnoiOSStandby                  bool          `mapstructure:"gnoi-os-standby,omitempty" yaml:"gnoi-os-standby,omitempty" json:"gnoi-os-standby,omitempty"`
+	GnoiOSNoReboot                 bool          `mapstructure:"gnoi-os-no-reboot,omitempty" yaml:"gnoi-os-no-reboot,omitempty" json:"gnoi-os-no-reboot,omitempty"`
+	GnoiOSChunkSize                uint64        `mapstructure:"gnoi-os-chunk-size,omitempty" yaml:"gnoi-os-chunk-size,omitempty" json:"gnoi-os-chunk-size,omitempty"`
+	GnoiHealthzPath                string        `mapstructure:"gnoi-healthz-path,omitempty" yaml:"gnoi-healthz-path,omitempty" json:"gnoi-healthz-path,omitempty"`
+	GnoiHealthzIncludeAcknowledged bool          `mapstructure:"gnoi-healthz-include-acknowledged,omitempty" yaml:"gnoi-healthz-include-acknowledged,omitempty" json:"gnoi-healthz-include-acknowledged,omitempty"`
+	// Simulate
+	SimulateName           string        `mapstructure:"simulate-name,omitempty" yaml:"simulate-name,omitempty" json:"simulate-name,omitempty"`
+	SimulateTree           []string      `mapstructure:"simulate-tree,omitempty" yaml:"simulate-tree,omitempty" json:"simulate-tree,omitempty"`
+	SimulateReplay         string        `mapstructure:"simulate-replay,omitempty" yaml:"simulate-replay,omitempty" json:"simulate-replay,omitempty"`
+	SimulateReplayInterval time.Duration `mapstructure:"simulate-replay-interval,omitempty" yaml:"simulate-replay-interval,omitempty" json:"simulate-replay-interval,omitempty"`
+	SimulateReplayLoop     bool          `mapstructure:"simulate-replay-loop,omitempty" yaml:"simulate-replay-loop,omitempty" json:"simulate-replay-loop,omitempty"`
+	SimulateRandomPath     []string      `mapstructure:"simulate-random-path,omitempty" yaml:"simulate-random-path,omitempty" json:"simulate-random-path,omitempty"`
+	SimulateRandomInterval time.Duration `mapstructure:"simulate-random-interval,omitempty" yaml:"simulate-random-interval,omitempty" json:"simulate-random-interval,omitempty"`
+	SimulateListSize       int           `mapstructure:"simulate-list-size,omitempty" yaml:"simulate-list-size,omitempty" json:"simulate-list-size,omitempty"`
 }
 
 func New() *Config {
@@ -396,8 +464,15 @@ func (c *Config) SetPersistentFlagsFromFile(cmd *cobra.Command) {
 }
 
 func (c *Config) SetLocalFlagsFromFile(cmd *cobra.Command) {
+	c.SetLocalFlagsFromFileWithPrefix(cmd, cmd.Name())
+}
+
+// SetLocalFlagsFromFileWithPrefix sets the command local flags not set on the command line
+// from the config file keys `<prefix>-<flag name>`.
+// It is used by nested commands that share the same name, e.g `gnmic gnoi file get` and `gnmic get`.
+func (c *Config) SetLocalFlagsFromFileWithPrefix(cmd *cobra.Command, prefix string) {
 	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
-		flagName := fmt.Sprintf("%s-%s", cmd.Name(), f.Name)
+		flagName := fmt.Sprintf("%s-%s", prefix, f.Name)
 		if c.Debug {
 			c.logger.Printf("cmd=%s, flagName=%s, changed=%v, isSetInFile=%v",
 				cmd.Name(), f.Name, f.Changed, c.FileConfig.IsSet(flagName))
@@ -177,6 +177,20 @@ func (c *Config) GetGNMIServer() error {
 	return nil
 }
 
+// GetGNMIServerWithDefaults reads the gnmi-server config section
+// and initializes it with its default values if it is not set.
+func (c *Config) GetGNMIServerWithDefaults() error {
+	err := c.GetGNMIServer()
+	if err != nil {
+		return err
+	}
+	if c.GnmiServer == nil {
+		c.GnmiServer = new(gnmiServer)
+		c.setGnmiServerDefaults()
+	}
+	return nil
+}
+
 func (c *Config) setGnmiServerDefaults() {
 	if c.GnmiServer.Address == "" {
 		c.GnmiServer.Address = defaultAddress
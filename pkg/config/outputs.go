@@ -80,6 +80,28 @@ func (c *Config) GetOutputs() (map[string]map[string]interface{}, error) {
 	return filteredOutputs, nil
 }
 
+// DecodeOutputConfig validates an output config received at runtime,
+// e.g: through the API, and sets its default values.
+func (c *Config) DecodeOutputConfig(cfg map[string]interface{}) (map[string]interface{}, error) {
+	outCfg := convert(cfg).(map[string]interface{})
+	outType, ok := outCfg["type"].(string)
+	if !ok || outType == "" {
+		return nil, fmt.Errorf("missing output 'type'")
+	}
+	if _, ok := outputs.OutputTypes[outType]; !ok {
+		return nil, fmt.Errorf("unknown output type: %q", outType)
+	}
+	if _, ok := outputs.Outputs[outType]; !ok {
+		return nil, fmt.Errorf("unknown output type: %q", outType)
+	}
+	format, ok := outCfg["format"]
+	if !ok || format == "" {
+		outCfg["format"] = c.FileConfig.GetString("format")
+	}
+	expandMapEnv(outCfg, expandExcept("msg-template", "target-template"))
+	return outCfg, nil
+}
+
 func convert(i interface{}) interface{} {
 	switch x := i.(type) {
 	case map[interface{}]interface{}:
@@ -39,14 +39,7 @@ func (c *Config) GetEventProcessors() (map[string]map[string]interface{}, error)
 		c.Processors[n] = es
 	}
 	for n := range c.Processors {
-		expandMapEnv(c.Processors[n], expandExcept(
-			"expression",
-			"condition",
-			"value-names", "values",
-			"tag-names", "tags",
-			"old", "new", // strings.replace
-			"source", // starlark
-		))
+		expandProcessorEnv(c.Processors[n])
 	}
 	if c.Debug {
 		c.logger.Printf("processors: %+v", c.Processors)
@@ -54,6 +47,34 @@ func (c *Config) GetEventProcessors() (map[string]map[string]interface{}, error)
 	return c.Processors, nil
 }
 
+// DecodeProcessorConfig validates an event processor config received at runtime,
+// e.g: through the API.
+func (c *Config) DecodeProcessorConfig(pcfg map[string]interface{}) (map[string]interface{}, error) {
+	if len(pcfg) != 1 {
+		return nil, fmt.Errorf("processor config must have exactly one processor type, got %d", len(pcfg))
+	}
+	err := c.validateProcessorConfig(pcfg)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range pcfg {
+		pcfg[k] = convert(v)
+	}
+	expandProcessorEnv(pcfg)
+	return pcfg, nil
+}
+
+func expandProcessorEnv(pcfg map[string]interface{}) {
+	expandMapEnv(pcfg, expandExcept(
+		"expression",
+		"condition",
+		"value-names", "values",
+		"tag-names", "tags",
+		"old", "new", // strings.replace
+		"source", // starlark
+	))
+}
+
 func (c *Config) validateProcessorConfig(pcfg map[string]interface{}) error {
 	for epType := range pcfg {
 		if !strInlist(epType, formatters.EventProcessorTypes) {
@@ -0,0 +1,167 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	gfile "github.com/openconfig/gnmic/pkg/file"
+)
+
+// reloadableSections are the config file sections read again by Reload.
+var reloadableSections = []string{
+	"targets",
+	"subscriptions",
+	"outputs",
+	"inputs",
+	"processors",
+	"actions",
+}
+
+// Reload reads the config file again and returns a new Config containing
+// its targets, subscriptions, outputs, inputs, processors and actions.
+// The other config file sections as well as the flags keep their current values.
+// The returned config is fully validated, c is not modified.
+func (c *Config) Reload(ctx context.Context, cmd *cobra.Command) (*Config, error) {
+	cfgFile := c.FileConfig.ConfigFileUsed()
+	if cfgFile == "" {
+		return nil, errors.New("no config file to reload")
+	}
+	nc := New()
+	nc.GlobalFlags = c.GlobalFlags
+	nc.LocalFlags = c.LocalFlags
+	nc.logger = c.logger
+
+	nc.FileConfig.SetEnvPrefix(envPrefix)
+	nc.FileConfig.SetEnvKeyReplacer(strings.NewReplacer("/", "_", "-", "_"))
+	nc.FileConfig.AutomaticEnv()
+	nc.FileConfig.SetConfigFile(cfgFile)
+	b, err := gfile.ReadFile(ctx, cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	err = nc.FileConfig.ReadConfig(bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	nc.mergeEnvVars()
+	// keep the current values of the non reloadable keys,
+	// this includes the ones bound to flags.
+	for _, k := range c.FileConfig.AllKeys() {
+		if isReloadableKey(k) {
+			continue
+		}
+		nc.FileConfig.Set(k, c.FileConfig.Get(k))
+	}
+
+	_, err = nc.GetSubscriptions(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading subscriptions config: %v", err)
+	}
+	_, err = nc.GetOutputs()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading outputs config: %v", err)
+	}
+	_, err = nc.GetInputs()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading inputs config: %v", err)
+	}
+	_, err = nc.GetActions()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading actions config: %v", err)
+	}
+	_, err = nc.GetEventProcessors()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading event processors config: %v", err)
+	}
+	_, err = nc.GetTargets()
+	if err != nil && !errors.Is(err, ErrNoTargetsFound) {
+		return nil, fmt.Errorf("failed reading targets config: %v", err)
+	}
+	err = nc.validateReferences()
+	if err != nil {
+		return nil, err
+	}
+	return nc, nil
+}
+
+// validateReferences checks that the subscriptions, outputs and processors
+// referenced by the targets, subscriptions, outputs and inputs exist.
+func (c *Config) validateReferences() error {
+	for n, tc := range c.Targets {
+		for _, s := range tc.Subscriptions {
+			if _, ok := c.Subscriptions[s]; !ok {
+				return fmt.Errorf("%w: target %q: unknown subscription %q", ErrConfig, n, s)
+			}
+		}
+		for _, o := range tc.Outputs {
+			if _, ok := c.Outputs[o]; !ok {
+				return fmt.Errorf("%w: target %q: unknown output %q", ErrConfig, n, o)
+			}
+		}
+	}
+	for n, sc := range c.Subscriptions {
+		for _, o := range sc.Outputs {
+			if _, ok := c.Outputs[o]; !ok {
+				return fmt.Errorf("%w: subscription %q: unknown output %q", ErrConfig, n, o)
+			}
+		}
+	}
+	for n, cfg := range c.Outputs {
+		for _, p := range stringList(cfg["event-processors"]) {
+			if _, ok := c.Processors[p]; !ok {
+				return fmt.Errorf("%w: output %q: unknown event processor %q", ErrConfig, n, p)
+			}
+		}
+	}
+	for n, cfg := range c.Inputs {
+		for _, p := range stringList(cfg["event-processors"]) {
+			if _, ok := c.Processors[p]; !ok {
+				return fmt.Errorf("%w: input %q: unknown event processor %q", ErrConfig, n, p)
+			}
+		}
+		for _, o := range stringList(cfg["outputs"]) {
+			if _, ok := c.Outputs[o]; !ok {
+				return fmt.Errorf("%w: input %q: unknown output %q", ErrConfig, n, o)
+			}
+		}
+	}
+	return nil
+}
+
+func isReloadableKey(k string) bool {
+	for _, s := range reloadableSections {
+		if k == s || strings.HasPrefix(k, s+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func stringList(i interface{}) []string {
+	switch i := i.(type) {
+	case []string:
+		return i
+	case []interface{}:
+		ls := make([]string, 0, len(i))
+		for _, item := range i {
+			if s, ok := item.(string); ok {
+				ls = append(ls, s)
+			}
+		}
+		return ls
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var reloadInitialConfig = []byte(`
+format: event
+targets:
+  router1:
+    address: 10.0.0.1:57400
+    subscriptions:
+      - sub1
+subscriptions:
+  sub1:
+    paths:
+      - /interfaces
+outputs:
+  out1:
+    type: file
+    file-type: stdout
+`)
+
+func TestReload(t *testing.T) {
+	tests := map[string]struct {
+		in      string
+		wantErr bool
+		check   func(t *testing.T, nc *Config)
+	}{
+		"valid": {
+			in: `
+format: json
+targets:
+  router1:
+    address: 10.0.0.1:57400
+    subscriptions:
+      - sub2
+  router2:
+    address: 10.0.0.2:57400
+subscriptions:
+  sub2:
+    paths:
+      - /system
+outputs:
+  out1:
+    type: file
+    file-type: stdout
+    event-processors:
+      - proc1
+processors:
+  proc1:
+    event-drop:
+      condition: .tags.x == "y"
+`,
+			check: func(t *testing.T, nc *Config) {
+				if len(nc.Targets) != 2 {
+					t.Errorf("got %d targets, want 2", len(nc.Targets))
+				}
+				if _, ok := nc.Subscriptions["sub2"]; !ok || len(nc.Subscriptions) != 1 {
+					t.Errorf("unexpected subscriptions: %v", nc.Subscriptions)
+				}
+				if _, ok := nc.Processors["proc1"]; !ok {
+					t.Errorf("missing processor proc1")
+				}
+				// non reloadable keys keep their value
+				if f := nc.Outputs["out1"]["format"]; f != "event" {
+					t.Errorf("got output format %v, want event", f)
+				}
+			},
+		},
+		"unknown_subscription": {
+			in: `
+targets:
+  router1:
+    subscriptions:
+      - sub3
+subscriptions:
+  sub1:
+    paths:
+      - /interfaces
+`,
+			wantErr: true,
+		},
+		"unknown_processor": {
+			in: `
+subscriptions:
+  sub1:
+    paths:
+      - /interfaces
+outputs:
+  out1:
+    type: file
+    event-processors:
+      - proc2
+`,
+			wantErr: true,
+		},
+		"unknown_output_type": {
+			in: `
+outputs:
+  out1:
+    type: not-an-output
+`,
+			wantErr: true,
+		},
+		"malformed": {
+			in:      "targets: [",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfgFile := filepath.Join(t.TempDir(), "gnmic.yaml")
+			if err := os.WriteFile(cfgFile, reloadInitialConfig, 0600); err != nil {
+				t.Fatal(err)
+			}
+			c := New()
+			c.GlobalFlags.CfgFile = cfgFile
+			if err := c.Load(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.GetSubscriptions(nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(cfgFile, []byte(tt.in), 0600); err != nil {
+				t.Fatal(err)
+			}
+			nc, err := c.Reload(context.Background(), nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := c.Subscriptions["sub1"]; !ok {
+				t.Errorf("current config modified by reload")
+			}
+			tt.check(t, nc)
+		})
+	}
+}
@@ -66,7 +66,7 @@ func (c *Config) GetSubscriptions(cmd *cobra.Command) (map[string]*types.Subscri
 		if c.Debug {
 			c.logger.Printf("subscriptions: %s", c.Subscriptions)
 		}
-		err := validateSubscriptionsConfig(c.Subscriptions)
+		err := ValidateSubscriptionsConfig(c.Subscriptions)
 		if err != nil {
 			return nil, err
 		}
@@ -87,7 +87,7 @@ func (c *Config) GetSubscriptions(cmd *cobra.Command) (map[string]*types.Subscri
 	if c.Debug {
 		c.logger.Printf("subscriptions: %s", filteredSubscriptions)
 	}
-	err := validateSubscriptionsConfig(filteredSubscriptions)
+	err := ValidateSubscriptionsConfig(filteredSubscriptions)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +174,20 @@ func (c *Config) decodeSubscriptionConfig(sn string, s any, cmd *cobra.Command)
 	return sub, nil
 }
 
+// DecodeSubscriptionConfig decodes and validates a subscription config
+// received at runtime, e.g: through the API.
+func (c *Config) DecodeSubscriptionConfig(name string, s map[string]any) (*types.SubscriptionConfig, error) {
+	sub, err := c.decodeSubscriptionConfig(name, s, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = validateAndSetDefaults(sub)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
 func (c *Config) setSubscriptionFieldsFromFlags(sub *types.SubscriptionConfig, cmd *cobra.Command) error {
 	if sub.SampleInterval == nil && flagIsSet(cmd, "sample-interval") {
 		sub.SampleInterval = &c.LocalFlags.SubscribeSampleInterval
@@ -490,7 +504,8 @@ func validateAndSetDefaults(sc *types.SubscriptionConfig) error {
 	return nil
 }
 
-func validateSubscriptionsConfig(subs map[string]*types.SubscriptionConfig) error {
+// ValidateSubscriptionsConfig checks that the subscriptions in subs can be used together.
+func ValidateSubscriptionsConfig(subs map[string]*types.SubscriptionConfig) error {
 	var hasPoll bool
 	var hasOnce bool
 	var hasStream bool
@@ -10,6 +10,7 @@ package all
 
 import (
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_add_tag"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_aggregate"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_allow"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_combine"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_convert"
@@ -24,6 +25,7 @@ import (
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_jq"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_merge"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_override_ts"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_rate"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_rate_limit"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_starlark"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_strings"
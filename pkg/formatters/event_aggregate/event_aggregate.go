@@ -0,0 +1,510 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_aggregate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-aggregate"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+var defaultFunctions = []string{"min", "max", "avg", "sum", "count"}
+
+// aggregate groups events by tags over tumbling or sliding time windows
+// and replaces their values with per window statistics.
+type aggregate struct {
+	Tags       []string      `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	ValueNames []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Window     time.Duration `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Slide      time.Duration `mapstructure:"slide,omitempty" json:"slide,omitempty"`
+	Functions  []string      `mapstructure:"functions,omitempty" json:"functions,omitempty"`
+	// AllowedLateness is how far behind its group's newest event
+	// an event can be and still be added to its windows.
+	AllowedLateness time.Duration `mapstructure:"allowed-lateness,omitempty" json:"allowed-lateness,omitempty"`
+	// IdleTimeout is the wall clock duration after which the open windows
+	// of a group that stopped receiving events are flushed.
+	IdleTimeout  time.Duration `mapstructure:"idle-timeout,omitempty" json:"idle-timeout,omitempty"`
+	KeepOriginal bool          `mapstructure:"keep-original,omitempty" json:"keep-original,omitempty"`
+	Debug        bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	functions  []aggFunc
+	// keepSamples is true if a percentile is calculated,
+	// in which case all the samples of a window are stored.
+	keepSamples bool
+
+	m sync.Mutex
+	// groups indexed by their key, see groupKey.
+	groups map[uint64]*group
+	now    func() time.Time
+	logger *log.Logger
+}
+
+type group struct {
+	name string
+	tags map[string]string
+	// watermark is the highest event timestamp of the group.
+	// windows ending more than AllowedLateness before it are complete.
+	watermark int64
+	// lastSeen is the wall clock time of the group's last event.
+	lastSeen time.Time
+	// windows indexed by their start time (unix nano)
+	windows map[int64]map[string]*stats
+}
+
+type stats struct {
+	count   int64
+	sum     float64
+	min     float64
+	max     float64
+	samples []float64
+}
+
+type aggFunc struct {
+	name string
+	// suffix is appended to the value name to build the aggregated value name.
+	suffix     string
+	percentile float64
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &aggregate{
+			now:    time.Now,
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *aggregate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Window <= 0 {
+		return errors.New("window must be greater than 0")
+	}
+	if p.Slide <= 0 {
+		p.Slide = p.Window
+	}
+	if p.Slide > p.Window {
+		return fmt.Errorf("slide %s must not be greater than window %s", p.Slide, p.Window)
+	}
+	if p.AllowedLateness < 0 {
+		return errors.New("allowed-lateness must not be negative")
+	}
+	if p.IdleTimeout <= 0 {
+		p.IdleTimeout = p.Window + p.AllowedLateness
+	}
+	p.valueNames = make([]*regexp.Regexp, 0, len(p.ValueNames))
+	for _, reg := range p.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.valueNames = append(p.valueNames, re)
+	}
+	if len(p.Functions) == 0 {
+		p.Functions = defaultFunctions
+	}
+	p.functions = make([]aggFunc, 0, len(p.Functions))
+	for _, fn := range p.Functions {
+		af, err := parseFunction(fn)
+		if err != nil {
+			return err
+		}
+		if af.name == "percentile" {
+			p.keepSamples = true
+		}
+		p.functions = append(p.functions, af)
+	}
+	p.groups = make(map[uint64]*group)
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func parseFunction(fn string) (aggFunc, error) {
+	fn = strings.ToLower(strings.TrimSpace(fn))
+	switch fn {
+	case "min", "max", "avg", "sum", "count":
+		return aggFunc{name: fn, suffix: fn}, nil
+	}
+	if strings.HasPrefix(fn, "p") {
+		pv, err := strconv.ParseFloat(fn[1:], 64)
+		if err == nil && pv > 0 && pv <= 100 {
+			return aggFunc{name: "percentile", suffix: fn, percentile: pv}, nil
+		}
+	}
+	return aggFunc{}, fmt.Errorf("unknown aggregation function %q", fn)
+}
+
+func (p *aggregate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		aggregated := p.add(e)
+		if p.KeepOriginal || len(aggregated) == 0 {
+			result = append(result, e)
+			continue
+		}
+		// the values that are not aggregated are passed through.
+		for _, k := range aggregated {
+			delete(e.Values, k)
+		}
+		if len(e.Values) > 0 || len(e.Deletes) > 0 {
+			result = append(result, e)
+		}
+	}
+	return append(result, p.flush()...)
+}
+
+// add adds the event values to the open windows it belongs to
+// and returns the names of the aggregated values.
+// it returns nil if the event has no value to aggregate
+// or if all its windows were already flushed.
+func (p *aggregate) add(e *formatters.EventMsg) []string {
+	var g *group
+	var starts []int64
+	var aggregated []string
+	for k, v := range e.Values {
+		if !p.matchValueName(k) {
+			continue
+		}
+		f, err := toFloat(v)
+		if err != nil {
+			if p.Debug {
+				p.logger.Printf("skipping value %q: %v", k, err)
+			}
+			continue
+		}
+		if g == nil {
+			g = p.getGroup(e)
+			starts = p.windowsOf(g, e.Timestamp)
+			if len(starts) == 0 {
+				if p.Debug {
+					p.logger.Printf("late event passed through: timestamp=%d, watermark=%d", e.Timestamp, g.watermark)
+				}
+				return nil
+			}
+		}
+		aggregated = append(aggregated, k)
+		for _, start := range starts {
+			w, ok := g.windows[start]
+			if !ok {
+				w = make(map[string]*stats)
+				g.windows[start] = w
+			}
+			st, ok := w[k]
+			if !ok {
+				st = &stats{min: f, max: f}
+				w[k] = st
+			}
+			st.add(f, p.keepSamples)
+		}
+	}
+	if g == nil {
+		return nil
+	}
+	if e.Timestamp > g.watermark {
+		g.watermark = e.Timestamp
+	}
+	g.lastSeen = p.now()
+	return aggregated
+}
+
+// windowsOf returns the start of the open windows of group g the timestamp ts belongs to.
+func (p *aggregate) windowsOf(g *group, ts int64) []int64 {
+	window, slide := p.Window.Nanoseconds(), p.Slide.Nanoseconds()
+	lateness := p.AllowedLateness.Nanoseconds()
+	starts := make([]int64, 0, window/slide)
+	for start := ts - mod(ts, slide); start > ts-window; start -= slide {
+		end := start + window
+		if end+lateness <= g.watermark {
+			// window already flushed
+			break
+		}
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+func mod(a, b int64) int64 {
+	r := a % b
+	if r < 0 {
+		r += b
+	}
+	return r
+}
+
+func (p *aggregate) getGroup(e *formatters.EventMsg) *group {
+	tags := p.groupTags(e)
+	key := groupKey(e.Name, tags)
+	g, ok := p.groups[key]
+	if !ok {
+		g = &group{
+			name:    e.Name,
+			tags:    tags,
+			windows: make(map[int64]map[string]*stats),
+		}
+		p.groups[key] = g
+	}
+	return g
+}
+
+func (p *aggregate) groupTags(e *formatters.EventMsg) map[string]string {
+	tags := make(map[string]string, len(e.Tags))
+	if len(p.Tags) == 0 {
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		return tags
+	}
+	for _, k := range p.Tags {
+		if v, ok := e.Tags[k]; ok {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+func groupKey(name string, tags map[string]string) uint64 {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(tags[k]))
+	}
+	return h.Sum64()
+}
+
+func (p *aggregate) matchValueName(name string) bool {
+	if len(p.valueNames) == 0 {
+		return true
+	}
+	for _, re := range p.valueNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush returns one event per group of each complete window,
+// ordered by window start time.
+// a window is complete once its group's watermark is past its end plus AllowedLateness,
+// or once its group did not receive any event for IdleTimeout.
+// idle groups are removed once their windows are flushed.
+func (p *aggregate) flush() []*formatters.EventMsg {
+	type complete struct {
+		start int64
+		key   uint64
+	}
+	window := p.Window.Nanoseconds()
+	lateness := p.AllowedLateness.Nanoseconds()
+	now := p.now()
+	done := make([]complete, 0)
+	idle := make([]uint64, 0)
+	for key, g := range p.groups {
+		isIdle := now.Sub(g.lastSeen) >= p.IdleTimeout
+		if isIdle {
+			idle = append(idle, key)
+			if p.Debug && len(g.windows) > 0 {
+				p.logger.Printf("flushing idle group %q %v", g.name, g.tags)
+			}
+		}
+		for start := range g.windows {
+			if isIdle || start+window+lateness <= g.watermark {
+				done = append(done, complete{start: start, key: key})
+			}
+		}
+	}
+	defer func() {
+		for _, key := range idle {
+			delete(p.groups, key)
+		}
+	}()
+	if len(done) == 0 {
+		return nil
+	}
+	sort.Slice(done, func(i, j int) bool {
+		if done[i].start != done[j].start {
+			return done[i].start < done[j].start
+		}
+		return done[i].key < done[j].key
+	})
+	result := make([]*formatters.EventMsg, 0, len(done))
+	for _, c := range done {
+		g := p.groups[c.key]
+		result = append(result, p.toEvent(c.start+window, g, g.windows[c.start]))
+		delete(g.windows, c.start)
+	}
+	if p.Debug {
+		p.logger.Printf("flushed %d event(s)", len(result))
+	}
+	return result
+}
+
+func (p *aggregate) toEvent(ts int64, g *group, w map[string]*stats) *formatters.EventMsg {
+	e := &formatters.EventMsg{
+		Name:      g.name,
+		Timestamp: ts,
+		Tags:      make(map[string]string, len(g.tags)),
+		Values:    make(map[string]interface{}, len(w)*len(p.functions)),
+	}
+	// the group tags are shared by all its windows
+	for k, v := range g.tags {
+		e.Tags[k] = v
+	}
+	for k, st := range w {
+		if p.keepSamples {
+			sort.Float64s(st.samples)
+		}
+		for _, fn := range p.functions {
+			e.Values[k+"_"+fn.suffix] = st.value(fn)
+		}
+	}
+	return e
+}
+
+func (s *stats) add(f float64, keepSample bool) {
+	s.count++
+	s.sum += f
+	if f < s.min {
+		s.min = f
+	}
+	if f > s.max {
+		s.max = f
+	}
+	if keepSample {
+		s.samples = append(s.samples, f)
+	}
+}
+
+// value returns the result of fn,
+// the samples are expected to be sorted.
+func (s *stats) value(fn aggFunc) interface{} {
+	switch fn.name {
+	case "min":
+		return s.min
+	case "max":
+		return s.max
+	case "avg":
+		return s.sum / float64(s.count)
+	case "sum":
+		return s.sum
+	case "count":
+		return s.count
+	case "percentile":
+		// nearest-rank method
+		rank := int(math.Ceil(fn.percentile / 100 * float64(len(s.samples))))
+		if rank < 1 {
+			rank = 1
+		}
+		return s.samples[rank-1]
+	}
+	return nil
+}
+
+func toFloat(i interface{}) (float64, error) {
+	switch i := i.(type) {
+	case []uint8:
+		switch len(i) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(i))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(i)), nil
+		}
+		return 0, fmt.Errorf("cannot convert %v to float64", i)
+	case string:
+		return strconv.ParseFloat(i, 64)
+	case int:
+		return float64(i), nil
+	case int8:
+		return float64(i), nil
+	case int16:
+		return float64(i), nil
+	case int32:
+		return float64(i), nil
+	case int64:
+		return float64(i), nil
+	case uint:
+		return float64(i), nil
+	case uint8:
+		return float64(i), nil
+	case uint16:
+		return float64(i), nil
+	case uint32:
+		return float64(i), nil
+	case uint64:
+		return float64(i), nil
+	case float32:
+		return float64(i), nil
+	case float64:
+		return i, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %v to float64, type %T", i, i)
+	}
+}
+
+func (p *aggregate) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *aggregate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *aggregate) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *aggregate) WithProcessors(procs map[string]map[string]any) {}
@@ -0,0 +1,383 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_aggregate
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const sec = int64(time.Second)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"tumbling_window": {
+		processor: map[string]interface{}{
+			"window":    "10s",
+			"tags":      []string{"interface"},
+			"functions": []string{"min", "max", "avg", "sum", "count"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1 * sec,
+						Tags:      map[string]string{"interface": "e1", "source": "r1"},
+						Values:    map[string]interface{}{"in": 10},
+					},
+					{
+						Name:      "sub1",
+						Timestamp: 5 * sec,
+						Tags:      map[string]string{"interface": "e1", "source": "r1"},
+						Values:    map[string]interface{}{"in": "30"},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 11 * sec,
+						Tags:      map[string]string{"interface": "e1", "source": "r1"},
+						Values:    map[string]interface{}{"in": uint64(5)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 10 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values: map[string]interface{}{
+							"in_min":   10.0,
+							"in_max":   30.0,
+							"in_avg":   20.0,
+							"in_sum":   40.0,
+							"in_count": int64(2),
+						},
+					},
+				},
+			},
+			// late event, passed through
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 9 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in": 100},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 9 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in": 100},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 20 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 20 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values: map[string]interface{}{
+							"in_min":   5.0,
+							"in_max":   5.0,
+							"in_avg":   5.0,
+							"in_sum":   5.0,
+							"in_count": int64(1),
+						},
+					},
+				},
+			},
+		},
+	},
+	"sliding_window": {
+		processor: map[string]interface{}{
+			"window":    "10s",
+			"slide":     "5s",
+			"functions": []string{"sum"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 6 * sec, Values: map[string]interface{}{"v": 1}},
+					{Timestamp: 12 * sec, Values: map[string]interface{}{"v": 2}},
+				},
+				// window [0s, 10s) is complete
+				output: []*formatters.EventMsg{
+					{Timestamp: 10 * sec, Tags: map[string]string{}, Values: map[string]interface{}{"v_sum": 1.0}},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 21 * sec, Values: map[string]interface{}{"v": 4}},
+				},
+				// windows [5s, 15s) and [10s, 20s) are complete
+				output: []*formatters.EventMsg{
+					{Timestamp: 15 * sec, Tags: map[string]string{}, Values: map[string]interface{}{"v_sum": 3.0}},
+					{Timestamp: 20 * sec, Tags: map[string]string{}, Values: map[string]interface{}{"v_sum": 2.0}},
+				},
+			},
+		},
+	},
+	"percentiles_and_value_names": {
+		processor: map[string]interface{}{
+			"window":      "1m",
+			"value-names": []string{"^counter$"},
+			"functions":   []string{"p50", "P90", "p100"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"counter": 7}},
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"counter": 1}},
+					{Timestamp: 3 * sec, Values: map[string]interface{}{"counter": 3}},
+					{Timestamp: 4 * sec, Values: map[string]interface{}{"counter": 9}},
+					{Timestamp: 5 * sec, Values: map[string]interface{}{"counter": 5}},
+					// not aggregated, passed through
+					{Timestamp: 6 * sec, Values: map[string]interface{}{"status": "up"}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 6 * sec, Values: map[string]interface{}{"status": "up"}},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 60 * sec, Values: map[string]interface{}{"counter": 0}},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 60 * sec,
+						Tags:      map[string]string{},
+						Values: map[string]interface{}{
+							"counter_p50":  5.0,
+							"counter_p90":  9.0,
+							"counter_p100": 9.0,
+						},
+					},
+				},
+			},
+		},
+	},
+	"per_group_watermark": {
+		processor: map[string]interface{}{
+			"window":    "10s",
+			"tags":      []string{"source"},
+			"functions": []string{"count"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 9 * sec, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v": 1}},
+					// r2 is ahead, r1 window [0s, 10s) stays open
+					{Timestamp: 12 * sec, Tags: map[string]string{"source": "r2"}, Values: map[string]interface{}{"v": 1}},
+					{Timestamp: 9 * sec, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 10 * sec, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 10 * sec, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v_count": int64(2)}},
+				},
+			},
+		},
+	},
+	"allowed_lateness": {
+		processor: map[string]interface{}{
+			"window":           "10s",
+			"allowed-lateness": "5s",
+			"functions":        []string{"count"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 8 * sec, Values: map[string]interface{}{"v": 1}},
+					{Timestamp: 12 * sec, Values: map[string]interface{}{"v": 1}},
+					// late but within the allowed lateness
+					{Timestamp: 9 * sec, Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 15 * sec, Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 10 * sec, Tags: map[string]string{}, Values: map[string]interface{}{"v_count": int64(2)}},
+				},
+			},
+		},
+	},
+	"unaggregated_values_passed_through": {
+		processor: map[string]interface{}{
+			"window":      "10s",
+			"value-names": []string{"^counter$", "^status$"},
+			"functions":   []string{"count"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"counter": 1, "status": "up", "mtu": 1500}},
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"counter": 1}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"status": "up", "mtu": 1500}},
+				},
+			},
+		},
+	},
+	"keep_original": {
+		processor: map[string]interface{}{
+			"window":        "10s",
+			"functions":     []string{"count"},
+			"keep-original": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"v": 1}},
+					{Timestamp: 10 * sec, Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"v": 1}},
+					{Timestamp: 10 * sec, Values: map[string]interface{}{"v": 1}},
+					{Timestamp: 10 * sec, Tags: map[string]string{}, Values: map[string]interface{}{"v_count": int64(1)}},
+				},
+			},
+		},
+	},
+}
+
+func TestEventAggregate(t *testing.T) {
+	for name, ts := range testset {
+		pi, ok := formatters.EventProcessors[processorType]
+		if !ok {
+			t.Fatalf("processor %q not registered", processorType)
+		}
+		p := pi()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Errorf("%s: failed to initialize processor: %v", name, err)
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			for i, item := range ts.tests {
+				outs := p.Apply(item.input...)
+				if !reflect.DeepEqual(outs, item.output) {
+					t.Errorf("item %d: failed at %q", i, name)
+					for _, o := range outs {
+						t.Errorf("got:  %+v", o)
+					}
+					for _, o := range item.output {
+						t.Errorf("want: %+v", o)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEventAggregateIdleFlush(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := &aggregate{
+		now:    func() time.Time { return now },
+		logger: log.New(io.Discard, "", 0),
+	}
+	err := p.Init(map[string]interface{}{
+		"window":    "10s",
+		"tags":      []string{"source"},
+		"functions": []string{"count"},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	outs := p.Apply(&formatters.EventMsg{Timestamp: 1 * sec, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v": 1}})
+	if len(outs) != 0 {
+		t.Fatalf("unexpected output: %+v", outs)
+	}
+	// r1 goes quiet, its window is flushed once it is idle for the window duration
+	now = now.Add(10 * time.Second)
+	outs = p.Apply(&formatters.EventMsg{Timestamp: 1 * sec, Tags: map[string]string{"source": "r2"}, Values: map[string]interface{}{"v": 1}})
+	want := []*formatters.EventMsg{
+		{Timestamp: 10 * sec, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v_count": int64(1)}},
+	}
+	if !reflect.DeepEqual(outs, want) {
+		t.Fatalf("got %+v, want %+v", outs, want)
+	}
+	// the idle group is removed
+	if _, ok := p.groups[groupKey("", map[string]string{"source": "r1"})]; ok {
+		t.Errorf("idle group not removed")
+	}
+	if len(p.groups) != 1 {
+		t.Errorf("unexpected number of groups: %d", len(p.groups))
+	}
+	// r2 goes quiet too, all the groups are removed
+	now = now.Add(10 * time.Second)
+	outs = p.Apply()
+	want = []*formatters.EventMsg{
+		{Timestamp: 10 * sec, Tags: map[string]string{"source": "r2"}, Values: map[string]interface{}{"v_count": int64(1)}},
+	}
+	if !reflect.DeepEqual(outs, want) {
+		t.Fatalf("got %+v, want %+v", outs, want)
+	}
+	if len(p.groups) != 0 {
+		t.Errorf("unexpected number of groups: %d", len(p.groups))
+	}
+}
+
+func TestEventAggregateInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_window":    {},
+		"slide_gt_window":   {"window": "10s", "slide": "20s"},
+		"unknown_function":  {"window": "10s", "functions": []string{"median"}},
+		"bad_percentile":    {"window": "10s", "functions": []string{"p101"}},
+		"bad_value_name":    {"window": "10s", "value-names": []string{"("}},
+		"negative_lateness": {"window": "10s", "allowed-lateness": "-1s"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-rate"
+	loggingPrefix = "[" + processorType + "] "
+
+	modeRate  = "rate"
+	modeDelta = "delta"
+
+	defaultCounterSize = 64
+	defaultExpiration  = time.Hour
+)
+
+// rate replaces monotonic counter values with their per second rate
+// or their delta since the previous sample of the same series.
+type rate struct {
+	ValueNames  []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Mode        string        `mapstructure:"mode,omitempty" json:"mode,omitempty"`
+	CounterSize int           `mapstructure:"counter-size,omitempty" json:"counter-size,omitempty"`
+	MaxGap      time.Duration `mapstructure:"max-gap,omitempty" json:"max-gap,omitempty"`
+	DropFirst   bool          `mapstructure:"drop-first,omitempty" json:"drop-first,omitempty"`
+	Suffix      string        `mapstructure:"suffix,omitempty" json:"suffix,omitempty"`
+	Expiration  time.Duration `mapstructure:"expiration,omitempty" json:"expiration,omitempty"`
+	Debug       bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	counterMax uint64
+
+	m sync.Mutex
+	// series last sample, indexed by the hash of the event name, tags and value name.
+	series    map[uint64]*sample
+	lastPrune time.Time
+	logger    *log.Logger
+}
+
+type sample struct {
+	ts int64
+	// isInt is true if the counter value is an unsigned integer,
+	// in which case u is used instead of f.
+	isInt bool
+	u     uint64
+	f     float64
+	// seen is the local time the sample was received at,
+	// used to expire the series.
+	seen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &rate{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *rate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	switch p.Mode {
+	case "":
+		p.Mode = modeRate
+	case modeRate, modeDelta:
+	default:
+		return fmt.Errorf("unknown mode %q, must be one of %q or %q", p.Mode, modeRate, modeDelta)
+	}
+	switch p.CounterSize {
+	case 0:
+		p.CounterSize = defaultCounterSize
+		p.counterMax = math.MaxUint64
+	case 32:
+		p.counterMax = math.MaxUint32
+	case 64:
+		p.counterMax = math.MaxUint64
+	default:
+		return fmt.Errorf("unsupported counter-size %d, must be 32 or 64", p.CounterSize)
+	}
+	if p.Expiration <= 0 {
+		p.Expiration = defaultExpiration
+	}
+	p.valueNames = make([]*regexp.Regexp, 0, len(p.ValueNames))
+	for _, reg := range p.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.valueNames = append(p.valueNames, re)
+	}
+	p.series = make(map[uint64]*sample)
+	p.lastPrune = time.Now()
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *rate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	now := time.Now()
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		matched := false
+		// rates are added after iterating over the values
+		// so that they are not processed as values themselves.
+		rates := make(map[string]interface{})
+		for k, v := range e.Values {
+			if !p.matchValueName(k) {
+				continue
+			}
+			cur, err := newSample(e.Timestamp, v)
+			if err != nil {
+				if p.Debug {
+					p.logger.Printf("skipping value %q: %v", k, err)
+				}
+				continue
+			}
+			matched = true
+			cur.seen = now
+			key := seriesKey(e, k)
+			prev := p.series[key]
+			rv, ok := p.compute(prev, cur)
+			if prev == nil || cur.ts >= prev.ts {
+				// store the sample unless it is out of order
+				p.series[key] = cur
+			}
+			if !ok {
+				if p.Suffix == "" {
+					delete(e.Values, k)
+				}
+				continue
+			}
+			rates[k+p.Suffix] = rv
+		}
+		for k, v := range rates {
+			e.Values[k] = v
+		}
+		if matched && len(e.Values) == 0 && len(e.Deletes) == 0 {
+			// all the values were removed
+			continue
+		}
+		result = append(result, e)
+	}
+	if now.Sub(p.lastPrune) > p.Expiration {
+		p.prune(now)
+	}
+	return result
+}
+
+// compute returns the rate or delta between the previous sample prev and cur.
+// it returns false if there is no value to report, e.g: first sample or reset with drop-first set,
+// out of order samples.
+func (p *rate) compute(prev, cur *sample) (interface{}, bool) {
+	if prev == nil {
+		return p.firstSample()
+	}
+	dt := cur.ts - prev.ts
+	if dt < 0 || (dt == 0 && p.Mode == modeRate) {
+		if p.Debug {
+			p.logger.Printf("ignoring sample: timestamp %d, previous timestamp %d", cur.ts, prev.ts)
+		}
+		return nil, false
+	}
+	if p.MaxGap > 0 && dt > p.MaxGap.Nanoseconds() {
+		if p.Debug {
+			p.logger.Printf("counter reset: gap %s greater than %s", time.Duration(dt), p.MaxGap)
+		}
+		return p.firstSample()
+	}
+	var delta interface{}
+	var fdelta float64
+	switch {
+	case prev.isInt && cur.isInt:
+		var d uint64
+		if cur.u >= prev.u {
+			d = cur.u - prev.u
+		} else if prev.u <= p.counterMax && prev.u > p.counterMax/2 && cur.u < p.counterMax/2 {
+			// wrap: the previous value was in the upper half of the counter range
+			// and the new one is in the lower half.
+			d = p.counterMax - prev.u + cur.u + 1
+		} else {
+			if p.Debug {
+				p.logger.Printf("counter reset: value %d lower than previous value %d", cur.u, prev.u)
+			}
+			return p.firstSample()
+		}
+		delta, fdelta = d, float64(d)
+	default:
+		cf, pf := cur.float(), prev.float()
+		if cf < pf {
+			if p.Debug {
+				p.logger.Printf("counter reset: value %f lower than previous value %f", cf, pf)
+			}
+			return p.firstSample()
+		}
+		delta, fdelta = cf-pf, cf-pf
+	}
+	if p.Mode == modeDelta {
+		return delta, true
+	}
+	return fdelta / (float64(dt) / float64(time.Second)), true
+}
+
+// firstSample returns the value reported for the first sample of a series,
+// or the first sample after a reset.
+func (p *rate) firstSample() (interface{}, bool) {
+	if p.DropFirst {
+		return nil, false
+	}
+	if p.Mode == modeDelta {
+		return uint64(0), true
+	}
+	return float64(0), true
+}
+
+func (p *rate) prune(now time.Time) {
+	for k, s := range p.series {
+		if now.Sub(s.seen) > p.Expiration {
+			delete(p.series, k)
+		}
+	}
+	p.lastPrune = now
+}
+
+func (p *rate) matchValueName(name string) bool {
+	if len(p.valueNames) == 0 {
+		return true
+	}
+	for _, re := range p.valueNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func seriesKey(e *formatters.EventMsg, valueName string) uint64 {
+	keys := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	h.Write([]byte(e.Name))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(e.Tags[k]))
+	}
+	h.Write([]byte{1})
+	h.Write([]byte(valueName))
+	return h.Sum64()
+}
+
+func newSample(ts int64, v interface{}) (*sample, error) {
+	s := &sample{ts: ts, isInt: true}
+	switch v := v.(type) {
+	case int:
+		return s.fromInt(int64(v))
+	case int8:
+		return s.fromInt(int64(v))
+	case int16:
+		return s.fromInt(int64(v))
+	case int32:
+		return s.fromInt(int64(v))
+	case int64:
+		return s.fromInt(v)
+	case uint:
+		s.u = uint64(v)
+	case uint8:
+		s.u = uint64(v)
+	case uint16:
+		s.u = uint64(v)
+	case uint32:
+		s.u = uint64(v)
+	case uint64:
+		s.u = v
+	case float32:
+		s.isInt = false
+		s.f = float64(v)
+	case float64:
+		s.isInt = false
+		s.f = v
+	case string:
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err == nil {
+			s.u = u
+			return s, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		s.isInt = false
+		s.f = f
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+	return s, nil
+}
+
+func (s *sample) fromInt(i int64) (*sample, error) {
+	if i < 0 {
+		s.isInt = false
+		s.f = float64(i)
+		return s, nil
+	}
+	s.u = uint64(i)
+	return s, nil
+}
+
+func (s *sample) float() float64 {
+	if s.isInt {
+		return float64(s.u)
+	}
+	return s.f
+}
+
+func (p *rate) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *rate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *rate) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *rate) WithProcessors(procs map[string]map[string]any) {}
@@ -0,0 +1,277 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_rate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const sec = int64(time.Second)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"rate_drop_first": {
+		processor: map[string]interface{}{
+			"value-names": []string{"octets$"},
+			"drop-first":  true,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 10 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": uint64(1000), "oper-status": "UP"},
+					},
+					{
+						Timestamp: 10 * sec,
+						Tags:      map[string]string{"interface": "e2"},
+						Values:    map[string]interface{}{"in-octets": uint64(50)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 10 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"oper-status": "UP"},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 12 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": "3000"},
+					},
+					{
+						Timestamp: 15 * sec,
+						Tags:      map[string]string{"interface": "e2"},
+						Values:    map[string]interface{}{"in-octets": 100},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 12 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": 1000.0},
+					},
+					{
+						Timestamp: 15 * sec,
+						Tags:      map[string]string{"interface": "e2"},
+						Values:    map[string]interface{}{"in-octets": 10.0},
+					},
+				},
+			},
+			// out of order sample, ignored
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 11 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": uint64(2000)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			// reset, value decreased
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 13 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": uint64(10)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 14 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": uint64(20)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 14 * sec,
+						Tags:      map[string]string{"interface": "e1"},
+						Values:    map[string]interface{}{"in-octets": 10.0},
+					},
+				},
+			},
+		},
+	},
+	"delta_32bit_wrap": {
+		processor: map[string]interface{}{
+			"mode":         "delta",
+			"counter-size": 32,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"c": uint32(math.MaxUint32 - 9)}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"c": uint64(0)}},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"c": uint32(5)}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"c": uint64(15)}},
+				},
+			},
+			// same timestamp is allowed in delta mode
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"c": uint32(7)}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"c": uint64(2)}},
+				},
+			},
+		},
+	},
+	"delta_64bit_reset": {
+		processor: map[string]interface{}{
+			"mode": "delta",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"c": uint64(1000)}},
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"c": uint64(10)}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 1 * sec, Values: map[string]interface{}{"c": uint64(0)}},
+					{Timestamp: 2 * sec, Values: map[string]interface{}{"c": uint64(0)}},
+				},
+			},
+		},
+	},
+	"max_gap_and_suffix": {
+		processor: map[string]interface{}{
+			"max-gap":    "1m",
+			"suffix":     "_rate",
+			"drop-first": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 0, Values: map[string]interface{}{"c": 100}},
+					{Name: "sub1", Timestamp: 10 * sec, Values: map[string]interface{}{"c": 105.0}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 0, Values: map[string]interface{}{"c": 100}},
+					{Name: "sub1", Timestamp: 10 * sec, Values: map[string]interface{}{"c": 105.0, "c_rate": 0.5}},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 120 * sec, Values: map[string]interface{}{"c": 200}},
+					// different series
+					{Name: "sub2", Timestamp: 130 * sec, Values: map[string]interface{}{"c": 300}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 120 * sec, Values: map[string]interface{}{"c": 200}},
+					{Name: "sub2", Timestamp: 130 * sec, Values: map[string]interface{}{"c": 300}},
+				},
+			},
+		},
+	},
+}
+
+func TestEventRate(t *testing.T) {
+	for name, ts := range testset {
+		pi, ok := formatters.EventProcessors[processorType]
+		if !ok {
+			t.Fatalf("processor %q not registered", processorType)
+		}
+		p := pi()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Errorf("%s: failed to initialize processor: %v", name, err)
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			for i, item := range ts.tests {
+				outs := p.Apply(item.input...)
+				if !reflect.DeepEqual(outs, item.output) {
+					t.Errorf("item %d: failed at %q", i, name)
+					for _, o := range outs {
+						t.Errorf("got:  %+v", o)
+					}
+					for _, o := range item.output {
+						t.Errorf("want: %+v", o)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEventRateSuffixNotReprocessed(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{"suffix": "_rate"})
+	if err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	for i := int64(0); i < 5; i++ {
+		values := make(map[string]interface{})
+		for j := 0; j < 20; j++ {
+			values[fmt.Sprintf("c%d", j)] = i * 10
+		}
+		outs := p.Apply(&formatters.EventMsg{Timestamp: i * sec, Values: values})
+		for _, o := range outs {
+			for k := range o.Values {
+				if strings.HasSuffix(k, "_rate_rate") {
+					t.Fatalf("event %d: unexpected value %q", i, k)
+				}
+			}
+		}
+	}
+}
+
+func TestEventRateInit(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown_mode":   {"mode": "average"},
+		"counter_size":   {"counter-size": 16},
+		"bad_value_name": {"value-names": []string{"("}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -45,6 +45,8 @@ var EventProcessorTypes = []string{
 	"event-combine",
 	"event-ieeefloat32",
 	"event-time-epoch",
+	"event-aggregate",
+	"event-rate",
 }
 
 type Initializer func() EventProcessor
@@ -0,0 +1,261 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gnoi provides the descriptors of the gNOI services used by
+// the gnmic gnoi commands. The messages are built as dynamic messages
+// from a set of embedded proto files.
+// The proto files are subsets of the github.com/openconfig/gnoi ones,
+// their messages and fields keep the upstream names and numbers
+// so that they are wire compatible with the gNOI servers.
+package gnoi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+//go:embed protos
+var protosFS embed.FS
+
+var (
+	once     sync.Once
+	files    *protoregistry.Files
+	filesErr error
+)
+
+// Files returns the registry of the embedded gNOI proto files.
+func Files() (*protoregistry.Files, error) {
+	once.Do(func() {
+		files, filesErr = parse()
+	})
+	return files, filesErr
+}
+
+func parse() (*protoregistry.Files, error) {
+	sources := make(map[string]string)
+	names := make([]string, 0)
+	err := fs.WalkDir(protosFS, "protos", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".proto") {
+			return nil
+		}
+		b, err := protosFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path, "protos/")
+		sources[name] = string(b)
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(sources),
+	}
+	fds, err := p.ParseFiles(names...)
+	if err != nil {
+		return nil, err
+	}
+	reg := new(protoregistry.Files)
+	for _, fd := range fds {
+		for _, dep := range fd.GetDependencies() {
+			if _, err := reg.FindFileByPath(dep.GetName()); err == nil {
+				continue
+			}
+			if err := reg.RegisterFile(dep.UnwrapFile()); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := reg.FindFileByPath(fd.GetName()); err == nil {
+			continue
+		}
+		if err := reg.RegisterFile(fd.UnwrapFile()); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// MethodDesc returns the descriptor of a gNOI RPC given its
+// full name, e.g: gnoi.system.System.Time
+func MethodDesc(fullName string) (protoreflect.MethodDescriptor, error) {
+	reg, err := Files()
+	if err != nil {
+		return nil, err
+	}
+	d, err := reg.FindDescriptorByName(protoreflect.FullName(fullName))
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a method", fullName)
+	}
+	return md, nil
+}
+
+// RPC is a gNOI RPC with its input and output message descriptors.
+type RPC struct {
+	desc protoreflect.MethodDescriptor
+}
+
+// NewRPC returns the RPC with the given full name, e.g: gnoi.system.System.Time
+func NewRPC(fullName string) (*RPC, error) {
+	md, err := MethodDesc(fullName)
+	if err != nil {
+		return nil, err
+	}
+	return &RPC{desc: md}, nil
+}
+
+// Method returns the gRPC method name, e.g: /gnoi.system.System/Time
+func (r *RPC) Method() string {
+	return fmt.Sprintf("/%s/%s", r.desc.Parent().FullName(), r.desc.Name())
+}
+
+// StreamDesc returns the gRPC stream description of the RPC.
+func (r *RPC) StreamDesc() *grpc.StreamDesc {
+	return &grpc.StreamDesc{
+		StreamName:    string(r.desc.Name()),
+		ClientStreams: r.desc.IsStreamingClient(),
+		ServerStreams: r.desc.IsStreamingServer(),
+	}
+}
+
+// NewRequest builds a request message from a map following the protoJSON mapping,
+// keys can be the proto field names.
+func (r *RPC) NewRequest(fields map[string]interface{}) (*dynamicpb.Message, error) {
+	return NewMessage(r.desc.Input(), fields)
+}
+
+// NewBytesRequest builds a request message with the bytes field called name set to b.
+// It avoids the protoJSON encoding of large payloads such as file contents.
+func (r *RPC) NewBytesRequest(name string, b []byte) (*dynamicpb.Message, error) {
+	m := dynamicpb.NewMessage(r.desc.Input())
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || fd.Kind() != protoreflect.BytesKind {
+		return nil, fmt.Errorf("%s has no bytes field %q", m.Descriptor().FullName(), name)
+	}
+	m.Set(fd, protoreflect.ValueOfBytes(b))
+	return m, nil
+}
+
+// NewResponse returns an empty response message.
+func (r *RPC) NewResponse() *dynamicpb.Message {
+	return dynamicpb.NewMessage(r.desc.Output())
+}
+
+// NewMessage builds a message of type md from a map following the protoJSON mapping.
+// Empty string values are ignored, so that unset enum values can be passed as is.
+func NewMessage(md protoreflect.MessageDescriptor, fields map[string]interface{}) (*dynamicpb.Message, error) {
+	m := dynamicpb.NewMessage(md)
+	if len(fields) == 0 {
+		return m, nil
+	}
+	set := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if s, ok := v.(string); ok && s == "" {
+			continue
+		}
+		set[k] = v
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		return nil, err
+	}
+	err = protojson.Unmarshal(b, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s: %v", md.FullName(), err)
+	}
+	return m, nil
+}
+
+// Get returns the value of the field at the given path of field names.
+// It returns an invalid value if one of the fields does not exist or is not set.
+func Get(m protoreflect.Message, fields ...string) protoreflect.Value {
+	var v protoreflect.Value
+	for i, name := range fields {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil || !m.Has(fd) {
+			return protoreflect.Value{}
+		}
+		v = m.Get(fd)
+		if i < len(fields)-1 {
+			if fd.Message() == nil {
+				return protoreflect.Value{}
+			}
+			m = v.Message()
+		}
+	}
+	return v
+}
+
+// EnumName returns the name of the value of the enum field called name,
+// or an empty string if the field is not an enum.
+func EnumName(m protoreflect.Message, name string) string {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || fd.Enum() == nil {
+		return ""
+	}
+	ev := fd.Enum().Values().ByNumber(m.Get(fd).Enum())
+	if ev == nil {
+		return ""
+	}
+	return string(ev.Name())
+}
+
+// WhichOneof returns the name of the field set in the oneof called name,
+// or an empty string if none is set.
+func WhichOneof(m protoreflect.Message, name string) string {
+	od := m.Descriptor().Oneofs().ByName(protoreflect.Name(name))
+	if od == nil {
+		return ""
+	}
+	fd := m.WhichOneof(od)
+	if fd == nil {
+		return ""
+	}
+	return string(fd.Name())
+}
+
+// Path converts a gNMI path into a gnoi.types.Path in its protoJSON form.
+func Path(p *gnmi.Path) map[string]interface{} {
+	if p == nil {
+		return nil
+	}
+	elems := make([]interface{}, 0, len(p.GetElem()))
+	for _, pe := range p.GetElem() {
+		e := map[string]interface{}{"name": pe.GetName()}
+		if len(pe.GetKey()) > 0 {
+			e["key"] = pe.GetKey()
+		}
+		elems = append(elems, e)
+	}
+	res := map[string]interface{}{"elem": elems}
+	if p.GetOrigin() != "" {
+		res["origin"] = p.GetOrigin()
+	}
+	return res
+}
@@ -0,0 +1,189 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gnoi
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestRPCs(t *testing.T) {
+	tests := map[string]struct {
+		method        string
+		clientStreams bool
+		serverStreams bool
+	}{
+		"gnoi.system.System.Time":                       {method: "/gnoi.system.System/Time"},
+		"gnoi.system.System.Ping":                       {method: "/gnoi.system.System/Ping", serverStreams: true},
+		"gnoi.file.File.Put":                            {method: "/gnoi.file.File/Put", clientStreams: true},
+		"gnoi.certificate.CertificateManagement.Rotate": {method: "/gnoi.certificate.CertificateManagement/Rotate", clientStreams: true, serverStreams: true},
+		"gnoi.os.OS.Verify":                             {method: "/gnoi.os.OS/Verify"},
+		"gnoi.healthz.Healthz.Get":                      {method: "/gnoi.healthz.Healthz/Get"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rpc, err := NewRPC(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rpc.Method() != tc.method {
+				t.Errorf("got method %q, want %q", rpc.Method(), tc.method)
+			}
+			sd := rpc.StreamDesc()
+			if sd.ClientStreams != tc.clientStreams || sd.ServerStreams != tc.serverStreams {
+				t.Errorf("unexpected stream desc: %+v", sd)
+			}
+		})
+	}
+	if _, err := NewRPC("gnoi.system.System.Unknown"); err == nil {
+		t.Error("expected an error for an unknown RPC")
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	rpc, err := NewRPC("gnoi.system.System.Reboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &gnmi.Path{Elem: []*gnmi.PathElem{
+		{Name: "components"},
+		{Name: "component", Key: map[string]string{"name": "linecard1"}},
+	}}
+	req, err := rpc.NewRequest(map[string]interface{}{
+		"method":        "COLD",
+		"delay":         uint64(10),
+		"subcomponents": []interface{}{Path(p)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := Get(req, "method"); v.Enum() != 1 {
+		t.Errorf("unexpected method value %v", v.Enum())
+	}
+	if v := Get(req, "delay"); v.Uint() != 10 {
+		t.Errorf("unexpected delay value %v", v.Uint())
+	}
+	subs := Get(req, "subcomponents").List()
+	if subs.Len() != 1 {
+		t.Fatalf("expected 1 subcomponent, got %d", subs.Len())
+	}
+	elems := Get(subs.Get(0).Message(), "elem").List()
+	if elems.Len() != 2 {
+		t.Fatalf("expected 2 path elems, got %d", elems.Len())
+	}
+	if name := Get(elems.Get(1).Message(), "name").String(); name != "component" {
+		t.Errorf("unexpected elem name %q", name)
+	}
+	if _, err := rpc.NewRequest(map[string]interface{}{"method": "NOT_A_METHOD"}); err == nil {
+		t.Error("expected an error for an invalid enum value")
+	}
+}
+
+func TestWhichOneof(t *testing.T) {
+	rpc, err := NewRPC("gnoi.file.File.Get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp := rpc.NewResponse()
+	if WhichOneof(rsp, "response") != "" {
+		t.Error("expected an empty oneof")
+	}
+	rsp, err = NewMessage(rsp.Descriptor(), map[string]interface{}{"contents": []byte("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := WhichOneof(rsp, "response"); got != "contents" {
+		t.Errorf("got oneof %q, want %q", got, "contents")
+	}
+	if got := string(Get(rsp, "contents").Bytes()); got != "abc" {
+		t.Errorf("got contents %q", got)
+	}
+}
+
+func TestFieldNumbers(t *testing.T) {
+	// field numbers of github.com/openconfig/gnoi messages.
+	tests := map[protoreflect.FullName]map[protoreflect.Name]protoreflect.FieldNumber{
+		"gnoi.system.PingResponse": {
+			"source": 1, "time": 2, "bytes": 3, "sequence": 4, "ttl": 5,
+			"sent": 10, "received": 11, "min_time": 12, "avg_time": 13, "max_time": 14, "std_dev": 15,
+		},
+		"gnoi.system.TracerouteRequest": {
+			"source": 1, "destination": 2, "initial_ttl": 3, "max_ttl": 4, "wait": 5,
+			"do_not_fragment": 6, "do_not_resolve": 7, "l3protocol": 8, "l4protocol": 9,
+			"do_not_lookup_asn": 10, "network_instance": 11,
+		},
+		"gnoi.system.PingRequest": {
+			"destination": 1, "source": 2, "count": 3, "interval": 4, "wait": 5,
+			"size": 6, "do_not_fragment": 7, "do_not_resolve": 8, "l3protocol": 9, "network_instance": 10,
+		},
+		"gnoi.healthz.ComponentStatus": {
+			"path": 1, "subcomponents": 2, "status": 3, "id": 6, "acknowledged": 7,
+		},
+	}
+	reg, err := Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, fields := range tests {
+		t.Run(string(name), func(t *testing.T) {
+			d, err := reg.FindDescriptorByName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			md := d.(protoreflect.MessageDescriptor)
+			for field, num := range fields {
+				fd := md.Fields().ByName(field)
+				if fd == nil {
+					t.Errorf("missing field %q", field)
+					continue
+				}
+				if fd.Number() != num {
+					t.Errorf("field %q has number %d, want %d", field, fd.Number(), num)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodePingResponse(t *testing.T) {
+	rpc, err := NewRPC("gnoi.system.System.Ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a PingResponse reply as encoded by a gNOI server.
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "10.0.0.1")
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	b = protowire.AppendVarint(b, 64)
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, 2)
+	b = protowire.AppendTag(b, 10, protowire.VarintType)
+	b = protowire.AppendVarint(b, 5)
+	rsp := rpc.NewResponse()
+	if err := proto.Unmarshal(b, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get(rsp, "source").String(); got != "10.0.0.1" {
+		t.Errorf("got source %q", got)
+	}
+	if got := Get(rsp, "bytes").Int(); got != 64 {
+		t.Errorf("got bytes %d, want 64", got)
+	}
+	if got := Get(rsp, "sequence").Int(); got != 2 {
+		t.Errorf("got sequence %d, want 2", got)
+	}
+	if got := Get(rsp, "sent").Int(); got != 5 {
+		t.Errorf("got sent %d, want 5", got)
+	}
+}
@@ -9,8 +9,15 @@
 package all
 
 import (
+	_ "github.com/openconfig/gnmic/pkg/inputs/dialout_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/file_input"
 	_ "github.com/openconfig/gnmic/pkg/inputs/jetstream_input"
 	_ "github.com/openconfig/gnmic/pkg/inputs/kafka_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/mqtt_input"
 	_ "github.com/openconfig/gnmic/pkg/inputs/nats_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/prometheus_write_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/redis_input"
 	_ "github.com/openconfig/gnmic/pkg/inputs/stan_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/tcp_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/udp_input"
 )
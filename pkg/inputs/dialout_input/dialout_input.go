@@ -0,0 +1,356 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dialout_input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/google/uuid"
+	nokiasros "github.com/karimra/sros-dialout"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix               = "[dialout_input] "
+	defaultMaxConcurrentStreams = 256
+	defaultNumWorkers           = 1
+	defaultBufferSize           = 100
+)
+
+func init() {
+	inputs.Register("dialout", func() inputs.Input {
+		return &DialoutInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// DialoutInput //
+type DialoutInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	grpcServer *grpc.Server
+	msgCh      chan *message
+	wg         *sync.WaitGroup
+	// all the configured outputs, used by targets with their own outputs list.
+	allOutputs map[string]outputs.Output
+	outputs    []outputs.Output
+	evps       []formatters.EventProcessor
+	// the root level targets configs, a snapshot taken when the input starts.
+	targetConfigs map[string]*types.TargetConfig
+	// targets built from targetConfigs, matched by name or address
+	// before the input target matches.
+	globalTargets []*targetMatch
+}
+
+// Config //
+type Config struct {
+	Name                 string           `mapstructure:"name,omitempty"`
+	Address              string           `mapstructure:"address,omitempty"`
+	TLS                  *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	MaxConcurrentStreams uint32           `mapstructure:"max-concurrent-streams,omitempty"`
+	MaxRecvMsgSize       int              `mapstructure:"max-recv-msg-size,omitempty"`
+	Targets              []*targetMatch   `mapstructure:"targets,omitempty"`
+	Debug                bool             `mapstructure:"debug,omitempty"`
+	NumWorkers           int              `mapstructure:"num-workers,omitempty"`
+	BufferSize           int              `mapstructure:"buffer-size,omitempty"`
+	Outputs              []string         `mapstructure:"outputs,omitempty"`
+	EventProcessors      []string         `mapstructure:"event-processors,omitempty"`
+}
+
+// targetMatch assigns a target configuration to the dial-out
+// connections with an ID matching a regular expression,
+// or to the connections of a root level target if re is nil.
+type targetMatch struct {
+	// a Regex pattern to check the target ID,
+	// the `system-name` sent by the target or its IP address.
+	ID string `mapstructure:"id,omitempty" json:"id,omitempty"`
+	// Optional gnmic.Target Configuration that will be assigned to the target with
+	// an ID matching the above regex
+	Config types.TargetConfig `mapstructure:"config,omitempty" json:"config,omitempty"`
+
+	re *regexp.Regexp
+	// decodes the proto bytes values if proto-files are set.
+	t *target.Target
+}
+
+// message is a dial-out notification and the metadata of the target that sent it.
+type message struct {
+	rsp  *gnmi.SubscribeResponse
+	meta outputs.Meta
+	// target outputs, nil if the input outputs are used.
+	outputs []outputs.Output
+	t       *target.Target
+}
+
+// Start //
+func (d *DialoutInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, d.Cfg)
+	if err != nil {
+		return err
+	}
+	if d.Cfg.Name == "" {
+		d.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return err
+		}
+	}
+	err = d.setDefaults()
+	if err != nil {
+		return err
+	}
+	d.logger.Printf("input starting with config: %+v", d.Cfg)
+
+	l, err := net.Listen("tcp", d.Cfg.Address)
+	if err != nil {
+		return err
+	}
+	srvOpts := []grpc.ServerOption{
+		grpc.MaxConcurrentStreams(d.Cfg.MaxConcurrentStreams),
+	}
+	if d.Cfg.MaxRecvMsgSize > 0 {
+		srvOpts = append(srvOpts, grpc.MaxRecvMsgSize(d.Cfg.MaxRecvMsgSize))
+	}
+	if d.Cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			d.Cfg.TLS.CaFile,
+			d.Cfg.TLS.CertFile,
+			d.Cfg.TLS.KeyFile,
+			d.Cfg.TLS.ClientAuth,
+			false,
+			true,
+		)
+		if err != nil {
+			l.Close()
+			return err
+		}
+		srvOpts = append(srvOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	ctx, d.cfn = context.WithCancel(ctx)
+	d.msgCh = make(chan *message, d.Cfg.BufferSize)
+	d.wg.Add(d.Cfg.NumWorkers)
+	for i := 0; i < d.Cfg.NumWorkers; i++ {
+		go d.worker(ctx, i)
+	}
+
+	d.grpcServer = grpc.NewServer(srvOpts...)
+	srv := &server{input: d, ctx: ctx}
+	nokiasros.RegisterDialoutTelemetryServer(d.grpcServer, srv)
+	d.grpcServer.RegisterService(&gnmiDialOutServiceDesc, srv)
+
+	d.logger.Printf("waiting for connections on %s", l.Addr())
+	go func() {
+		err := d.grpcServer.Serve(l)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			d.logger.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (d *DialoutInput) worker(ctx context.Context, idx int) {
+	defer d.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
+	d.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-d.msgCh:
+			if msg.t != nil {
+				err := msg.t.DecodeProtoBytes(msg.rsp)
+				if err != nil {
+					d.logger.Printf("%s target %q: failed to decode proto bytes: %v", workerLogPrefix, msg.meta["source"], err)
+					continue
+				}
+			}
+			outs := msg.outputs
+			if outs == nil {
+				outs = d.outputs
+			}
+			if len(d.evps) == 0 {
+				for _, o := range outs {
+					o.Write(ctx, msg.rsp, msg.meta)
+				}
+				continue
+			}
+			evMsgs, err := formatters.ResponseToEventMsgs(msg.meta["subscription-name"], msg.rsp, msg.meta, d.evps...)
+			if err != nil {
+				if d.Cfg.Debug {
+					d.logger.Printf("%s failed to convert message to events: %v", workerLogPrefix, err)
+				}
+				continue
+			}
+			for _, o := range outs {
+				for _, ev := range evMsgs {
+					o.WriteEvent(ctx, ev)
+				}
+			}
+		}
+	}
+}
+
+// Close //
+func (d *DialoutInput) Close() error {
+	if d.grpcServer != nil {
+		d.grpcServer.Stop()
+	}
+	if d.cfn != nil {
+		d.cfn()
+	}
+	d.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (d *DialoutInput) SetLogger(logger *log.Logger) {
+	if logger != nil && d.logger != nil {
+		d.logger.SetOutput(logger.Writer())
+		d.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (d *DialoutInput) SetOutputs(outs map[string]outputs.Output) {
+	d.allOutputs = outs
+	if len(d.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			d.outputs = append(d.outputs, o)
+		}
+		return
+	}
+	for _, name := range d.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			d.outputs = append(d.outputs, o)
+		}
+	}
+}
+
+func (d *DialoutInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(d.Cfg.Name)
+	sb.WriteString("-dialout")
+	d.Cfg.Name = sb.String()
+}
+
+func (d *DialoutInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	d.targetConfigs = make(map[string]*types.TargetConfig, len(tcs))
+	for n, tc := range tcs {
+		d.targetConfigs[n] = tc
+	}
+	var err error
+	d.evps, err = formatters.MakeEventProcessors(
+		logger,
+		d.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper funcs
+
+func (d *DialoutInput) setDefaults() error {
+	if d.Cfg.Address == "" {
+		return errors.New("missing address field")
+	}
+	if d.Cfg.Name == "" {
+		d.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	for _, tm := range d.Cfg.Targets {
+		var err error
+		tm.re, err = regexp.Compile(tm.ID)
+		if err != nil {
+			return fmt.Errorf("invalid target id regex %q: %v", tm.ID, err)
+		}
+		err = tm.loadProtoFiles()
+		if err != nil {
+			return fmt.Errorf("target %q: %v", tm.ID, err)
+		}
+	}
+	names := make([]string, 0, len(d.targetConfigs))
+	for n := range d.targetConfigs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	d.globalTargets = make([]*targetMatch, 0, len(names))
+	for _, n := range names {
+		tm := &targetMatch{ID: n, Config: *d.targetConfigs[n]}
+		if tm.Config.Name == "" {
+			tm.Config.Name = n
+		}
+		err := tm.loadProtoFiles()
+		if err != nil {
+			return fmt.Errorf("target %q: %v", n, err)
+		}
+		d.globalTargets = append(d.globalTargets, tm)
+	}
+	if d.Cfg.MaxConcurrentStreams == 0 {
+		d.Cfg.MaxConcurrentStreams = defaultMaxConcurrentStreams
+	}
+	if d.Cfg.NumWorkers <= 0 {
+		d.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if d.Cfg.BufferSize <= 0 {
+		d.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
+
+// loadProtoFiles loads the target proto files used to decode
+// the Nokia SR OS proto encoded values, if any.
+func (tm *targetMatch) loadProtoFiles() error {
+	if len(tm.Config.ProtoFiles) == 0 {
+		return nil
+	}
+	descSource, err := grpcurl.DescriptorSourceFromProtoFiles(tm.Config.ProtoDirs, tm.Config.ProtoFiles...)
+	if err != nil {
+		return fmt.Errorf("failed to load proto files: %v", err)
+	}
+	tm.t = target.NewTarget(&tm.Config)
+	tm.t.RootDesc, err = descSource.FindSymbol("Nokia.SROS.root")
+	if err != nil {
+		return fmt.Errorf("could not get symbol 'Nokia.SROS.root': %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dialout_input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	nokiasros "github.com/karimra/sros-dialout"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// server implements the Nokia SR OS DialoutTelemetry service
+// and the openconfig gnmi_dialout gNMIDialOut service.
+type server struct {
+	input *DialoutInput
+	ctx   context.Context
+}
+
+// Publish handles the Nokia SR OS dial-out streams,
+// each received notification is acknowledged with a PublishResponse.
+func (s *server) Publish(stream nokiasros.DialoutTelemetry_PublishServer) error {
+	return s.publish(stream.Context(), stream.Recv, func() error {
+		return stream.Send(&nokiasros.PublishResponse{})
+	})
+}
+
+// gNMIDialOutServer is the server API of the openconfig gnmi_dialout service:
+//
+//	service gNMIDialOut {
+//	  rpc Publish(stream gnmi.SubscribeResponse) returns (stream PublishResponse);
+//	}
+//	message PublishResponse {}
+type gNMIDialOutServer interface {
+	publishGNMIDialOut(grpc.ServerStream) error
+}
+
+var gnmiDialOutServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gnmi_dialout.gNMIDialOut",
+	HandlerType: (*gNMIDialOutServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Publish",
+			Handler:       gnmiDialOutPublishHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "gnmi_dialout.proto",
+}
+
+func gnmiDialOutPublishHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(gNMIDialOutServer).publishGNMIDialOut(stream)
+}
+
+func (s *server) publishGNMIDialOut(stream grpc.ServerStream) error {
+	return s.publish(stream.Context(), func() (*gnmi.SubscribeResponse, error) {
+		rsp := new(gnmi.SubscribeResponse)
+		return rsp, stream.RecvMsg(rsp)
+	}, nil)
+}
+
+// publish reads the notifications sent by a target and queues them for the input workers.
+func (s *server) publish(ctx context.Context, recv func() (*gnmi.SubscribeResponse, error), ack func() error) error {
+	d := s.input
+	meta, tm, err := d.targetMeta(ctx)
+	if err != nil {
+		d.logger.Printf("rejecting dial-out stream: %v", err)
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	d.logger.Printf("target %q connected, subscription %q", meta["source"], meta["subscription-name"])
+	msgTpl := &message{meta: meta}
+	if tm != nil {
+		msgTpl.outputs = d.targetOutputs(tm)
+		msgTpl.t = tm.t
+	}
+	for {
+		rsp, err := recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && status.Code(err) != codes.Canceled {
+				d.logger.Printf("target %q: dial-out receive error: %v", meta["source"], err)
+			}
+			d.logger.Printf("target %q disconnected", meta["source"])
+			return nil
+		}
+		if ack != nil {
+			if err := ack(); err != nil {
+				d.logger.Printf("target %q: failed to send publish response: %v", meta["source"], err)
+			}
+		}
+		if d.Cfg.Debug {
+			d.logger.Printf("target %q: received msg: %v", meta["source"], rsp)
+		}
+		select {
+		case <-s.ctx.Done():
+			return status.Error(codes.Unavailable, "input stopped")
+		case <-ctx.Done():
+			return nil
+		case d.msgCh <- &message{rsp: rsp, meta: msgTpl.meta, outputs: msgTpl.outputs, t: msgTpl.t}:
+		}
+	}
+}
+
+// targetMeta identifies the target of a dial-out stream and returns its metadata
+// and target match, if any.
+// The target ID is the `system-name` sent in the stream metadata, or the target IP address.
+// A root level target named after the target ID or with the target IP address,
+// or else the first target match with a regex matching the target ID or its IP address,
+// sets the target name and event tags.
+// If target matches are configured, streams from targets not matching any of them
+// nor any root level target are rejected.
+// The `system-name` is set by the client, matching it does not authenticate the target.
+func (d *DialoutInput) targetMeta(ctx context.Context) (outputs.Meta, *targetMatch, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = utils.GetHost(p.Addr.String())
+	}
+	id := firstValue(md, "system-name")
+	if id == "" {
+		id = addr
+	}
+	meta := outputs.Meta{
+		"source":            id,
+		"subscription-name": firstValue(md, "subscription-name"),
+	}
+	if meta["subscription-name"] == "" {
+		meta["subscription-name"] = "default"
+	}
+	if sn := firstValue(md, "system-name"); sn != "" {
+		meta["system-name"] = sn
+	}
+	tm := d.matchTarget(id, addr)
+	if tm == nil {
+		if len(d.Cfg.Targets) == 0 {
+			return meta, nil, nil
+		}
+		return nil, nil, fmt.Errorf("target %q (%s) does not match any of the configured targets", id, addr)
+	}
+	if tm.Config.Name != "" {
+		meta["source"] = tm.Config.Name
+	}
+	for k, v := range tm.Config.EventTags {
+		meta[k] = v
+	}
+	return meta, tm, nil
+}
+
+// matchTarget returns the root level target or the target match
+// of the target with ID id and IP address addr, nil if there is none.
+func (d *DialoutInput) matchTarget(id, addr string) *targetMatch {
+	for _, tm := range d.globalTargets {
+		if tm.Config.Name == id {
+			return tm
+		}
+		if addr == "" {
+			continue
+		}
+		for _, a := range strings.Split(tm.Config.Address, ",") {
+			if utils.GetHost(strings.TrimSpace(a)) == addr {
+				return tm
+			}
+		}
+	}
+	for _, tm := range d.Cfg.Targets {
+		if tm.re.MatchString(id) || tm.re.MatchString(addr) {
+			return tm
+		}
+	}
+	return nil
+}
+
+// targetOutputs returns the outputs configured under a target match,
+// nil if it has none and the input outputs are used.
+func (d *DialoutInput) targetOutputs(tm *targetMatch) []outputs.Output {
+	if len(tm.Config.Outputs) == 0 {
+		return nil
+	}
+	outs := make([]outputs.Output, 0, len(tm.Config.Outputs))
+	for _, name := range tm.Config.Outputs {
+		if o, ok := d.allOutputs[name]; ok {
+			outs = append(outs, o)
+		}
+	}
+	return outs
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if vs := md.Get(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
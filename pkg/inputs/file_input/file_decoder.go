@@ -0,0 +1,269 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file_input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// record is a message read from a file,
+// either a subscribe response and its metadata or a list of events.
+type record struct {
+	rsp  *gnmi.SubscribeResponse
+	meta outputs.Meta
+	evs  []*formatters.EventMsg
+}
+
+// timestamp returns the record timestamp in nanoseconds, 0 if it has none.
+func (r *record) timestamp() int64 {
+	if r.rsp != nil {
+		return r.rsp.GetUpdate().GetTimestamp()
+	}
+	var ts int64
+	for _, ev := range r.evs {
+		if ev.Timestamp > 0 && (ts == 0 || ev.Timestamp < ts) {
+			ts = ev.Timestamp
+		}
+	}
+	return ts
+}
+
+// shift adds d nanoseconds to the record timestamps.
+func (r *record) shift(d int64) {
+	if upd := r.rsp.GetUpdate(); upd != nil && upd.Timestamp > 0 {
+		upd.Timestamp += d
+	}
+	for _, ev := range r.evs {
+		if ev.Timestamp > 0 {
+			ev.Timestamp += d
+		}
+	}
+}
+
+// decoder reads the records of a file, it returns io.EOF once all of them are read.
+// A *recordError is returned for a record that could not be converted,
+// the following records can still be read.
+type decoder interface {
+	next() (*record, error)
+}
+
+type recordError struct {
+	err error
+}
+
+func (e *recordError) Error() string {
+	return e.err.Error()
+}
+
+func newDecoder(format string, r io.Reader, maxMsgSize int) decoder {
+	br := bufio.NewReader(r)
+	if format == "proto" {
+		return &protoDecoder{r: br, opts: protodelim.UnmarshalOptions{MaxSize: int64(maxMsgSize)}}
+	}
+	return &jsonDecoder{format: format, dec: json.NewDecoder(br)}
+}
+
+// protoDecoder reads varint length-prefixed gNMI SubscribeResponses.
+type protoDecoder struct {
+	r    *bufio.Reader
+	opts protodelim.UnmarshalOptions
+}
+
+func (d *protoDecoder) next() (*record, error) {
+	rsp := new(gnmi.SubscribeResponse)
+	err := d.opts.UnmarshalFrom(d.r, rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &record{rsp: rsp, meta: protoMeta(rsp)}, nil
+}
+
+// jsonDecoder reads consecutive JSON documents,
+// they can span multiple lines and be separated by any whitespace.
+type jsonDecoder struct {
+	format string
+	dec    *json.Decoder
+}
+
+func (d *jsonDecoder) next() (*record, error) {
+	var raw json.RawMessage
+	err := d.dec.Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+	var r *record
+	switch d.format {
+	case "event":
+		r, err = decodeEvents(raw)
+	case "protojson":
+		rsp := new(gnmi.SubscribeResponse)
+		err = protojson.Unmarshal(raw, rsp)
+		r = &record{rsp: rsp, meta: protoMeta(rsp)}
+	default:
+		r, err = decodeJSON(raw)
+	}
+	if err != nil {
+		return nil, &recordError{err: err}
+	}
+	return r, nil
+}
+
+func decodeEvents(b []byte) (*record, error) {
+	evs := make([]*formatters.EventMsg, 0, 1)
+	var err error
+	switch b[0] {
+	case '[':
+		err = json.Unmarshal(b, &evs)
+	default:
+		ev := new(formatters.EventMsg)
+		err = json.Unmarshal(b, ev)
+		evs = append(evs, ev)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record{evs: evs}, nil
+}
+
+// protoMeta returns the metadata of a response read in a proto based format,
+// the only metadata available is the prefix target.
+func protoMeta(rsp *gnmi.SubscribeResponse) outputs.Meta {
+	meta := outputs.Meta{}
+	if t := rsp.GetUpdate().GetPrefix().GetTarget(); t != "" {
+		meta["source"] = t
+	}
+	return meta
+}
+
+// jsonMsg is a subscribe response written by the file output with format `json`.
+type jsonMsg struct {
+	Source           string        `json:"source,omitempty"`
+	SystemName       string        `json:"system-name,omitempty"`
+	SubscriptionName string        `json:"subscription-name,omitempty"`
+	Timestamp        int64         `json:"timestamp,omitempty"`
+	Prefix           string        `json:"prefix,omitempty"`
+	Target           string        `json:"target,omitempty"`
+	Updates          []*jsonUpdate `json:"updates,omitempty"`
+	Deletes          []string      `json:"deletes,omitempty"`
+	SyncResponse     bool          `json:"sync-response,omitempty"`
+}
+
+type jsonUpdate struct {
+	Path   string                 `json:"Path,omitempty"`
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// decodeJSON rebuilds a subscribe response from its gNMIc JSON representation.
+// Numbers are decoded as integers when possible, maps and lists as JSON values.
+func decodeJSON(b []byte) (*record, error) {
+	msg := new(jsonMsg)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err := dec.Decode(msg)
+	if err != nil {
+		return nil, err
+	}
+	meta := outputs.Meta{}
+	if msg.Source != "" {
+		meta["source"] = msg.Source
+	}
+	if msg.SystemName != "" {
+		meta["system-name"] = msg.SystemName
+	}
+	if msg.SubscriptionName != "" {
+		meta["subscription-name"] = msg.SubscriptionName
+	}
+	if msg.SyncResponse {
+		return &record{
+			rsp:  &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}},
+			meta: meta,
+		}, nil
+	}
+	if msg.Timestamp == 0 && len(msg.Updates) == 0 && len(msg.Deletes) == 0 {
+		return nil, fmt.Errorf("not a subscribe response notification: %s", b)
+	}
+	n := &gnmi.Notification{
+		Timestamp: msg.Timestamp,
+		Update:    make([]*gnmi.Update, 0, len(msg.Updates)),
+		Delete:    make([]*gnmi.Path, 0, len(msg.Deletes)),
+	}
+	n.Prefix, err = path.CreatePrefix(msg.Prefix, msg.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix %q: %v", msg.Prefix, err)
+	}
+	for _, u := range msg.Updates {
+		upd := new(gnmi.Update)
+		upd.Path, err = path.ParsePath(u.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid update path %q: %v", u.Path, err)
+		}
+		// the file output writes a single value per update.
+		for _, v := range u.Values {
+			upd.Val, err = typedValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %v", u.Path, err)
+			}
+		}
+		n.Update = append(n.Update, upd)
+	}
+	for _, d := range msg.Deletes {
+		p, err := path.ParsePath(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delete path %q: %v", d, err)
+		}
+		n.Delete = append(n.Delete, p)
+	}
+	return &record{
+		rsp:  &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: n}},
+		meta: meta,
+	}, nil
+}
+
+// typedValue converts a value decoded from JSON into a gNMI TypedValue.
+func typedValue(v interface{}) (*gnmi.TypedValue, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: v}}, nil
+	case bool:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: v}}, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: i}}, nil
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: u}}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: f}}, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: b}}, nil
+	}
+}
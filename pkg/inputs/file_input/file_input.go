@@ -0,0 +1,311 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file_input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix     = "[file_input] "
+	defaultFormat     = "json"
+	defaultSpeed      = 1
+	defaultMaxMsgSize = 64 * 1024 * 1024 // 64MB
+)
+
+func init() {
+	inputs.Register("file", func() inputs.Input {
+		return &FileInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// FileInput //
+type FileInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	wg      *sync.WaitGroup
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name     string `mapstructure:"name,omitempty"`
+	FileName string `mapstructure:"filename,omitempty"`
+	// the format the file was written with by the file output:
+	// json, protojson, event or proto.
+	Format string `mapstructure:"format,omitempty"`
+	// replay speed relative to the original timing of the messages.
+	Speed            float64 `mapstructure:"speed,omitempty"`
+	AsFastAsPossible bool    `mapstructure:"as-fast-as-possible,omitempty"`
+	Loop             bool    `mapstructure:"loop,omitempty"`
+	// shift the messages timestamps so that the replay starts at the current time.
+	ShiftTimestamps bool     `mapstructure:"shift-timestamps,omitempty"`
+	MaxMsgSize      int      `mapstructure:"max-msg-size,omitempty"`
+	Debug           bool     `mapstructure:"debug,omitempty"`
+	Outputs         []string `mapstructure:"outputs,omitempty"`
+	EventProcessors []string `mapstructure:"event-processors,omitempty"`
+}
+
+// Start //
+func (f *FileInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, f.Cfg)
+	if err != nil {
+		return err
+	}
+	if f.Cfg.Name == "" {
+		f.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			return err
+		}
+	}
+	err = f.setDefaults()
+	if err != nil {
+		return err
+	}
+	// fail early if the file cannot be read.
+	fd, err := os.Open(f.Cfg.FileName)
+	if err != nil {
+		return err
+	}
+	fd.Close()
+	f.logger.Printf("input starting with config: %+v", f.Cfg)
+
+	ctx, f.cfn = context.WithCancel(ctx)
+	f.wg.Add(1)
+	go f.replay(ctx)
+	return nil
+}
+
+// replay reads the file until it is fully replayed or the input is closed.
+func (f *FileInput) replay(ctx context.Context) {
+	defer f.wg.Done()
+	for i := 1; ; i++ {
+		n, err := f.replayFile(ctx)
+		if err != nil {
+			f.logger.Printf("replay %d of %q stopped after %d messages: %v", i, f.Cfg.FileName, n, err)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		f.logger.Printf("replay %d of %q done: %d messages", i, f.Cfg.FileName, n)
+		if !f.Cfg.Loop {
+			return
+		}
+		if n == 0 {
+			f.logger.Printf("file %q has no messages to replay, not looping", f.Cfg.FileName)
+			return
+		}
+	}
+}
+
+// replayFile sends the file messages to the outputs and returns the number of messages sent.
+// When pacing, each message is sent after the time elapsed between the first message timestamp
+// and its own timestamp, divided by the speed. Messages without a timestamp are sent right away.
+func (f *FileInput) replayFile(ctx context.Context) (int, error) {
+	fd, err := os.Open(f.Cfg.FileName)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+	dec := newDecoder(f.Cfg.Format, fd, f.Cfg.MaxMsgSize)
+
+	var n int
+	var first int64
+	var start time.Time
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		r, err := dec.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			var rErr *recordError
+			if errors.As(err, &rErr) {
+				f.logger.Printf("skipping message: %v", err)
+				continue
+			}
+			return n, err
+		}
+		if ts := r.timestamp(); ts > 0 {
+			if first == 0 {
+				first = ts
+				start = time.Now()
+			}
+			// elapsed replay time since the first message.
+			elapsed := ts - first
+			if !f.Cfg.AsFastAsPossible {
+				elapsed = int64(float64(elapsed) / f.Cfg.Speed)
+				if d := time.Until(start.Add(time.Duration(elapsed))); d > 0 {
+					timer.Reset(d)
+					select {
+					case <-ctx.Done():
+						return n, nil
+					case <-timer.C:
+					}
+				}
+			}
+			if f.Cfg.ShiftTimestamps {
+				r.shift(start.UnixNano() + elapsed - ts)
+			}
+		}
+		if ctx.Err() != nil {
+			return n, nil
+		}
+		f.send(ctx, r)
+		n++
+	}
+}
+
+func (f *FileInput) send(ctx context.Context, r *record) {
+	evs := r.evs
+	if r.rsp != nil {
+		if len(f.evps) == 0 {
+			for _, o := range f.outputs {
+				o.Write(ctx, r.rsp, r.meta)
+			}
+			return
+		}
+		subscriptionName, ok := r.meta["subscription-name"]
+		if !ok {
+			subscriptionName = "default"
+		}
+		var err error
+		evs, err = formatters.ResponseToEventMsgs(subscriptionName, r.rsp, r.meta, f.evps...)
+		if err != nil {
+			if f.Cfg.Debug {
+				f.logger.Printf("failed to convert message to events: %v", err)
+			}
+			return
+		}
+	} else {
+		for _, p := range f.evps {
+			evs = p.Apply(evs...)
+		}
+	}
+	for _, o := range f.outputs {
+		for _, ev := range evs {
+			o.WriteEvent(ctx, ev)
+		}
+	}
+}
+
+// Close //
+func (f *FileInput) Close() error {
+	if f.cfn != nil {
+		f.cfn()
+	}
+	f.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (f *FileInput) SetLogger(logger *log.Logger) {
+	if logger != nil && f.logger != nil {
+		f.logger.SetOutput(logger.Writer())
+		f.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (f *FileInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(f.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			f.outputs = append(f.outputs, o)
+		}
+		return
+	}
+	for _, name := range f.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			f.outputs = append(f.outputs, o)
+		}
+	}
+}
+
+func (f *FileInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(f.Cfg.Name)
+	sb.WriteString("-file")
+	f.Cfg.Name = sb.String()
+}
+
+func (f *FileInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	f.evps, err = formatters.MakeEventProcessors(
+		logger,
+		f.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper funcs
+
+func (f *FileInput) setDefaults() error {
+	if f.Cfg.FileName == "" {
+		return errors.New("missing filename field")
+	}
+	if f.Cfg.Name == "" {
+		f.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if f.Cfg.Format == "" {
+		f.Cfg.Format = defaultFormat
+	}
+	switch f.Cfg.Format {
+	case "json", "protojson", "event", "proto":
+	default:
+		return fmt.Errorf("unsupported input format %q", f.Cfg.Format)
+	}
+	if f.Cfg.Speed < 0 {
+		return fmt.Errorf("invalid speed %v", f.Cfg.Speed)
+	}
+	if f.Cfg.Speed == 0 {
+		f.Cfg.Speed = defaultSpeed
+	}
+	if f.Cfg.MaxMsgSize <= 0 {
+		f.Cfg.MaxMsgSize = defaultMaxMsgSize
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inputs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// SplitDelimiter returns a bufio.SplitFunc returning the frames separated by delim.
+// The last frame is returned even if it is not followed by the delimiter.
+func SplitDelimiter(delim []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// SplitLengthPrefix returns a bufio.SplitFunc returning the frames prefixed
+// with their length encoded as a protobuf varint.
+// Frames larger than maxSize are rejected.
+func SplitLengthPrefix(maxSize int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		l, n := binary.Uvarint(data)
+		switch {
+		case n == 0:
+			if atEOF {
+				return 0, nil, io.ErrUnexpectedEOF
+			}
+			return 0, nil, nil
+		case n < 0 || l > uint64(maxSize):
+			return 0, nil, fmt.Errorf("frame larger than %d bytes", maxSize)
+		}
+		end := n + int(l)
+		if len(data) < end {
+			if atEOF {
+				return 0, nil, io.ErrUnexpectedEOF
+			}
+			return 0, nil, nil
+		}
+		return end, data[n:end], nil
+	}
+}
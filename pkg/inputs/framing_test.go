@@ -0,0 +1,84 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inputs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func scan(t *testing.T, r io.Reader, split bufio.SplitFunc) ([]string, error) {
+	t.Helper()
+	sc := bufio.NewScanner(r)
+	// a small buffer to exercise the frames spanning multiple reads.
+	sc.Buffer(make([]byte, 0, 4), 1024)
+	sc.Split(split)
+	var frames []string
+	for sc.Scan() {
+		frames = append(frames, sc.Text())
+	}
+	return frames, sc.Err()
+}
+
+func TestSplitDelimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		delim string
+		want  []string
+	}{
+		{name: "new_line", input: "a\nbc\n\nd\n", delim: "\n", want: []string{"a", "bc", "", "d"}},
+		{name: "no_trailing_delimiter", input: "a\nbc", delim: "\n", want: []string{"a", "bc"}},
+		{name: "multi_bytes", input: "{\"a\":1}|||{\"b\":2}|||", delim: "|||", want: []string{`{"a":1}`, `{"b":2}`}},
+		{name: "empty", input: "", delim: "\n", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scan(t, strings.NewReader(tt.input), SplitDelimiter([]byte(tt.delim)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLengthPrefix(t *testing.T) {
+	msgs := []string{"a", "", strings.Repeat("x", 300), "bc\n"}
+	var b []byte
+	for _, m := range msgs {
+		b = protowire.AppendVarint(b, uint64(len(m)))
+		b = append(b, m...)
+	}
+	got, err := scan(t, bytes.NewReader(b), SplitLengthPrefix(512))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msgs) {
+		t.Errorf("got %q, want %q", got, msgs)
+	}
+
+	_, err = scan(t, bytes.NewReader(b[:len(b)-1]), SplitLengthPrefix(512))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated frame: expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+	_, err = scan(t, bytes.NewReader(b), SplitLengthPrefix(100))
+	if err == nil {
+		t.Errorf("expected an error for a frame larger than the max size")
+	}
+}
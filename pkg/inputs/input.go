@@ -32,6 +32,13 @@ var InputTypes = []string{
 	"stan",
 	"kafka",
 	"jetstream",
+	"mqtt",
+	"redis",
+	"dialout",
+	"prometheus_write",
+	"file",
+	"tcp",
+	"udp",
 }
 
 var Inputs = map[string]Initializer{}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/api/utils"
 	"github.com/openconfig/gnmic/pkg/formatters"
@@ -41,6 +42,8 @@ const (
 	defaultRecoveryWaitTime  = 2 * time.Second
 	defaultAddress           = "localhost:9092"
 	defaultGroupID           = "gnmic-consumers"
+	defaultStartFrom         = "latest"
+	defaultCommitInterval    = time.Second
 )
 
 var defaultVersion = sarama.V2_5_0_0
@@ -83,10 +86,17 @@ type Config struct {
 	Format            string           `mapstructure:"format,omitempty"`
 	Debug             bool             `mapstructure:"debug,omitempty"`
 	NumWorkers        int              `mapstructure:"num-workers,omitempty"`
-	Outputs           []string         `mapstructure:"outputs,omitempty"`
-	EventProcessors   []string         `mapstructure:"event-processors,omitempty"`
+	// where to start consuming the partitions without a committed offset: latest or earliest.
+	StartFrom string `mapstructure:"start-from,omitempty"`
+	// RFC3339 timestamp or offset to start consuming the partitions without a committed offset from.
+	StartTimestamp  string        `mapstructure:"start-timestamp,omitempty"`
+	StartOffset     *int64        `mapstructure:"start-offset,omitempty"`
+	CommitInterval  time.Duration `mapstructure:"commit-interval,omitempty"`
+	Outputs         []string      `mapstructure:"outputs,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty"`
 
 	kafkaVersion sarama.KafkaVersion
+	startTime    time.Time
 }
 
 func (k *KafkaInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
@@ -110,6 +120,7 @@ func (k *KafkaInput) Start(ctx context.Context, name string, cfg map[string]inte
 	if err != nil {
 		return err
 	}
+	ctx, k.cfn = context.WithCancel(ctx)
 	k.wg.Add(k.Cfg.NumWorkers)
 	for i := 0; i < k.Cfg.NumWorkers; i++ {
 		cfg := *config
@@ -125,17 +136,35 @@ func (k *KafkaInput) worker(ctx context.Context, idx int, config *sarama.Config)
 	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
 START:
 	k.logger.Printf("%s starting consumer group %s", workerLogPrefix, k.Cfg.GroupID)
-	consumerGrp, err := sarama.NewConsumerGroup(strings.Split(k.Cfg.Address, ","), k.Cfg.GroupID, config)
+	client, err := sarama.NewClient(strings.Split(k.Cfg.Address, ","), config)
+	if err != nil {
+		k.logger.Printf("%s failed to create kafka client: %v", workerLogPrefix, err)
+		if !k.wait(ctx) {
+			return
+		}
+		goto START
+	}
+	consumerGrp, err := sarama.NewConsumerGroupFromClient(k.Cfg.GroupID, client)
 	if err != nil {
 		k.logger.Printf("%s failed to create consumer group: %v", workerLogPrefix, err)
-		time.Sleep(k.Cfg.RecoveryWaitTime)
+		client.Close()
+		if !k.wait(ctx) {
+			return
+		}
 		goto START
 	}
 	k.logger.Printf("%s started consumer group %s", workerLogPrefix, k.Cfg.GroupID)
-	defer consumerGrp.Close()
+	// the consumer group must be closed before its client.
+	closeGrp := func() {
+		consumerGrp.Close()
+		client.Close()
+	}
 	cons := &consumer{
-		ready:   make(chan bool),
-		msgChan: make(chan *sarama.ConsumerMessage),
+		k:         k,
+		client:    client,
+		logPrefix: workerLogPrefix,
+		clientID:  config.ClientID,
+		ready:     make(chan bool),
 	}
 	go func() {
 		var err error
@@ -145,83 +174,150 @@ START:
 			}
 			err = consumerGrp.Consume(ctx, strings.Split(k.Cfg.Topics, ","), cons)
 			if err != nil {
+				if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+					return
+				}
 				if k.Cfg.Debug {
 					k.logger.Printf("%s failed to start consumer, topics=%q, group=%q : %v", workerLogPrefix, k.Cfg.Topics, k.Cfg.GroupID, err)
 				}
+				if !k.wait(ctx) {
+					return
+				}
 				continue
 			}
 			cons.ready = make(chan bool)
 		}
 	}()
-	<-cons.ready
+	select {
+	case <-ctx.Done():
+		closeGrp()
+		return
+	case <-cons.ready:
+	}
 	k.logger.Printf("%s kafka consumer ready", workerLogPrefix)
 	for {
 		select {
 		case <-ctx.Done():
+			closeGrp()
 			return
-		case m := <-cons.msgChan:
-			if len(m.Value) == 0 {
-				continue
+		case err := <-consumerGrp.Errors():
+			k.logger.Printf("%s client=%s, consumer-group=%s error: %v", workerLogPrefix, config.ClientID, k.Cfg.GroupID, err)
+			closeGrp()
+			if !k.wait(ctx) {
+				return
 			}
+			goto START
+		}
+	}
+}
+
+// handle decodes a consumed message and writes it to the outputs.
+// The outputs implementing outputs.DeliveryWriter are written to synchronously,
+// retrying every recovery-wait-time until they confirm the delivery,
+// the other outputs only queue the message.
+// It returns false if ctx is done before all the outputs got the message.
+func (k *KafkaInput) handle(ctx context.Context, m *sarama.ConsumerMessage, logPrefix, clientID string) bool {
+	if len(m.Value) == 0 {
+		return true
+	}
+	if k.Cfg.Debug {
+		k.logger.Printf("%s client=%s received msg, topic=%s, partition=%d, offset=%d, key=%q, length=%d, value=%s", logPrefix, clientID, m.Topic, m.Partition, m.Offset, string(m.Key), len(m.Value), string(m.Value))
+	}
+	var err error
+	switch k.Cfg.Format {
+	case "event":
+		m.Value = bytes.TrimSpace(m.Value)
+		evMsgs := make([]*formatters.EventMsg, 1)
+		switch {
+		case len(m.Value) == 0:
+			return true
+		case m.Value[0] == openSquareBracket[0]:
+			err = json.Unmarshal(m.Value, &evMsgs)
+		case m.Value[0] == openCurlyBrace[0]:
+			evMsgs[0] = new(formatters.EventMsg)
+			err = json.Unmarshal(m.Value, evMsgs[0])
+		default:
+			err = errors.New("not a JSON event or array of events")
+		}
+		if err != nil {
 			if k.Cfg.Debug {
-				k.logger.Printf("%s client=%s received msg, topic=%s, partition=%d, key=%q, length=%d, value=%s", workerLogPrefix, config.ClientID, m.Topic, m.Partition, string(m.Key), len(m.Value), string(m.Value))
+				k.logger.Printf("%s failed to unmarshal event msg: %v", logPrefix, err)
 			}
-			switch k.Cfg.Format {
-			case "event":
-				m.Value = bytes.TrimSpace(m.Value)
-				evMsgs := make([]*formatters.EventMsg, 1)
-				switch {
-				case len(m.Value) == 0:
-					continue
-				case m.Value[0] == openSquareBracket[0]:
-					err = json.Unmarshal(m.Value, &evMsgs)
-				case m.Value[0] == openCurlyBrace[0]:
-					err = json.Unmarshal(m.Value, evMsgs[0])
-				}
-				if err != nil {
-					if k.Cfg.Debug {
-						k.logger.Printf("%s failed to unmarshal event msg: %v", workerLogPrefix, err)
-					}
-					continue
-				}
+			return true
+		}
 
-				for _, p := range k.evps {
-					evMsgs = p.Apply(evMsgs...)
-				}
+		for _, p := range k.evps {
+			evMsgs = p.Apply(evMsgs...)
+		}
 
-				go func() {
-					for _, o := range k.outputs {
-						for _, ev := range evMsgs {
-							o.WriteEvent(ctx, ev)
-						}
-					}
-				}()
-			case "proto":
-				var protoMsg proto.Message
-				err = proto.Unmarshal(m.Value, protoMsg)
-				if err != nil {
-					if k.Cfg.Debug {
-						k.logger.Printf("%s failed to unmarshal proto msg: %v", workerLogPrefix, err)
-					}
+		for _, o := range k.outputs {
+			dw, ok := o.(outputs.DeliveryWriter)
+			for _, ev := range evMsgs {
+				if !ok {
+					o.WriteEvent(ctx, ev)
 					continue
 				}
-				meta := outputs.Meta{}
-				go func() {
-					for _, o := range k.outputs {
-						o.Write(ctx, protoMsg, meta)
-					}
-				}()
+				if !k.deliver(ctx, logPrefix, func() error { return dw.WriteEventSync(ctx, ev) }) {
+					return false
+				}
 			}
-		case err := <-consumerGrp.Errors():
-			k.logger.Printf("%s client=%s, consumer-group=%s error: %v", workerLogPrefix, config.ClientID, k.Cfg.GroupID, err)
-			time.Sleep(k.Cfg.RecoveryWaitTime)
-			goto START
+		}
+	case "proto":
+		protoMsg := new(gnmi.SubscribeResponse)
+		err = proto.Unmarshal(m.Value, protoMsg)
+		if err != nil {
+			if k.Cfg.Debug {
+				k.logger.Printf("%s failed to unmarshal proto msg: %v", logPrefix, err)
+			}
+			return true
+		}
+		meta := outputs.Meta{}
+		for _, o := range k.outputs {
+			dw, ok := o.(outputs.DeliveryWriter)
+			if !ok {
+				o.Write(ctx, protoMsg, meta)
+				continue
+			}
+			if !k.deliver(ctx, logPrefix, func() error { return dw.WriteSync(ctx, protoMsg, meta) }) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// deliver calls write until it succeeds, waiting recovery-wait-time between attempts,
+// it returns false if ctx is done before.
+func (k *KafkaInput) deliver(ctx context.Context, logPrefix string, write func() error) bool {
+	for {
+		err := write()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		k.logger.Printf("%s failed to deliver message, retrying in %s: %v", logPrefix, k.Cfg.RecoveryWaitTime, err)
+		if !k.wait(ctx) {
+			return false
 		}
 	}
 }
 
+// wait sleeps for the recovery wait time, it returns false if the context is done.
+func (k *KafkaInput) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(k.Cfg.RecoveryWaitTime):
+		return true
+	}
+}
+
 func (k *KafkaInput) Close() error {
-	k.cfn()
+	if k.cfn != nil {
+		k.cfn()
+	}
 	k.wg.Wait()
 	return nil
 }
@@ -289,7 +385,8 @@ func (k *KafkaInput) setDefaults() error {
 	if k.Cfg.Format == "" {
 		k.Cfg.Format = defaultFormat
 	}
-	if !(strings.ToLower(k.Cfg.Format) == "event" || strings.ToLower(k.Cfg.Format) == "proto") {
+	k.Cfg.Format = strings.ToLower(k.Cfg.Format)
+	if !(k.Cfg.Format == "event" || k.Cfg.Format == "proto") {
 		return fmt.Errorf("unsupported input format")
 	}
 	if k.Cfg.Topics == "" {
@@ -313,6 +410,27 @@ func (k *KafkaInput) setDefaults() error {
 	if k.Cfg.RecoveryWaitTime <= 0 {
 		k.Cfg.RecoveryWaitTime = defaultRecoveryWaitTime
 	}
+	if k.Cfg.StartFrom == "" {
+		k.Cfg.StartFrom = defaultStartFrom
+	}
+	if k.Cfg.StartFrom != "latest" && k.Cfg.StartFrom != "earliest" {
+		return fmt.Errorf("unsupported start-from value %q, must be one of latest or earliest", k.Cfg.StartFrom)
+	}
+	if k.Cfg.StartTimestamp != "" {
+		if k.Cfg.StartOffset != nil {
+			return errors.New("start-timestamp and start-offset are mutually exclusive")
+		}
+		k.Cfg.startTime, err = time.Parse(time.RFC3339, k.Cfg.StartTimestamp)
+		if err != nil {
+			return fmt.Errorf("invalid start-timestamp: %v", err)
+		}
+	}
+	if k.Cfg.StartOffset != nil && *k.Cfg.StartOffset < 0 {
+		return fmt.Errorf("invalid start-offset %d", *k.Cfg.StartOffset)
+	}
+	if k.Cfg.CommitInterval <= 0 {
+		k.Cfg.CommitInterval = defaultCommitInterval
+	}
 	if k.Cfg.Name == "" {
 		k.Cfg.Name = "gnmic-" + uuid.New().String()
 	}
@@ -338,6 +456,11 @@ func (k *KafkaInput) createConfig() (*sarama.Config, error) {
 	cfg.Consumer.Group.Session.Timeout = k.Cfg.SessionTimeout
 	cfg.Consumer.Group.Heartbeat.Interval = k.Cfg.HeartbeatInterval
 	cfg.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategyRange()
+	if k.Cfg.StartFrom == "earliest" {
+		cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	// the offsets of the messages delivered to the outputs are committed by the consumer.
+	cfg.Consumer.Offsets.AutoCommit.Enable = false
 	// SASL_PLAINTEXT or SASL_SSL
 	if k.Cfg.SASL != nil {
 		cfg.Net.SASL.Enable = true
@@ -379,27 +502,129 @@ func (k *KafkaInput) createConfig() (*sarama.Config, error) {
 // ref: https://github.com/Shopify/sarama/blob/master/examples/consumergroup/main.go
 // consumer represents a Sarama consumer group consumer
 type consumer struct {
-	ready   chan bool
-	msgChan chan *sarama.ConsumerMessage
+	k         *KafkaInput
+	client    sarama.Client
+	logPrefix string
+	clientID  string
+	ready     chan bool
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
-func (consumer *consumer) Setup(sarama.ConsumerGroupSession) error {
+func (consumer *consumer) Setup(session sarama.ConsumerGroupSession) error {
+	err := consumer.resetOffsets(session)
+	if err != nil {
+		return err
+	}
+	go consumer.commit(session)
 	// Mark the consumer as ready
 	close(consumer.ready)
 	return nil
 }
 
 // Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
-func (consumer *consumer) Cleanup(sarama.ConsumerGroupSession) error {
+func (consumer *consumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	session.Commit()
 	return nil
 }
 
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
+// The messages of a partition are handled sequentially, in order,
+// a message is marked for commit once all the outputs got it.
+// If the session ends first, the message is not marked
+// and is consumed again by the partition's next owner.
 func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for message := range claim.Messages() {
-		consumer.msgChan <- message
-		session.MarkMessage(message, "")
+	for {
+		select {
+		case <-session.Context().Done():
+			return nil
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if !consumer.k.handle(session.Context(), message, consumer.logPrefix, consumer.clientID) {
+				return nil
+			}
+			session.MarkMessage(message, "")
+		}
+	}
+}
+
+// commit periodically commits the marked offsets until the end of the session.
+func (consumer *consumer) commit(session sarama.ConsumerGroupSession) {
+	ticker := time.NewTicker(consumer.k.Cfg.CommitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session.Context().Done():
+			return
+		case <-ticker.C:
+			session.Commit()
+		}
+	}
+}
+
+// resetOffsets sets the offsets of the claimed partitions without a committed offset
+// to the configured start offset or timestamp.
+// The partitions with a committed offset resume from it,
+// so the start offset or timestamp is applied once per consumer group.
+func (consumer *consumer) resetOffsets(session sarama.ConsumerGroupSession) error {
+	k := consumer.k
+	if k.Cfg.StartOffset == nil && k.Cfg.startTime.IsZero() {
+		return nil
+	}
+	claims := session.Claims()
+	committed, err := consumer.committedOffsets(claims)
+	if err != nil {
+		return fmt.Errorf("failed to fetch committed offsets: %v", err)
+	}
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			block := committed.GetBlock(topic, partition)
+			if block == nil {
+				return fmt.Errorf("missing committed offset of topic %q partition %d", topic, partition)
+			}
+			if block.Err != sarama.ErrNoError {
+				return fmt.Errorf("failed to fetch committed offset of topic %q partition %d: %v", topic, partition, block.Err)
+			}
+			if block.Offset >= 0 {
+				continue
+			}
+			offset, err := consumer.startOffset(topic, partition)
+			if err != nil {
+				return fmt.Errorf("failed to get start offset of topic %q partition %d: %v", topic, partition, err)
+			}
+			k.logger.Printf("%s setting offset of topic %q partition %d to %d", consumer.logPrefix, topic, partition, offset)
+			// without a committed offset, the marked offset is the one consumed from.
+			session.MarkOffset(topic, partition, offset, "")
+		}
 	}
 	return nil
 }
+
+// committedOffsets fetches the consumer group committed offsets of the given partitions,
+// a partition without a committed offset has an offset of -1.
+func (consumer *consumer) committedOffsets(claims map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	coordinator, err := consumer.client.Coordinator(consumer.k.Cfg.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	req := sarama.NewOffsetFetchRequest(consumer.client.Config().Version, consumer.k.Cfg.GroupID, claims)
+	return coordinator.FetchOffset(req)
+}
+
+// startOffset returns the configured start offset,
+// or the offset of the first message produced after the configured start timestamp.
+func (consumer *consumer) startOffset(topic string, partition int32) (int64, error) {
+	if consumer.k.Cfg.StartOffset != nil {
+		return *consumer.k.Cfg.StartOffset, nil
+	}
+	offset, err := consumer.client.GetOffset(topic, partition, consumer.k.Cfg.startTime.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	if offset >= 0 {
+		return offset, nil
+	}
+	// no message was produced after the start timestamp.
+	return consumer.client.GetOffset(topic, partition, sarama.OffsetNewest)
+}
@@ -0,0 +1,265 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka_input
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	testTopic = "telemetry"
+	testGroup = "gnmic-consumers"
+)
+
+func newTestInput(t *testing.T, cfg *Config) *KafkaInput {
+	t.Helper()
+	k := inputs.Inputs["kafka"]().(*KafkaInput)
+	k.Cfg = cfg
+	if err := k.setDefaults(); err != nil {
+		t.Fatalf("failed to set defaults: %v", err)
+	}
+	return k
+}
+
+// newTestClient returns a client connected to a mock broker
+// leading all the partitions and coordinating the test consumer group.
+func newTestClient(t *testing.T, k *KafkaInput, partitions int32, handlers map[string]sarama.MockResponse) sarama.Client {
+	t.Helper()
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID())
+	for p := int32(0); p < partitions; p++ {
+		metadata.SetLeader(testTopic, p, broker.BrokerID())
+	}
+	handlers["ApiVersionsRequest"] = sarama.NewMockApiVersionsResponse(t)
+	handlers["MetadataRequest"] = metadata
+	handlers["FindCoordinatorRequest"] = sarama.NewMockFindCoordinatorResponse(t).
+		SetCoordinator(sarama.CoordinatorGroup, testGroup, broker)
+	broker.SetHandlerByMap(handlers)
+
+	config, err := k.createConfig()
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// testSession records the offsets marked during a consumer group session.
+type testSession struct {
+	sarama.ConsumerGroupSession
+	claims map[string][]int32
+	marked map[int32]int64
+}
+
+func (s *testSession) Claims() map[string][]int32 { return s.claims }
+
+func (s *testSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.marked[partition] = offset
+}
+
+func TestSetDefaults(t *testing.T) {
+	offset := int64(5)
+	negOffset := int64(-1)
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{name: "defaults", cfg: &Config{}},
+		{name: "start_timestamp", cfg: &Config{StartTimestamp: "2024-01-01T00:00:00Z"}},
+		{name: "start_offset", cfg: &Config{StartOffset: &offset}},
+		{name: "invalid_start_timestamp", cfg: &Config{StartTimestamp: "yesterday"}, wantErr: true},
+		{name: "negative_start_offset", cfg: &Config{StartOffset: &negOffset}, wantErr: true},
+		{name: "start_timestamp_and_offset", cfg: &Config{StartTimestamp: "2024-01-01T00:00:00Z", StartOffset: &offset}, wantErr: true},
+		{name: "invalid_start_from", cfg: &Config{StartFrom: "middle"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := inputs.Inputs["kafka"]().(*KafkaInput)
+			k.Cfg = tt.cfg
+			err := k.setDefaults()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setDefaults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResetOffsets(t *testing.T) {
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	offset := int64(5)
+	tests := []struct {
+		name string
+		cfg  *Config
+		// committed offsets of partitions 0 and 1, -1 if none.
+		committed [2]int64
+		// offsets by timestamp of partitions 0 and 1.
+		offsets [2]map[int64]int64
+		want    map[int32]int64
+	}{
+		{
+			name:      "no_start_offset",
+			cfg:       &Config{},
+			committed: [2]int64{-1, 10},
+			want:      map[int32]int64{},
+		},
+		{
+			name:      "start_offset",
+			cfg:       &Config{StartOffset: &offset},
+			committed: [2]int64{-1, 10},
+			want:      map[int32]int64{0: 5},
+		},
+		{
+			name:      "start_offset_all_committed",
+			cfg:       &Config{StartOffset: &offset},
+			committed: [2]int64{3, 10},
+			want:      map[int32]int64{},
+		},
+		{
+			name:      "start_timestamp",
+			cfg:       &Config{StartTimestamp: startTime.Format(time.RFC3339)},
+			committed: [2]int64{-1, -1},
+			offsets: [2]map[int64]int64{
+				{startTime.UnixMilli(): 7},
+				// no message produced after the start timestamp.
+				{startTime.UnixMilli(): -1, sarama.OffsetNewest: 20},
+			},
+			want: map[int32]int64{0: 7, 1: 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestInput(t, tt.cfg)
+			offsetFetch := sarama.NewMockOffsetFetchResponse(t)
+			offsetResponse := sarama.NewMockOffsetResponse(t)
+			for p := int32(0); p < 2; p++ {
+				offsetFetch.SetOffset(testGroup, testTopic, p, tt.committed[p], "", sarama.ErrNoError)
+				for ts, o := range tt.offsets[p] {
+					offsetResponse.SetOffset(testTopic, p, ts, o)
+				}
+			}
+			client := newTestClient(t, k, 2, map[string]sarama.MockResponse{
+				"OffsetFetchRequest": offsetFetch,
+				"OffsetRequest":      offsetResponse,
+			})
+
+			cons := &consumer{k: k, client: client}
+			session := &testSession{
+				claims: map[string][]int32{testTopic: {0, 1}},
+				marked: make(map[int32]int64),
+			}
+			if err := cons.resetOffsets(session); err != nil {
+				t.Fatalf("resetOffsets() error = %v", err)
+			}
+			if !reflect.DeepEqual(session.marked, tt.want) {
+				t.Errorf("marked offsets = %v, want %v", session.marked, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetOffsetsFetchError(t *testing.T) {
+	offset := int64(5)
+	k := newTestInput(t, &Config{StartOffset: &offset})
+	client := newTestClient(t, k, 1, map[string]sarama.MockResponse{
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(testGroup, testTopic, 0, -1, "", sarama.ErrUnknownTopicOrPartition),
+	})
+
+	cons := &consumer{k: k, client: client}
+	session := &testSession{
+		claims: map[string][]int32{testTopic: {0}},
+		marked: make(map[int32]int64),
+	}
+	if err := cons.resetOffsets(session); err == nil {
+		t.Fatal("resetOffsets() succeeded, want error")
+	}
+	if len(session.marked) != 0 {
+		t.Errorf("marked offsets = %v, want none", session.marked)
+	}
+}
+
+// testOutput is an output reporting delivery errors
+// until it was written to failures+1 times.
+type testOutput struct {
+	outputs.Output
+	failures int
+	writes   int
+}
+
+func (o *testOutput) WriteSync(context.Context, proto.Message, outputs.Meta) error {
+	return o.write()
+}
+
+func (o *testOutput) WriteEventSync(context.Context, *formatters.EventMsg) error {
+	return o.write()
+}
+
+func (o *testOutput) write() error {
+	o.writes++
+	if o.writes <= o.failures {
+		return errors.New("destination unreachable")
+	}
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic: testTopic,
+		Value: []byte(`{"name":"sub1","timestamp":1,"values":{"counter":1}}`),
+	}
+	tests := []struct {
+		name       string
+		failures   int
+		timeout    time.Duration
+		want       bool
+		wantWrites int
+	}{
+		{name: "delivered", want: true, wantWrites: 1},
+		{name: "delivered_after_retries", failures: 2, timeout: time.Second, want: true, wantWrites: 3},
+		{name: "not_delivered", failures: 1000, timeout: 50 * time.Millisecond, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestInput(t, &Config{RecoveryWaitTime: 10 * time.Millisecond})
+			o := &testOutput{failures: tt.failures}
+			k.outputs = []outputs.Output{o}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			m := *msg
+			if got := k.handle(ctx, &m, "", ""); got != tt.want {
+				t.Fatalf("handle() = %v, want %v", got, tt.want)
+			}
+			if tt.wantWrites > 0 && o.writes != tt.wantWrites {
+				t.Errorf("writes = %d, want %d", o.writes, tt.wantWrites)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_input
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"strings"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+// message is a received MQTT message.
+type message struct {
+	topic   string
+	payload []byte
+	// MQTT 5 user properties
+	props map[string]string
+}
+
+// subscriber abstracts the MQTT 3.1.1 and MQTT 5 clients.
+type subscriber interface {
+	close(ctx context.Context) error
+}
+
+func (m *MqttInput) createSubscriber(ctx context.Context, msgCh chan<- *message) (subscriber, error) {
+	var tlsCfg *tls.Config
+	var err error
+	if m.Cfg.TLS != nil {
+		tlsCfg, err = utils.NewTLSConfig(
+			m.Cfg.TLS.CaFile,
+			m.Cfg.TLS.CertFile,
+			m.Cfg.TLS.KeyFile,
+			"",
+			m.Cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch m.Cfg.ProtocolVersion {
+	case protocolVersion5:
+		return m.newSubscriberV5(ctx, tlsCfg, msgCh)
+	default:
+		return m.newSubscriberV3(ctx, tlsCfg, msgCh)
+	}
+}
+
+// brokerURL adds a scheme to the address if it doesn't have one.
+func brokerURL(address string, secure bool) string {
+	if strings.Contains(address, "://") {
+		return address
+	}
+	if secure {
+		return "ssl://" + address
+	}
+	return "tcp://" + address
+}
+
+// MQTT 3.1.1
+
+type subscriberV3 struct {
+	client mqtt.Client
+}
+
+func (m *MqttInput) newSubscriberV3(ctx context.Context, tlsCfg *tls.Config, msgCh chan<- *message) (subscriber, error) {
+	handler := func(_ mqtt.Client, msg mqtt.Message) {
+		select {
+		case <-ctx.Done():
+		case msgCh <- &message{topic: msg.Topic(), payload: msg.Payload()}:
+		}
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(brokerURL(m.Cfg.Address, tlsCfg != nil)).
+		SetClientID(m.Cfg.ClientID).
+		SetCleanSession(!m.Cfg.PersistentSession).
+		SetKeepAlive(m.Cfg.KeepAlive).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(m.Cfg.ConnectTimeWait).
+		SetMaxReconnectInterval(m.Cfg.ConnectTimeWait).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			m.logger.Printf("connected to MQTT broker %s", m.Cfg.Address)
+			// subscribe on each (re)connection.
+			t := c.Subscribe(m.Cfg.Topic, m.Cfg.QoS, handler)
+			go func() {
+				<-t.Done()
+				if err := t.Error(); err != nil {
+					m.logger.Printf("failed to subscribe to topic %q: %v", m.Cfg.Topic, err)
+					return
+				}
+				m.logger.Printf("subscribed to topic %q", m.Cfg.Topic)
+			}()
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Printf("connection to MQTT broker lost: %v", err)
+		})
+	if m.Cfg.Username != "" {
+		opts.SetUsername(m.Cfg.Username)
+		opts.SetPassword(m.Cfg.Password)
+	}
+	if tlsCfg != nil {
+		opts.SetTLSConfig(tlsCfg)
+	}
+	client := mqtt.NewClient(opts)
+	client.Connect()
+	return &subscriberV3{client: client}, nil
+}
+
+func (s *subscriberV3) close(context.Context) error {
+	s.client.Disconnect(250)
+	return nil
+}
+
+// MQTT 5
+
+type subscriberV5 struct {
+	cm *autopaho.ConnectionManager
+}
+
+func (m *MqttInput) newSubscriberV5(ctx context.Context, tlsCfg *tls.Config, msgCh chan<- *message) (subscriber, error) {
+	u, err := url.Parse(brokerURL(m.Cfg.Address, tlsCfg != nil))
+	if err != nil {
+		return nil, err
+	}
+	cfg := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{u},
+		TlsCfg:                        tlsCfg,
+		KeepAlive:                     uint16(m.Cfg.KeepAlive.Seconds()),
+		CleanStartOnInitialConnection: !m.Cfg.PersistentSession,
+		ConnectRetryDelay:             m.Cfg.ConnectTimeWait,
+		ConnectTimeout:                m.Cfg.ConnectTimeWait,
+		ConnectUsername:               m.Cfg.Username,
+		ConnectPassword:               []byte(m.Cfg.Password),
+		OnConnectionUp: func(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+			m.logger.Printf("connected to MQTT broker %s", m.Cfg.Address)
+			// subscribe on each (re)connection.
+			_, err := cm.Subscribe(ctx, &paho.Subscribe{
+				Subscriptions: []paho.SubscribeOptions{
+					{Topic: m.Cfg.Topic, QoS: m.Cfg.QoS},
+				},
+			})
+			if err != nil {
+				m.logger.Printf("failed to subscribe to topic %q: %v", m.Cfg.Topic, err)
+				return
+			}
+			m.logger.Printf("subscribed to topic %q", m.Cfg.Topic)
+		},
+		OnConnectError: func(err error) {
+			m.logger.Printf("failed to connect to MQTT broker %s: %v", m.Cfg.Address, err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: m.Cfg.ClientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				func(pr paho.PublishReceived) (bool, error) {
+					msg := &message{
+						topic:   pr.Packet.Topic,
+						payload: pr.Packet.Payload,
+					}
+					if pr.Packet.Properties != nil && len(pr.Packet.Properties.User) > 0 {
+						msg.props = make(map[string]string, len(pr.Packet.Properties.User))
+						for _, up := range pr.Packet.Properties.User {
+							msg.props[up.Key] = up.Value
+						}
+					}
+					select {
+					case <-ctx.Done():
+					case msgCh <- msg:
+					}
+					return true, nil
+				},
+			},
+			OnClientError: func(err error) {
+				m.logger.Printf("MQTT client error: %v", err)
+			},
+		},
+	}
+	if m.Cfg.PersistentSession {
+		cfg.SessionExpiryInterval = sessionExpiryInterval
+	}
+	cm, err := autopaho.NewConnection(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &subscriberV5{cm: cm}, nil
+}
+
+func (s *subscriberV5) close(ctx context.Context) error {
+	return s.cm.Disconnect(ctx)
+}
@@ -0,0 +1,330 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_input
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix          = "[mqtt_input] "
+	defaultAddress         = "localhost:1883"
+	defaultTopic           = "telemetry/#"
+	defaultFormat          = "event"
+	defaultNumWorkers      = 1
+	defaultBufferSize      = 100
+	defaultConnectTimeWait = 2 * time.Second
+	defaultKeepAlive       = 30 * time.Second
+	closeTimeout           = 2 * time.Second
+	sessionExpiryInterval  = 24 * 60 * 60 // seconds
+
+	protocolVersion311 = "3.1.1"
+	protocolVersion5   = "5"
+)
+
+func init() {
+	inputs.Register("mqtt", func() inputs.Input {
+		return &MqttInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// MqttInput //
+type MqttInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	sub     subscriber
+	wg      *sync.WaitGroup
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name              string           `mapstructure:"name,omitempty"`
+	Address           string           `mapstructure:"address,omitempty"`
+	ProtocolVersion   string           `mapstructure:"protocol-version,omitempty"`
+	ClientID          string           `mapstructure:"client-id,omitempty"`
+	Username          string           `mapstructure:"username,omitempty"`
+	Password          string           `mapstructure:"password,omitempty"`
+	TLS               *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	PersistentSession bool             `mapstructure:"persistent-session,omitempty"`
+	KeepAlive         time.Duration    `mapstructure:"keep-alive,omitempty"`
+	ConnectTimeWait   time.Duration    `mapstructure:"connect-time-wait,omitempty"`
+	Topic             string           `mapstructure:"topic,omitempty"`
+	QoS               byte             `mapstructure:"qos,omitempty"`
+	Format            string           `mapstructure:"format,omitempty"`
+	Debug             bool             `mapstructure:"debug,omitempty"`
+	NumWorkers        int              `mapstructure:"num-workers,omitempty"`
+	BufferSize        int              `mapstructure:"buffer-size,omitempty"`
+	Outputs           []string         `mapstructure:"outputs,omitempty"`
+	EventProcessors   []string         `mapstructure:"event-processors,omitempty"`
+}
+
+// Start //
+func (m *MqttInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	// allow setting the protocol version as a number, e.g: `protocol-version: 5`
+	if v, ok := cfg["protocol-version"]; ok && v != nil {
+		cfg["protocol-version"] = fmt.Sprint(v)
+	}
+	err := outputs.DecodeConfig(cfg, m.Cfg)
+	if err != nil {
+		return err
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return err
+		}
+	}
+	err = m.setDefaults()
+	if err != nil {
+		return err
+	}
+	ctx, m.cfn = context.WithCancel(ctx)
+	m.logger.Printf("input starting with config: %+v", m.Cfg)
+
+	msgCh := make(chan *message, m.Cfg.BufferSize)
+	m.wg.Add(m.Cfg.NumWorkers)
+	for i := 0; i < m.Cfg.NumWorkers; i++ {
+		go m.worker(ctx, i, msgCh)
+	}
+	m.sub, err = m.createSubscriber(ctx, msgCh)
+	if err != nil {
+		m.cfn()
+		return err
+	}
+	return nil
+}
+
+func (m *MqttInput) worker(ctx context.Context, idx int, msgCh <-chan *message) {
+	defer m.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
+	m.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-msgCh:
+			if len(msg.payload) == 0 {
+				continue
+			}
+			if m.Cfg.Debug {
+				m.logger.Printf("%s received msg, topic=%s, len=%d, data=%s", workerLogPrefix, msg.topic, len(msg.payload), string(msg.payload))
+			}
+			switch m.Cfg.Format {
+			case "event":
+				evMsgs, err := decodeEvents(msg.payload)
+				if err != nil {
+					if m.Cfg.Debug {
+						m.logger.Printf("%s failed to unmarshal event msg: %v", workerLogPrefix, err)
+					}
+					continue
+				}
+				for _, p := range m.evps {
+					evMsgs = p.Apply(evMsgs...)
+				}
+				for _, o := range m.outputs {
+					for _, ev := range evMsgs {
+						o.WriteEvent(ctx, ev)
+					}
+				}
+			case "proto":
+				protoMsg := new(gnmi.SubscribeResponse)
+				err := proto.Unmarshal(msg.payload, protoMsg)
+				if err != nil {
+					if m.Cfg.Debug {
+						m.logger.Printf("%s failed to unmarshal proto msg: %v", workerLogPrefix, err)
+					}
+					continue
+				}
+				meta := msgMeta(msg)
+				for _, o := range m.outputs {
+					o.Write(ctx, protoMsg, meta)
+				}
+			}
+		}
+	}
+}
+
+// decodeEvents decodes a JSON event or a JSON array of events.
+func decodeEvents(b []byte) ([]*formatters.EventMsg, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		ev := new(formatters.EventMsg)
+		err := json.Unmarshal(b, ev)
+		if err != nil {
+			return nil, err
+		}
+		return []*formatters.EventMsg{ev}, nil
+	}
+	evMsgs := make([]*formatters.EventMsg, 0)
+	err := json.Unmarshal(b, &evMsgs)
+	if err != nil {
+		return nil, err
+	}
+	return evMsgs, nil
+}
+
+// msgMeta builds the proto message metadata from the MQTT 5 user properties,
+// or from the topic if it has the default output topic layout: <prefix>/<subscription-name>/<source>.
+func msgMeta(msg *message) outputs.Meta {
+	meta := outputs.Meta{}
+	if len(msg.props) > 0 {
+		for k, v := range msg.props {
+			meta[k] = v
+		}
+		return meta
+	}
+	topicSections := strings.SplitN(msg.topic, "/", 3)
+	if len(topicSections) == 3 {
+		meta["subscription-name"] = topicSections[1]
+		meta["source"] = topicSections[2]
+	}
+	return meta
+}
+
+// Close //
+func (m *MqttInput) Close() error {
+	if m.cfn == nil {
+		return nil
+	}
+	if m.sub != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		if err := m.sub.close(ctx); err != nil {
+			m.logger.Printf("failed to disconnect: %v", err)
+		}
+	}
+	m.cfn()
+	m.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (m *MqttInput) SetLogger(logger *log.Logger) {
+	if logger != nil && m.logger != nil {
+		m.logger.SetOutput(logger.Writer())
+		m.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (m *MqttInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(m.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			m.outputs = append(m.outputs, o)
+		}
+		return
+	}
+	for _, name := range m.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			m.outputs = append(m.outputs, o)
+		}
+	}
+}
+
+func (m *MqttInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(m.Cfg.Name)
+	sb.WriteString("-mqtt-sub")
+	m.Cfg.Name = sb.String()
+}
+
+func (m *MqttInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	m.evps, err = formatters.MakeEventProcessors(
+		logger,
+		m.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper functions
+
+func (m *MqttInput) setDefaults() error {
+	if m.Cfg.Format == "" {
+		m.Cfg.Format = defaultFormat
+	}
+	m.Cfg.Format = strings.ToLower(m.Cfg.Format)
+	if !(m.Cfg.Format == "event" || m.Cfg.Format == "proto") {
+		return fmt.Errorf("unsupported input format")
+	}
+	switch m.Cfg.ProtocolVersion {
+	case "", "3", "4", protocolVersion311:
+		m.Cfg.ProtocolVersion = protocolVersion311
+	case protocolVersion5, "5.0":
+		m.Cfg.ProtocolVersion = protocolVersion5
+	default:
+		return fmt.Errorf("unsupported MQTT protocol version %q", m.Cfg.ProtocolVersion)
+	}
+	if m.Cfg.QoS > 2 {
+		return fmt.Errorf("invalid qos value %d", m.Cfg.QoS)
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if m.Cfg.ClientID == "" {
+		m.Cfg.ClientID = m.Cfg.Name + "-" + uuid.New().String()[:8]
+	}
+	if m.Cfg.Address == "" {
+		m.Cfg.Address = defaultAddress
+	}
+	if m.Cfg.Topic == "" {
+		m.Cfg.Topic = defaultTopic
+	}
+	if m.Cfg.KeepAlive <= 0 {
+		m.Cfg.KeepAlive = defaultKeepAlive
+	}
+	if m.Cfg.ConnectTimeWait <= 0 {
+		m.Cfg.ConnectTimeWait = defaultConnectTimeWait
+	}
+	if m.Cfg.NumWorkers <= 0 {
+		m.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if m.Cfg.BufferSize <= 0 {
+		m.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus_write_input
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/google/uuid"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix         = "[prometheus_write_input] "
+	defaultPath           = "/api/v1/write"
+	defaultEventName      = "prometheus"
+	defaultTimeout        = 10 * time.Second
+	defaultMaxRequestSize = 32 * 1024 * 1024 // 32MB
+	shutdownTimeout       = 5 * time.Second
+
+	metricNameLabel = "__name__"
+)
+
+func init() {
+	inputs.Register("prometheus_write", func() inputs.Input {
+		return &PrometheusWriteInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+// PrometheusWriteInput //
+type PrometheusWriteInput struct {
+	Cfg    *Config
+	ctx    context.Context
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	server  *http.Server
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name    string           `mapstructure:"name,omitempty"`
+	Address string           `mapstructure:"address,omitempty"`
+	Path    string           `mapstructure:"path,omitempty"`
+	TLS     *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	// basic authentication credentials expected from the remote write clients.
+	Username string        `mapstructure:"username,omitempty"`
+	Password string        `mapstructure:"password,omitempty"`
+	Timeout  time.Duration `mapstructure:"timeout,omitempty"`
+	// max size of a decompressed write request.
+	MaxRequestSize  int      `mapstructure:"max-request-size,omitempty"`
+	EventName       string   `mapstructure:"event-name,omitempty"`
+	Debug           bool     `mapstructure:"debug,omitempty"`
+	Outputs         []string `mapstructure:"outputs,omitempty"`
+	EventProcessors []string `mapstructure:"event-processors,omitempty"`
+}
+
+// Start //
+func (p *PrometheusWriteInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, p.Cfg)
+	if err != nil {
+		return err
+	}
+	if p.Cfg.Name == "" {
+		p.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return err
+		}
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	p.logger.Printf("input starting with config: %+v", p.Cfg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(p.Cfg.Path, p.handleWrite)
+	p.server = &http.Server{
+		Addr:         p.Cfg.Address,
+		Handler:      mux,
+		ReadTimeout:  p.Cfg.Timeout,
+		WriteTimeout: p.Cfg.Timeout,
+	}
+	if p.Cfg.TLS != nil {
+		p.server.TLSConfig, err = utils.NewTLSConfig(
+			p.Cfg.TLS.CaFile,
+			p.Cfg.TLS.CertFile,
+			p.Cfg.TLS.KeyFile,
+			p.Cfg.TLS.ClientAuth,
+			false, // skip-verify
+			true,  // genSelfSigned
+		)
+		if err != nil {
+			return err
+		}
+	}
+	l, err := net.Listen("tcp", p.Cfg.Address)
+	if err != nil {
+		return err
+	}
+	p.ctx, p.cfn = context.WithCancel(ctx)
+	p.logger.Printf("waiting for remote write requests on %s%s", l.Addr(), p.Cfg.Path)
+	go func() {
+		var err error
+		if p.server.TLSConfig != nil {
+			err = p.server.ServeTLS(l, "", "")
+		} else {
+			err = p.server.Serve(l)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.logger.Printf("HTTP server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (p *PrometheusWriteInput) handleWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if p.Cfg.Username != "" || p.Cfg.Password != "" {
+		u, pw, ok := r.BasicAuth()
+		if !ok || !p.checkCredentials(u, pw) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gnmic"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	// only remote write 1.0 (prometheus.WriteRequest) is supported.
+	if ct := r.Header.Get("Content-Type"); strings.Contains(ct, "proto=") &&
+		!strings.Contains(ct, "proto=prometheus.WriteRequest") {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", ct), http.StatusUnsupportedMediaType)
+		return
+	}
+	req, err := p.decodeRequest(r)
+	if err != nil {
+		if p.Cfg.Debug {
+			p.logger.Printf("failed to decode write request from %s: %v", r.RemoteAddr, err)
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	evs := p.requestEvents(req)
+	if p.Cfg.Debug {
+		p.logger.Printf("received %d time series from %s, %d events", len(req.Timeseries), r.RemoteAddr, len(evs))
+	}
+	for _, proc := range p.evps {
+		evs = proc.Apply(evs...)
+	}
+	for _, o := range p.outputs {
+		for _, ev := range evs {
+			o.WriteEvent(p.ctx, ev)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkCredentials compares the credentials in constant time.
+func (p *PrometheusWriteInput) checkCredentials(username, password string) bool {
+	uok := subtle.ConstantTimeCompare([]byte(username), []byte(p.Cfg.Username)) == 1
+	pok := subtle.ConstantTimeCompare([]byte(password), []byte(p.Cfg.Password)) == 1
+	return uok && pok
+}
+
+func (p *PrometheusWriteInput) decodeRequest(r *http.Request) (*prompb.WriteRequest, error) {
+	// the compressed body is at most as large as the decompressed one.
+	b, err := io.ReadAll(io.LimitReader(r.Body, int64(p.Cfg.MaxRequestSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > p.Cfg.MaxRequestSize {
+		return nil, fmt.Errorf("request body larger than %d bytes", p.Cfg.MaxRequestSize)
+	}
+	n, err := snappy.DecodedLen(b)
+	if err != nil {
+		return nil, err
+	}
+	if n > p.Cfg.MaxRequestSize {
+		return nil, fmt.Errorf("decompressed request larger than %d bytes", p.Cfg.MaxRequestSize)
+	}
+	b, err = snappy.Decode(nil, b)
+	if err != nil {
+		return nil, err
+	}
+	req := new(prompb.WriteRequest)
+	err = req.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// requestEvents converts the samples of a write request to events.
+// The samples sharing the same labels (other than the metric name) and timestamp
+// are grouped in a single event, with the metric names as value names and the labels as tags.
+// Stale markers, NaN and infinite values as well as native histograms are ignored.
+func (p *PrometheusWriteInput) requestEvents(req *prompb.WriteRequest) []*formatters.EventMsg {
+	evs := make([]*formatters.EventMsg, 0, len(req.Timeseries))
+	idx := make(map[string]*formatters.EventMsg)
+	sb := new(strings.Builder)
+	for _, ts := range req.Timeseries {
+		var name string
+		tags := make(map[string]string, len(ts.Labels))
+		sb.Reset()
+		// labels are sorted by name in a remote write request.
+		for _, l := range ts.Labels {
+			if l.Name == metricNameLabel {
+				name = l.Value
+				continue
+			}
+			tags[l.Name] = l.Value
+			sb.WriteString(l.Name)
+			sb.WriteByte(0xff)
+			sb.WriteString(l.Value)
+			sb.WriteByte(0xff)
+		}
+		if name == "" {
+			continue
+		}
+		key := sb.String()
+		for _, s := range ts.Samples {
+			if value.IsStaleNaN(s.Value) || math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+				continue
+			}
+			evKey := fmt.Sprintf("%d\xff%s", s.Timestamp, key)
+			ev, ok := idx[evKey]
+			if !ok {
+				ev = &formatters.EventMsg{
+					Name:      p.Cfg.EventName,
+					Timestamp: s.Timestamp * int64(time.Millisecond),
+					Tags:      make(map[string]string, len(tags)),
+					Values:    make(map[string]interface{}, 1),
+				}
+				for k, v := range tags {
+					ev.Tags[k] = v
+				}
+				idx[evKey] = ev
+				evs = append(evs, ev)
+			}
+			ev.Values[name] = s.Value
+		}
+		if p.Cfg.Debug && len(ts.Histograms) > 0 {
+			p.logger.Printf("ignoring %d native histogram samples of %q", len(ts.Histograms), name)
+		}
+	}
+	return evs
+}
+
+// Close //
+func (p *PrometheusWriteInput) Close() error {
+	if p.cfn != nil {
+		p.cfn()
+	}
+	if p.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return p.server.Shutdown(ctx)
+}
+
+// SetLogger //
+func (p *PrometheusWriteInput) SetLogger(logger *log.Logger) {
+	if logger != nil && p.logger != nil {
+		p.logger.SetOutput(logger.Writer())
+		p.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (p *PrometheusWriteInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(p.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			p.outputs = append(p.outputs, o)
+		}
+		return
+	}
+	for _, name := range p.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			p.outputs = append(p.outputs, o)
+		}
+	}
+}
+
+func (p *PrometheusWriteInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(p.Cfg.Name)
+	sb.WriteString("-prometheus-write")
+	p.Cfg.Name = sb.String()
+}
+
+func (p *PrometheusWriteInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	p.evps, err = formatters.MakeEventProcessors(
+		logger,
+		p.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper funcs
+
+func (p *PrometheusWriteInput) setDefaults() error {
+	if p.Cfg.Address == "" {
+		return errors.New("missing address field")
+	}
+	if p.Cfg.Name == "" {
+		p.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if p.Cfg.Path == "" {
+		p.Cfg.Path = defaultPath
+	}
+	if !strings.HasPrefix(p.Cfg.Path, "/") {
+		p.Cfg.Path = "/" + p.Cfg.Path
+	}
+	if p.Cfg.Timeout <= 0 {
+		p.Cfg.Timeout = defaultTimeout
+	}
+	if p.Cfg.MaxRequestSize <= 0 {
+		p.Cfg.MaxRequestSize = defaultMaxRequestSize
+	}
+	if p.Cfg.EventName == "" {
+		p.Cfg.EventName = defaultEventName
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus_write_input
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+)
+
+func newTestInput(t *testing.T, cfg *Config) *PrometheusWriteInput {
+	t.Helper()
+	p := inputs.Inputs["prometheus_write"]().(*PrometheusWriteInput)
+	p.Cfg = cfg
+	p.ctx = context.Background()
+	p.Cfg.Address = ":0"
+	if err := p.setDefaults(); err != nil {
+		t.Fatalf("failed to set defaults: %v", err)
+	}
+	return p
+}
+
+func encodeRequest(t *testing.T, req *prompb.WriteRequest) []byte {
+	t.Helper()
+	b, err := req.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal write request: %v", err)
+	}
+	return snappy.Encode(nil, b)
+}
+
+var testRequest = &prompb.WriteRequest{
+	Timeseries: []prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "in_octets"},
+				{Name: "interface", Value: "e1"},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: 1000, Value: 10},
+				{Timestamp: 2000, Value: math.Float64frombits(value.StaleNaN)},
+			},
+		},
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "out_octets"},
+				{Name: "interface", Value: "e1"},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: 1000, Value: 20},
+				{Timestamp: 2000, Value: math.Inf(1)},
+			},
+		},
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "in_octets"},
+				{Name: "interface", Value: "e2"},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: 1000, Value: 30},
+			},
+		},
+		// no metric name
+		{
+			Labels:  []prompb.Label{{Name: "interface", Value: "e3"}},
+			Samples: []prompb.Sample{{Timestamp: 1000, Value: 40}},
+		},
+	},
+}
+
+func TestDecodeRequest(t *testing.T) {
+	p := newTestInput(t, &Config{})
+	body := encodeRequest(t, testRequest)
+	req, err := p.decodeRequest(httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body)))
+	if err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+	// NaN values are not equal to themselves, compare the encoded requests.
+	if !bytes.Equal(encodeRequest(t, req), body) {
+		t.Errorf("got %+v, want %+v", req.Timeseries, testRequest.Timeseries)
+	}
+
+	tests := map[string]struct {
+		maxSize int
+		body    []byte
+	}{
+		"not_snappy":        {body: []byte("not snappy")},
+		"body_too_large":    {maxSize: 4, body: body},
+		"decoded_too_large": {maxSize: len(body), body: snappy.Encode(nil, make([]byte, 10*len(body)))},
+		"not_protobuf":      {body: snappy.Encode(nil, []byte{0xff, 0xff, 0xff})},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestInput(t, &Config{MaxRequestSize: tt.maxSize})
+			_, err := p.decodeRequest(httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(tt.body)))
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestRequestEvents(t *testing.T) {
+	p := newTestInput(t, &Config{})
+	got := p.requestEvents(testRequest)
+	want := []*formatters.EventMsg{
+		{
+			Name:      defaultEventName,
+			Timestamp: 1000 * 1000 * 1000,
+			Tags:      map[string]string{"interface": "e1"},
+			Values:    map[string]interface{}{"in_octets": 10.0, "out_octets": 20.0},
+		},
+		{
+			Name:      defaultEventName,
+			Timestamp: 1000 * 1000 * 1000,
+			Tags:      map[string]string{"interface": "e2"},
+			Values:    map[string]interface{}{"in_octets": 30.0},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, ev := range got {
+			t.Errorf("got:  %+v", ev)
+		}
+		for _, ev := range want {
+			t.Errorf("want: %+v", ev)
+		}
+	}
+}
+
+func TestHandleWrite(t *testing.T) {
+	body := encodeRequest(t, testRequest)
+	tests := map[string]struct {
+		username string
+		password string
+		setAuth  bool
+		want     int
+	}{
+		"valid_credentials": {username: "admin", password: "secret", setAuth: true, want: http.StatusNoContent},
+		"wrong_password":    {username: "admin", password: "secre", setAuth: true, want: http.StatusUnauthorized},
+		"wrong_username":    {username: "admin2", password: "secret", setAuth: true, want: http.StatusUnauthorized},
+		"no_credentials":    {want: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestInput(t, &Config{Username: "admin", Password: "secret"})
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+			if tt.setAuth {
+				r.SetBasicAuth(tt.username, tt.password)
+			}
+			w := httptest.NewRecorder()
+			p.handleWrite(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
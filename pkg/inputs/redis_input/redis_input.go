@@ -0,0 +1,540 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redis_input
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix          = "[redis_input] "
+	defaultAddress         = "127.0.0.1:6379"
+	defaultStream          = "gnmic:default"
+	defaultGroup           = "gnmic"
+	defaultStartID         = "$"
+	defaultFormat          = "event"
+	defaultNumWorkers      = 1
+	defaultBufferSize      = 100
+	defaultBatchSize       = 100
+	defaultBlock           = 5 * time.Second
+	defaultConnectTimeWait = 2 * time.Second
+	// name of the stream entry field holding the message.
+	dataField = "data"
+)
+
+func init() {
+	inputs.Register("redis", func() inputs.Input {
+		return &RedisInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// RedisInput //
+type RedisInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	client  *redis.Client
+	wg      *sync.WaitGroup
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name     string           `mapstructure:"name,omitempty"`
+	Address  string           `mapstructure:"address,omitempty"`
+	Username string           `mapstructure:"username,omitempty"`
+	Password string           `mapstructure:"password,omitempty"`
+	DB       int              `mapstructure:"db,omitempty"`
+	TLS      *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Streams  []string         `mapstructure:"streams,omitempty"`
+	Group    string           `mapstructure:"group,omitempty"`
+	Consumer string           `mapstructure:"consumer,omitempty"`
+	// ID from which a newly created group starts reading, `$` or `0`.
+	StartID string `mapstructure:"start-id,omitempty"`
+	// max number of entries read at once.
+	BatchSize int64         `mapstructure:"batch-size,omitempty"`
+	Block     time.Duration `mapstructure:"block,omitempty"`
+	// entries pending for longer than this duration in other consumers are claimed.
+	ClaimMinIdle    time.Duration `mapstructure:"claim-min-idle,omitempty"`
+	ConnectTimeWait time.Duration `mapstructure:"connect-time-wait,omitempty"`
+	Format          string        `mapstructure:"format,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty"`
+	Outputs         []string      `mapstructure:"outputs,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty"`
+}
+
+type message struct {
+	stream string
+	msg    redis.XMessage
+}
+
+// Start //
+func (r *RedisInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, r.Cfg)
+	if err != nil {
+		return err
+	}
+	if r.Cfg.Name == "" {
+		r.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return err
+		}
+	}
+	err = r.setDefaults()
+	if err != nil {
+		return err
+	}
+	r.client, err = r.createClient()
+	if err != nil {
+		return err
+	}
+	ctx, r.cfn = context.WithCancel(ctx)
+	r.logger.Printf("input starting with config: %+v", r.Cfg)
+
+	msgCh := make(chan *message, r.Cfg.BufferSize)
+	r.wg.Add(r.Cfg.NumWorkers + 1)
+	for i := 0; i < r.Cfg.NumWorkers; i++ {
+		go r.worker(ctx, i, msgCh)
+	}
+	go r.read(ctx, msgCh)
+	return nil
+}
+
+func (r *RedisInput) createClient() (*redis.Client, error) {
+	opts := &redis.Options{
+		Addr:     r.Cfg.Address,
+		Username: r.Cfg.Username,
+		Password: r.Cfg.Password,
+		DB:       r.Cfg.DB,
+		// the blocking reads must not time out before the server replies.
+		ReadTimeout: r.Cfg.Block + 5*time.Second,
+	}
+	if r.Cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			r.Cfg.TLS.CaFile,
+			r.Cfg.TLS.CertFile,
+			r.Cfg.TLS.KeyFile,
+			"",
+			r.Cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsCfg
+	}
+	return redis.NewClient(opts), nil
+}
+
+// read creates the consumer groups if needed, then reads the entries
+// left pending by a previous run of this consumer before reading new entries.
+func (r *RedisInput) read(ctx context.Context, msgCh chan<- *message) {
+	defer r.wg.Done()
+START:
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+	err := r.createGroups(ctx)
+	if err != nil {
+		r.logger.Printf("failed to create consumer group %q: %v", r.Cfg.Group, err)
+		if !r.wait(ctx) {
+			return
+		}
+		goto START
+	}
+	r.logger.Printf("reading streams %v as consumer %q of group %q", r.Cfg.Streams, r.Cfg.Consumer, r.Cfg.Group)
+	for _, s := range r.Cfg.Streams {
+		if !r.readPending(ctx, s, msgCh) {
+			return
+		}
+	}
+	var lastClaim time.Time
+	for {
+		if r.Cfg.ClaimMinIdle > 0 && time.Since(lastClaim) >= r.Cfg.ClaimMinIdle {
+			lastClaim = time.Now()
+			r.claim(ctx, msgCh)
+		}
+		res, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.Cfg.Group,
+			Consumer: r.Cfg.Consumer,
+			Streams:  streamsArgs(r.Cfg.Streams, ">"),
+			Count:    r.Cfg.BatchSize,
+			Block:    r.Cfg.Block,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, redis.Nil) { // block timeout
+				continue
+			}
+			r.logger.Printf("failed to read streams: %v", err)
+			if !r.wait(ctx) {
+				return
+			}
+			// the group may have been deleted.
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				goto START
+			}
+			continue
+		}
+		for _, s := range res {
+			for _, m := range s.Messages {
+				select {
+				case <-ctx.Done():
+					return
+				case msgCh <- &message{stream: s.Stream, msg: m}:
+				}
+			}
+		}
+	}
+}
+
+// readPending reads the entries delivered to this consumer but not acknowledged,
+// e.g: before a restart. It returns false if the context is done.
+func (r *RedisInput) readPending(ctx context.Context, stream string, msgCh chan<- *message) bool {
+	id := "0"
+	for {
+		res, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.Cfg.Group,
+			Consumer: r.Cfg.Consumer,
+			Streams:  []string{stream, id},
+			Count:    r.Cfg.BatchSize,
+			Block:    -1, // the history of pending entries is returned without blocking
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			if !errors.Is(err, redis.Nil) {
+				r.logger.Printf("failed to read pending entries of stream %q: %v", stream, err)
+			}
+			return true
+		}
+		n := 0
+		for _, s := range res {
+			for _, m := range s.Messages {
+				n++
+				id = m.ID
+				select {
+				case <-ctx.Done():
+					return false
+				case msgCh <- &message{stream: s.Stream, msg: m}:
+				}
+			}
+		}
+		if n == 0 {
+			return true
+		}
+		if r.Cfg.Debug {
+			r.logger.Printf("read %d pending entries of stream %q", n, stream)
+		}
+	}
+}
+
+func (r *RedisInput) createGroups(ctx context.Context) error {
+	for _, s := range r.Cfg.Streams {
+		err := r.client.XGroupCreateMkStream(ctx, s, r.Cfg.Group, r.Cfg.StartID).Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	return nil
+}
+
+// claim takes over the entries pending for more than claim-min-idle in other consumers.
+func (r *RedisInput) claim(ctx context.Context, msgCh chan<- *message) {
+	for _, s := range r.Cfg.Streams {
+		start := "0-0"
+		for {
+			msgs, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   s,
+				Group:    r.Cfg.Group,
+				Consumer: r.Cfg.Consumer,
+				MinIdle:  r.Cfg.ClaimMinIdle,
+				Start:    start,
+				Count:    r.Cfg.BatchSize,
+			}).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Printf("failed to claim pending entries of stream %q: %v", s, err)
+				}
+				break
+			}
+			if len(msgs) > 0 && r.Cfg.Debug {
+				r.logger.Printf("claimed %d entries of stream %q", len(msgs), s)
+			}
+			for _, m := range msgs {
+				select {
+				case <-ctx.Done():
+					return
+				case msgCh <- &message{stream: s, msg: m}:
+				}
+			}
+			if next == "0-0" || next == "" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+func (r *RedisInput) worker(ctx context.Context, idx int, msgCh <-chan *message) {
+	defer r.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
+	r.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-msgCh:
+			err := r.handle(ctx, msg)
+			if err != nil && r.Cfg.Debug {
+				r.logger.Printf("%s failed to handle entry %s of stream %q: %v", workerLogPrefix, msg.msg.ID, msg.stream, err)
+			}
+			// entries that cannot be decoded are acknowledged as well,
+			// they would fail again if delivered to another consumer.
+			err = r.client.XAck(ctx, msg.stream, r.Cfg.Group, msg.msg.ID).Err()
+			if err != nil && ctx.Err() == nil {
+				r.logger.Printf("%s failed to ack entry %s of stream %q: %v", workerLogPrefix, msg.msg.ID, msg.stream, err)
+			}
+		}
+	}
+}
+
+// handle decodes a stream entry and writes it to the outputs.
+func (r *RedisInput) handle(ctx context.Context, msg *message) error {
+	data, ok := msg.msg.Values[dataField].(string)
+	if !ok || len(data) == 0 {
+		return fmt.Errorf("missing %q field", dataField)
+	}
+	if r.Cfg.Debug {
+		r.logger.Printf("received entry %s of stream %q, len=%d, data=%s", msg.msg.ID, msg.stream, len(data), data)
+	}
+	switch r.Cfg.Format {
+	case "event":
+		evMsgs, err := decodeEvents([]byte(data))
+		if err != nil {
+			return err
+		}
+		for _, p := range r.evps {
+			evMsgs = p.Apply(evMsgs...)
+		}
+		for _, o := range r.outputs {
+			for _, ev := range evMsgs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	case "proto":
+		protoMsg := new(gnmi.SubscribeResponse)
+		err := proto.Unmarshal([]byte(data), protoMsg)
+		if err != nil {
+			return err
+		}
+		meta := msgMeta(msg.msg.Values)
+		for _, o := range r.outputs {
+			o.Write(ctx, protoMsg, meta)
+		}
+	}
+	return nil
+}
+
+// decodeEvents decodes a JSON event or a JSON array of events.
+func decodeEvents(b []byte) ([]*formatters.EventMsg, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		ev := new(formatters.EventMsg)
+		err := json.Unmarshal(b, ev)
+		if err != nil {
+			return nil, err
+		}
+		return []*formatters.EventMsg{ev}, nil
+	}
+	evMsgs := make([]*formatters.EventMsg, 0)
+	err := json.Unmarshal(b, &evMsgs)
+	if err != nil {
+		return nil, err
+	}
+	return evMsgs, nil
+}
+
+// msgMeta builds the proto message metadata from the stream entry fields other than `data`.
+func msgMeta(values map[string]any) outputs.Meta {
+	meta := outputs.Meta{}
+	for k, v := range values {
+		if k == dataField {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			meta[k] = s
+		}
+	}
+	return meta
+}
+
+func streamsArgs(streams []string, id string) []string {
+	args := make([]string, 0, 2*len(streams))
+	args = append(args, streams...)
+	for range streams {
+		args = append(args, id)
+	}
+	return args
+}
+
+func (r *RedisInput) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(r.Cfg.ConnectTimeWait):
+		return true
+	}
+}
+
+// Close //
+func (r *RedisInput) Close() error {
+	if r.cfn == nil {
+		return nil
+	}
+	r.cfn()
+	r.wg.Wait()
+	return r.client.Close()
+}
+
+// SetLogger //
+func (r *RedisInput) SetLogger(logger *log.Logger) {
+	if logger != nil && r.logger != nil {
+		r.logger.SetOutput(logger.Writer())
+		r.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (r *RedisInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(r.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			r.outputs = append(r.outputs, o)
+		}
+		return
+	}
+	for _, name := range r.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			r.outputs = append(r.outputs, o)
+		}
+	}
+}
+
+func (r *RedisInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(r.Cfg.Name)
+	sb.WriteString("-redis-sub")
+	r.Cfg.Name = sb.String()
+}
+
+func (r *RedisInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	r.evps, err = formatters.MakeEventProcessors(
+		logger,
+		r.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper functions
+
+func (r *RedisInput) setDefaults() error {
+	if r.Cfg.Format == "" {
+		r.Cfg.Format = defaultFormat
+	}
+	r.Cfg.Format = strings.ToLower(r.Cfg.Format)
+	if !(r.Cfg.Format == "event" || r.Cfg.Format == "proto") {
+		return fmt.Errorf("unsupported input format")
+	}
+	if r.Cfg.Name == "" {
+		r.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if r.Cfg.Address == "" {
+		r.Cfg.Address = defaultAddress
+	}
+	if len(r.Cfg.Streams) == 0 {
+		r.Cfg.Streams = []string{defaultStream}
+	}
+	if r.Cfg.Group == "" {
+		r.Cfg.Group = defaultGroup
+	}
+	if r.Cfg.Consumer == "" {
+		// a stable consumer name allows resuming its pending entries after a restart.
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = uuid.New().String()[:8]
+		}
+		r.Cfg.Consumer = hostname + "-" + r.Cfg.Name
+	}
+	if r.Cfg.StartID == "" {
+		r.Cfg.StartID = defaultStartID
+	}
+	if r.Cfg.BatchSize <= 0 {
+		r.Cfg.BatchSize = defaultBatchSize
+	}
+	if r.Cfg.Block <= 0 {
+		r.Cfg.Block = defaultBlock
+	}
+	if r.Cfg.ConnectTimeWait <= 0 {
+		r.Cfg.ConnectTimeWait = defaultConnectTimeWait
+	}
+	if r.Cfg.NumWorkers <= 0 {
+		r.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if r.Cfg.BufferSize <= 0 {
+		r.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tcp_input
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix     = "[tcp_input] "
+	defaultFormat     = "event"
+	defaultDelimiter  = "\n"
+	defaultMaxMsgSize = 4 * 1024 * 1024 // 4MB
+)
+
+func init() {
+	inputs.Register("tcp", func() inputs.Input {
+		return &TCPInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+			conns:  make(map[net.Conn]struct{}),
+		}
+	})
+}
+
+// TCPInput //
+type TCPInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	listener net.Listener
+	wg       *sync.WaitGroup
+	m        sync.Mutex
+	conns    map[net.Conn]struct{}
+	outputs  []outputs.Output
+	evps     []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name    string `mapstructure:"name,omitempty"`
+	Address string `mapstructure:"address,omitempty"`
+	Format  string `mapstructure:"format,omitempty"`
+	// the bytes separating the messages, ignored if length-prefix is true.
+	Delimiter string `mapstructure:"delimiter,omitempty"`
+	// each message is prefixed with its length encoded as a protobuf varint.
+	LengthPrefix    bool          `mapstructure:"length-prefix,omitempty"`
+	MaxMsgSize      int           `mapstructure:"max-msg-size,omitempty"`
+	KeepAlive       time.Duration `mapstructure:"keep-alive,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty"`
+	Outputs         []string      `mapstructure:"outputs,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty"`
+}
+
+// Start //
+func (t *TCPInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, t.Cfg)
+	if err != nil {
+		return err
+	}
+	if t.Cfg.Name == "" {
+		t.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return err
+		}
+	}
+	err = t.setDefaults()
+	if err != nil {
+		return err
+	}
+	t.logger.Printf("input starting with config: %+v", t.Cfg)
+
+	lc := net.ListenConfig{KeepAlive: t.Cfg.KeepAlive}
+	t.listener, err = lc.Listen(ctx, "tcp", t.Cfg.Address)
+	if err != nil {
+		return err
+	}
+	ctx, t.cfn = context.WithCancel(ctx)
+	t.logger.Printf("waiting for connections on %s", t.listener.Addr())
+	t.wg.Add(1)
+	go t.serve(ctx)
+	return nil
+}
+
+func (t *TCPInput) serve(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				t.logger.Printf("failed to accept connection: %v", err)
+			}
+			return
+		}
+		t.m.Lock()
+		if ctx.Err() != nil {
+			t.m.Unlock()
+			conn.Close()
+			return
+		}
+		t.conns[conn] = struct{}{}
+		t.wg.Add(1)
+		t.m.Unlock()
+		go t.handleConn(ctx, conn)
+	}
+}
+
+// handleConn reads the messages sent over a connection and writes them to the outputs,
+// in the order they were received.
+func (t *TCPInput) handleConn(ctx context.Context, conn net.Conn) {
+	defer t.wg.Done()
+	defer func() {
+		t.m.Lock()
+		delete(t.conns, conn)
+		t.m.Unlock()
+		conn.Close()
+	}()
+	remote := conn.RemoteAddr().String()
+	t.logger.Printf("connection from %s", remote)
+
+	sc := bufio.NewScanner(conn)
+	if t.Cfg.LengthPrefix {
+		// room for the frame and its length.
+		sc.Buffer(make([]byte, 0, 64*1024), t.Cfg.MaxMsgSize+binary.MaxVarintLen64)
+		sc.Split(inputs.SplitLengthPrefix(t.Cfg.MaxMsgSize))
+	} else {
+		sc.Buffer(make([]byte, 0, 64*1024), t.Cfg.MaxMsgSize+len(t.Cfg.Delimiter))
+		sc.Split(inputs.SplitDelimiter([]byte(t.Cfg.Delimiter)))
+	}
+	for sc.Scan() {
+		b := sc.Bytes()
+		if len(b) == 0 {
+			continue
+		}
+		if t.Cfg.Debug {
+			t.logger.Printf("received msg from %s, length=%d", remote, len(b))
+		}
+		err := t.handle(ctx, b)
+		if err != nil {
+			t.logger.Printf("failed to handle msg from %s: %v", remote, err)
+		}
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		t.logger.Printf("connection from %s: read error: %v", remote, err)
+	}
+	t.logger.Printf("connection from %s closed", remote)
+}
+
+func (t *TCPInput) handle(ctx context.Context, b []byte) error {
+	switch t.Cfg.Format {
+	case "event":
+		evMsgs, err := decodeEvents(b)
+		if err != nil {
+			return err
+		}
+		for _, p := range t.evps {
+			evMsgs = p.Apply(evMsgs...)
+		}
+		for _, o := range t.outputs {
+			for _, ev := range evMsgs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	case "proto":
+		protoMsg := new(gnmi.SubscribeResponse)
+		err := proto.Unmarshal(b, protoMsg)
+		if err != nil {
+			return err
+		}
+		meta := outputs.Meta{}
+		if tg := protoMsg.GetUpdate().GetPrefix().GetTarget(); tg != "" {
+			meta["source"] = tg
+		}
+		if len(t.evps) == 0 {
+			for _, o := range t.outputs {
+				o.Write(ctx, protoMsg, meta)
+			}
+			return nil
+		}
+		evMsgs, err := formatters.ResponseToEventMsgs("default", protoMsg, meta, t.evps...)
+		if err != nil {
+			return err
+		}
+		for _, o := range t.outputs {
+			for _, ev := range evMsgs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeEvents decodes a JSON event or a JSON array of events.
+func decodeEvents(b []byte) ([]*formatters.EventMsg, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		ev := new(formatters.EventMsg)
+		err := json.Unmarshal(b, ev)
+		if err != nil {
+			return nil, err
+		}
+		return []*formatters.EventMsg{ev}, nil
+	}
+	evMsgs := make([]*formatters.EventMsg, 0)
+	err := json.Unmarshal(b, &evMsgs)
+	if err != nil {
+		return nil, err
+	}
+	return evMsgs, nil
+}
+
+// Close //
+func (t *TCPInput) Close() error {
+	if t.cfn != nil {
+		t.cfn()
+	}
+	if t.listener != nil {
+		t.listener.Close()
+	}
+	t.m.Lock()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.m.Unlock()
+	t.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (t *TCPInput) SetLogger(logger *log.Logger) {
+	if logger != nil && t.logger != nil {
+		t.logger.SetOutput(logger.Writer())
+		t.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (t *TCPInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(t.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			t.outputs = append(t.outputs, o)
+		}
+		return
+	}
+	for _, name := range t.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			t.outputs = append(t.outputs, o)
+		}
+	}
+}
+
+func (t *TCPInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(t.Cfg.Name)
+	sb.WriteString("-tcp")
+	t.Cfg.Name = sb.String()
+}
+
+func (t *TCPInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	t.evps, err = formatters.MakeEventProcessors(
+		logger,
+		t.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper funcs
+
+func (t *TCPInput) setDefaults() error {
+	if t.Cfg.Address == "" {
+		return errors.New("missing address field")
+	}
+	if t.Cfg.Name == "" {
+		t.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if t.Cfg.Format == "" {
+		t.Cfg.Format = defaultFormat
+	}
+	if t.Cfg.Format != "event" && t.Cfg.Format != "proto" {
+		return fmt.Errorf("unsupported input format %q", t.Cfg.Format)
+	}
+	if t.Cfg.Delimiter == "" {
+		t.Cfg.Delimiter = defaultDelimiter
+	}
+	if t.Cfg.MaxMsgSize <= 0 {
+		t.Cfg.MaxMsgSize = defaultMaxMsgSize
+	}
+	return nil
+}
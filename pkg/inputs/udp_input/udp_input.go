@@ -0,0 +1,367 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package udp_input
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix     = "[udp_input] "
+	defaultFormat     = "event"
+	defaultMaxMsgSize = 65535
+	defaultNumWorkers = 1
+	defaultBufferSize = 100
+)
+
+func init() {
+	inputs.Register("udp", func() inputs.Input {
+		return &UDPInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// UDPInput //
+type UDPInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	conn    *net.UDPConn
+	msgCh   chan *datagram
+	wg      *sync.WaitGroup
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name    string `mapstructure:"name,omitempty"`
+	Address string `mapstructure:"address,omitempty"`
+	Format  string `mapstructure:"format,omitempty"`
+	// the bytes separating the messages of a datagram,
+	// if empty and length-prefix is false, each datagram is a single message.
+	Delimiter string `mapstructure:"delimiter,omitempty"`
+	// each message is prefixed with its length encoded as a protobuf varint.
+	LengthPrefix bool `mapstructure:"length-prefix,omitempty"`
+	// max size of a datagram.
+	MaxMsgSize int `mapstructure:"max-msg-size,omitempty"`
+	// size of the socket receive buffer.
+	ReadBufferSize  int      `mapstructure:"read-buffer-size,omitempty"`
+	Debug           bool     `mapstructure:"debug,omitempty"`
+	NumWorkers      int      `mapstructure:"num-workers,omitempty"`
+	BufferSize      int      `mapstructure:"buffer-size,omitempty"`
+	Outputs         []string `mapstructure:"outputs,omitempty"`
+	EventProcessors []string `mapstructure:"event-processors,omitempty"`
+}
+
+type datagram struct {
+	b    []byte
+	addr string
+}
+
+// Start //
+func (u *UDPInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, u.Cfg)
+	if err != nil {
+		return err
+	}
+	if u.Cfg.Name == "" {
+		u.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(u); err != nil {
+			return err
+		}
+	}
+	err = u.setDefaults()
+	if err != nil {
+		return err
+	}
+	u.logger.Printf("input starting with config: %+v", u.Cfg)
+
+	addr, err := net.ResolveUDPAddr("udp", u.Cfg.Address)
+	if err != nil {
+		return err
+	}
+	u.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	if u.Cfg.ReadBufferSize > 0 {
+		err = u.conn.SetReadBuffer(u.Cfg.ReadBufferSize)
+		if err != nil {
+			u.conn.Close()
+			return err
+		}
+	}
+	ctx, u.cfn = context.WithCancel(ctx)
+	u.msgCh = make(chan *datagram, u.Cfg.BufferSize)
+	u.wg.Add(u.Cfg.NumWorkers)
+	for i := 0; i < u.Cfg.NumWorkers; i++ {
+		go u.worker(ctx, i)
+	}
+	u.logger.Printf("waiting for datagrams on %s", u.conn.LocalAddr())
+	u.wg.Add(1)
+	go u.read(ctx)
+	return nil
+}
+
+func (u *UDPInput) read(ctx context.Context) {
+	defer u.wg.Done()
+	buf := make([]byte, u.Cfg.MaxMsgSize)
+	for {
+		n, addr, err := u.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				u.logger.Printf("failed to read datagram: %v", err)
+			}
+			return
+		}
+		if n == 0 {
+			continue
+		}
+		b := make([]byte, n)
+		copy(b, buf[:n])
+		select {
+		case <-ctx.Done():
+			return
+		case u.msgCh <- &datagram{b: b, addr: addr.String()}:
+		}
+	}
+}
+
+func (u *UDPInput) worker(ctx context.Context, idx int) {
+	defer u.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
+	u.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-u.msgCh:
+			if u.Cfg.Debug {
+				u.logger.Printf("%s received datagram from %s, length=%d", workerLogPrefix, d.addr, len(d.b))
+			}
+			msgs, err := u.split(d.b)
+			if err != nil {
+				u.logger.Printf("%s failed to split datagram from %s: %v", workerLogPrefix, d.addr, err)
+			}
+			for _, b := range msgs {
+				err = u.handle(ctx, b)
+				if err != nil {
+					u.logger.Printf("%s failed to handle msg from %s: %v", workerLogPrefix, d.addr, err)
+				}
+			}
+		}
+	}
+}
+
+// split returns the messages of a datagram.
+// On error, the messages read before the error are returned.
+func (u *UDPInput) split(b []byte) ([][]byte, error) {
+	var split bufio.SplitFunc
+	switch {
+	case u.Cfg.LengthPrefix:
+		split = inputs.SplitLengthPrefix(u.Cfg.MaxMsgSize)
+	case u.Cfg.Delimiter != "":
+		split = inputs.SplitDelimiter([]byte(u.Cfg.Delimiter))
+	default:
+		return [][]byte{b}, nil
+	}
+	msgs := make([][]byte, 0, 1)
+	for len(b) > 0 {
+		adv, msg, err := split(b, true)
+		if err != nil {
+			return msgs, err
+		}
+		if adv == 0 {
+			break
+		}
+		if len(msg) > 0 {
+			msgs = append(msgs, msg)
+		}
+		b = b[adv:]
+	}
+	return msgs, nil
+}
+
+func (u *UDPInput) handle(ctx context.Context, b []byte) error {
+	switch u.Cfg.Format {
+	case "event":
+		evMsgs, err := decodeEvents(b)
+		if err != nil {
+			return err
+		}
+		for _, p := range u.evps {
+			evMsgs = p.Apply(evMsgs...)
+		}
+		for _, o := range u.outputs {
+			for _, ev := range evMsgs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	case "proto":
+		protoMsg := new(gnmi.SubscribeResponse)
+		err := proto.Unmarshal(b, protoMsg)
+		if err != nil {
+			return err
+		}
+		meta := outputs.Meta{}
+		if tg := protoMsg.GetUpdate().GetPrefix().GetTarget(); tg != "" {
+			meta["source"] = tg
+		}
+		if len(u.evps) == 0 {
+			for _, o := range u.outputs {
+				o.Write(ctx, protoMsg, meta)
+			}
+			return nil
+		}
+		evMsgs, err := formatters.ResponseToEventMsgs("default", protoMsg, meta, u.evps...)
+		if err != nil {
+			return err
+		}
+		for _, o := range u.outputs {
+			for _, ev := range evMsgs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeEvents decodes a JSON event or a JSON array of events.
+func decodeEvents(b []byte) ([]*formatters.EventMsg, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		ev := new(formatters.EventMsg)
+		err := json.Unmarshal(b, ev)
+		if err != nil {
+			return nil, err
+		}
+		return []*formatters.EventMsg{ev}, nil
+	}
+	evMsgs := make([]*formatters.EventMsg, 0)
+	err := json.Unmarshal(b, &evMsgs)
+	if err != nil {
+		return nil, err
+	}
+	return evMsgs, nil
+}
+
+// Close //
+func (u *UDPInput) Close() error {
+	if u.cfn != nil {
+		u.cfn()
+	}
+	if u.conn != nil {
+		u.conn.Close()
+	}
+	u.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (u *UDPInput) SetLogger(logger *log.Logger) {
+	if logger != nil && u.logger != nil {
+		u.logger.SetOutput(logger.Writer())
+		u.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (u *UDPInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(u.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			u.outputs = append(u.outputs, o)
+		}
+		return
+	}
+	for _, name := range u.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			u.outputs = append(u.outputs, o)
+		}
+	}
+}
+
+func (u *UDPInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(u.Cfg.Name)
+	sb.WriteString("-udp")
+	u.Cfg.Name = sb.String()
+}
+
+func (u *UDPInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	u.evps, err = formatters.MakeEventProcessors(
+		logger,
+		u.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper funcs
+
+func (u *UDPInput) setDefaults() error {
+	if u.Cfg.Address == "" {
+		return errors.New("missing address field")
+	}
+	if u.Cfg.Name == "" {
+		u.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if u.Cfg.Format == "" {
+		u.Cfg.Format = defaultFormat
+	}
+	if u.Cfg.Format != "event" && u.Cfg.Format != "proto" {
+		return fmt.Errorf("unsupported input format %q", u.Cfg.Format)
+	}
+	if u.Cfg.MaxMsgSize <= 0 {
+		u.Cfg.MaxMsgSize = defaultMaxMsgSize
+	}
+	if u.Cfg.NumWorkers <= 0 {
+		u.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if u.Cfg.BufferSize <= 0 {
+		u.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
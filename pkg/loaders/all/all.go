@@ -13,4 +13,5 @@ import (
 	_ "github.com/openconfig/gnmic/pkg/loaders/docker_loader"
 	_ "github.com/openconfig/gnmic/pkg/loaders/file_loader"
 	_ "github.com/openconfig/gnmic/pkg/loaders/http_loader"
+	_ "github.com/openconfig/gnmic/pkg/loaders/k8s_loader"
 )
@@ -0,0 +1,844 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s_loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openconfig/gnmic/pkg/actions"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	gfile "github.com/openconfig/gnmic/pkg/file"
+	"github.com/openconfig/gnmic/pkg/loaders"
+)
+
+const (
+	loggingPrefix           = "[k8s_loader] "
+	loaderType              = "k8s"
+	defaultInterval         = 5 * time.Minute
+	defaultTimeout          = 10 * time.Second
+	defaultAnnotationPrefix = "gnmic.openconfig.net/"
+
+	resourcePods           = "pods"
+	resourceServices       = "services"
+	resourceEndpointSlices = "endpointslices"
+
+	// annotation names, prefixed with cfg.AnnotationPrefix
+	annotationName          = "name"
+	annotationPort          = "port"
+	annotationSubscriptions = "subscriptions"
+	annotationOutputs       = "outputs"
+	annotationTags          = "tags"
+	annotationEventTags     = "event-tags"
+	annotationInsecure      = "insecure"
+	annotationSkipVerify    = "skip-verify"
+	annotationEncoding      = "encoding"
+)
+
+func init() {
+	loaders.Register(loaderType, func() loaders.TargetLoader {
+		return &k8sLoader{
+			cfg:         new(cfg),
+			m:           new(sync.Mutex),
+			lastTargets: make(map[string]*types.TargetConfig),
+			logger:      log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type k8sLoader struct {
+	cfg    *cfg
+	client kubernetes.Interface
+
+	m              *sync.Mutex
+	lastTargets    map[string]*types.TargetConfig
+	targetConfigFn func(*types.TargetConfig) error
+	logger         *log.Logger
+	//
+	vars          map[string]interface{}
+	actionsConfig map[string]map[string]interface{}
+	addActions    []actions.Action
+	delActions    []actions.Action
+	numActions    int
+}
+
+type cfg struct {
+	// path to a kubeconfig file, if empty the in-cluster config is used
+	Kubeconfig string `json:"kubeconfig,omitempty" mapstructure:"kubeconfig,omitempty"`
+	// namespace to watch, all namespaces if empty
+	Namespace string `json:"namespace,omitempty" mapstructure:"namespace,omitempty"`
+	// kind of resource to discover targets from: pods, services or endpointslices
+	Resource string `json:"resource,omitempty" mapstructure:"resource,omitempty"`
+	// kubernetes label selector
+	LabelSelector string `json:"label-selector,omitempty" mapstructure:"label-selector,omitempty"`
+	// kubernetes field selector
+	FieldSelector string `json:"field-selector,omitempty" mapstructure:"field-selector,omitempty"`
+	// annotations the resource must have to be selected,
+	// an empty value matches any value.
+	AnnotationSelector map[string]string `json:"annotation-selector,omitempty" mapstructure:"annotation-selector,omitempty"`
+	// prefix of the annotations used to build the target config
+	AnnotationPrefix string `json:"annotation-prefix,omitempty" mapstructure:"annotation-prefix,omitempty"`
+	// gNMI port, overridden by the port annotation
+	Port int `json:"port,omitempty" mapstructure:"port,omitempty"`
+	// name of the container/service/endpoint port to use as gNMI port,
+	// overridden by the port annotation
+	PortName string `json:"port-name,omitempty" mapstructure:"port-name,omitempty"`
+	// target config applied to all discovered targets
+	TargetConfig map[string]interface{} `json:"target-config,omitempty" mapstructure:"target-config,omitempty"`
+	// informers resync interval
+	Interval time.Duration `json:"interval,omitempty" mapstructure:"interval,omitempty"`
+	// timeout of kubernetes API list requests
+	Timeout time.Duration `json:"timeout,omitempty" mapstructure:"timeout,omitempty"`
+	// enable debug mode for more logging messages
+	Debug bool `json:"debug,omitempty" mapstructure:"debug,omitempty"`
+	// if true, registers k8sLoader prometheus metrics with the provided
+	// prometheus registry
+	EnableMetrics bool `json:"enable-metrics,omitempty" mapstructure:"enable-metrics,omitempty"`
+	// variables definitions to be passed to the actions
+	Vars map[string]interface{}
+	// variable file, values in this file will be overwritten by
+	// the ones defined in Vars
+	VarsFile string `mapstructure:"vars-file,omitempty"`
+	// list of Actions to run on new target discovery
+	OnAdd []string `json:"on-add,omitempty" mapstructure:"on-add,omitempty"`
+	// list of Actions to run on target removal
+	OnDelete []string `json:"on-delete,omitempty" mapstructure:"on-delete,omitempty"`
+}
+
+func (k *k8sLoader) Init(ctx context.Context, cfg map[string]interface{}, logger *log.Logger, opts ...loaders.Option) error {
+	err := loaders.DecodeConfig(cfg, k.cfg)
+	if err != nil {
+		return err
+	}
+	err = k.setDefaults()
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if logger != nil {
+		k.logger.SetOutput(logger.Writer())
+		k.logger.SetFlags(logger.Flags())
+	}
+	k.client, err = k.createClient()
+	if err != nil {
+		return err
+	}
+	err = k.readVars(ctx)
+	if err != nil {
+		return err
+	}
+	for _, actName := range k.cfg.OnAdd {
+		if cfg, ok := k.actionsConfig[actName]; ok {
+			a, err := k.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			k.addActions = append(k.addActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	for _, actName := range k.cfg.OnDelete {
+		if cfg, ok := k.actionsConfig[actName]; ok {
+			a, err := k.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			k.delActions = append(k.delActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	k.numActions = len(k.addActions) + len(k.delActions)
+	k.logger.Printf("initialized loader type %q: %s", loaderType, k)
+	return nil
+}
+
+func (k *k8sLoader) setDefaults() error {
+	k.cfg.Resource = strings.ToLower(k.cfg.Resource)
+	switch k.cfg.Resource {
+	case "":
+		k.cfg.Resource = resourcePods
+	case resourcePods, resourceServices, resourceEndpointSlices:
+	default:
+		return fmt.Errorf("unknown resource %q, must be one of %q, %q or %q",
+			k.cfg.Resource, resourcePods, resourceServices, resourceEndpointSlices)
+	}
+	if k.cfg.AnnotationPrefix == "" {
+		k.cfg.AnnotationPrefix = defaultAnnotationPrefix
+	}
+	if k.cfg.Interval <= 0 {
+		k.cfg.Interval = defaultInterval
+	}
+	if k.cfg.Timeout <= 0 {
+		k.cfg.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+func (k *k8sLoader) createClient() (kubernetes.Interface, error) {
+	var restCfg *rest.Config
+	var err error
+	if k.cfg.Kubeconfig == "" {
+		restCfg, err = rest.InClusterConfig()
+	} else {
+		restCfg, err = clientcmd.BuildConfigFromFlags("", k.cfg.Kubeconfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restCfg)
+}
+
+func (k *k8sLoader) String() string {
+	b, err := json.Marshal(k.cfg)
+	if err != nil {
+		return fmt.Sprintf("%+v", k.cfg)
+	}
+	return string(b)
+}
+
+// Start runs informers on the configured resource kind.
+// Each change notification triggers a rebuild of the targets list from
+// the informers cache, the result is diffed against the last known targets.
+func (k *k8sLoader) Start(ctx context.Context) chan *loaders.TargetOperation {
+	opChan := make(chan *loaders.TargetOperation)
+	notifyCh := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case notifyCh <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(k.client, k.cfg.Interval,
+		informers.WithNamespace(k.cfg.Namespace),
+		informers.WithTweakListOptions(k.tweakListOptions),
+	)
+	factories := []informers.SharedInformerFactory{factory}
+	var list func() (map[string]*types.TargetConfig, error)
+	switch k.cfg.Resource {
+	case resourcePods:
+		inf := factory.Core().V1().Pods()
+		inf.Informer().AddEventHandler(handler)
+		list = func() (map[string]*types.TargetConfig, error) {
+			pods, err := inf.Lister().List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			return k.podsTargets(pods), nil
+		}
+	case resourceServices:
+		inf := factory.Core().V1().Services()
+		inf.Informer().AddEventHandler(handler)
+		list = func() (map[string]*types.TargetConfig, error) {
+			svcs, err := inf.Lister().List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			return k.servicesTargets(svcs), nil
+		}
+	case resourceEndpointSlices:
+		inf := factory.Discovery().V1().EndpointSlices()
+		inf.Informer().AddEventHandler(handler)
+		// endpointSlices do not carry the service annotations,
+		// they are read from the parent services.
+		svcFactory := informers.NewSharedInformerFactoryWithOptions(k.client, k.cfg.Interval,
+			informers.WithNamespace(k.cfg.Namespace),
+		)
+		svcInf := svcFactory.Core().V1().Services()
+		svcInf.Informer().AddEventHandler(handler)
+		svcFactory.Start(ctx.Done())
+		factories = append(factories, svcFactory)
+		list = func() (map[string]*types.TargetConfig, error) {
+			slices, err := inf.Lister().List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			svcs, err := svcInf.Lister().List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			return k.endpointSlicesTargets(slices, svcs), nil
+		}
+	}
+	factory.Start(ctx.Done())
+	go func() {
+		defer close(opChan)
+		// the targets are only listed once all the informer caches are synced.
+		synced := true
+		for _, f := range factories {
+			for t, ok := range f.WaitForCacheSync(ctx.Done()) {
+				if !ok {
+					k.logger.Printf("failed to sync informer cache for %v", t)
+					synced = false
+				}
+			}
+		}
+		if !synced {
+			return
+		}
+		notify()
+		for {
+			select {
+			case <-ctx.Done():
+				k.logger.Printf("%q context done: %v", loaderType, ctx.Err())
+				return
+			case <-notifyCh:
+				k8sLoaderListRequestsTotal.WithLabelValues(loaderType).Add(1)
+				readTargets, err := list()
+				if err != nil {
+					k8sLoaderFailedListRequests.WithLabelValues(loaderType, fmt.Sprintf("%v", err)).Add(1)
+					k.logger.Printf("failed to list %s from cache: %v", k.cfg.Resource, err)
+					continue
+				}
+				if k.cfg.Debug {
+					k.logger.Printf("k8s loader discovered %d target(s)", len(readTargets))
+				}
+				k.updateTargets(ctx, readTargets, opChan)
+			}
+		}
+	}()
+	return opChan
+}
+
+func (k *k8sLoader) RunOnce(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	k.logger.Printf("querying %q targets", loaderType)
+	readTargets, err := k.getTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if k.cfg.Debug {
+		k.logger.Printf("k8s loader discovered %d target(s)", len(readTargets))
+	}
+	return readTargets, nil
+}
+
+// getTargets lists the configured resource kind using the kubernetes API
+// and builds the target configs.
+func (k *k8sLoader) getTargets(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		k8sLoaderListRequestDuration.WithLabelValues(loaderType).
+			Set(float64(time.Since(start).Nanoseconds()))
+	}()
+	k8sLoaderListRequestsTotal.WithLabelValues(loaderType).Add(1)
+
+	opts := metav1.ListOptions{}
+	k.tweakListOptions(&opts)
+	var tcs map[string]*types.TargetConfig
+	var err error
+	switch k.cfg.Resource {
+	case resourcePods:
+		var pl *corev1.PodList
+		pl, err = k.client.CoreV1().Pods(k.cfg.Namespace).List(ctx, opts)
+		if err != nil {
+			break
+		}
+		pods := make([]*corev1.Pod, 0, len(pl.Items))
+		for i := range pl.Items {
+			pods = append(pods, &pl.Items[i])
+		}
+		tcs = k.podsTargets(pods)
+	case resourceServices:
+		var sl *corev1.ServiceList
+		sl, err = k.client.CoreV1().Services(k.cfg.Namespace).List(ctx, opts)
+		if err != nil {
+			break
+		}
+		tcs = k.servicesTargets(serviceListItems(sl))
+	case resourceEndpointSlices:
+		var el *discoveryv1.EndpointSliceList
+		el, err = k.client.DiscoveryV1().EndpointSlices(k.cfg.Namespace).List(ctx, opts)
+		if err != nil {
+			break
+		}
+		var sl *corev1.ServiceList
+		sl, err = k.client.CoreV1().Services(k.cfg.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			break
+		}
+		slices := make([]*discoveryv1.EndpointSlice, 0, len(el.Items))
+		for i := range el.Items {
+			slices = append(slices, &el.Items[i])
+		}
+		tcs = k.endpointSlicesTargets(slices, serviceListItems(sl))
+	}
+	if err != nil {
+		k8sLoaderFailedListRequests.WithLabelValues(loaderType, fmt.Sprintf("%v", err)).Add(1)
+		return nil, fmt.Errorf("failed to list %s: %v", k.cfg.Resource, err)
+	}
+	return tcs, nil
+}
+
+func (k *k8sLoader) tweakListOptions(opts *metav1.ListOptions) {
+	opts.LabelSelector = k.cfg.LabelSelector
+	opts.FieldSelector = k.cfg.FieldSelector
+}
+
+func (k *k8sLoader) podsTargets(pods []*corev1.Pod) map[string]*types.TargetConfig {
+	tcs := make(map[string]*types.TargetConfig)
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if !k.annotationsMatch(pod.Annotations) {
+			continue
+		}
+		port := k.cfg.Port
+		if k.cfg.PortName != "" {
+		CONTAINERS:
+			for _, c := range pod.Spec.Containers {
+				for _, p := range c.Ports {
+					if p.Name == k.cfg.PortName {
+						port = int(p.ContainerPort)
+						break CONTAINERS
+					}
+				}
+			}
+		}
+		tc, err := k.buildTargetConfig(pod.Name, pod.Namespace, pod.Status.PodIP, port, pod.Annotations)
+		if err != nil {
+			k.logger.Printf("pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		tcs[tc.Name] = tc
+	}
+	return tcs
+}
+
+func (k *k8sLoader) servicesTargets(svcs []*corev1.Service) map[string]*types.TargetConfig {
+	tcs := make(map[string]*types.TargetConfig)
+	for _, svc := range svcs {
+		if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			continue
+		}
+		if !k.annotationsMatch(svc.Annotations) {
+			continue
+		}
+		port := k.cfg.Port
+		if k.cfg.PortName != "" {
+			for _, p := range svc.Spec.Ports {
+				if p.Name == k.cfg.PortName {
+					port = int(p.Port)
+					break
+				}
+			}
+		}
+		tc, err := k.buildTargetConfig(svc.Name, svc.Namespace, svc.Spec.ClusterIP, port, svc.Annotations)
+		if err != nil {
+			k.logger.Printf("service %s/%s: %v", svc.Namespace, svc.Name, err)
+			continue
+		}
+		tcs[tc.Name] = tc
+	}
+	return tcs
+}
+
+// endpointSlicesTargets builds a target per ready endpoint,
+// the annotations are read from the service owning the endpointSlice.
+func (k *k8sLoader) endpointSlicesTargets(slices []*discoveryv1.EndpointSlice, svcs []*corev1.Service) map[string]*types.TargetConfig {
+	svcAnnotations := make(map[string]map[string]string, len(svcs))
+	for _, svc := range svcs {
+		svcAnnotations[svc.Namespace+"/"+svc.Name] = svc.Annotations
+	}
+	tcs := make(map[string]*types.TargetConfig)
+	for _, es := range slices {
+		svcName := es.Labels[discoveryv1.LabelServiceName]
+		annotations := svcAnnotations[es.Namespace+"/"+svcName]
+		if !k.annotationsMatch(annotations) {
+			continue
+		}
+		port := k.cfg.Port
+		if k.cfg.PortName != "" {
+			for _, p := range es.Ports {
+				if p.Name != nil && *p.Name == k.cfg.PortName && p.Port != nil {
+					port = int(*p.Port)
+					break
+				}
+			}
+		}
+		for i, ep := range es.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			if len(ep.Addresses) == 0 {
+				continue
+			}
+			name := fmt.Sprintf("%s-%d", es.Name, i)
+			if ep.TargetRef != nil && ep.TargetRef.Name != "" {
+				name = ep.TargetRef.Name
+			}
+			// the name annotation is set on the service, it cannot be used
+			// as is for all its endpoints.
+			tc, err := k.buildTargetConfig(name, es.Namespace, ep.Addresses[0], port, withoutKey(annotations, k.cfg.AnnotationPrefix+annotationName))
+			if err != nil {
+				k.logger.Printf("endpointSlice %s/%s: %v", es.Namespace, es.Name, err)
+				continue
+			}
+			tcs[tc.Name] = tc
+		}
+	}
+	return tcs
+}
+
+func (k *k8sLoader) annotationsMatch(annotations map[string]string) bool {
+	for ak, av := range k.cfg.AnnotationSelector {
+		v, ok := annotations[ak]
+		if !ok {
+			return false
+		}
+		if av != "" && v != av {
+			return false
+		}
+	}
+	return true
+}
+
+// buildTargetConfig builds a target config from the loader target-config,
+// the discovered address and the resource annotations.
+// The target name defaults to <name>.<namespace>.
+func (k *k8sLoader) buildTargetConfig(name, namespace, ip string, port int, annotations map[string]string) (*types.TargetConfig, error) {
+	tc := new(types.TargetConfig)
+	if k.cfg.TargetConfig != nil {
+		err := mapstructure.Decode(k.cfg.TargetConfig, tc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode target-config: %v", err)
+		}
+	}
+	tc.Name = name + "." + namespace
+	get := func(key string) (string, bool) {
+		v, ok := annotations[k.cfg.AnnotationPrefix+key]
+		return strings.TrimSpace(v), ok && strings.TrimSpace(v) != ""
+	}
+	if v, ok := get(annotationName); ok {
+		tc.Name = v
+	}
+	if v, ok := get(annotationPort); ok {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port annotation %q: %v", v, err)
+		}
+		port = p
+	}
+	tc.Address = ip
+	if port > 0 {
+		tc.Address = net.JoinHostPort(ip, strconv.Itoa(port))
+	}
+	if v, ok := get(annotationSubscriptions); ok {
+		tc.Subscriptions = splitList(v)
+	}
+	if v, ok := get(annotationOutputs); ok {
+		tc.Outputs = splitList(v)
+	}
+	if v, ok := get(annotationTags); ok {
+		tc.Tags = splitList(v)
+	}
+	if v, ok := get(annotationEventTags); ok {
+		tc.EventTags = make(map[string]string)
+		for _, kv := range splitList(v) {
+			tk, tv, _ := strings.Cut(kv, "=")
+			tc.EventTags[strings.TrimSpace(tk)] = strings.TrimSpace(tv)
+		}
+	}
+	if v, ok := get(annotationInsecure); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid insecure annotation %q: %v", v, err)
+		}
+		tc.Insecure = &b
+	}
+	if v, ok := get(annotationSkipVerify); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid skip-verify annotation %q: %v", v, err)
+		}
+		tc.SkipVerify = &b
+	}
+	if v, ok := get(annotationEncoding); ok {
+		tc.Encoding = &v
+	}
+	if k.cfg.Debug {
+		k.logger.Printf("discovered target config %s", tc)
+	}
+	return tc, nil
+}
+
+func (k *k8sLoader) diff(m map[string]*types.TargetConfig) *loaders.TargetOperation {
+	k.m.Lock()
+	defer k.m.Unlock()
+	result := loaders.Diff(k.lastTargets, m)
+	for _, t := range result.Add {
+		if _, ok := k.lastTargets[t.Name]; !ok {
+			k.lastTargets[t.Name] = t
+		}
+	}
+	for _, n := range result.Del {
+		delete(k.lastTargets, n)
+	}
+	if k.cfg.Debug {
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			k.logger.Printf("discovery diff result: %v", result)
+		} else {
+			k.logger.Printf("discovery diff result:\n%s", string(b))
+		}
+	}
+	return result
+}
+
+func (k *k8sLoader) updateTargets(ctx context.Context, tcs map[string]*types.TargetConfig, opChan chan *loaders.TargetOperation) {
+	var err error
+	for _, tc := range tcs {
+		err = k.targetConfigFn(tc)
+		if err != nil {
+			k.logger.Printf("failed running target config fn on target %q", tc.Name)
+		}
+	}
+	targetOp, err := k.runActions(ctx, tcs, k.diff(tcs))
+	if err != nil {
+		k.logger.Printf("failed to run actions: %v", err)
+		return
+	}
+	numAdds := len(targetOp.Add)
+	numDels := len(targetOp.Del)
+	defer func() {
+		k8sLoaderLoadedTargets.WithLabelValues(loaderType).Set(float64(numAdds))
+		k8sLoaderDeletedTargets.WithLabelValues(loaderType).Set(float64(numDels))
+	}()
+	if numAdds+numDels == 0 {
+		return
+	}
+	k.m.Lock()
+	// do deletes first since change is delete+add
+	for _, del := range targetOp.Del {
+		delete(k.lastTargets, del)
+	}
+	for _, add := range targetOp.Add {
+		k.lastTargets[add.Name] = add
+	}
+	k.m.Unlock()
+	select {
+	case <-ctx.Done():
+	case opChan <- targetOp:
+	}
+}
+
+func (k *k8sLoader) readVars(ctx context.Context) error {
+	if k.cfg.VarsFile == "" {
+		k.vars = k.cfg.Vars
+		return nil
+	}
+	b, err := gfile.ReadFile(ctx, k.cfg.VarsFile)
+	if err != nil {
+		return err
+	}
+	v := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	k.vars = utils.MergeMaps(v, k.cfg.Vars)
+	return nil
+}
+
+func (k *k8sLoader) initializeAction(cfg map[string]interface{}) (actions.Action, error) {
+	if len(cfg) == 0 {
+		return nil, errors.New("missing action definition")
+	}
+	if actType, ok := cfg["type"]; ok {
+		switch actType := actType.(type) {
+		case string:
+			if in, ok := actions.Actions[actType]; ok {
+				act := in()
+				err := act.Init(cfg, actions.WithLogger(k.logger), actions.WithTargets(nil))
+				if err != nil {
+					return nil, err
+				}
+				return act, nil
+			}
+			return nil, fmt.Errorf("unknown action type %q", actType)
+		default:
+			return nil, fmt.Errorf("unexpected action field type %T", actType)
+		}
+	}
+	return nil, errors.New("missing type field under action")
+}
+
+func (k *k8sLoader) runActions(ctx context.Context, tcs map[string]*types.TargetConfig, targetOp *loaders.TargetOperation) (*loaders.TargetOperation, error) {
+	if k.numActions == 0 {
+		return targetOp, nil
+	}
+	opChan := make(chan *loaders.TargetOperation)
+	// some actions are defined,
+	doneCh := make(chan struct{})
+	result := &loaders.TargetOperation{
+		Add: make(map[string]*types.TargetConfig, len(targetOp.Add)),
+		Del: make([]string, 0, len(targetOp.Del)),
+	}
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Interval)
+	defer cancel()
+	// start gathering goroutine
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				close(doneCh)
+				return
+			case op, ok := <-opChan:
+				if !ok {
+					close(doneCh)
+					return
+				}
+				for n, t := range op.Add {
+					result.Add[n] = t
+				}
+				result.Del = append(result.Del, op.Del...)
+			}
+		}
+	}()
+	// create waitGroup and add the number of target operations to it
+	wg := new(sync.WaitGroup)
+	wg.Add(len(targetOp.Add) + len(targetOp.Del))
+	// run OnAdd actions
+	for n, tAdd := range targetOp.Add {
+		go func(n string, tc *types.TargetConfig) {
+			defer wg.Done()
+			err := k.runOnAddActions(ctx, tc.Name, tcs)
+			if err != nil {
+				k.logger.Printf("failed running OnAdd actions: %v", err)
+				return
+			}
+			opChan <- &loaders.TargetOperation{Add: map[string]*types.TargetConfig{n: tc}}
+		}(n, tAdd)
+	}
+	// run OnDelete actions
+	for _, tDel := range targetOp.Del {
+		go func(name string) {
+			defer wg.Done()
+			err := k.runOnDeleteActions(ctx, name)
+			if err != nil {
+				k.logger.Printf("failed running OnDelete actions: %v", err)
+				return
+			}
+			opChan <- &loaders.TargetOperation{Del: []string{name}}
+		}(tDel)
+	}
+	wg.Wait()
+	close(opChan)
+	<-doneCh //wait for gathering goroutine to finish
+	return result, nil
+}
+
+func (k *k8sLoader) runOnAddActions(ctx context.Context, tName string, tcs map[string]*types.TargetConfig) error {
+	aCtx := &actions.Context{
+		Input:   tName,
+		Env:     make(map[string]interface{}),
+		Vars:    k.vars,
+		Targets: tcs,
+	}
+	for _, act := range k.addActions {
+		k.logger.Printf("running action %q for target %q", act.NName(), tName)
+		res, err := act.Run(ctx, aCtx)
+		if err != nil {
+			// delete target from known targets map
+			k.m.Lock()
+			delete(k.lastTargets, tName)
+			k.m.Unlock()
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+
+		aCtx.Env[act.NName()] = utils.Convert(res)
+		if k.cfg.Debug {
+			k.logger.Printf("action %q, target %q result: %+v", act.NName(), tName, res)
+			b, _ := json.MarshalIndent(aCtx, "", "  ")
+			k.logger.Printf("action %q context:\n%s", act.NName(), string(b))
+		}
+	}
+	return nil
+}
+
+func (k *k8sLoader) runOnDeleteActions(ctx context.Context, tName string) error {
+	env := make(map[string]interface{})
+	for _, act := range k.delActions {
+		res, err := act.Run(ctx, &actions.Context{Input: tName, Env: env, Vars: k.vars})
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+		env[act.NName()] = res
+	}
+	return nil
+}
+
+/// helpers
+
+func serviceListItems(sl *corev1.ServiceList) []*corev1.Service {
+	svcs := make([]*corev1.Service, 0, len(sl.Items))
+	for i := range sl.Items {
+		svcs = append(svcs, &sl.Items[i])
+	}
+	return svcs
+}
+
+// splitList splits a comma separated annotation value.
+func splitList(s string) []string {
+	items := strings.Split(s, ",")
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func withoutKey(m map[string]string, key string) map[string]string {
+	if _, ok := m[key]; !ok {
+		return m
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		if k != key {
+			res[k] = v
+		}
+	}
+	return res
+}
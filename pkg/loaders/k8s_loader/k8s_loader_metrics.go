@@ -0,0 +1,78 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s_loader
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var k8sLoaderLoadedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_loaded_targets",
+	Help:      "Number of new targets successfully loaded",
+}, []string{"loader_type"})
+
+var k8sLoaderDeletedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_deleted_targets",
+	Help:      "Number of targets successfully deleted",
+}, []string{"loader_type"})
+
+var k8sLoaderFailedListRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_failed_k8s_list",
+	Help:      "Number of times a kubernetes list failed",
+}, []string{"loader_type", "error"})
+
+var k8sLoaderListRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_k8s_list_total",
+	Help:      "Number of times the loader sent a kubernetes list request",
+}, []string{"loader_type"})
+
+var k8sLoaderListRequestDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "k8s_list_duration_ns",
+	Help:      "Duration of kubernetes list request in ns",
+}, []string{"loader_type"})
+
+func initMetrics() {
+	k8sLoaderLoadedTargets.WithLabelValues(loaderType).Set(0)
+	k8sLoaderDeletedTargets.WithLabelValues(loaderType).Set(0)
+	k8sLoaderFailedListRequests.WithLabelValues(loaderType, "").Add(0)
+	k8sLoaderListRequestsTotal.WithLabelValues(loaderType).Add(0)
+	k8sLoaderListRequestDuration.WithLabelValues(loaderType).Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	if reg == nil {
+		return nil
+	}
+	initMetrics()
+	var err error
+	if err = reg.Register(k8sLoaderLoadedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderDeletedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderFailedListRequests); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderListRequestsTotal); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderListRequestDuration); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s_loader
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+func (k *k8sLoader) RegisterMetrics(reg *prometheus.Registry) {
+	if !k.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		k.logger.Printf("ERR: metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		k.logger.Printf("failed to register metrics: %v", err)
+	}
+}
+
+func (k *k8sLoader) WithActions(acts map[string]map[string]interface{}) {
+	k.actionsConfig = acts
+}
+
+func (k *k8sLoader) WithTargetsDefaults(fn func(tc *types.TargetConfig) error) {
+	k.targetConfigFn = fn
+}
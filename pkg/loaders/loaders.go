@@ -49,6 +49,7 @@ var LoadersTypes = []string{
 	"consul",
 	"docker",
 	"http",
+	"k8s",
 }
 
 func Register(name string, initFn Initializer) {
@@ -10,16 +10,26 @@ package all
 
 import (
 	_ "github.com/openconfig/gnmic/pkg/outputs/asciigraph_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/clickhouse_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/elasticsearch_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/file"
 	_ "github.com/openconfig/gnmic/pkg/outputs/gnmi_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/graphite_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/http_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/influxdb_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/kafka_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/loki_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/mqtt_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/nats_outputs/jetstream"
 	_ "github.com/openconfig/gnmic/pkg/outputs/nats_outputs/nats"
 	_ "github.com/openconfig/gnmic/pkg/outputs/nats_outputs/stan"
+	_ "github.com/openconfig/gnmic/pkg/outputs/otlp_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/postgres_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/prometheus_output/prometheus_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/prometheus_output/prometheus_write_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/redis_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/snmp_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/syslog_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/tcp_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/udp_output"
 )
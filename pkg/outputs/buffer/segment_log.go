@@ -0,0 +1,500 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package buffer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentExt       = ".seg"
+	cursorFileName   = "cursor"
+	recordHeaderSize = 8 // 4 bytes length + 4 bytes crc32
+
+	DefaultMaxSize     = 1 << 30 // 1GiB
+	DefaultSegmentSize = 16 << 20
+)
+
+var (
+	ErrClosed         = errors.New("segment log closed")
+	ErrRecordTooLarge = errors.New("record larger than the segment log max size")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// SegmentLog is a bounded, disk-backed FIFO of records.
+// Records are appended to segment files of at most SegmentSize bytes,
+// and read back in the order they were written.
+// Fully read segments are removed.
+// When the total size exceeds MaxSize, the oldest segments are removed
+// and their unread records are counted as dropped.
+// The read position is persisted to a cursor file on Sync and Close
+// so that unread records are replayed after a restart.
+type SegmentLog struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+	syncWrites  bool
+
+	m        sync.Mutex
+	closed   bool
+	segments []*segment // ordered by id, the last one is the write segment
+	w        *os.File
+	r        *os.File
+	rOffset  int64
+	// size of the record returned by the last Peek, 0 if none.
+	peeked   int64
+	size     int64
+	pending  int64
+	dropped  uint64
+	notifyCh chan struct{}
+}
+
+type segment struct {
+	id      uint64
+	size    int64
+	records int64
+	// number of records read from this segment
+	read int64
+}
+
+// Open opens or creates a segment log in directory dir.
+func Open(dir string, maxSize, segmentSize int64, syncWrites bool) (*SegmentLog, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if segmentSize > maxSize {
+		segmentSize = maxSize
+	}
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+	l := &SegmentLog{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		syncWrites:  syncWrites,
+		notifyCh:    make(chan struct{}, 1),
+	}
+	err = l.load()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Append writes a record at the end of the log.
+func (l *SegmentLog) Append(b []byte) error {
+	recSize := int64(len(b) + recordHeaderSize)
+	if recSize > l.maxSize {
+		return ErrRecordTooLarge
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	ws := l.segments[len(l.segments)-1]
+	if ws.size > 0 && ws.size+recSize > l.segmentSize {
+		err := l.roll()
+		if err != nil {
+			return err
+		}
+		ws = l.segments[len(l.segments)-1]
+	}
+	// make room by removing the oldest segments
+	for l.size+recSize > l.maxSize && len(l.segments) > 1 {
+		err := l.dropOldest()
+		if err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, recSize)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(b)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(b, crcTable))
+	copy(buf[recordHeaderSize:], b)
+	_, err := l.w.Write(buf)
+	if err != nil {
+		return err
+	}
+	if l.syncWrites {
+		err = l.w.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	ws.size += recSize
+	ws.records++
+	l.size += recSize
+	l.pending++
+	select {
+	case l.notifyCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Read returns the next unread record and moves the read position past it,
+// it blocks until a record is available, the context is canceled or the log is closed.
+func (l *SegmentLog) Read(ctx context.Context) ([]byte, error) {
+	b, err := l.Peek(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.Advance()
+	return b, nil
+}
+
+// Peek returns the next unread record without moving the read position,
+// it blocks until a record is available, the context is canceled or the log is closed.
+// Successive calls return the same record until Advance is called.
+func (l *SegmentLog) Peek(ctx context.Context) ([]byte, error) {
+	for {
+		b, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			return b, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-l.notifyCh:
+		}
+	}
+}
+
+// Advance moves the read position past the record returned by the last Peek.
+// It is a noop if that record was dropped to keep the log under its max size.
+func (l *SegmentLog) Advance() {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed || l.peeked == 0 {
+		return
+	}
+	l.rOffset += l.peeked
+	l.peeked = 0
+	l.segments[0].read++
+	l.pending--
+}
+
+// Pending returns the number of unread records.
+func (l *SegmentLog) Pending() int64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.pending
+}
+
+// Size returns the total size of the segments on disk in bytes.
+func (l *SegmentLog) Size() int64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.size
+}
+
+// Dropped returns the number of unread records removed to keep the log under its max size.
+func (l *SegmentLog) Dropped() uint64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.dropped
+}
+
+// Sync flushes the write segment to disk and persists the read position.
+func (l *SegmentLog) Sync() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	return l.sync()
+}
+
+// Close syncs and closes the segment log.
+func (l *SegmentLog) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return nil
+	}
+	err := l.sync()
+	l.closed = true
+	if l.r != nil {
+		l.r.Close()
+	}
+	if cerr := l.w.Close(); err == nil {
+		err = cerr
+	}
+	// wake up a blocked reader
+	close(l.notifyCh)
+	return err
+}
+
+// next returns the next record without moving the read position, or nil if there is none.
+// must not be called with the lock held.
+func (l *SegmentLog) next() ([]byte, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return nil, ErrClosed
+	}
+	for {
+		rs := l.segments[0]
+		if l.rOffset < rs.size {
+			break
+		}
+		// current read segment fully read
+		if len(l.segments) == 1 {
+			return nil, nil
+		}
+		err := l.removeSegment(rs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rs := l.segments[0]
+	if l.r == nil {
+		var err error
+		l.r, err = os.Open(l.segmentPath(rs.id))
+		if err != nil {
+			return nil, err
+		}
+	}
+	hdr := make([]byte, recordHeaderSize)
+	_, err := l.r.ReadAt(hdr, l.rOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record header: %w", err)
+	}
+	b := make([]byte, binary.BigEndian.Uint32(hdr[0:4]))
+	_, err = l.r.ReadAt(b, l.rOffset+recordHeaderSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record: %w", err)
+	}
+	if crc32.Checksum(b, crcTable) != binary.BigEndian.Uint32(hdr[4:8]) {
+		// skip the corrupted record
+		l.rOffset += int64(len(b) + recordHeaderSize)
+		rs.read++
+		l.pending--
+		return nil, fmt.Errorf("record checksum mismatch in segment %d", rs.id)
+	}
+	l.peeked = int64(len(b) + recordHeaderSize)
+	return b, nil
+}
+
+func (l *SegmentLog) roll() error {
+	if l.syncWrites {
+		if err := l.w.Sync(); err != nil {
+			return err
+		}
+	}
+	err := l.w.Close()
+	if err != nil {
+		return err
+	}
+	id := l.segments[len(l.segments)-1].id + 1
+	l.w, err = os.OpenFile(l.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, &segment{id: id})
+	return nil
+}
+
+// dropOldest removes the oldest segment and counts its unread records as dropped.
+func (l *SegmentLog) dropOldest() error {
+	rs := l.segments[0]
+	unread := rs.records - rs.read
+	l.dropped += uint64(unread)
+	l.pending -= unread
+	return l.removeSegment(rs)
+}
+
+// removeSegment removes the first segment and resets the read position
+// to the beginning of the next one.
+func (l *SegmentLog) removeSegment(rs *segment) error {
+	if l.r != nil {
+		l.r.Close()
+		l.r = nil
+	}
+	err := os.Remove(l.segmentPath(rs.id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l.size -= rs.size
+	l.segments = l.segments[1:]
+	l.rOffset = 0
+	l.peeked = 0
+	return l.writeCursor()
+}
+
+func (l *SegmentLog) sync() error {
+	err := l.w.Sync()
+	if err != nil {
+		return err
+	}
+	return l.writeCursor()
+}
+
+func (l *SegmentLog) writeCursor() error {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[0:8], l.segments[0].id)
+	binary.BigEndian.PutUint64(b[8:16], uint64(l.rOffset))
+	tmp := filepath.Join(l.dir, cursorFileName+".tmp")
+	err := os.WriteFile(tmp, b, 0o640)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(l.dir, cursorFileName))
+}
+
+func (l *SegmentLog) segmentPath(id uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// load reads the existing segments and cursor from disk,
+// truncating any partially written record at the end of the last segment.
+func (l *SegmentLog) load() error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var cursorID uint64
+	var cursorOffset int64
+	cb, err := os.ReadFile(filepath.Join(l.dir, cursorFileName))
+	switch {
+	case err == nil && len(cb) == 16:
+		cursorID = binary.BigEndian.Uint64(cb[0:8])
+		cursorOffset = int64(binary.BigEndian.Uint64(cb[8:16]))
+	case err != nil && !os.IsNotExist(err):
+		return err
+	}
+
+	for _, id := range ids {
+		if id < cursorID {
+			// already read
+			if err := os.Remove(l.segmentPath(id)); err != nil {
+				return err
+			}
+			continue
+		}
+		seg, err := l.scanSegment(id)
+		if err != nil {
+			return err
+		}
+		if id == cursorID {
+			read, offset, err := l.countRecords(id, cursorOffset)
+			if err != nil {
+				return err
+			}
+			seg.read = read
+			l.rOffset = offset
+		}
+		l.segments = append(l.segments, seg)
+		l.size += seg.size
+		l.pending += seg.records - seg.read
+	}
+	if len(l.segments) == 0 {
+		l.segments = append(l.segments, &segment{id: cursorID})
+		l.rOffset = 0
+	}
+	ws := l.segments[len(l.segments)-1]
+	l.w, err = os.OpenFile(l.segmentPath(ws.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	return l.writeCursor()
+}
+
+// scanSegment validates the records of segment id and returns its stats.
+// a corrupted or partial record and everything after it is truncated.
+func (l *SegmentLog) scanSegment(id uint64) (*segment, error) {
+	f, err := os.OpenFile(l.segmentPath(id), os.O_RDWR, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	seg := &segment{id: id}
+	hdr := make([]byte, recordHeaderSize)
+	for {
+		if _, err := f.ReadAt(hdr, seg.size); err != nil {
+			break
+		}
+		b := make([]byte, binary.BigEndian.Uint32(hdr[0:4]))
+		if _, err := f.ReadAt(b, seg.size+recordHeaderSize); err != nil {
+			break
+		}
+		if crc32.Checksum(b, crcTable) != binary.BigEndian.Uint32(hdr[4:8]) {
+			break
+		}
+		seg.size += int64(len(b) + recordHeaderSize)
+		seg.records++
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() > seg.size {
+		if err := f.Truncate(seg.size); err != nil {
+			return nil, err
+		}
+	}
+	return seg, nil
+}
+
+// countRecords returns the number of records fully contained
+// in the first `offset` bytes of segment id, and the offset of the next record.
+func (l *SegmentLog) countRecords(id uint64, offset int64) (int64, int64, error) {
+	f, err := os.Open(l.segmentPath(id))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	hdr := make([]byte, recordHeaderSize)
+	var count, pos int64
+	for pos < offset {
+		_, err = f.ReadAt(hdr, pos)
+		if err != nil {
+			break
+		}
+		next := pos + int64(binary.BigEndian.Uint32(hdr[0:4])+recordHeaderSize)
+		if next > offset {
+			break
+		}
+		pos = next
+		count++
+	}
+	return count, pos, nil
+}
@@ -0,0 +1,208 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package buffer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readN(t *testing.T, l *SegmentLog, n int) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b, err := l.Read(ctx)
+		if err != nil {
+			t.Fatalf("read %d failed: %v", i, err)
+		}
+		res = append(res, string(b))
+	}
+	return res
+}
+
+func TestSegmentLogOrder(t *testing.T) {
+	l, err := Open(t.TempDir(), 1<<20, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for i := 0; i < 20; i++ {
+		if err := l.Append([]byte(fmt.Sprintf("msg-%02d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.Pending() != 20 {
+		t.Fatalf("expected 20 pending records, got %d", l.Pending())
+	}
+	got := readN(t, l, 20)
+	for i, s := range got {
+		if want := fmt.Sprintf("msg-%02d", i); s != want {
+			t.Errorf("record %d: got %q, want %q", i, s, want)
+		}
+	}
+	if l.Pending() != 0 {
+		t.Fatalf("expected 0 pending records, got %d", l.Pending())
+	}
+}
+
+func TestSegmentLogMaxSize(t *testing.T) {
+	// each record is 6+8 bytes, 4 records per segment, 2 segments max.
+	l, err := Open(t.TempDir(), 112, 56, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for i := 0; i < 12; i++ {
+		if err := l.Append([]byte(fmt.Sprintf("msg-%02d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.Dropped() != 4 {
+		t.Fatalf("expected 4 dropped records, got %d", l.Dropped())
+	}
+	if l.Pending() != 8 {
+		t.Fatalf("expected 8 pending records, got %d", l.Pending())
+	}
+	got := readN(t, l, 8)
+	if got[0] != "msg-04" || got[7] != "msg-11" {
+		t.Errorf("unexpected records: %v", got)
+	}
+}
+
+func TestSegmentLogReopen(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, 1<<20, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := l.Append([]byte(fmt.Sprintf("msg-%02d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readN(t, l, 6)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a partially written record at the end of the last segment
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == segmentExt {
+			last = filepath.Join(dir, e.Name())
+		}
+	}
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 42, 1, 2})
+	f.Close()
+
+	l, err = Open(dir, 1<<20, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if l.Pending() != 4 {
+		t.Fatalf("expected 4 pending records after reopen, got %d", l.Pending())
+	}
+	if err := l.Append([]byte("msg-10")); err != nil {
+		t.Fatal(err)
+	}
+	got := readN(t, l, 5)
+	want := []string{"msg-06", "msg-07", "msg-08", "msg-09", "msg-10"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSegmentLogReadBlocks(t *testing.T) {
+	l, err := Open(t.TempDir(), 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = l.Read(ctx)
+	if err == nil {
+		t.Fatal("expected read to time out on an empty log")
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.Append([]byte("late"))
+	}()
+	got := readN(t, l, 1)
+	if got[0] != "late" {
+		t.Errorf("got %q, want %q", got[0], "late")
+	}
+}
+
+func TestSegmentLogPeek(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, 1<<20, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := l.Append([]byte(fmt.Sprintf("msg-%02d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		b, err := l.Peek(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "msg-00" {
+			t.Fatalf("peek %d: got %q, want %q", i, b, "msg-00")
+		}
+	}
+	l.Advance()
+	b, err := l.Peek(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "msg-01" {
+		t.Fatalf("got %q, want %q", b, "msg-01")
+	}
+	if l.Pending() != 2 {
+		t.Fatalf("expected 2 pending records, got %d", l.Pending())
+	}
+	// the peeked record was not advanced past, it is replayed after a restart.
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Open(dir, 1<<20, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got := readN(t, l, 2)
+	want := []string{"msg-01", "msg-02"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
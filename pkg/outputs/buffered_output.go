@@ -0,0 +1,409 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs/buffer"
+)
+
+const (
+	bufferLoggingPrefix        = "[output_buffer:%s] "
+	defaultBufferSyncPeriod    = time.Second
+	defaultBufferRetryInterval = time.Second
+
+	recordTypeProto byte = 1
+	recordTypeEvent byte = 2
+)
+
+// BufferConfig is the configuration of the optional disk buffer of an output,
+// set under the output's `buffer` field.
+type BufferConfig struct {
+	Directory   string        `mapstructure:"directory,omitempty" json:"directory,omitempty"`
+	MaxSize     int64         `mapstructure:"max-size,omitempty" json:"max-size,omitempty"`
+	SegmentSize int64         `mapstructure:"segment-size,omitempty" json:"segment-size,omitempty"`
+	SyncWrites  bool          `mapstructure:"sync-writes,omitempty" json:"sync-writes,omitempty"`
+	SyncPeriod  time.Duration `mapstructure:"sync-period,omitempty" json:"sync-period,omitempty"`
+	// wait time before retrying the delivery of a buffered message.
+	RetryInterval time.Duration `mapstructure:"retry-interval,omitempty" json:"retry-interval,omitempty"`
+	Debug         bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+// DeliveryWriter is implemented by the outputs that support the disk buffer.
+// WriteSync and WriteEventSync write a message to the output destination
+// and return once it was delivered, or an error if it could not be.
+type DeliveryWriter interface {
+	WriteSync(context.Context, proto.Message, Meta) error
+	WriteEventSync(context.Context, *formatters.EventMsg) error
+}
+
+// bufferedOutput wraps an Output with a disk-backed segment log.
+// Write and WriteEvent payloads are appended to the log,
+// a single goroutine reads them back in order and writes them
+// to the wrapped Output, a message is removed from the log once delivered.
+type bufferedOutput struct {
+	Output
+	dw     DeliveryWriter
+	name   string
+	cfg    *BufferConfig
+	logger *log.Logger
+	reg    *prometheus.Registry
+	cfn    context.CancelFunc
+	wg     *sync.WaitGroup
+
+	m sync.RWMutex
+	// nil until Init succeeds, messages are then written to the wrapped Output directly.
+	sl *buffer.SegmentLog
+}
+
+// WithBuffer returns o wrapped with a disk buffer if the output configuration
+// cfg has a `buffer` section, otherwise it returns o.
+// It fails if the buffer configuration is invalid or if o does not implement DeliveryWriter.
+func WithBuffer(o Output, cfg map[string]interface{}) (Output, error) {
+	if cfg == nil {
+		return o, nil
+	}
+	if _, ok := cfg["buffer"]; !ok {
+		return o, nil
+	}
+	dw, ok := o.(DeliveryWriter)
+	if !ok {
+		return nil, fmt.Errorf("output type %v does not support the disk buffer", cfg["type"])
+	}
+	bcfg := new(BufferConfig)
+	err := DecodeConfig(cfg["buffer"], bcfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid buffer config: %v", err)
+	}
+	if bcfg.Directory == "" {
+		return nil, errors.New("missing buffer directory")
+	}
+	if bcfg.SyncPeriod <= 0 {
+		bcfg.SyncPeriod = defaultBufferSyncPeriod
+	}
+	if bcfg.RetryInterval <= 0 {
+		bcfg.RetryInterval = defaultBufferRetryInterval
+	}
+	return &bufferedOutput{
+		Output: o,
+		dw:     dw,
+		cfg:    bcfg,
+		logger: log.New(io.Discard, bufferLoggingPrefix, utils.DefaultLoggingFlags),
+		wg:     new(sync.WaitGroup),
+	}, nil
+}
+
+func (b *bufferedOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...Option) error {
+	b.name = name
+	b.logger.SetPrefix(fmt.Sprintf(bufferLoggingPrefix, name))
+	// apply the logger and registry options to the wrapper,
+	// the wrapped output applies all options in its own Init.
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return err
+		}
+	}
+	sl, err := buffer.Open(filepath.Join(b.cfg.Directory, name), b.cfg.MaxSize, b.cfg.SegmentSize, b.cfg.SyncWrites)
+	if err != nil {
+		return fmt.Errorf("failed to open buffer: %v", err)
+	}
+	err = b.Output.Init(ctx, name, cfg, opts...)
+	if err != nil {
+		sl.Close()
+		return err
+	}
+	err = b.registerMetrics(sl)
+	if err != nil {
+		sl.Close()
+		return err
+	}
+	if n := sl.Pending(); n > 0 {
+		b.logger.Printf("replaying %d buffered messages", n)
+	}
+	b.m.Lock()
+	b.sl = sl
+	b.m.Unlock()
+	ctx, b.cfn = context.WithCancel(ctx)
+	b.wg.Add(2)
+	go b.replay(ctx, sl)
+	go b.syncer(ctx, sl)
+	return nil
+}
+
+func (b *bufferedOutput) Write(ctx context.Context, m proto.Message, meta Meta) {
+	sl := b.segmentLog()
+	if sl == nil {
+		b.Output.Write(ctx, m, meta)
+		return
+	}
+	rec, err := encodeProtoRecord(m, meta)
+	if err != nil {
+		if b.cfg.Debug {
+			b.logger.Printf("cannot buffer message: %v, writing it directly", err)
+		}
+		b.Output.Write(ctx, m, meta)
+		return
+	}
+	b.append(sl, rec)
+}
+
+func (b *bufferedOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	sl := b.segmentLog()
+	if sl == nil {
+		b.Output.WriteEvent(ctx, ev)
+		return
+	}
+	rec, err := json.Marshal(ev)
+	if err != nil {
+		if b.cfg.Debug {
+			b.logger.Printf("cannot buffer event: %v, writing it directly", err)
+		}
+		b.Output.WriteEvent(ctx, ev)
+		return
+	}
+	b.append(sl, append([]byte{recordTypeEvent}, rec...))
+}
+
+// WriteSync appends a message to the buffer, it returns once the message is appended.
+// Before Init, or if the message cannot be buffered, it is written to the output directly.
+func (b *bufferedOutput) WriteSync(ctx context.Context, m proto.Message, meta Meta) error {
+	sl := b.segmentLog()
+	if sl == nil {
+		return b.dw.WriteSync(ctx, m, meta)
+	}
+	rec, err := encodeProtoRecord(m, meta)
+	if err != nil {
+		if b.cfg.Debug {
+			b.logger.Printf("cannot buffer message: %v, writing it directly", err)
+		}
+		return b.dw.WriteSync(ctx, m, meta)
+	}
+	return b.append(sl, rec)
+}
+
+// WriteEventSync appends an event to the buffer, it returns once the event is appended.
+// Before Init, or if the event cannot be buffered, it is written to the output directly.
+func (b *bufferedOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	sl := b.segmentLog()
+	if sl == nil {
+		return b.dw.WriteEventSync(ctx, ev)
+	}
+	rec, err := json.Marshal(ev)
+	if err != nil {
+		if b.cfg.Debug {
+			b.logger.Printf("cannot buffer event: %v, writing it directly", err)
+		}
+		return b.dw.WriteEventSync(ctx, ev)
+	}
+	return b.append(sl, append([]byte{recordTypeEvent}, rec...))
+}
+
+func (b *bufferedOutput) Close() error {
+	if b.cfn != nil {
+		b.cfn()
+	}
+	if sl := b.segmentLog(); sl != nil {
+		err := sl.Close()
+		if err != nil {
+			b.logger.Printf("failed to close buffer: %v", err)
+		}
+	}
+	b.wg.Wait()
+	return b.Output.Close()
+}
+
+func (b *bufferedOutput) RegisterMetrics(reg *prometheus.Registry) {
+	b.reg = reg
+}
+
+func (b *bufferedOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && b.logger != nil {
+		b.logger.SetOutput(logger.Writer())
+		b.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetEventProcessors is a noop, event processors are applied by the wrapped output.
+func (b *bufferedOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
+	return nil
+}
+
+func (b *bufferedOutput) SetName(string) {}
+
+func (b *bufferedOutput) SetClusterName(string) {}
+
+func (b *bufferedOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+func (b *bufferedOutput) segmentLog() *buffer.SegmentLog {
+	b.m.RLock()
+	defer b.m.RUnlock()
+	return b.sl
+}
+
+func (b *bufferedOutput) append(sl *buffer.SegmentLog, rec []byte) error {
+	err := sl.Append(rec)
+	if err != nil {
+		b.logger.Printf("failed to append message to buffer: %v", err)
+		outputBufferFailedAppends.WithLabelValues(b.name).Inc()
+		return err
+	}
+	outputBufferAppended.WithLabelValues(b.name).Inc()
+	b.updateMetrics(sl)
+	return nil
+}
+
+// replay reads the buffered messages in order and writes them to the wrapped output.
+// A message is removed from the buffer once delivered,
+// while the output fails to deliver it, replay pauses and retries it every retry-interval.
+func (b *bufferedOutput) replay(ctx context.Context, sl *buffer.SegmentLog) {
+	defer b.wg.Done()
+	for {
+		rec, err := sl.Peek(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, buffer.ErrClosed) {
+				return
+			}
+			b.logger.Printf("failed to read from buffer, retrying in %s: %v", b.cfg.RetryInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.cfg.RetryInterval):
+			}
+			continue
+		}
+		err = b.deliver(ctx, rec)
+		switch {
+		case err == nil:
+			outputBufferReplayed.WithLabelValues(b.name).Inc()
+		case errors.Is(err, errInvalidRecord):
+			// not retried
+			b.logger.Printf("%v", err)
+		default:
+			if ctx.Err() != nil {
+				return
+			}
+			outputBufferFailedDeliveries.WithLabelValues(b.name).Inc()
+			if b.cfg.Debug {
+				b.logger.Printf("failed to deliver buffered message, retrying in %s: %v", b.cfg.RetryInterval, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.cfg.RetryInterval):
+			}
+			continue
+		}
+		sl.Advance()
+		b.updateMetrics(sl)
+	}
+}
+
+var errInvalidRecord = errors.New("invalid buffered record")
+
+func (b *bufferedOutput) deliver(ctx context.Context, rec []byte) error {
+	if len(rec) == 0 {
+		return fmt.Errorf("%w: empty record", errInvalidRecord)
+	}
+	switch rec[0] {
+	case recordTypeProto:
+		m, meta, err := decodeProtoRecord(rec)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidRecord, err)
+		}
+		return b.dw.WriteSync(ctx, m, meta)
+	case recordTypeEvent:
+		ev := new(formatters.EventMsg)
+		err := json.Unmarshal(rec[1:], ev)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidRecord, err)
+		}
+		return b.dw.WriteEventSync(ctx, ev)
+	default:
+		return fmt.Errorf("%w: unknown record type %d", errInvalidRecord, rec[0])
+	}
+}
+
+// syncer periodically persists the buffer read position.
+func (b *bufferedOutput) syncer(ctx context.Context, sl *buffer.SegmentLog) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.cfg.SyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := sl.Sync()
+			if err != nil && !errors.Is(err, buffer.ErrClosed) {
+				b.logger.Printf("failed to sync buffer: %v", err)
+			}
+			b.updateMetrics(sl)
+		}
+	}
+}
+
+// encodeProtoRecord encodes a gNMI SubscribeResponse and its metadata as:
+// type(1 byte) | meta length (4 bytes) | meta JSON | proto bytes
+func encodeProtoRecord(m proto.Message, meta Meta) ([]byte, error) {
+	rsp, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message type %T", m)
+	}
+	mb, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	pb, err := proto.Marshal(rsp)
+	if err != nil {
+		return nil, err
+	}
+	rec := make([]byte, 5, 5+len(mb)+len(pb))
+	rec[0] = recordTypeProto
+	binary.BigEndian.PutUint32(rec[1:5], uint32(len(mb)))
+	rec = append(rec, mb...)
+	return append(rec, pb...), nil
+}
+
+func decodeProtoRecord(rec []byte) (proto.Message, Meta, error) {
+	if len(rec) < 5 {
+		return nil, nil, errors.New("short record")
+	}
+	ml := int(binary.BigEndian.Uint32(rec[1:5]))
+	if len(rec) < 5+ml {
+		return nil, nil, errors.New("short record")
+	}
+	meta := Meta{}
+	err := json.Unmarshal(rec[5:5+ml], &meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	rsp := new(gnmi.SubscribeResponse)
+	err = proto.Unmarshal(rec[5+ml:], rsp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rsp, meta, nil
+}
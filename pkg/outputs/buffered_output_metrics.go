@@ -0,0 +1,103 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openconfig/gnmic/pkg/outputs/buffer"
+)
+
+var registerBufferMetricsOnce sync.Once
+
+var outputBufferQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "queue_depth",
+	Help:      "Number of messages waiting in the output disk buffer",
+}, []string{"name"})
+
+var outputBufferSizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "size_bytes",
+	Help:      "Size of the output disk buffer segments in bytes",
+}, []string{"name"})
+
+var outputBufferDropped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "dropped_msgs",
+	Help:      "Number of messages dropped from the output disk buffer because its max size was reached",
+}, []string{"name"})
+
+var outputBufferAppended = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "appended_msgs_total",
+	Help:      "Number of messages appended to the output disk buffer",
+}, []string{"name"})
+
+var outputBufferFailedAppends = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "failed_appends_total",
+	Help:      "Number of messages that failed to be appended to the output disk buffer",
+}, []string{"name"})
+
+var outputBufferReplayed = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "replayed_msgs_total",
+	Help:      "Number of messages read from the output disk buffer and written to the output",
+}, []string{"name"})
+
+var outputBufferFailedDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "failed_deliveries_total",
+	Help:      "Number of failed attempts to deliver a message read from the output disk buffer",
+}, []string{"name"})
+
+func (b *bufferedOutput) registerMetrics(sl *buffer.SegmentLog) error {
+	if b.reg == nil {
+		return nil
+	}
+	var err error
+	registerBufferMetricsOnce.Do(func() {
+		for _, c := range []prometheus.Collector{
+			outputBufferQueueDepth,
+			outputBufferSizeBytes,
+			outputBufferDropped,
+			outputBufferAppended,
+			outputBufferFailedAppends,
+			outputBufferReplayed,
+			outputBufferFailedDeliveries,
+		} {
+			if err = b.reg.Register(c); err != nil {
+				b.logger.Printf("failed to register metric: %v", err)
+				return
+			}
+		}
+	})
+	outputBufferAppended.WithLabelValues(b.name).Add(0)
+	outputBufferFailedAppends.WithLabelValues(b.name).Add(0)
+	outputBufferReplayed.WithLabelValues(b.name).Add(0)
+	outputBufferFailedDeliveries.WithLabelValues(b.name).Add(0)
+	b.updateMetrics(sl)
+	return err
+}
+
+func (b *bufferedOutput) updateMetrics(sl *buffer.SegmentLog) {
+	outputBufferQueueDepth.WithLabelValues(b.name).Set(float64(sl.Pending()))
+	outputBufferSizeBytes.WithLabelValues(b.name).Set(float64(sl.Size()))
+	outputBufferDropped.WithLabelValues(b.name).Set(float64(sl.Dropped()))
+}
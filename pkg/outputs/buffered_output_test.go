@@ -0,0 +1,163 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// testOutput records the delivered events,
+// its WriteEventSync fails while down is true.
+type testOutput struct {
+	m         sync.Mutex
+	down      bool
+	attempts  int
+	delivered []string
+	direct    []string
+}
+
+func (o *testOutput) Init(context.Context, string, map[string]interface{}, ...Option) error {
+	return nil
+}
+func (o *testOutput) Write(context.Context, proto.Message, Meta) {}
+func (o *testOutput) WriteEvent(_ context.Context, ev *formatters.EventMsg) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.direct = append(o.direct, ev.Name)
+}
+func (o *testOutput) WriteSync(context.Context, proto.Message, Meta) error { return nil }
+func (o *testOutput) WriteEventSync(_ context.Context, ev *formatters.EventMsg) error {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.attempts++
+	if o.down {
+		return errors.New("destination unavailable")
+	}
+	o.delivered = append(o.delivered, ev.Name)
+	return nil
+}
+func (o *testOutput) Close() error                         { return nil }
+func (o *testOutput) RegisterMetrics(*prometheus.Registry) {}
+func (o *testOutput) String() string                       { return "" }
+func (o *testOutput) SetLogger(*log.Logger)                {}
+func (o *testOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
+	return nil
+}
+func (o *testOutput) SetName(string)                                  {}
+func (o *testOutput) SetClusterName(string)                           {}
+func (o *testOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+func (o *testOutput) setDown(down bool) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.down = down
+}
+
+func (o *testOutput) state() (int, []string) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	return o.attempts, append([]string(nil), o.delivered...)
+}
+
+func TestWithBuffer(t *testing.T) {
+	o, err := WithBuffer(&testOutput{}, map[string]interface{}{"type": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := o.(*testOutput); !ok {
+		t.Errorf("expected the output to not be wrapped without a buffer config")
+	}
+	_, err = WithBuffer(&testOutput{}, map[string]interface{}{"type": "test", "buffer": map[string]interface{}{}})
+	if err == nil {
+		t.Errorf("expected an error for a buffer config without directory")
+	}
+	type noDelivery struct{ Output }
+	_, err = WithBuffer(noDelivery{&testOutput{}}, map[string]interface{}{"type": "test", "buffer": map[string]interface{}{"directory": t.TempDir()}})
+	if err == nil {
+		t.Errorf("expected an error for an output not implementing DeliveryWriter")
+	}
+}
+
+func TestBufferedOutputReplay(t *testing.T) {
+	to := &testOutput{down: true}
+	cfg := map[string]interface{}{
+		"type": "test",
+		"buffer": map[string]interface{}{
+			"directory":      t.TempDir(),
+			"retry-interval": "10ms",
+		},
+	}
+	o, err := WithBuffer(to, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// written before Init, the message bypasses the buffer.
+	o.WriteEvent(ctx, &formatters.EventMsg{Name: "before-init"})
+	if len(to.direct) != 1 {
+		t.Fatalf("expected the message to be written directly before Init, got %v", to.direct)
+	}
+	err = o.Init(ctx, "out1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	want := []string{"ev0", "ev1", "ev2"}
+	for _, name := range want[:2] {
+		o.WriteEvent(ctx, &formatters.EventMsg{Name: name})
+	}
+	// WriteEventSync returns once the event is buffered, even while the destination is down.
+	err = o.(DeliveryWriter).WriteEventSync(ctx, &formatters.EventMsg{Name: want[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// while the destination is down, the first message is retried and none is delivered.
+	deadline := time.Now().Add(time.Second)
+	for {
+		attempts, delivered := to.state()
+		if len(delivered) != 0 {
+			t.Fatalf("unexpected delivery while the destination is down: %v", delivered)
+		}
+		if attempts >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected delivery retries, got %d attempts", attempts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	to.setDown(false)
+	deadline = time.Now().Add(time.Second)
+	for {
+		_, delivered := to.state()
+		if len(delivered) == len(want) {
+			for i := range want {
+				if delivered[i] != want[i] {
+					t.Fatalf("got %v, want %v", delivered, want)
+				}
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v, want %v", delivered, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,366 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package clickhouse_output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	backoff      = 100 * time.Millisecond
+	drainTimeout = 5 * time.Second
+)
+
+var errEmptyTable = errors.New("table template rendered an empty table name")
+
+func (c *clickhouseOutput) connect() (driver.Conn, error) {
+	opts := &clickhouse.Options{
+		Protocol: clickhouse.Native,
+		Addr:     c.cfg.Addresses,
+		Auth: clickhouse.Auth{
+			Database: c.cfg.Database,
+			Username: c.cfg.Username,
+			Password: c.cfg.Password,
+		},
+		DialTimeout: c.cfg.DialTimeout,
+		ReadTimeout: c.cfg.Timeout,
+		ClientInfo: clickhouse.ClientInfo{
+			Products: []struct {
+				Name    string
+				Version string
+			}{
+				{Name: "gnmic"},
+			},
+		},
+	}
+	if c.cfg.Protocol == protocolHTTP {
+		opts.Protocol = clickhouse.HTTP
+	}
+	if c.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			c.cfg.TLS.CaFile,
+			c.cfg.TLS.CertFile,
+			c.cfg.TLS.KeyFile,
+			"",
+			c.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLS = tlsCfg
+	}
+	if c.cfg.Debug {
+		opts.Debug = true
+		opts.Debugf = c.logger.Printf
+	}
+	return clickhouse.Open(opts)
+}
+
+func (c *clickhouseOutput) writer(ctx context.Context) {
+	defer c.wg.Done()
+	c.logger.Printf("starting writer")
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	evs := make([]*formatters.EventMsg, 0, c.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			c.drain(evs)
+			return
+		case ev := <-c.rowCh:
+			evs = append(evs, ev)
+			if len(evs) < c.cfg.BatchSize {
+				continue
+			}
+			if c.cfg.Debug {
+				c.logger.Printf("batch size reached, writing to clickhouse")
+			}
+			c.write(ctx, evs)
+			evs = evs[:0]
+		case <-ticker.C:
+			if len(evs) == 0 {
+				continue
+			}
+			if c.cfg.Debug {
+				c.logger.Printf("flush interval reached, writing to clickhouse")
+			}
+			c.write(ctx, evs)
+			evs = evs[:0]
+		}
+	}
+}
+
+// drain writes the pending rows when the output is closed.
+func (c *clickhouseOutput) drain(evs []*formatters.EventMsg) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for {
+		select {
+		case ev := <-c.rowCh:
+			evs = append(evs, ev)
+			if len(evs) >= c.cfg.BatchSize {
+				c.write(ctx, evs)
+				evs = evs[:0]
+			}
+		default:
+			if len(evs) > 0 {
+				c.write(ctx, evs)
+			}
+			return
+		}
+	}
+}
+
+// write groups the events per table and inserts each group.
+func (c *clickhouseOutput) write(ctx context.Context, evs []*formatters.EventMsg) {
+	tables, order := c.groupByTable(evs)
+	for _, table := range order {
+		c.writeTable(ctx, table, tables[table])
+	}
+}
+
+// writeSync groups the events per table and inserts each group without retries,
+// it returns the first insert error.
+func (c *clickhouseOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	tables, order := c.groupByTable(evs)
+	for _, table := range order {
+		start := time.Now()
+		err := c.insertTable(ctx, table, tables[table])
+		if err != nil {
+			return fmt.Errorf("failed to insert %d row(s) into table %q: %v", len(tables[table]), table, err)
+		}
+		clickhouseInsertDuration.WithLabelValues(c.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		clickhouseNumberOfInsertedRows.WithLabelValues(c.cfg.Name).Add(float64(len(tables[table])))
+	}
+	return nil
+}
+
+// groupByTable groups the events with values per table name,
+// it returns the groups and the table names in order of appearance.
+func (c *clickhouseOutput) groupByTable(evs []*formatters.EventMsg) (map[string][]*formatters.EventMsg, []string) {
+	tables := make(map[string][]*formatters.EventMsg)
+	order := make([]string, 0)
+	for _, ev := range evs {
+		// deletes are not stored.
+		if len(ev.Values) == 0 {
+			continue
+		}
+		table, err := c.tableName(ev)
+		if err != nil {
+			c.logger.Printf("failed to render table name: %v", err)
+			clickhouseNumberOfFailedRows.WithLabelValues(c.cfg.Name, "table_template").Inc()
+			continue
+		}
+		if _, ok := tables[table]; !ok {
+			order = append(order, table)
+		}
+		tables[table] = append(tables[table], ev)
+	}
+	return tables, order
+}
+
+func (c *clickhouseOutput) tableName(ev *formatters.EventMsg) (string, error) {
+	b := new(bytes.Buffer)
+	err := c.tableTpl.Execute(b, ev)
+	if err != nil {
+		return "", err
+	}
+	table := strings.TrimSpace(b.String())
+	if table == "" {
+		return "", errEmptyTable
+	}
+	return table, nil
+}
+
+func (c *clickhouseOutput) writeTable(ctx context.Context, table string, evs []*formatters.EventMsg) {
+	retries := 0
+RETRY:
+	start := time.Now()
+	err := c.insertTable(ctx, table, evs)
+	if err != nil {
+		c.logger.Printf("failed to insert %d row(s) into table %q: %v", len(evs), table, err)
+		if retries < c.cfg.MaxRetries && ctx.Err() == nil {
+			retries++
+			time.Sleep(backoff)
+			goto RETRY
+		}
+		clickhouseNumberOfFailedRows.WithLabelValues(c.cfg.Name, "insert_error").Add(float64(len(evs)))
+		return
+	}
+	clickhouseInsertDuration.WithLabelValues(c.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	clickhouseNumberOfInsertedRows.WithLabelValues(c.cfg.Name).Add(float64(len(evs)))
+}
+
+// insertTable inserts the events into table, if the insert fails
+// the table schema is reloaded on the next insert,
+// since the table might have been changed by someone else.
+func (c *clickhouseOutput) insertTable(ctx context.Context, table string, evs []*formatters.EventMsg) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	err := c.insert(ctx, table, evs)
+	if err != nil {
+		delete(c.schemas, table)
+	}
+	return err
+}
+
+func (c *clickhouseOutput) insert(ctx context.Context, table string, evs []*formatters.EventMsg) error {
+	var cols []column
+	if c.cfg.Layout == layoutMap {
+		cols = mapLayoutColumns()
+	} else {
+		cols = eventsColumns(evs)
+	}
+	ts, err := c.ensureTable(ctx, table, cols)
+	if err != nil {
+		return err
+	}
+	// only insert the columns with a supported type.
+	insertCols := make([]string, 0, len(cols))
+	for _, col := range cols {
+		ct, ok := ts[col.name]
+		if !ok {
+			continue
+		}
+		if c.cfg.Layout == layoutColumns && !isReservedColumn(col.name) && zeroValue(ct.base) == nil {
+			if c.cfg.Debug {
+				c.logger.Printf("table %q: skipping column %q with unsupported type %s", table, col.name, ct.name)
+			}
+			continue
+		}
+		insertCols = append(insertCols, col.name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	batch, err := c.conn.PrepareBatch(ctx, insertQuery(c.cfg.Database, table, insertCols))
+	if err != nil {
+		return err
+	}
+	defer batch.Abort()
+	for _, ev := range evs {
+		var row []interface{}
+		if c.cfg.Layout == layoutMap {
+			row = mapRow(ev)
+		} else {
+			var errs []error
+			row, errs = columnsRow(ev, insertCols, ts)
+			for _, err := range errs {
+				clickhouseNumberOfFailedValues.WithLabelValues(c.cfg.Name, table).Inc()
+				if c.cfg.Debug {
+					c.logger.Printf("table %q: %v", table, err)
+				}
+			}
+		}
+		err = batch.Append(row...)
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+// ensureTable makes sure the table exists and has the given columns,
+// it returns the table schema.
+func (c *clickhouseOutput) ensureTable(ctx context.Context, table string, cols []column) (tableSchema, error) {
+	ts, ok := c.schemas[table]
+	if !ok {
+		var err error
+		ts, err = c.describeTable(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(ts) == 0 {
+			err = c.exec(ctx, createTableQuery(c.cfg.Database, table, cols, c.tableOptions()))
+			if err != nil {
+				return nil, err
+			}
+			c.logger.Printf("created table %q", table)
+			clickhouseNumberOfSchemaChanges.WithLabelValues(c.cfg.Name, "create").Inc()
+			ts, err = c.describeTable(ctx, table)
+			if err != nil {
+				return nil, err
+			}
+		}
+		c.schemas[table] = ts
+	}
+	if c.cfg.Layout == layoutMap {
+		return ts, nil
+	}
+	missing := missingColumns(ts, cols)
+	if len(missing) == 0 {
+		return ts, nil
+	}
+	err := c.exec(ctx, addColumnsQuery(c.cfg.Database, table, missing))
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.Debug {
+		c.logger.Printf("added %d column(s) to table %q", len(missing), table)
+	}
+	clickhouseNumberOfSchemaChanges.WithLabelValues(c.cfg.Name, "alter").Inc()
+	// reload the schema in case the columns were added concurrently with different types.
+	ts, err = c.describeTable(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	c.schemas[table] = ts
+	return ts, nil
+}
+
+func (c *clickhouseOutput) describeTable(ctx context.Context, table string) (tableSchema, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	rows, err := c.conn.Query(ctx,
+		"SELECT name, type FROM system.columns WHERE database = ? AND table = ?",
+		c.cfg.Database, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ts := make(tableSchema)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		ts[name] = parseColumnType(typ)
+	}
+	return ts, rows.Err()
+}
+
+func (c *clickhouseOutput) exec(ctx context.Context, query string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	if c.cfg.Debug {
+		c.logger.Printf("executing: %s", query)
+	}
+	return c.conn.Exec(ctx, query)
+}
+
+func (c *clickhouseOutput) tableOptions() *tableOptions {
+	return &tableOptions{
+		engine:      c.cfg.Engine,
+		orderBy:     c.cfg.OrderBy,
+		partitionBy: c.cfg.PartitionBy,
+		ttl:         c.cfg.TTL,
+	}
+}
@@ -0,0 +1,94 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package clickhouse_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "clickhouse_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var clickhouseNumberOfInsertedRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_rows_inserted_total",
+	Help:      "Number of rows successfully inserted by gnmic clickhouse output",
+}, []string{"name"})
+
+var clickhouseNumberOfFailedRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_rows_insert_fail_total",
+	Help:      "Number of rows gnmic clickhouse output failed to insert",
+}, []string{"name", "reason"})
+
+var clickhouseNumberOfFailedValues = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_values_conversion_fail_total",
+	Help:      "Number of values gnmic clickhouse output failed to convert to their column type",
+}, []string{"name", "table"})
+
+var clickhouseNumberOfSchemaChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_schema_changes_total",
+	Help:      "Number of tables created or altered by gnmic clickhouse output",
+}, []string{"name", "type"})
+
+var clickhouseInsertDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "insert_duration_ns",
+	Help:      "gnmic clickhouse output insert duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	clickhouseNumberOfInsertedRows.WithLabelValues(name).Add(0)
+	clickhouseNumberOfFailedRows.WithLabelValues(name, "").Add(0)
+	clickhouseInsertDuration.WithLabelValues(name).Set(0)
+}
+
+func (c *clickhouseOutput) registerMetrics() error {
+	if c.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = c.reg.Register(clickhouseNumberOfInsertedRows); err != nil {
+			c.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = c.reg.Register(clickhouseNumberOfFailedRows); err != nil {
+			c.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = c.reg.Register(clickhouseNumberOfFailedValues); err != nil {
+			c.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = c.reg.Register(clickhouseNumberOfSchemaChanges); err != nil {
+			c.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = c.reg.Register(clickhouseInsertDuration); err != nil {
+			c.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(c.cfg.Name)
+	return err
+}
@@ -0,0 +1,427 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package clickhouse_output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType            = "clickhouse"
+	loggingPrefix         = "[clickhouse_output:%s] "
+	defaultNativeAddress  = "localhost:9000"
+	defaultHTTPAddress    = "localhost:8123"
+	defaultDatabase       = "default"
+	defaultTimeout        = 10 * time.Second
+	defaultFlushInterval  = 5 * time.Second
+	defaultBufferSize     = 1000
+	defaultBatchSize      = 1000
+	defaultMaxRetries     = 3
+	defaultNumWorkers     = 1
+	defaultEngine         = "MergeTree"
+	defaultOrderBy        = "name, timestamp"
+	defaultColumnsTable   = "{{ .Name }}"
+	defaultMapLayoutTable = "gnmic"
+
+	protocolNative = "native"
+	protocolHTTP   = "http"
+	layoutColumns  = "columns"
+	layoutMap      = "map"
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &clickhouseOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				schemas:   make(map[string]tableSchema),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type clickhouseOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	conn      driver.Conn
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	rowCh     chan *formatters.EventMsg
+	// protects schemas, the writer and WriteSync insert concurrently.
+	m sync.Mutex
+	// known tables schemas.
+	schemas map[string]tableSchema
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	tableTpl  *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name        string           `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Addresses   []string         `mapstructure:"addresses,omitempty" json:"addresses,omitempty"`
+	Protocol    string           `mapstructure:"protocol,omitempty" json:"protocol,omitempty"`
+	Database    string           `mapstructure:"database,omitempty" json:"database,omitempty"`
+	Username    string           `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password    string           `mapstructure:"password,omitempty" json:"password,omitempty"`
+	TLS         *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	DialTimeout time.Duration    `mapstructure:"dial-timeout,omitempty" json:"dial-timeout,omitempty"`
+	Timeout     time.Duration    `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	// table name template
+	Table string `mapstructure:"table,omitempty" json:"table,omitempty"`
+	// table layout, `columns` or `map`.
+	Layout string `mapstructure:"layout,omitempty" json:"layout,omitempty"`
+	// settings used when creating tables
+	Engine          string        `mapstructure:"engine,omitempty" json:"engine,omitempty"`
+	OrderBy         string        `mapstructure:"order-by,omitempty" json:"order-by,omitempty"`
+	PartitionBy     string        `mapstructure:"partition-by,omitempty" json:"partition-by,omitempty"`
+	TTL             string        `mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
+	FlushInterval   time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	BatchSize       int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	MaxRetries      int           `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	AddTarget       string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics   bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+func (c *clickhouseOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, c.cfg)
+	if err != nil {
+		return err
+	}
+	if c.cfg.Name == "" {
+		c.cfg.Name = name
+	}
+	c.logger.SetPrefix(fmt.Sprintf(loggingPrefix, c.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return err
+		}
+	}
+	err = c.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = c.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if c.cfg.TargetTemplate == "" {
+		c.targetTpl = outputs.DefaultTargetTemplate
+	} else if c.cfg.AddTarget != "" {
+		c.targetTpl, err = gtemplate.CreateTemplate("target-template", c.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		c.targetTpl = c.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	c.tableTpl, err = gtemplate.CreateTemplate("table", c.cfg.Table)
+	if err != nil {
+		return fmt.Errorf("failed to parse table template: %v", err)
+	}
+	c.tableTpl = c.tableTpl.Funcs(outputs.TemplateFuncs)
+
+	c.conn, err = c.connect()
+	if err != nil {
+		return err
+	}
+	c.rowCh = make(chan *formatters.EventMsg, c.cfg.BufferSize)
+
+	ctx, c.cfn = context.WithCancel(ctx)
+	for i := 0; i < c.cfg.NumWorkers; i++ {
+		go c.worker(ctx)
+	}
+	c.wg.Add(1)
+	go c.writer(ctx)
+	c.logger.Printf("initialized clickhouse output %s: %s", c.cfg.Name, c.String())
+	return nil
+}
+
+func (c *clickhouseOutput) setDefaults() error {
+	switch c.cfg.Protocol {
+	case "":
+		c.cfg.Protocol = protocolNative
+	case protocolNative, protocolHTTP:
+	default:
+		return fmt.Errorf("unknown protocol %q", c.cfg.Protocol)
+	}
+	if len(c.cfg.Addresses) == 0 {
+		if c.cfg.Protocol == protocolHTTP {
+			c.cfg.Addresses = []string{defaultHTTPAddress}
+		} else {
+			c.cfg.Addresses = []string{defaultNativeAddress}
+		}
+	}
+	switch c.cfg.Layout {
+	case "":
+		c.cfg.Layout = layoutColumns
+	case layoutColumns, layoutMap:
+	default:
+		return fmt.Errorf("unknown layout %q", c.cfg.Layout)
+	}
+	if c.cfg.Table == "" {
+		if c.cfg.Layout == layoutMap {
+			c.cfg.Table = defaultMapLayoutTable
+		} else {
+			c.cfg.Table = defaultColumnsTable
+		}
+	}
+	if c.cfg.Database == "" {
+		c.cfg.Database = defaultDatabase
+	}
+	if c.cfg.Engine == "" {
+		c.cfg.Engine = defaultEngine
+	}
+	if c.cfg.OrderBy == "" {
+		c.cfg.OrderBy = defaultOrderBy
+	}
+	if c.cfg.Timeout <= 0 {
+		c.cfg.Timeout = defaultTimeout
+	}
+	if c.cfg.DialTimeout <= 0 {
+		c.cfg.DialTimeout = c.cfg.Timeout
+	}
+	if c.cfg.FlushInterval <= 0 {
+		c.cfg.FlushInterval = defaultFlushInterval
+	}
+	if c.cfg.BufferSize <= 0 {
+		c.cfg.BufferSize = defaultBufferSize
+	}
+	if c.cfg.BatchSize <= 0 {
+		c.cfg.BatchSize = defaultBatchSize
+	}
+	if c.cfg.MaxRetries < 0 {
+		c.cfg.MaxRetries = 0
+	} else if c.cfg.MaxRetries == 0 {
+		c.cfg.MaxRetries = defaultMaxRetries
+	}
+	if c.cfg.NumWorkers <= 0 {
+		c.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
+
+func (c *clickhouseOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case c.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if c.cfg.Debug {
+			c.logger.Printf("writing expired after %s", c.cfg.Timeout)
+		}
+		clickhouseNumberOfFailedRows.WithLabelValues(c.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (c *clickhouseOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range c.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case c.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync inserts the rows built from a message,
+// it returns once clickhouse accepted them.
+func (c *clickhouseOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := c.protoEvents(rsp, meta)
+	if err != nil {
+		c.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return c.writeSync(ctx, events)
+}
+
+// WriteEventSync inserts the rows built from an event,
+// it returns once clickhouse accepted them.
+func (c *clickhouseOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range c.evps {
+		evs = proc.Apply(evs...)
+	}
+	return c.writeSync(ctx, evs)
+}
+
+func (c *clickhouseOutput) Close() error {
+	if c.cfn == nil {
+		return nil
+	}
+	c.cfn()
+	// wait for the buffered rows to be flushed
+	c.wg.Wait()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+func (c *clickhouseOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !c.cfg.EnableMetrics {
+		return
+	}
+	c.reg = reg
+}
+
+func (c *clickhouseOutput) String() string {
+	cfg := *c.cfg
+	if cfg.Password != "" {
+		cfg.Password = "****"
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (c *clickhouseOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && c.logger != nil {
+		c.logger.SetOutput(logger.Writer())
+		c.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (c *clickhouseOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	c.evps, err = formatters.MakeEventProcessors(
+		logger,
+		c.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *clickhouseOutput) SetName(name string) {
+	if c.cfg.Name == "" {
+		c.cfg.Name = name
+	}
+}
+
+func (c *clickhouseOutput) SetClusterName(_ string) {}
+
+func (c *clickhouseOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (c *clickhouseOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-c.eventChan:
+			c.workerHandleEvent(ctx, ev)
+		case m := <-c.msgChan:
+			c.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (c *clickhouseOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	events, err := c.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		c.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		c.workerHandleEvent(ctx, ev)
+	}
+}
+
+func (c *clickhouseOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	rsp, err := outputs.AddSubscriptionTarget(pmsg, meta, c.cfg.AddTarget, c.targetTpl)
+	if err != nil {
+		c.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if rsp != nil {
+		pmsg = rsp
+	}
+	return formatters.ResponseToEventMsgs(subName, pmsg, meta, c.evps...)
+}
+
+func (c *clickhouseOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	// deletes are not stored.
+	if len(ev.Values) == 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case c.rowCh <- ev:
+	}
+}
@@ -0,0 +1,568 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package clickhouse_output
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	timestampColumn    = "timestamp"
+	nameColumn         = "name"
+	tagsColumn         = "tags"
+	valuesColumn       = "values"
+	stringValuesColumn = "string_values"
+
+	timestampColumnType    = "DateTime64(9, 'UTC')"
+	nameColumnType         = "LowCardinality(String)"
+	tagColumnType          = "LowCardinality(String)"
+	tagsColumnType         = "Map(LowCardinality(String), String)"
+	valuesColumnType       = "Map(String, Float64)"
+	stringValuesColumnType = "Map(String, String)"
+)
+
+// columnType is a parsed ClickHouse column type.
+type columnType struct {
+	// full type as known by ClickHouse
+	name string
+	// type without the Nullable and LowCardinality wrappers
+	base     string
+	nullable bool
+}
+
+func parseColumnType(t string) columnType {
+	ct := columnType{name: t, base: t}
+	for {
+		switch {
+		case strings.HasPrefix(ct.base, "Nullable(") && strings.HasSuffix(ct.base, ")"):
+			ct.nullable = true
+			ct.base = ct.base[len("Nullable(") : len(ct.base)-1]
+		case strings.HasPrefix(ct.base, "LowCardinality(") && strings.HasSuffix(ct.base, ")"):
+			ct.base = ct.base[len("LowCardinality(") : len(ct.base)-1]
+		default:
+			return ct
+		}
+	}
+}
+
+// column is a table column name and type.
+type column struct {
+	name string
+	typ  string
+}
+
+// tableSchema is the set of columns of a table, indexed by name.
+type tableSchema map[string]columnType
+
+// tableOptions are the table settings used when creating a table.
+type tableOptions struct {
+	engine      string
+	orderBy     string
+	partitionBy string
+	ttl         string
+}
+
+var identEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// quoteIdent quotes the identifier s with backticks,
+// escaping the backslashes and backticks it contains.
+func quoteIdent(s string) string {
+	return "`" + identEscaper.Replace(s) + "`"
+}
+
+func tableIdent(db, table string) string {
+	if db == "" {
+		return quoteIdent(table)
+	}
+	return quoteIdent(db) + "." + quoteIdent(table)
+}
+
+func createTableQuery(db, table string, cols []column, opts *tableOptions) string {
+	sb := new(strings.Builder)
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(tableIdent(db, table))
+	sb.WriteString(" (")
+	for i, c := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(c.name))
+		sb.WriteString(" ")
+		sb.WriteString(c.typ)
+	}
+	sb.WriteString(") ENGINE = ")
+	sb.WriteString(opts.engine)
+	if opts.partitionBy != "" {
+		sb.WriteString(" PARTITION BY ")
+		sb.WriteString(opts.partitionBy)
+	}
+	sb.WriteString(" ORDER BY (")
+	sb.WriteString(opts.orderBy)
+	sb.WriteString(")")
+	if opts.ttl != "" {
+		sb.WriteString(" TTL ")
+		sb.WriteString(opts.ttl)
+	}
+	return sb.String()
+}
+
+func addColumnsQuery(db, table string, cols []column) string {
+	sb := new(strings.Builder)
+	sb.WriteString("ALTER TABLE ")
+	sb.WriteString(tableIdent(db, table))
+	for i, c := range cols {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(" ADD COLUMN IF NOT EXISTS ")
+		sb.WriteString(quoteIdent(c.name))
+		sb.WriteString(" ")
+		sb.WriteString(c.typ)
+	}
+	return sb.String()
+}
+
+func insertQuery(db, table string, cols []string) string {
+	qcols := make([]string, 0, len(cols))
+	for _, c := range cols {
+		qcols = append(qcols, quoteIdent(c))
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s)", tableIdent(db, table), strings.Join(qcols, ", "))
+}
+
+// mapLayoutColumns returns the columns of a table using the map layout.
+func mapLayoutColumns() []column {
+	return []column{
+		{name: timestampColumn, typ: timestampColumnType},
+		{name: nameColumn, typ: nameColumnType},
+		{name: tagsColumn, typ: tagsColumnType},
+		{name: valuesColumn, typ: valuesColumnType},
+		{name: stringValuesColumn, typ: stringValuesColumnType},
+	}
+}
+
+// eventsColumns returns the columns needed to store the events using the columns layout.
+// Tags are mapped to LowCardinality(String) columns and values to Nullable typed columns.
+// A value with the same name as a tag is ignored.
+func eventsColumns(evs []*formatters.EventMsg) []column {
+	tags := make(map[string]struct{})
+	values := make(map[string]string)
+	for _, ev := range evs {
+		for k := range ev.Tags {
+			if isReservedColumn(k) {
+				continue
+			}
+			tags[k] = struct{}{}
+		}
+		for k, v := range ev.Values {
+			if isReservedColumn(k) {
+				continue
+			}
+			t := valueType(v)
+			if pt, ok := values[k]; ok {
+				t = mergeValueTypes(pt, t)
+			}
+			values[k] = t
+		}
+	}
+	cols := make([]column, 0, 2+len(tags)+len(values))
+	cols = append(cols,
+		column{name: timestampColumn, typ: timestampColumnType},
+		column{name: nameColumn, typ: nameColumnType},
+	)
+	tagNames := make([]string, 0, len(tags))
+	for k := range tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	for _, k := range tagNames {
+		cols = append(cols, column{name: k, typ: tagColumnType})
+	}
+	valueNames := make([]string, 0, len(values))
+	for k := range values {
+		if _, ok := tags[k]; ok {
+			continue
+		}
+		valueNames = append(valueNames, k)
+	}
+	sort.Strings(valueNames)
+	for _, k := range valueNames {
+		cols = append(cols, column{name: k, typ: "Nullable(" + values[k] + ")"})
+	}
+	return cols
+}
+
+func isReservedColumn(name string) bool {
+	return name == timestampColumn || name == nameColumn
+}
+
+// missingColumns returns the columns not present in the table schema.
+func missingColumns(ts tableSchema, cols []column) []column {
+	var missing []column
+	for _, c := range cols {
+		if _, ok := ts[c.name]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// valueType returns the ClickHouse type used to store the value v.
+func valueType(v interface{}) string {
+	switch normalizeValue(v).(type) {
+	case int64:
+		return "Int64"
+	case uint64:
+		return "UInt64"
+	case float64:
+		return "Float64"
+	case bool:
+		return "Bool"
+	default:
+		return "String"
+	}
+}
+
+// mergeValueTypes returns a type able to hold values of both types t1 and t2.
+func mergeValueTypes(t1, t2 string) string {
+	if t1 == t2 {
+		return t1
+	}
+	isNum := func(t string) bool { return t == "Int64" || t == "UInt64" || t == "Float64" }
+	switch {
+	case isNum(t1) && isNum(t2):
+		if t1 == "Float64" || t2 == "Float64" {
+			return "Float64"
+		}
+		return "Int64"
+	default:
+		return "String"
+	}
+}
+
+// normalizeValue converts v to one of int64, uint64, float64, bool or string.
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case uint64:
+		return v
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		return v
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// convertValue converts v to the Go type expected by the driver for the column base type.
+func convertValue(v interface{}, base string) (interface{}, error) {
+	v = normalizeValue(v)
+	switch base {
+	case "String":
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case "Bool":
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case uint64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case "Float64", "Float32":
+		var f float64
+		switch v := v.(type) {
+		case float64:
+			f = v
+		case int64:
+			f = float64(v)
+		case uint64:
+			f = float64(v)
+		case bool:
+			if v {
+				f = 1
+			}
+		case string:
+			var err error
+			f, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if base == "Float32" {
+			return float32(f), nil
+		}
+		return f, nil
+	case "Int8", "Int16", "Int32", "Int64":
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch base {
+		case "Int8":
+			if i < math.MinInt8 || i > math.MaxInt8 {
+				return nil, errOutOfRange(v, base)
+			}
+			return int8(i), nil
+		case "Int16":
+			if i < math.MinInt16 || i > math.MaxInt16 {
+				return nil, errOutOfRange(v, base)
+			}
+			return int16(i), nil
+		case "Int32":
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return nil, errOutOfRange(v, base)
+			}
+			return int32(i), nil
+		}
+		return i, nil
+	case "UInt8", "UInt16", "UInt32", "UInt64":
+		u, err := toUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch base {
+		case "UInt8":
+			if u > math.MaxUint8 {
+				return nil, errOutOfRange(v, base)
+			}
+			return uint8(u), nil
+		case "UInt16":
+			if u > math.MaxUint16 {
+				return nil, errOutOfRange(v, base)
+			}
+			return uint16(u), nil
+		case "UInt32":
+			if u > math.MaxUint32 {
+				return nil, errOutOfRange(v, base)
+			}
+			return uint32(u), nil
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %q", base)
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", v, base)
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, errOutOfRange(v, "Int64")
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v > math.MaxInt64 {
+			return 0, errOutOfRange(v, "Int64")
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to Int64", v)
+}
+
+func toUint64(v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case uint64:
+		return v, nil
+	case int64:
+		if v < 0 {
+			return 0, errOutOfRange(v, "UInt64")
+		}
+		return uint64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v > math.MaxUint64 {
+			return 0, errOutOfRange(v, "UInt64")
+		}
+		return uint64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to UInt64", v)
+}
+
+func errOutOfRange(v interface{}, t string) error {
+	return fmt.Errorf("value %v out of %s range", v, t)
+}
+
+// zeroValue returns the value inserted in a non nullable column
+// when the event has no value for it.
+func zeroValue(base string) interface{} {
+	switch base {
+	case "String":
+		return ""
+	case "Bool":
+		return false
+	case "Float64":
+		return float64(0)
+	case "Float32":
+		return float32(0)
+	case "Int8":
+		return int8(0)
+	case "Int16":
+		return int16(0)
+	case "Int32":
+		return int32(0)
+	case "Int64":
+		return int64(0)
+	case "UInt8":
+		return uint8(0)
+	case "UInt16":
+		return uint16(0)
+	case "UInt32":
+		return uint32(0)
+	case "UInt64":
+		return uint64(0)
+	}
+	return nil
+}
+
+func eventTime(ev *formatters.EventMsg) time.Time {
+	if ev.Timestamp > 0 {
+		return time.Unix(0, ev.Timestamp).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// columnsRow builds the row of an event for the given insert columns.
+// Values that cannot be converted to their column type are skipped and returned as errors.
+func columnsRow(ev *formatters.EventMsg, cols []string, ts tableSchema) ([]interface{}, []error) {
+	row := make([]interface{}, 0, len(cols))
+	var errs []error
+	for _, c := range cols {
+		switch c {
+		case timestampColumn:
+			row = append(row, eventTime(ev))
+			continue
+		case nameColumn:
+			row = append(row, ev.Name)
+			continue
+		}
+		ct := ts[c]
+		var v interface{}
+		var ok bool
+		if v, ok = ev.Tags[c]; !ok {
+			v, ok = ev.Values[c]
+		}
+		if ok {
+			cv, err := convertValue(v, ct.base)
+			if err == nil {
+				row = append(row, cv)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("column %q: %v", c, err))
+		}
+		if ct.nullable {
+			row = append(row, nil)
+			continue
+		}
+		row = append(row, zeroValue(ct.base))
+	}
+	return row, errs
+}
+
+// mapRow builds the row of an event using the map layout.
+// Numeric and boolean values are stored in the values column,
+// other values in the string_values column.
+func mapRow(ev *formatters.EventMsg) []interface{} {
+	tags := make(map[string]string, len(ev.Tags))
+	for k, v := range ev.Tags {
+		tags[k] = v
+	}
+	values := make(map[string]float64)
+	strValues := make(map[string]string)
+	for k, v := range ev.Values {
+		switch v := normalizeValue(v).(type) {
+		case int64:
+			values[k] = float64(v)
+		case uint64:
+			values[k] = float64(v)
+		case float64:
+			values[k] = v
+		case bool:
+			if v {
+				values[k] = 1
+			} else {
+				values[k] = 0
+			}
+		case string:
+			strValues[k] = v
+		}
+	}
+	return []interface{}{eventTime(ev), ev.Name, tags, values, strValues}
+}
@@ -0,0 +1,182 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package clickhouse_output
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func TestParseColumnType(t *testing.T) {
+	tests := map[string]columnType{
+		"Int64":                            {name: "Int64", base: "Int64"},
+		"Nullable(Float64)":                {name: "Nullable(Float64)", base: "Float64", nullable: true},
+		"LowCardinality(String)":           {name: "LowCardinality(String)", base: "String"},
+		"LowCardinality(Nullable(String))": {name: "LowCardinality(Nullable(String))", base: "String", nullable: true},
+	}
+	for in, want := range tests {
+		if got := parseColumnType(in); got != want {
+			t.Errorf("%s: got %+v, want %+v", in, got, want)
+		}
+	}
+}
+
+func TestEventsColumns(t *testing.T) {
+	evs := []*formatters.EventMsg{
+		{
+			Name: "sub1",
+			Tags: map[string]string{"source": "r1", "interface_name": "eth0"},
+			Values: map[string]interface{}{
+				"/interface/state/counters/in-octets": uint64(10),
+				"/interface/state/oper-status":        "UP",
+				"/interface/state/mtu":                int64(1500),
+			},
+		},
+		{
+			Name: "sub1",
+			Tags: map[string]string{"source": "r2", "name": "ignored"},
+			Values: map[string]interface{}{
+				"/interface/state/counters/in-octets": int64(5),
+				"/interface/state/mtu":                1500.5,
+				"source":                              "ignored",
+			},
+		},
+	}
+	want := []column{
+		{name: "timestamp", typ: timestampColumnType},
+		{name: "name", typ: nameColumnType},
+		{name: "interface_name", typ: tagColumnType},
+		{name: "source", typ: tagColumnType},
+		{name: "/interface/state/counters/in-octets", typ: "Nullable(Int64)"},
+		{name: "/interface/state/mtu", typ: "Nullable(Float64)"},
+		{name: "/interface/state/oper-status", typ: "Nullable(String)"},
+	}
+	got := eventsColumns(evs)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	ts := tableSchema{
+		"timestamp":            parseColumnType(timestampColumnType),
+		"name":                 parseColumnType(nameColumnType),
+		"source":               parseColumnType(tagColumnType),
+		"/interface/state/mtu": parseColumnType("Nullable(Float64)"),
+	}
+	missing := missingColumns(ts, got)
+	if len(missing) != 3 || missing[0].name != "interface_name" {
+		t.Errorf("unexpected missing columns: %+v", missing)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	cols := []column{
+		{name: "timestamp", typ: timestampColumnType},
+		{name: "/a/b", typ: "Nullable(Int64)"},
+	}
+	opts := &tableOptions{
+		engine:      "MergeTree",
+		orderBy:     "name, timestamp",
+		partitionBy: "toYYYYMMDD(timestamp)",
+		ttl:         "toDateTime(timestamp) + INTERVAL 30 DAY",
+	}
+	got := createTableQuery("db", "sub1", cols, opts)
+	want := "CREATE TABLE IF NOT EXISTS `db`.`sub1` (`timestamp` DateTime64(9, 'UTC'), `/a/b` Nullable(Int64)) " +
+		"ENGINE = MergeTree PARTITION BY toYYYYMMDD(timestamp) ORDER BY (name, timestamp) TTL toDateTime(timestamp) + INTERVAL 30 DAY"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	got = addColumnsQuery("db", "sub1", cols[1:])
+	want = "ALTER TABLE `db`.`sub1` ADD COLUMN IF NOT EXISTS `/a/b` Nullable(Int64)"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	got = insertQuery("db", "sub1", []string{"timestamp", "/a/b"})
+	want = "INSERT INTO `db`.`sub1` (`timestamp`, `/a/b`)"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := map[string]string{
+		"in-octets":  "`in-octets`",
+		"a`b":        "`a\\`b`",
+		"name\\":     "`name\\\\`",
+		"x\\`; DROP": "`x\\\\\\`; DROP`",
+	}
+	for in, want := range tests {
+		if got := quoteIdent(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		base    string
+		want    interface{}
+		wantErr bool
+	}{
+		{in: int64(1), base: "Float64", want: float64(1)},
+		{in: uint64(42), base: "Int32", want: int32(42)},
+		{in: "42", base: "UInt64", want: uint64(42)},
+		{in: 1.5, base: "Int64", wantErr: true},
+		{in: int64(-1), base: "UInt8", wantErr: true},
+		{in: int64(300), base: "UInt8", wantErr: true},
+		{in: true, base: "String", want: "true"},
+		{in: "true", base: "Bool", want: true},
+		{in: map[string]interface{}{"a": 1}, base: "String", want: `{"a":1}`},
+		{in: "x", base: "Array(String)", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := convertValue(tt.in, tt.base)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v to %s: expected an error", tt.in, tt.base)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v to %s: unexpected error: %v", tt.in, tt.base, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v to %s: got %#v, want %#v", tt.in, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestColumnsRow(t *testing.T) {
+	ts := tableSchema{
+		"timestamp": parseColumnType(timestampColumnType),
+		"name":      parseColumnType(nameColumnType),
+		"source":    parseColumnType(tagColumnType),
+		"/a":        parseColumnType("Nullable(Int64)"),
+		"/b":        parseColumnType("Nullable(String)"),
+		"/c":        parseColumnType("UInt32"),
+	}
+	ev := &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1000,
+		Tags:      map[string]string{"source": "r1"},
+		Values:    map[string]interface{}{"/a": "not-a-number", "/b": "x"},
+	}
+	row, errs := columnsRow(ev, []string{"timestamp", "name", "source", "/a", "/b", "/c"}, ts)
+	want := []interface{}{time.Unix(0, 1000).UTC(), "sub1", "r1", nil, "x", uint32(0)}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("got %#v\nwant %#v", row, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got %d errors, want 1", len(errs))
+	}
+}
@@ -0,0 +1,383 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	backoff         = 100 * time.Millisecond
+	maxBackoff      = 5 * time.Second
+	drainTimeout    = 5 * time.Second
+	timestampField  = "@timestamp"
+	contentTypeJSON = "application/x-ndjson"
+)
+
+// bulkItem is a single document pending indexing.
+type bulkItem struct {
+	index string
+	doc   []byte
+}
+
+// document is the indexed representation of an event.
+type document struct {
+	*formatters.EventMsg
+	Time string `json:"@timestamp,omitempty"`
+}
+
+type bulkResponse struct {
+	Errors bool                             `json:"errors,omitempty"`
+	Items  []map[string]*bulkResponseResult `json:"items,omitempty"`
+}
+
+type bulkResponseResult struct {
+	Status int             `json:"status,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+func (e *elasticsearchOutput) createHTTPClient() error {
+	c := &http.Client{
+		Timeout: e.cfg.Timeout,
+	}
+	if e.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			e.cfg.TLS.CaFile,
+			e.cfg.TLS.CertFile,
+			e.cfg.TLS.KeyFile,
+			"",
+			e.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	e.httpClient = c
+	return nil
+}
+
+func (e *elasticsearchOutput) newBulkItem(ev *formatters.EventMsg) (*bulkItem, error) {
+	ts := time.Now()
+	if ev.Timestamp > 0 {
+		ts = time.Unix(0, ev.Timestamp)
+	}
+	index, err := e.indexName(ev, ts)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(&document{
+		EventMsg: ev,
+		Time:     ts.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bulkItem{index: index, doc: b}, nil
+}
+
+// indexName executes the index template against the event and
+// appends the rollover date suffix.
+func (e *elasticsearchOutput) indexName(ev *formatters.EventMsg, ts time.Time) (string, error) {
+	b := new(bytes.Buffer)
+	err := e.indexTpl.Execute(b, ev)
+	if err != nil {
+		return "", err
+	}
+	index := strings.ToLower(strings.TrimSpace(b.String()))
+	if index == "" {
+		return "", errors.New("index template rendered an empty index name")
+	}
+	return index + rolloverSuffix(e.cfg.Rollover, ts), nil
+}
+
+func rolloverSuffix(rollover string, ts time.Time) string {
+	ts = ts.UTC()
+	switch rollover {
+	case "hourly":
+		return ts.Format("-2006.01.02.15")
+	case "daily":
+		return ts.Format("-2006.01.02")
+	case "weekly":
+		y, w := ts.ISOWeek()
+		return fmt.Sprintf("-%d.w%02d", y, w)
+	case "monthly":
+		return ts.Format("-2006.01")
+	}
+	return ""
+}
+
+func (e *elasticsearchOutput) writer(ctx context.Context) {
+	defer e.wg.Done()
+	e.logger.Printf("starting writer")
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	items := make([]*bulkItem, 0, e.cfg.BulkSize)
+	for {
+		select {
+		case <-ctx.Done():
+			e.drain(items)
+			return
+		case item := <-e.docCh:
+			items = append(items, item)
+			if len(items) < e.cfg.BulkSize {
+				continue
+			}
+			if e.cfg.Debug {
+				e.logger.Printf("bulk size reached, writing to elasticsearch")
+			}
+			e.write(ctx, items)
+			items = items[:0]
+		case <-ticker.C:
+			if len(items) == 0 {
+				continue
+			}
+			if e.cfg.Debug {
+				e.logger.Printf("flush interval reached, writing to elasticsearch")
+			}
+			e.write(ctx, items)
+			items = items[:0]
+		}
+	}
+}
+
+// drain writes the pending documents when the output is closed.
+func (e *elasticsearchOutput) drain(items []*bulkItem) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for {
+		select {
+		case item := <-e.docCh:
+			items = append(items, item)
+			if len(items) >= e.cfg.BulkSize {
+				e.write(ctx, items)
+				items = items[:0]
+			}
+		default:
+			if len(items) > 0 {
+				e.write(ctx, items)
+			}
+			return
+		}
+	}
+}
+
+// write sends the items using the bulk API, documents rejected
+// with a retryable status are sent again up to max-retries times.
+func (e *elasticsearchOutput) write(ctx context.Context, items []*bulkItem) {
+	retries := 0
+	wait := backoff
+	for {
+		start := time.Now()
+		failed, err := e.bulk(ctx, items)
+		elasticsearchBulkDuration.WithLabelValues(e.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		if err == nil && len(failed) == 0 {
+			return
+		}
+		if err != nil {
+			e.logger.Printf("bulk request failed: %v", err)
+			failed = items
+		}
+		if retries >= e.cfg.MaxRetries {
+			elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, "max_retries").Add(float64(len(failed)))
+			e.logger.Printf("dropping %d document(s) after %d retries", len(failed), retries)
+			return
+		}
+		retries++
+		elasticsearchNumberOfRetriedDocs.WithLabelValues(e.cfg.Name).Add(float64(len(failed)))
+		if e.cfg.Debug {
+			e.logger.Printf("retrying %d document(s) in %s", len(failed), wait)
+		}
+		select {
+		case <-ctx.Done():
+			elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, "canceled").Add(float64(len(failed)))
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		items = failed
+	}
+}
+
+// writeSync sends the documents built from evs in a single bulk request,
+// the documents are not retried, an error is returned if any of them should be.
+func (e *elasticsearchOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	items := make([]*bulkItem, 0, len(evs))
+	for _, ev := range evs {
+		item, err := e.newBulkItem(ev)
+		if err != nil {
+			e.logger.Printf("failed to build document: %v", err)
+			elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, "marshal_error").Inc()
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	retry, err := e.bulk(ctx, items)
+	if err != nil {
+		return err
+	}
+	if len(retry) > 0 {
+		return fmt.Errorf("%d document(s) rejected with a retryable status", len(retry))
+	}
+	return nil
+}
+
+// bulk sends a single bulk request and returns the items that should be retried.
+func (e *elasticsearchOutput) bulk(ctx context.Context, items []*bulkItem) ([]*bulkItem, error) {
+	body, err := bulkBody(e.cfg.OpType, items)
+	if err != nil {
+		return nil, err
+	}
+	req, err := e.makeHTTPRequest(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if e.cfg.Debug {
+		e.logger.Printf("got response from %s: status=%s", req.URL.Host, rsp.Status)
+	}
+	if rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= 500 {
+		return nil, fmt.Errorf("bulk response failed, code=%d, body=%s", rsp.StatusCode, string(b))
+	}
+	if rsp.StatusCode >= 300 {
+		// the whole request is rejected, retrying won't help.
+		elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, fmt.Sprintf("status_code=%d", rsp.StatusCode)).Add(float64(len(items)))
+		e.logger.Printf("bulk response failed, code=%d, body=%s", rsp.StatusCode, string(b))
+		return nil, nil
+	}
+	retry, sent, rejected, err := parseBulkResponse(b, items)
+	if err != nil {
+		return nil, err
+	}
+	elasticsearchNumberOfSentDocs.WithLabelValues(e.cfg.Name).Add(float64(sent))
+	for _, r := range rejected {
+		elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, fmt.Sprintf("status_code=%d", r.Status)).Inc()
+		if e.cfg.Debug {
+			e.logger.Printf("document rejected: status=%d, error=%s", r.Status, string(r.Error))
+		}
+	}
+	return retry, nil
+}
+
+func (e *elasticsearchOutput) makeHTTPRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	idx := e.addrIdx.Add(1) - 1
+	addr := e.cfg.Addresses[idx%uint64(len(e.cfg.Addresses))]
+	u := addr + "/_bulk"
+	if e.cfg.Pipeline != "" {
+		u += "?pipeline=" + url.QueryEscape(e.cfg.Pipeline)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case e.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.cfg.APIKey)
+	case e.cfg.Authentication != nil:
+		req.SetBasicAuth(e.cfg.Authentication.Username, e.cfg.Authentication.Password)
+	}
+	return req, nil
+}
+
+// bulkBody builds the newline delimited bulk request body.
+func bulkBody(opType string, items []*bulkItem) ([]byte, error) {
+	b := new(bytes.Buffer)
+	for _, item := range items {
+		action, err := json.Marshal(map[string]map[string]string{
+			opType: {"_index": item.index},
+		})
+		if err != nil {
+			return nil, err
+		}
+		b.Write(action)
+		b.WriteByte('\n')
+		b.Write(item.doc)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// parseBulkResponse matches the bulk response items with the sent items.
+// It returns the items to be retried, the number of indexed items and
+// the results of the items that were rejected with a non retryable status.
+func parseBulkResponse(b []byte, items []*bulkItem) ([]*bulkItem, int, []*bulkResponseResult, error) {
+	br := new(bulkResponse)
+	err := json.Unmarshal(b, br)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to parse bulk response: %v", err)
+	}
+	if !br.Errors {
+		return nil, len(items), nil, nil
+	}
+	if len(br.Items) != len(items) {
+		return nil, 0, nil, fmt.Errorf("unexpected number of items in bulk response: got %d, expected %d", len(br.Items), len(items))
+	}
+	var retry []*bulkItem
+	var rejected []*bulkResponseResult
+	sent := 0
+	for i, it := range br.Items {
+		for _, r := range it {
+			switch {
+			case r == nil:
+			case r.Status < 300:
+				sent++
+			case r.Status == http.StatusTooManyRequests || r.Status >= 500:
+				retry = append(retry, items[i])
+			default:
+				rejected = append(rejected, r)
+			}
+		}
+	}
+	return retry, sent, rejected, nil
+}
+
+func joinPaths(prefix, p *gnmi.Path) string {
+	pf := path.GnmiPathToXPath(prefix, false)
+	if pf == "" {
+		return path.GnmiPathToXPath(p, false)
+	}
+	return strings.TrimSuffix(pf, "/") + "/" + path.GnmiPathToXPath(p, false)
+}
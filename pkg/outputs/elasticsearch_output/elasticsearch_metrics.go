@@ -0,0 +1,84 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "elasticsearch_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var elasticsearchNumberOfSentDocs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_docs_sent_success_total",
+	Help:      "Number of documents successfully indexed by gnmic elasticsearch output",
+}, []string{"name"})
+
+var elasticsearchNumberOfFailedDocs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_docs_sent_fail_total",
+	Help:      "Number of documents gnmic elasticsearch output failed to index",
+}, []string{"name", "reason"})
+
+var elasticsearchNumberOfRetriedDocs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_docs_retried_total",
+	Help:      "Number of documents retried by gnmic elasticsearch output",
+}, []string{"name"})
+
+var elasticsearchBulkDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "bulk_request_duration_ns",
+	Help:      "gnmic elasticsearch output bulk request duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	elasticsearchNumberOfSentDocs.WithLabelValues(name).Add(0)
+	elasticsearchNumberOfFailedDocs.WithLabelValues(name, "").Add(0)
+	elasticsearchNumberOfRetriedDocs.WithLabelValues(name).Add(0)
+	elasticsearchBulkDuration.WithLabelValues(name).Set(0)
+}
+
+func (e *elasticsearchOutput) registerMetrics() error {
+	if e.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = e.reg.Register(elasticsearchNumberOfSentDocs); err != nil {
+			e.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = e.reg.Register(elasticsearchNumberOfFailedDocs); err != nil {
+			e.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = e.reg.Register(elasticsearchNumberOfRetriedDocs); err != nil {
+			e.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = e.reg.Register(elasticsearchBulkDuration); err != nil {
+			e.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(e.cfg.Name)
+	return err
+}
@@ -0,0 +1,473 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "elasticsearch"
+	loggingPrefix        = "[elasticsearch_output:%s] "
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 1000
+	defaultBulkSize      = 500
+	defaultMaxRetries    = 3
+	defaultNumWorkers    = 1
+	defaultIndex         = "gnmic"
+	defaultOpType        = "index"
+	userAgent            = "gNMIc elasticsearch"
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &elasticsearchOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type elasticsearchOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	httpClient *http.Client
+	eventChan  chan *formatters.EventMsg
+	msgChan    chan *outputs.ProtoMsg
+	docCh      chan *bulkItem
+	// index of the next address to use
+	addrIdx atomic.Uint64
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	indexTpl  *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name           string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Addresses      []string          `mapstructure:"addresses,omitempty" json:"addresses,omitempty"`
+	Timeout        time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers        map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	Authentication *auth             `mapstructure:"authentication,omitempty" json:"authentication,omitempty"`
+	APIKey         string            `mapstructure:"api-key,omitempty" json:"api-key,omitempty"`
+	TLS            *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	// document format, `event` or `flat`.
+	Format string `mapstructure:"format,omitempty" json:"format,omitempty"`
+	// index name template
+	Index string `mapstructure:"index,omitempty" json:"index,omitempty"`
+	// date based index suffix, one of `hourly`, `daily`, `weekly` or `monthly`.
+	Rollover string `mapstructure:"rollover,omitempty" json:"rollover,omitempty"`
+	// ingest pipeline
+	Pipeline string `mapstructure:"pipeline,omitempty" json:"pipeline,omitempty"`
+	// bulk action, `index` or `create`.
+	OpType          string        `mapstructure:"op-type,omitempty" json:"op-type,omitempty"`
+	FlushInterval   time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	BulkSize        int           `mapstructure:"bulk-size,omitempty" json:"bulk-size,omitempty"`
+	MaxRetries      int           `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	AddTarget       string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics   bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+type auth struct {
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"password,omitempty"`
+}
+
+func (e *elasticsearchOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, e.cfg)
+	if err != nil {
+		return err
+	}
+	if e.cfg.Name == "" {
+		e.cfg.Name = name
+	}
+	e.logger.SetPrefix(fmt.Sprintf(loggingPrefix, e.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return err
+		}
+	}
+	err = e.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = e.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if e.cfg.TargetTemplate == "" {
+		e.targetTpl = outputs.DefaultTargetTemplate
+	} else if e.cfg.AddTarget != "" {
+		e.targetTpl, err = gtemplate.CreateTemplate("target-template", e.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		e.targetTpl = e.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	e.indexTpl, err = gtemplate.CreateTemplate("index", e.cfg.Index)
+	if err != nil {
+		return fmt.Errorf("failed to parse index template: %v", err)
+	}
+	e.indexTpl = e.indexTpl.Funcs(outputs.TemplateFuncs)
+
+	err = e.createHTTPClient()
+	if err != nil {
+		return err
+	}
+	e.docCh = make(chan *bulkItem, e.cfg.BufferSize)
+
+	ctx, e.cfn = context.WithCancel(ctx)
+	for i := 0; i < e.cfg.NumWorkers; i++ {
+		go e.worker(ctx)
+	}
+	e.wg.Add(1)
+	go e.writer(ctx)
+	e.logger.Printf("initialized elasticsearch output %s: %s", e.cfg.Name, e.String())
+	return nil
+}
+
+func (e *elasticsearchOutput) setDefaults() error {
+	if len(e.cfg.Addresses) == 0 {
+		return errors.New("missing addresses field")
+	}
+	for i, addr := range e.cfg.Addresses {
+		if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+			if e.cfg.TLS != nil {
+				addr = "https://" + addr
+			} else {
+				addr = "http://" + addr
+			}
+		}
+		_, err := url.Parse(addr)
+		if err != nil {
+			return err
+		}
+		e.cfg.Addresses[i] = strings.TrimSuffix(addr, "/")
+	}
+	switch e.cfg.Format {
+	case "flat":
+	default:
+		e.cfg.Format = "event"
+	}
+	switch e.cfg.Rollover {
+	case "", "hourly", "daily", "weekly", "monthly":
+	default:
+		return fmt.Errorf("unknown rollover value %q", e.cfg.Rollover)
+	}
+	switch e.cfg.OpType {
+	case "":
+		e.cfg.OpType = defaultOpType
+	case "index", "create":
+	default:
+		return fmt.Errorf("unknown op-type value %q", e.cfg.OpType)
+	}
+	if e.cfg.Index == "" {
+		e.cfg.Index = defaultIndex
+	}
+	if e.cfg.Timeout <= 0 {
+		e.cfg.Timeout = defaultTimeout
+	}
+	if e.cfg.FlushInterval <= 0 {
+		e.cfg.FlushInterval = defaultFlushInterval
+	}
+	if e.cfg.BufferSize <= 0 {
+		e.cfg.BufferSize = defaultBufferSize
+	}
+	if e.cfg.BulkSize <= 0 {
+		e.cfg.BulkSize = defaultBulkSize
+	}
+	if e.cfg.MaxRetries < 0 {
+		e.cfg.MaxRetries = 0
+	} else if e.cfg.MaxRetries == 0 {
+		e.cfg.MaxRetries = defaultMaxRetries
+	}
+	if e.cfg.NumWorkers <= 0 {
+		e.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
+
+func (e *elasticsearchOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case e.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if e.cfg.Debug {
+			e.logger.Printf("writing expired after %s", e.cfg.Timeout)
+		}
+		elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (e *elasticsearchOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range e.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case e.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync indexes the documents built from a message in a single bulk request,
+// it returns once elasticsearch accepted all of them.
+func (e *elasticsearchOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := e.protoEvents(rsp, meta)
+	if err != nil {
+		e.logger.Printf("%v", err)
+		return nil
+	}
+	return e.writeSync(ctx, events)
+}
+
+// WriteEventSync indexes an event in a single bulk request,
+// it returns once elasticsearch accepted the resulting documents.
+func (e *elasticsearchOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range e.evps {
+		evs = proc.Apply(evs...)
+	}
+	return e.writeSync(ctx, evs)
+}
+
+func (e *elasticsearchOutput) Close() error {
+	if e.cfn == nil {
+		return nil
+	}
+	e.cfn()
+	// wait for the buffered documents to be flushed
+	e.wg.Wait()
+	return nil
+}
+
+func (e *elasticsearchOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !e.cfg.EnableMetrics {
+		return
+	}
+	e.reg = reg
+}
+
+func (e *elasticsearchOutput) String() string {
+	cfg := *e.cfg
+	if cfg.Authentication != nil {
+		cfg.Authentication = &auth{Username: cfg.Authentication.Username, Password: "****"}
+	}
+	if cfg.APIKey != "" {
+		cfg.APIKey = "****"
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (e *elasticsearchOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && e.logger != nil {
+		e.logger.SetOutput(logger.Writer())
+		e.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (e *elasticsearchOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	e.evps, err = formatters.MakeEventProcessors(
+		logger,
+		e.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *elasticsearchOutput) SetName(name string) {
+	if e.cfg.Name == "" {
+		e.cfg.Name = name
+	}
+}
+
+func (e *elasticsearchOutput) SetClusterName(_ string) {}
+
+func (e *elasticsearchOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (e *elasticsearchOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-e.eventChan:
+			e.workerHandleEvent(ctx, ev)
+		case m := <-e.msgChan:
+			e.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (e *elasticsearchOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	events, err := e.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		e.logger.Printf("%v", err)
+		return
+	}
+	for _, ev := range events {
+		e.workerHandleEvent(ctx, ev)
+	}
+}
+
+// protoEvents converts a SubscribeResponse into the events to index
+// according to the configured format.
+func (e *elasticsearchOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	rsp, err := outputs.AddSubscriptionTarget(pmsg, meta, e.cfg.AddTarget, e.targetTpl)
+	if err != nil {
+		e.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if rsp != nil {
+		pmsg = rsp
+	}
+	if e.cfg.Format == "flat" {
+		ev, err := flatEvent(subName, pmsg, meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to flatten message: %v", err)
+		}
+		if ev == nil {
+			return nil, nil
+		}
+		return []*formatters.EventMsg{ev}, nil
+	}
+	events, err := formatters.ResponseToEventMsgs(subName, pmsg, meta, e.evps...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert message to event: %v", err)
+	}
+	return events, nil
+}
+
+func (e *elasticsearchOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	item, err := e.newBulkItem(ev)
+	if err != nil {
+		e.logger.Printf("failed to build document: %v", err)
+		elasticsearchNumberOfFailedDocs.WithLabelValues(e.cfg.Name, "marshal_error").Inc()
+		return
+	}
+	if e.cfg.Debug {
+		e.logger.Printf("buffering document for index %q: %s", item.index, item.doc)
+	}
+	select {
+	case <-ctx.Done():
+	case e.docCh <- item:
+	}
+}
+
+// flatEvent converts a SubscribeResponse into a single event
+// with the flattened notification paths as values.
+func flatEvent(name string, rsp *gnmi.SubscribeResponse, meta map[string]string) (*formatters.EventMsg, error) {
+	n := rsp.GetUpdate()
+	if n == nil {
+		return nil, nil
+	}
+	values, err := formatters.ResponsesFlat(rsp)
+	if err != nil {
+		return nil, err
+	}
+	ev := &formatters.EventMsg{
+		Name:      name,
+		Timestamp: n.GetTimestamp(),
+		Tags:      make(map[string]string, len(meta)+1),
+		Values:    values,
+	}
+	for k, v := range meta {
+		ev.Tags[k] = v
+	}
+	if t := n.GetPrefix().GetTarget(); t != "" {
+		ev.Tags["target"] = t
+	}
+	for _, d := range n.GetDelete() {
+		ev.Deletes = append(ev.Deletes, "/"+strings.TrimPrefix(joinPaths(n.GetPrefix(), d), "/"))
+	}
+	if len(ev.Values) == 0 && len(ev.Deletes) == 0 {
+		return nil, nil
+	}
+	return ev, nil
+}
@@ -0,0 +1,137 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestIndexName(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)
+	ev := &formatters.EventMsg{
+		Name: "sub1",
+		Tags: map[string]string{"source": "Router1"},
+	}
+	tests := map[string]struct {
+		index    string
+		rollover string
+		want     string
+	}{
+		"static": {
+			index: "gnmic",
+			want:  "gnmic",
+		},
+		"template": {
+			index: "gnmic-{{ .Name }}-{{ index .Tags \"source\" }}",
+			want:  "gnmic-sub1-router1",
+		},
+		"hourly": {
+			index:    "gnmic",
+			rollover: "hourly",
+			want:     "gnmic-2024.03.05.07",
+		},
+		"daily": {
+			index:    "gnmic",
+			rollover: "daily",
+			want:     "gnmic-2024.03.05",
+		},
+		"weekly": {
+			index:    "gnmic",
+			rollover: "weekly",
+			want:     "gnmic-2024.w10",
+		},
+		"monthly": {
+			index:    "gnmic",
+			rollover: "monthly",
+			want:     "gnmic-2024.03",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tpl, err := gtemplate.CreateTemplate("index", tt.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := &elasticsearchOutput{
+				cfg:      &config{Rollover: tt.rollover},
+				indexTpl: tpl.Funcs(outputs.TemplateFuncs),
+			}
+			got, err := e.indexName(ev, ts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkBody(t *testing.T) {
+	items := []*bulkItem{
+		{index: "idx1", doc: []byte(`{"name":"a"}`)},
+		{index: "idx2", doc: []byte(`{"name":"b"}`)},
+	}
+	b, err := bulkBody("create", items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		`{"create":{"_index":"idx1"}}`,
+		`{"name":"a"}`,
+		`{"create":{"_index":"idx2"}}`,
+		`{"name":"b"}`,
+		``,
+	}, "\n")
+	if string(b) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b, want)
+	}
+}
+
+func TestParseBulkResponse(t *testing.T) {
+	items := []*bulkItem{
+		{index: "idx1"},
+		{index: "idx2"},
+		{index: "idx3"},
+		{index: "idx4"},
+	}
+	rsp := `{"took":3,"errors":true,"items":[
+{"index":{"_index":"idx1","status":201}},
+{"index":{"_index":"idx2","status":429,"error":{"type":"es_rejected_execution_exception"}}},
+{"index":{"_index":"idx3","status":400,"error":{"type":"mapper_parsing_exception"}}},
+{"index":{"_index":"idx4","status":503,"error":{"type":"unavailable_shards_exception"}}}
+]}`
+	retry, sent, rejected, err := parseBulkResponse([]byte(rsp), items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("got %d sent items, want 1", sent)
+	}
+	if len(rejected) != 1 || rejected[0].Status != 400 {
+		t.Errorf("unexpected rejected items: %v", rejected)
+	}
+	if len(retry) != 2 || retry[0].index != "idx2" || retry[1].index != "idx4" {
+		t.Errorf("unexpected retry items: %v", retry)
+	}
+
+	retry, sent, _, err = parseBulkResponse([]byte(`{"errors":false,"items":[]}`), items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != len(items) || len(retry) != 0 {
+		t.Errorf("got sent=%d retry=%d, want sent=%d retry=0", sent, len(retry), len(items))
+	}
+}
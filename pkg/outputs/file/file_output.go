@@ -19,8 +19,10 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/semaphore"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/openconfig/gnmic/pkg/api/types"
@@ -50,6 +52,7 @@ func init() {
 type File struct {
 	cfg    *Config
 	file   file
+	pq     *parquetFile
 	logger *log.Logger
 	mo     *formatters.MarshalOptions
 	sem    *semaphore.Weighted
@@ -81,6 +84,7 @@ type Config struct {
 	Debug              bool            `mapstructure:"debug,omitempty"`
 	CalculateLatency   bool            `mapstructure:"calculate-latency,omitempty"`
 	Rotation           *rotationConfig `mapstructure:"rotation,omitempty"`
+	Parquet            *parquetConfig  `mapstructure:"parquet,omitempty"`
 }
 
 type file interface {
@@ -143,8 +147,8 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	if err != nil {
 		return err
 	}
-	if f.cfg.Format == "proto" {
-		return fmt.Errorf("proto format not supported in output type 'file'")
+	if f.cfg.Format == "proto" && f.cfg.MsgTemplate != "" {
+		return fmt.Errorf("msg-template not supported with format 'proto' in output type 'file'")
 	}
 	if f.cfg.Separator == "" {
 		f.cfg.Separator = defaultSeparator
@@ -158,6 +162,21 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 		f.file = os.Stdout
 	case "stderr":
 		f.file = os.Stderr
+	case "parquet":
+		if f.cfg.FileName == "" {
+			return fmt.Errorf("file-type 'parquet' requires a filename")
+		}
+		f.pq, err = newParquetFile(f.cfg, f.logger)
+		if err != nil {
+			return err
+		}
+		f.pq.onWrite = func(rows int, bytes int64) {
+			numberOfWrittenMsgs.WithLabelValues(f.cfg.Name, f.pq.Name()).Add(float64(rows))
+			numberOfWrittenBytes.WithLabelValues(f.cfg.Name, f.pq.Name()).Add(float64(bytes))
+		}
+		f.pq.onError = func(reason string) {
+			numberOfFailWriteMsgs.WithLabelValues(f.cfg.Name, f.pq.Name(), reason).Inc()
+		}
 	default:
 	CRFILE:
 		if f.cfg.Rotation != nil {
@@ -241,6 +260,10 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 	}
 	defer f.sem.Release(1)
 
+	if f.pq != nil {
+		f.writeParquet(rsp, meta)
+		return
+	}
 	numberOfReceivedMsgs.WithLabelValues(f.cfg.Name, f.file.Name()).Inc()
 	rsp, err = outputs.AddSubscriptionTarget(rsp, meta, f.cfg.AddTarget, f.targetTpl)
 	if err != nil {
@@ -269,7 +292,7 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 			}
 		}
 
-		n, err := f.file.Write(append(b, []byte(f.cfg.Separator)...))
+		n, err := f.file.Write(f.frame(b))
 		if err != nil {
 			if f.cfg.Debug {
 				f.logger.Printf("failed to write to file '%s': %v", f.file.Name(), err)
@@ -292,6 +315,20 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 	for _, proc := range f.evps {
 		evs = proc.Apply(evs...)
 	}
+	if f.pq != nil {
+		numberOfReceivedMsgs.WithLabelValues(f.cfg.Name, f.pq.Name()).Inc()
+		f.writeParquetEvents(evs)
+		return
+	}
+	if f.cfg.Format == "proto" {
+		// events have no proto representation, writing them as JSON
+		// would break the length-prefixed framing of the file.
+		if f.cfg.Debug {
+			f.logger.Printf("dropping event: format 'proto' does not support events")
+		}
+		numberOfFailWriteMsgs.WithLabelValues(f.cfg.Name, f.file.Name(), "format_error").Inc()
+		return
+	}
 	toWrite := []byte{}
 	if f.cfg.SplitEvents {
 		for _, pev := range evs {
@@ -337,8 +374,60 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 	numberOfWrittenMsgs.WithLabelValues(f.cfg.Name, f.file.Name()).Inc()
 }
 
+// frame delimits a marshaled message before it is written to the file.
+// proto messages are prefixed with their varint encoded length,
+// other formats are followed by the configured separator.
+func (f *File) frame(b []byte) []byte {
+	if f.cfg.Format == "proto" {
+		fb := make([]byte, 0, protowire.SizeVarint(uint64(len(b)))+len(b))
+		fb = protowire.AppendVarint(fb, uint64(len(b)))
+		return append(fb, b...)
+	}
+	return append(b, []byte(f.cfg.Separator)...)
+}
+
+func (f *File) writeParquet(rsp proto.Message, meta outputs.Meta) {
+	numberOfReceivedMsgs.WithLabelValues(f.cfg.Name, f.pq.Name()).Inc()
+	rsp, err := outputs.AddSubscriptionTarget(rsp, meta, f.cfg.AddTarget, f.targetTpl)
+	if err != nil {
+		f.logger.Printf("failed to add target to the response: %v", err)
+	}
+	subscriptionName, ok := meta["subscription-name"]
+	if !ok {
+		subscriptionName = "default"
+	}
+	var evs []*formatters.EventMsg
+	switch rsp := rsp.(type) {
+	case *gnmi.SubscribeResponse:
+		evs, err = formatters.ResponseToEventMsgs(subscriptionName, rsp, meta, f.evps...)
+	case *gnmi.GetResponse:
+		evs, err = formatters.GetResponseToEventMsgs(rsp, meta, f.evps...)
+	default:
+		return
+	}
+	if err != nil {
+		if f.cfg.Debug {
+			f.logger.Printf("failed to convert message to events: %v", err)
+		}
+		numberOfFailWriteMsgs.WithLabelValues(f.cfg.Name, f.pq.Name(), "marshal_error").Inc()
+		return
+	}
+	f.writeParquetEvents(evs)
+}
+
+func (f *File) writeParquetEvents(evs []*formatters.EventMsg) {
+	err := f.pq.WriteEvents(evs)
+	if err != nil && f.cfg.Debug {
+		f.logger.Printf("failed to write to parquet file '%s': %v", f.pq.Name(), err)
+	}
+}
+
 // Close //
 func (f *File) Close() error {
+	if f.pq != nil {
+		f.logger.Printf("closing parquet file '%s' output", f.pq.Name())
+		return f.pq.Close()
+	}
 	f.logger.Printf("closing file '%s' output", f.file.Name())
 	return f.file.Close()
 }
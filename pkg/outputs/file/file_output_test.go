@@ -0,0 +1,60 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFrame(t *testing.T) {
+	f := &File{cfg: &Config{Format: "json", Separator: "\n"}}
+	if got := f.frame([]byte(`{"a":1}`)); string(got) != "{\"a\":1}\n" {
+		t.Errorf("json: got %q", got)
+	}
+
+	f.cfg.Format = "proto"
+	rsps := []*gnmi.SubscribeResponse{
+		{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+			Timestamp: 42,
+			Update: []*gnmi.Update{{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: string(make([]byte, 200))}},
+			}},
+		}}},
+		{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}},
+	}
+	var b []byte
+	for _, rsp := range rsps {
+		pb, err := proto.Marshal(rsp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, f.frame(pb)...)
+	}
+	r := bufio.NewReader(bytes.NewReader(b))
+	for i, want := range rsps {
+		got := new(gnmi.SubscribeResponse)
+		err := protodelim.UnmarshalFrom(r, got)
+		if err != nil {
+			t.Fatalf("msg %d: %v", i, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("msg %d: got %v, want %v", i, got, want)
+		}
+	}
+	if r.Buffered() != 0 {
+		t.Errorf("%d trailing bytes", r.Buffered())
+	}
+}
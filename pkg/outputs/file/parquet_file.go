@@ -0,0 +1,633 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	defaultParquetCompression   = "zstd"
+	defaultParquetRowGroupSize  = 10000
+	defaultParquetRotationSize  = 100 // megabytes
+	defaultParquetRotationEvery = time.Hour
+
+	parquetTimestampColumn = "timestamp"
+	parquetNameColumn      = "name"
+	parquetTagsColumn      = "tags"
+	parquetValuesColumn    = "values"
+
+	parquetFileTimeFormat = "20060102T150405.000Z"
+	parquetTmpPrefix      = "."
+	parquetTmpSuffix      = ".tmp"
+)
+
+type parquetConfig struct {
+	// Compression is the codec used to compress the columns' pages,
+	// one of zstd, snappy, gzip or none.
+	Compression string `mapstructure:"compression,omitempty"`
+	// RowGroupSize is the number of rows buffered in memory before being written as a row group.
+	RowGroupSize int `mapstructure:"row-group-size,omitempty"`
+}
+
+func (c *parquetConfig) setDefaults() error {
+	if c.Compression == "" {
+		c.Compression = defaultParquetCompression
+	}
+	if _, err := parquetCodec(c.Compression); err != nil {
+		return err
+	}
+	if c.RowGroupSize <= 0 {
+		c.RowGroupSize = defaultParquetRowGroupSize
+	}
+	return nil
+}
+
+func parquetCodec(name string) (compress.Codec, error) {
+	switch strings.ToLower(name) {
+	case "zstd":
+		return &parquet.Zstd, nil
+	case "snappy":
+		return &parquet.Snappy, nil
+	case "gzip":
+		return &parquet.Gzip, nil
+	case "none":
+		return &parquet.Uncompressed, nil
+	default:
+		return nil, fmt.Errorf("unknown parquet compression %q", name)
+	}
+}
+
+// valueKind is the parquet type of a value column.
+type valueKind int
+
+const (
+	kindInt64 valueKind = iota
+	kindUint64
+	kindDouble
+	kindBoolean
+	kindString
+)
+
+func (k valueKind) node() parquet.Node {
+	switch k {
+	case kindInt64:
+		return parquet.Int(64)
+	case kindUint64:
+		return parquet.Uint(64)
+	case kindDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	case kindBoolean:
+		return parquet.Leaf(parquet.BooleanType)
+	default:
+		return parquet.String()
+	}
+}
+
+// kindOf returns the kind of the column a value is stored in.
+// Values that are neither numbers nor booleans are stored as strings,
+// maps and lists are JSON encoded.
+func kindOf(v any) valueKind {
+	switch v.(type) {
+	case int, int8, int16, int32, int64:
+		return kindInt64
+	case uint, uint8, uint16, uint32, uint64:
+		return kindUint64
+	case float32, float64:
+		return kindDouble
+	case bool:
+		return kindBoolean
+	default:
+		return kindString
+	}
+}
+
+// mergeKinds returns the kind of a column holding values of kinds a and b.
+func mergeKinds(a, b valueKind) valueKind {
+	switch {
+	case a == b:
+		return a
+	case a == kindString || b == kindString || a == kindBoolean || b == kindBoolean:
+		return kindString
+	default: // mixed numbers
+		return kindDouble
+	}
+}
+
+// convertValue converts v to the Go type written to a column of kind k.
+func convertValue(v any, k valueKind) (any, bool) {
+	switch k {
+	case kindInt64:
+		switch v := v.(type) {
+		case int:
+			return int64(v), true
+		case int8:
+			return int64(v), true
+		case int16:
+			return int64(v), true
+		case int32:
+			return int64(v), true
+		case int64:
+			return v, true
+		}
+	case kindUint64:
+		switch v := v.(type) {
+		case uint:
+			return uint64(v), true
+		case uint8:
+			return uint64(v), true
+		case uint16:
+			return uint64(v), true
+		case uint32:
+			return uint64(v), true
+		case uint64:
+			return v, true
+		}
+	case kindDouble:
+		switch v := v.(type) {
+		case float32:
+			return float64(v), true
+		case float64:
+			return v, true
+		}
+		if i, ok := convertValue(v, kindInt64); ok {
+			return float64(i.(int64)), true
+		}
+		if u, ok := convertValue(v, kindUint64); ok {
+			return float64(u.(uint64)), true
+		}
+	case kindBoolean:
+		if b, ok := v.(bool); ok {
+			return b, true
+		}
+	case kindString:
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case bool:
+			return strconv.FormatBool(v), true
+		case float32:
+			return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), true
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return fmt.Sprintf("%d", v), true
+		case []byte:
+			return string(v), true
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		return string(b), true
+	}
+	return nil, false
+}
+
+// parquetSchema holds the tag and value columns of a parquet file.
+type parquetSchema struct {
+	tags   map[string]struct{}
+	values map[string]valueKind
+}
+
+func newParquetSchema() *parquetSchema {
+	return &parquetSchema{
+		tags:   make(map[string]struct{}),
+		values: make(map[string]valueKind),
+	}
+}
+
+// add merges the event tags and values into the schema,
+// it returns true if a column was added or its type changed.
+func (s *parquetSchema) add(ev *formatters.EventMsg) bool {
+	changed := false
+	for k := range ev.Tags {
+		if _, ok := s.tags[k]; !ok {
+			s.tags[k] = struct{}{}
+			changed = true
+		}
+	}
+	for k, v := range ev.Values {
+		nk := kindOf(v)
+		if ck, ok := s.values[k]; ok {
+			nk = mergeKinds(ck, nk)
+			if nk == ck {
+				continue
+			}
+		}
+		s.values[k] = nk
+		changed = true
+	}
+	return changed
+}
+
+func (s *parquetSchema) copy() *parquetSchema {
+	ns := newParquetSchema()
+	for k := range s.tags {
+		ns.tags[k] = struct{}{}
+	}
+	for k, v := range s.values {
+		ns.values[k] = v
+	}
+	return ns
+}
+
+func (s *parquetSchema) equal(o *parquetSchema) bool {
+	if len(s.tags) != len(o.tags) || len(s.values) != len(o.values) {
+		return false
+	}
+	for k := range s.tags {
+		if _, ok := o.tags[k]; !ok {
+			return false
+		}
+	}
+	for k, v := range s.values {
+		if ov, ok := o.values[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *parquetSchema) parquet() *parquet.Schema {
+	g := parquet.Group{
+		parquetTimestampColumn: parquet.Timestamp(parquet.Nanosecond),
+		parquetNameColumn:      parquet.String(),
+	}
+	if len(s.tags) > 0 {
+		tags := make(parquet.Group, len(s.tags))
+		for k := range s.tags {
+			tags[k] = parquet.Optional(parquet.String())
+		}
+		g[parquetTagsColumn] = tags
+	}
+	values := make(parquet.Group, len(s.values))
+	for k, v := range s.values {
+		values[k] = parquet.Optional(v.node())
+	}
+	g[parquetValuesColumn] = values
+	return parquet.NewSchema("event", g)
+}
+
+// row builds the parquet row of an event,
+// values that cannot be converted to their column type are written as nulls.
+func (s *parquetSchema) row(ev *formatters.EventMsg) map[string]any {
+	r := map[string]any{
+		parquetTimestampColumn: time.Unix(0, ev.Timestamp).UTC(),
+		parquetNameColumn:      ev.Name,
+	}
+	if len(s.tags) > 0 {
+		tags := make(map[string]any, len(ev.Tags))
+		for k, v := range ev.Tags {
+			tags[k] = v
+		}
+		r[parquetTagsColumn] = tags
+	}
+	values := make(map[string]any, len(ev.Values))
+	for k, v := range ev.Values {
+		kind, ok := s.values[k]
+		if !ok {
+			continue
+		}
+		if cv, ok := convertValue(v, kind); ok {
+			values[k] = cv
+		}
+	}
+	r[parquetValuesColumn] = values
+	return r
+}
+
+// countingFile counts the bytes written to the underlying file.
+type countingFile struct {
+	*os.File
+	n int64
+}
+
+func (c *countingFile) Write(b []byte) (int, error) {
+	n, err := c.File.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// parquetFile writes events to parquet files.
+// Rows are buffered in memory and written as row groups.
+// A file is rotated when it reaches the max size or age, or when its schema changes.
+// Finished files are moved to a date partitioned directory:
+// $dir/year=YYYY/month=MM/day=DD/$base-$start_time.parquet
+type parquetFile struct {
+	name   string
+	dir    string
+	base   string
+	ext    string
+	cfg    *parquetConfig
+	codec  compress.Codec
+	logger *log.Logger
+
+	maxSize  int64
+	interval time.Duration
+	// optional callbacks used to update the output metrics.
+	onWrite func(rows int, bytes int64)
+	onError func(reason string)
+
+	m       sync.Mutex
+	schema  *parquetSchema // all the columns seen so far
+	pending []*formatters.EventMsg
+	timer   *time.Timer
+	gen     int // identifies the armed timer
+	closed  bool
+
+	// current file
+	f          *countingFile
+	w          *parquet.Writer
+	fileSchema *parquetSchema
+	start      time.Time
+	written    int64 // bytes written by previous row groups of the current file
+}
+
+func newParquetFile(cfg *Config, logger *log.Logger) (*parquetFile, error) {
+	if cfg.Parquet == nil {
+		cfg.Parquet = new(parquetConfig)
+	}
+	err := cfg.Parquet.setDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Rotation == nil {
+		cfg.Rotation = new(rotationConfig)
+	}
+	if cfg.Rotation.MaxSize <= 0 {
+		cfg.Rotation.MaxSize = defaultParquetRotationSize
+	}
+	if cfg.Rotation.Interval <= 0 {
+		cfg.Rotation.Interval = defaultParquetRotationEvery
+	}
+	codec, _ := parquetCodec(cfg.Parquet.Compression)
+	ext := filepath.Ext(cfg.FileName)
+	if ext == "" {
+		ext = ".parquet"
+	}
+	pf := &parquetFile{
+		name:     cfg.FileName,
+		dir:      filepath.Dir(cfg.FileName),
+		base:     strings.TrimSuffix(filepath.Base(cfg.FileName), filepath.Ext(cfg.FileName)),
+		ext:      ext,
+		cfg:      cfg.Parquet,
+		codec:    codec,
+		logger:   logger,
+		maxSize:  int64(cfg.Rotation.MaxSize) * 1024 * 1024,
+		interval: cfg.Rotation.Interval,
+		schema:   newParquetSchema(),
+	}
+	return pf, os.MkdirAll(pf.dir, 0755)
+}
+
+// Name returns the configured file name
+func (p *parquetFile) Name() string {
+	return p.name
+}
+
+// WriteEvents buffers the events and writes a row group
+// when the configured row group size is reached.
+func (p *parquetFile) WriteEvents(evs []*formatters.EventMsg) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed {
+		return os.ErrClosed
+	}
+	for _, ev := range evs {
+		// deletes and events without values are not stored.
+		if ev == nil || len(ev.Values) == 0 {
+			continue
+		}
+		p.schema.add(ev)
+		p.pending = append(p.pending, ev)
+	}
+	var err error
+	if len(p.pending) >= p.cfg.RowGroupSize {
+		err = p.flush()
+	}
+	p.armTimer()
+	return err
+}
+
+// armTimer starts the rotation timer if there are rows
+// waiting to be written to a file and the timer is not already running.
+func (p *parquetFile) armTimer() {
+	if p.timer != nil || (len(p.pending) == 0 && p.w == nil) {
+		return
+	}
+	p.gen++
+	gen := p.gen
+	p.timer = time.AfterFunc(p.interval, func() { p.rotate(gen) })
+}
+
+func (p *parquetFile) stopTimer() {
+	if p.timer == nil {
+		return
+	}
+	p.timer.Stop()
+	p.timer = nil
+}
+
+// rotate writes the pending rows and closes the current file.
+func (p *parquetFile) rotate(gen int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed || gen != p.gen {
+		return
+	}
+	p.timer = nil
+	err := p.flush()
+	if err != nil {
+		p.logger.Printf("failed to write parquet row group: %v", err)
+	}
+	err = p.closeFile()
+	if err != nil {
+		p.logger.Printf("failed to close parquet file: %v", err)
+	}
+}
+
+// flush writes the pending rows as a row group,
+// the current file is closed first if the schema changed since it was created,
+// and after if it reached the max size.
+func (p *parquetFile) flush() error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	rows := p.pending
+	p.pending = nil
+	if p.w != nil && !p.fileSchema.equal(p.schema) {
+		if err := p.closeFile(); err != nil {
+			p.logger.Printf("failed to close parquet file: %v", err)
+		}
+	}
+	if p.w == nil {
+		if err := p.openFile(); err != nil {
+			p.onErr("open_error")
+			return err
+		}
+	}
+	for _, ev := range rows {
+		if err := p.w.Write(p.fileSchema.row(ev)); err != nil {
+			p.onErr("write_error")
+			p.discardFile()
+			return err
+		}
+	}
+	if err := p.w.Flush(); err != nil {
+		p.onErr("write_error")
+		p.discardFile()
+		return err
+	}
+	if p.onWrite != nil {
+		p.onWrite(len(rows), p.f.n-p.written)
+	}
+	p.written = p.f.n
+	if p.f.n >= p.maxSize {
+		return p.closeFile()
+	}
+	return nil
+}
+
+func (p *parquetFile) openFile() error {
+	p.start = time.Now().UTC()
+	f, err := os.OpenFile(p.tmpName(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	p.f = &countingFile{File: f}
+	p.fileSchema = p.schema.copy()
+	p.w = parquet.NewWriter(p.f,
+		p.fileSchema.parquet(),
+		parquet.Compression(p.codec),
+		parquet.CreatedBy("gnmic", "", ""),
+	)
+	p.written = 0
+	return nil
+}
+
+// closeFile writes the current file footer and moves it to its partition directory.
+func (p *parquetFile) closeFile() error {
+	if p.w == nil {
+		return nil
+	}
+	p.stopTimer()
+	defer p.reset()
+	err := p.w.Close()
+	if err != nil {
+		p.f.Close()
+		os.Remove(p.f.Name())
+		p.onErr("write_error")
+		return err
+	}
+	if p.onWrite != nil {
+		p.onWrite(0, p.f.n-p.written)
+	}
+	err = p.f.Close()
+	if err != nil {
+		p.onErr("write_error")
+		return err
+	}
+	final, err := p.finalName()
+	if err != nil {
+		p.onErr("rename_error")
+		return err
+	}
+	err = os.Rename(p.f.Name(), final)
+	if err != nil {
+		p.onErr("rename_error")
+		return err
+	}
+	p.logger.Printf("rotated parquet file %q", final)
+	return nil
+}
+
+// discardFile removes the current file after a write failure.
+func (p *parquetFile) discardFile() {
+	if p.f == nil {
+		return
+	}
+	p.f.Close()
+	os.Remove(p.f.Name())
+	p.reset()
+}
+
+func (p *parquetFile) reset() {
+	p.f = nil
+	p.w = nil
+	p.fileSchema = nil
+	p.written = 0
+}
+
+func (p *parquetFile) tmpName() string {
+	return filepath.Join(p.dir,
+		parquetTmpPrefix+p.base+"-"+p.start.Format(parquetFileTimeFormat)+p.ext+parquetTmpSuffix)
+}
+
+// finalName returns the path of the finished file, creating its directory if needed.
+func (p *parquetFile) finalName() (string, error) {
+	dir := filepath.Join(p.dir, partitionPath(p.start))
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	name := p.base + "-" + p.start.Format(parquetFileTimeFormat)
+	final := filepath.Join(dir, name+p.ext)
+	for i := 1; i < math.MaxInt16; i++ {
+		if _, err := os.Stat(final); os.IsNotExist(err) {
+			break
+		}
+		final = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, p.ext))
+	}
+	return final, nil
+}
+
+func partitionPath(t time.Time) string {
+	return filepath.Join(
+		fmt.Sprintf("year=%04d", t.Year()),
+		fmt.Sprintf("month=%02d", t.Month()),
+		fmt.Sprintf("day=%02d", t.Day()),
+	)
+}
+
+func (p *parquetFile) onErr(reason string) {
+	if p.onError != nil {
+		p.onError(reason)
+	}
+}
+
+// Close writes the pending rows and closes the current file.
+func (p *parquetFile) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	p.stopTimer()
+	err := p.flush()
+	if err != nil {
+		p.logger.Printf("failed to write parquet row group: %v", err)
+	}
+	return p.closeFile()
+}
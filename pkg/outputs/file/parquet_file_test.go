@@ -0,0 +1,230 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func TestMergeKinds(t *testing.T) {
+	tests := []struct {
+		a, b valueKind
+		want valueKind
+	}{
+		{kindInt64, kindInt64, kindInt64},
+		{kindInt64, kindUint64, kindDouble},
+		{kindUint64, kindDouble, kindDouble},
+		{kindBoolean, kindInt64, kindString},
+		{kindDouble, kindString, kindString},
+		{kindBoolean, kindBoolean, kindBoolean},
+	}
+	for _, tt := range tests {
+		if got := mergeKinds(tt.a, tt.b); got != tt.want {
+			t.Errorf("mergeKinds(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		kind valueKind
+		want any
+		ok   bool
+	}{
+		{name: "int32_to_int64", v: int32(-3), kind: kindInt64, want: int64(-3), ok: true},
+		{name: "uint8_to_uint64", v: uint8(3), kind: kindUint64, want: uint64(3), ok: true},
+		{name: "int_to_double", v: 3, kind: kindDouble, want: float64(3), ok: true},
+		{name: "uint64_to_double", v: uint64(3), kind: kindDouble, want: float64(3), ok: true},
+		{name: "bool_to_string", v: true, kind: kindString, want: "true", ok: true},
+		{name: "float_to_string", v: 1.5, kind: kindString, want: "1.5", ok: true},
+		{name: "uint_to_string", v: uint64(42), kind: kindString, want: "42", ok: true},
+		{name: "map_to_string", v: map[string]any{"a": 1}, kind: kindString, want: `{"a":1}`, ok: true},
+		{name: "string_to_int64", v: "1", kind: kindInt64, ok: false},
+		{name: "uint_to_int64", v: uint64(1), kind: kindInt64, ok: false},
+		{name: "float_to_boolean", v: 1.0, kind: kindBoolean, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := convertValue(tt.v, tt.kind)
+			if ok != tt.ok {
+				t.Fatalf("got ok=%v, want %v", ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestParquetSchemaAdd(t *testing.T) {
+	s := newParquetSchema()
+	ev := &formatters.EventMsg{
+		Tags:   map[string]string{"source": "r1"},
+		Values: map[string]any{"a": int64(1), "b": "up"},
+	}
+	if !s.add(ev) {
+		t.Fatal("expected the schema to change on first event")
+	}
+	if s.add(ev) {
+		t.Fatal("expected the schema not to change on the same event")
+	}
+	old := s.copy()
+	if !s.add(&formatters.EventMsg{Values: map[string]any{"a": 1.5}}) {
+		t.Fatal("expected the schema to change on type change")
+	}
+	if s.values["a"] != kindDouble {
+		t.Errorf("expected column a to be widened to double, got %v", s.values["a"])
+	}
+	if s.equal(old) {
+		t.Error("expected the schemas to differ")
+	}
+	if !s.add(&formatters.EventMsg{Tags: map[string]string{"interface_name": "e1"}}) {
+		t.Fatal("expected the schema to change on new tag")
+	}
+}
+
+func TestParquetFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{
+		FileName: filepath.Join(dir, "telemetry.parquet"),
+		Parquet:  &parquetConfig{RowGroupSize: 2},
+	}
+	pf, err := newParquetFile(cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Rotation.Interval != defaultParquetRotationEvery || cfg.Rotation.MaxSize != defaultParquetRotationSize {
+		t.Fatalf("unexpected rotation defaults: %+v", cfg.Rotation)
+	}
+	now := time.Now().UnixNano()
+	evs := []*formatters.EventMsg{
+		{Name: "sub1", Timestamp: now, Tags: map[string]string{"source": "r1"}, Values: map[string]any{"a": int64(1)}},
+		{Name: "sub1", Timestamp: now, Tags: map[string]string{"source": "r1"}, Values: map[string]any{"a": int64(2)}},
+		// deletes are not stored
+		{Name: "sub1", Timestamp: now, Tags: map[string]string{"source": "r1"}, Deletes: []string{"a"}},
+	}
+	if err = pf.WriteEvents(evs); err != nil {
+		t.Fatal(err)
+	}
+	// a new value triggers a new file on the next row group.
+	evs = []*formatters.EventMsg{
+		{Name: "sub1", Timestamp: now, Tags: map[string]string{"source": "r2"}, Values: map[string]any{"a": int64(3), "b": "up"}},
+	}
+	if err = pf.WriteEvents(evs); err != nil {
+		t.Fatal(err)
+	}
+	if err = pf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pf.WriteEvents(evs); err == nil {
+		t.Fatal("expected an error writing to a closed file")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, partitionPath(time.Now().UTC()), "telemetry-*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+	tmp, _ := filepath.Glob(filepath.Join(dir, ".*"))
+	if len(tmp) != 0 {
+		t.Fatalf("expected no temporary files, got %v", tmp)
+	}
+	// files created within the same millisecond are suffixed with a counter,
+	// so order them by number of rows rather than by name.
+	fileRows := [][]map[string]any{readParquetFile(t, files[0]), readParquetFile(t, files[1])}
+	sort.Slice(fileRows, func(i, j int) bool { return len(fileRows[i]) > len(fileRows[j]) })
+
+	rows := fileRows[0]
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows in the first file, got %d", len(rows))
+	}
+	if rows[1]["values"].(map[string]any)["a"] != int64(2) {
+		t.Errorf("unexpected row: %v", rows[1])
+	}
+	rows = fileRows[1]
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row in the second file, got %d", len(rows))
+	}
+	values := rows[0]["values"].(map[string]any)
+	if values["a"] != int64(3) || values["b"] != "up" {
+		t.Errorf("unexpected row: %v", rows[0])
+	}
+	if rows[0]["tags"].(map[string]any)["source"] != "r2" {
+		t.Errorf("unexpected row: %v", rows[0])
+	}
+}
+
+func TestParquetFileInterval(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{
+		FileName: filepath.Join(dir, "telemetry.parquet"),
+		Rotation: &rotationConfig{Interval: 50 * time.Millisecond},
+	}
+	pf, err := newParquetFile(cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pf.Close()
+	err = pf.WriteEvents([]*formatters.EventMsg{
+		{Name: "sub1", Timestamp: time.Now().UnixNano(), Values: map[string]any{"a": true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		files, _ := filepath.Glob(filepath.Join(dir, "year=*", "month=*", "day=*", "telemetry-*.parquet"))
+		if len(files) == 1 {
+			rows := readParquetFile(t, files[0])
+			if len(rows) != 1 || rows[0]["values"].(map[string]any)["a"] != true {
+				t.Fatalf("unexpected rows: %v", rows)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("file not rotated after the configured interval")
+}
+
+func readParquetFile(t *testing.T, name string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := parquet.NewReader(f)
+	defer r.Close()
+	rows := make([]map[string]any, 0)
+	for {
+		row := make(map[string]any)
+		err := r.Read(&row)
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
@@ -1,6 +1,8 @@
 package file
 
 import (
+	"time"
+
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -10,6 +12,8 @@ type rotationConfig struct {
 	MaxBackups int  `mapstructure:"max-backups,omitempty"`
 	MaxAge     int  `mapstructure:"max-age,omitempty"`
 	Compress   bool `mapstructure:"compress,omitempty"`
+	// Interval is the max time a parquet file is written to before being rotated.
+	Interval time.Duration `mapstructure:"interval,omitempty"`
 }
 
 func (r *rotationConfig) SetDefaults() {
@@ -0,0 +1,187 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// max size of a UDP datagram payload, chosen to avoid IP fragmentation.
+const maxDatagramSize = 1400
+
+// writer batches the buffered metrics and sends them over a single connection,
+// a batch is sent when it reaches batch-size metrics or when the flush-interval expires.
+// TCP connections are re-established after a failure and the failed batch is sent again,
+// UDP batches that fail to be sent are dropped.
+func (g *graphiteOutput) writer(ctx context.Context) {
+	defer g.wg.Done()
+	g.logger.Printf("starting writer")
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	ticker := time.NewTicker(g.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*metric, 0, g.cfg.BatchSize)
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		payloads := g.payloads(batch)
+		for {
+			if conn == nil {
+				var err error
+				conn, err = g.dial(ctx)
+				if err != nil {
+					g.logger.Printf("failed to connect to %s: %v", g.cfg.Address, err)
+					graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "connect_error").Add(float64(len(batch)))
+					if !g.wait(ctx) {
+						return false
+					}
+					continue
+				}
+				g.logger.Printf("connected to %s over %s", g.cfg.Address, g.cfg.Network)
+			}
+			err := g.send(conn, payloads)
+			if err == nil {
+				graphiteNumberOfSentMetrics.WithLabelValues(g.cfg.Name).Add(float64(len(batch)))
+				break
+			}
+			g.logger.Printf("failed to send %d metrics: %v", len(batch), err)
+			graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "write_error").Add(float64(len(batch)))
+			if g.cfg.Network == networkUDP {
+				break
+			}
+			conn.Close()
+			conn = nil
+			if !g.wait(ctx) {
+				return false
+			}
+		}
+		batch = batch[:0]
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-g.buffer:
+			batch = append(batch, m)
+			if len(batch) < g.cfg.BatchSize {
+				continue
+			}
+			if !flush() {
+				return
+			}
+			ticker.Reset(g.cfg.FlushInterval)
+		case <-ticker.C:
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// payloads encodes a batch of metrics.
+// UDP plaintext batches are split in multiple datagrams.
+func (g *graphiteOutput) payloads(ms []*metric) [][]byte {
+	if g.cfg.Protocol == protocolPickle {
+		return [][]byte{pickle(ms)}
+	}
+	if g.cfg.Network != networkUDP {
+		b := make([]byte, 0, len(ms)*64)
+		for _, m := range ms {
+			b = m.plaintext(b)
+		}
+		return [][]byte{b}
+	}
+	res := make([][]byte, 0, 1)
+	var b []byte
+	for _, m := range ms {
+		l := len(b)
+		b = m.plaintext(b)
+		if len(b) > maxDatagramSize && l > 0 {
+			res = append(res, b[:l])
+			b = m.plaintext(nil)
+		}
+	}
+	if len(b) > 0 {
+		res = append(res, b)
+	}
+	return res
+}
+
+// writeSync sends the metrics built from evs on the WriteSync connection,
+// the connection is closed after a failure and dialed again on the next call.
+func (g *graphiteOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	var ms []*metric
+	for _, ev := range evs {
+		ms = append(ms, g.metrics(ev)...)
+	}
+	if len(ms) == 0 {
+		return nil
+	}
+	g.m.Lock()
+	defer g.m.Unlock()
+	if g.syncConn == nil {
+		var err error
+		g.syncConn, err = g.dial(ctx)
+		if err != nil {
+			graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "connect_error").Add(float64(len(ms)))
+			return err
+		}
+	}
+	err := g.send(g.syncConn, g.payloads(ms))
+	if err != nil {
+		graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "write_error").Add(float64(len(ms)))
+		g.syncConn.Close()
+		g.syncConn = nil
+		return err
+	}
+	graphiteNumberOfSentMetrics.WithLabelValues(g.cfg.Name).Add(float64(len(ms)))
+	return nil
+}
+
+func (g *graphiteOutput) dial(ctx context.Context) (net.Conn, error) {
+	d := &net.Dialer{Timeout: g.cfg.Timeout}
+	return d.DialContext(ctx, g.cfg.Network, g.cfg.Address)
+}
+
+func (g *graphiteOutput) send(conn net.Conn, payloads [][]byte) error {
+	err := conn.SetWriteDeadline(time.Now().Add(g.cfg.Timeout))
+	if err != nil {
+		return err
+	}
+	for _, b := range payloads {
+		n, err := conn.Write(b)
+		if err != nil {
+			return err
+		}
+		graphiteNumberOfSentBytes.WithLabelValues(g.cfg.Name).Add(float64(n))
+	}
+	return nil
+}
+
+// wait sleeps for the retry interval, it returns false if the context is done.
+func (g *graphiteOutput) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(g.cfg.RetryInterval):
+		return true
+	}
+}
@@ -0,0 +1,202 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// metric is a single Graphite data point.
+type metric struct {
+	path  string
+	value float64
+	// unix time in seconds
+	ts int64
+}
+
+// metrics returns the Graphite metrics of an event, one per numeric value.
+// The metric path is built from the prefix, the event name,
+// the configured tags values and the value name.
+// Values that cannot be converted to a number are skipped.
+func (g *graphiteOutput) metrics(ev *formatters.EventMsg) []*metric {
+	if len(ev.Values) == 0 {
+		return nil
+	}
+	ts := ev.Timestamp / int64(time.Second)
+	if g.cfg.OverrideTimestamps || ev.Timestamp <= 0 {
+		ts = time.Now().Unix()
+	}
+	base := make([]string, 0, len(g.prefix)+1+len(g.cfg.Tags))
+	base = append(base, g.prefix...)
+	if n := sanitize(ev.Name); n != "" {
+		base = append(base, n)
+	}
+	for _, t := range g.cfg.Tags {
+		if v := sanitize(ev.Tags[t]); v != "" {
+			base = append(base, v)
+		}
+	}
+	names := make([]string, 0, len(ev.Values))
+	for k := range ev.Values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	ms := make([]*metric, 0, len(ev.Values))
+	for _, k := range names {
+		v, ok := toFloat(ev.Values[k])
+		if !ok {
+			if g.cfg.Debug {
+				g.logger.Printf("skipping non numeric value %q: %v", k, ev.Values[k])
+			}
+			continue
+		}
+		p := append(base[:len(base):len(base)], splitPath(k, "/")...)
+		if len(p) == 0 {
+			continue
+		}
+		ms = append(ms, &metric{
+			path:  strings.Join(p, "."),
+			value: v,
+			ts:    ts,
+		})
+	}
+	return ms
+}
+
+// splitPath splits a path on sep and sanitizes its elements,
+// empty elements are removed.
+func splitPath(p, sep string) []string {
+	elems := strings.Split(p, sep)
+	res := make([]string, 0, len(elems))
+	for _, e := range elems {
+		if e = sanitize(e); e != "" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// sanitize makes s usable as a single metric path node:
+// all characters except letters, digits, '-' and '_' are replaced with '_'.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z',
+			r >= 'A' && r <= 'Z',
+			r >= '0' && r <= '9',
+			r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func toFloat(v any) (float64, bool) {
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int:
+		f = float64(v)
+	case int8:
+		f = float64(v)
+	case int16:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint:
+		f = float64(v)
+	case uint8:
+		f = float64(v)
+	case uint16:
+		f = float64(v)
+	case uint32:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case bool:
+		if v {
+			f = 1
+		}
+	case string:
+		var err error
+		f, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// plaintext appends the metric in the Carbon plaintext format to b:
+// `<path> <value> <timestamp>\n`.
+func (m *metric) plaintext(b []byte) []byte {
+	b = append(b, m.path...)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, m.value, 'f', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, m.ts, 10)
+	return append(b, '\n')
+}
+
+// pickle opcodes, protocol 2.
+const (
+	opProto      = 0x80
+	opEmptyList  = ']'
+	opMark       = '('
+	opBinUnicode = 'X'
+	opBinInt     = 'J'
+	opBinFloat   = 'G'
+	opTuple2     = 0x86
+	opAppends    = 'e'
+	opStop       = '.'
+)
+
+// pickle returns the metrics in the Carbon pickle format:
+// a 4 bytes big endian length header followed by the pickled
+// list of `(path, (timestamp, value))` tuples.
+func pickle(ms []*metric) []byte {
+	b := make([]byte, 4, 16+len(ms)*64)
+	b = append(b, opProto, 2, opEmptyList, opMark)
+	for _, m := range ms {
+		b = append(b, opBinUnicode)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(m.path)))
+		b = append(b, m.path...)
+		if m.ts >= math.MinInt32 && m.ts <= math.MaxInt32 {
+			b = append(b, opBinInt)
+			b = binary.LittleEndian.AppendUint32(b, uint32(int32(m.ts)))
+		} else {
+			b = append(b, opBinFloat)
+			b = binary.BigEndian.AppendUint64(b, math.Float64bits(float64(m.ts)))
+		}
+		b = append(b, opBinFloat)
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(m.value))
+		b = append(b, opTuple2, opTuple2)
+	}
+	b = append(b, opAppends, opStop)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
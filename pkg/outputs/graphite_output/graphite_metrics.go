@@ -0,0 +1,72 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "graphite_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var graphiteNumberOfSentMetrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_metrics_sent_success_total",
+	Help:      "Number of metrics successfully sent by gnmic graphite output",
+}, []string{"name"})
+
+var graphiteNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_bytes_sent_total",
+	Help:      "Number of bytes sent by gnmic graphite output",
+}, []string{"name"})
+
+var graphiteNumberOfFailedMetrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_metrics_sent_fail_total",
+	Help:      "Number of metrics gnmic graphite output failed to send",
+}, []string{"name", "reason"})
+
+func initMetrics(name string) {
+	graphiteNumberOfSentMetrics.WithLabelValues(name).Add(0)
+	graphiteNumberOfSentBytes.WithLabelValues(name).Add(0)
+	graphiteNumberOfFailedMetrics.WithLabelValues(name, "").Add(0)
+}
+
+func (g *graphiteOutput) registerMetrics() error {
+	if g.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = g.reg.Register(graphiteNumberOfSentMetrics); err != nil {
+			g.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = g.reg.Register(graphiteNumberOfSentBytes); err != nil {
+			g.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = g.reg.Register(graphiteNumberOfFailedMetrics); err != nil {
+			g.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(g.cfg.Name)
+	return err
+}
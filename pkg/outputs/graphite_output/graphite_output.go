@@ -0,0 +1,401 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType    = "graphite"
+	loggingPrefix = "[graphite_output:%s] "
+
+	networkTCP = "tcp"
+	networkUDP = "udp"
+
+	protocolPlaintext = "plaintext"
+	protocolPickle    = "pickle"
+
+	defaultPlaintextPort = "2003"
+	defaultPicklePort    = "2004"
+	defaultTimeout       = 10 * time.Second
+	defaultRetryInterval = 2 * time.Second
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 1000
+	defaultNumWorkers    = 1
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &graphiteOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type graphiteOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	// metrics waiting to be batched and sent
+	buffer chan *metric
+
+	m sync.Mutex
+	// connection used by WriteSync, dialed on first use.
+	syncConn net.Conn
+
+	// sanitized prefix path components
+	prefix []string
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name    string `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address string `mapstructure:"address,omitempty" json:"address,omitempty"`
+	// `tcp` or `udp`.
+	Network string `mapstructure:"network,omitempty" json:"network,omitempty"`
+	// `plaintext` or `pickle`.
+	Protocol string        `mapstructure:"protocol,omitempty" json:"protocol,omitempty"`
+	Timeout  time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	// wait time before reconnecting after a failure.
+	RetryInterval time.Duration `mapstructure:"retry-interval,omitempty" json:"retry-interval,omitempty"`
+	// dotted path prepended to all metric paths.
+	Prefix string `mapstructure:"prefix,omitempty" json:"prefix,omitempty"`
+	// ordered list of the tags whose values are added to the metric paths.
+	Tags []string `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	// max number of metrics sent at once.
+	BatchSize int `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	// max time a metric waits in a batch before being sent.
+	FlushInterval      time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	BufferSize         int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	AddTarget          string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors    []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers         int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug              bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+func (g *graphiteOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, g.cfg)
+	if err != nil {
+		return err
+	}
+	if g.cfg.Name == "" {
+		g.cfg.Name = name
+	}
+	g.logger.SetPrefix(fmt.Sprintf(loggingPrefix, g.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return err
+		}
+	}
+	err = g.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = g.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if g.cfg.TargetTemplate == "" {
+		g.targetTpl = outputs.DefaultTargetTemplate
+	} else if g.cfg.AddTarget != "" {
+		g.targetTpl, err = gtemplate.CreateTemplate("target-template", g.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		g.targetTpl = g.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	g.prefix = splitPath(g.cfg.Prefix, ".")
+	g.buffer = make(chan *metric, g.cfg.BufferSize)
+
+	ctx, g.cfn = context.WithCancel(ctx)
+	for i := 0; i < g.cfg.NumWorkers; i++ {
+		go g.worker(ctx)
+	}
+	g.wg.Add(1)
+	go g.writer(ctx)
+	g.logger.Printf("initialized graphite output %s: %s", g.cfg.Name, g.String())
+	return nil
+}
+
+func (g *graphiteOutput) setDefaults() error {
+	if g.cfg.Address == "" {
+		return errors.New("missing address field")
+	}
+	switch g.cfg.Network {
+	case "":
+		g.cfg.Network = networkTCP
+	case networkTCP, networkUDP:
+	default:
+		return fmt.Errorf("unknown network %q", g.cfg.Network)
+	}
+	switch g.cfg.Protocol {
+	case "":
+		g.cfg.Protocol = protocolPlaintext
+	case protocolPlaintext:
+	case protocolPickle:
+		// carbon only receives pickled metrics over TCP.
+		if g.cfg.Network != networkTCP {
+			return errors.New("the pickle protocol requires the tcp network")
+		}
+	default:
+		return fmt.Errorf("unknown protocol %q", g.cfg.Protocol)
+	}
+	if _, _, err := net.SplitHostPort(g.cfg.Address); err != nil {
+		port := defaultPlaintextPort
+		if g.cfg.Protocol == protocolPickle {
+			port = defaultPicklePort
+		}
+		g.cfg.Address = net.JoinHostPort(g.cfg.Address, port)
+	}
+	if g.cfg.Timeout <= 0 {
+		g.cfg.Timeout = defaultTimeout
+	}
+	if g.cfg.RetryInterval <= 0 {
+		g.cfg.RetryInterval = defaultRetryInterval
+	}
+	if g.cfg.BatchSize <= 0 {
+		g.cfg.BatchSize = defaultBatchSize
+	}
+	if g.cfg.FlushInterval <= 0 {
+		g.cfg.FlushInterval = defaultFlushInterval
+	}
+	if g.cfg.BufferSize <= 0 {
+		g.cfg.BufferSize = defaultBufferSize
+	}
+	if g.cfg.NumWorkers <= 0 {
+		g.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
+
+func (g *graphiteOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, g.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case g.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if g.cfg.Debug {
+			g.logger.Printf("writing expired after %s", g.cfg.Timeout)
+		}
+		graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (g *graphiteOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range g.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case g.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync sends the metrics built from a message on a dedicated connection,
+// it returns once they were written to the connection.
+func (g *graphiteOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := g.protoEvents(rsp, meta)
+	if err != nil {
+		g.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return g.writeSync(ctx, events)
+}
+
+// WriteEventSync sends the metrics built from an event on a dedicated connection,
+// it returns once they were written to the connection.
+func (g *graphiteOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range g.evps {
+		evs = proc.Apply(evs...)
+	}
+	return g.writeSync(ctx, evs)
+}
+
+func (g *graphiteOutput) Close() error {
+	if g.cfn == nil {
+		return nil
+	}
+	g.cfn()
+	g.wg.Wait()
+	g.m.Lock()
+	if g.syncConn != nil {
+		g.syncConn.Close()
+		g.syncConn = nil
+	}
+	g.m.Unlock()
+	return nil
+}
+
+func (g *graphiteOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !g.cfg.EnableMetrics {
+		return
+	}
+	g.reg = reg
+}
+
+func (g *graphiteOutput) String() string {
+	b, err := json.Marshal(g.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (g *graphiteOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && g.logger != nil {
+		g.logger.SetOutput(logger.Writer())
+		g.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (g *graphiteOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	g.evps, err = formatters.MakeEventProcessors(
+		logger,
+		g.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (g *graphiteOutput) SetName(name string) {
+	if g.cfg.Name == "" {
+		g.cfg.Name = name
+	}
+}
+
+func (g *graphiteOutput) SetClusterName(_ string) {}
+
+func (g *graphiteOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (g *graphiteOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-g.eventChan:
+			g.workerHandleEvent(ctx, ev)
+		case m := <-g.msgChan:
+			g.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (g *graphiteOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	events, err := g.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		g.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		g.workerHandleEvent(ctx, ev)
+	}
+}
+
+func (g *graphiteOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	rsp, err := outputs.AddSubscriptionTarget(pmsg, meta, g.cfg.AddTarget, g.targetTpl)
+	if err != nil {
+		g.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if rsp != nil {
+		pmsg = rsp
+	}
+	return formatters.ResponseToEventMsgs(subName, pmsg, meta, g.evps...)
+}
+
+func (g *graphiteOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	for _, m := range g.metrics(ev) {
+		if g.cfg.Debug {
+			g.logger.Printf("buffering metric: %s", m.plaintext(nil))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case g.buffer <- m:
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+var testTS = time.Date(2024, 5, 1, 10, 20, 30, 123456789, time.UTC).UnixNano()
+
+func newTestOutput(t *testing.T, cfg *config) *graphiteOutput {
+	t.Helper()
+	if cfg.Address == "" {
+		cfg.Address = "localhost"
+	}
+	g := &graphiteOutput{cfg: cfg, logger: log.New(io.Discard, "", 0)}
+	err := g.setDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.prefix = splitPath(cfg.Prefix, ".")
+	return g
+}
+
+func TestSetDefaults(t *testing.T) {
+	g := newTestOutput(t, &config{Address: "10.0.0.1"})
+	if g.cfg.Address != "10.0.0.1:2003" || g.cfg.Network != networkTCP || g.cfg.Protocol != protocolPlaintext {
+		t.Errorf("unexpected address/network/protocol: %s/%s/%s", g.cfg.Address, g.cfg.Network, g.cfg.Protocol)
+	}
+	g = newTestOutput(t, &config{Address: "carbon", Protocol: protocolPickle})
+	if g.cfg.Address != "carbon:2004" {
+		t.Errorf("unexpected pickle address: %s", g.cfg.Address)
+	}
+
+	for _, cfg := range []*config{
+		{},
+		{Address: "a", Network: "sctp"},
+		{Address: "a", Protocol: "json"},
+		{Address: "a", Network: networkUDP, Protocol: protocolPickle},
+	} {
+		g := &graphiteOutput{cfg: cfg}
+		if err := g.setDefaults(); err == nil {
+			t.Errorf("expected an error for config %+v", cfg)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: testTS,
+		Tags: map[string]string{
+			"source":         "10.0.0.1:57400",
+			"interface_name": "ethernet-1/1",
+		},
+		Values: map[string]any{
+			"/interfaces/interface/state/counters/in-octets": uint64(42),
+			"/interfaces/interface/state/oper-status":        "UP",
+			"/interfaces/interface/state/enabled":            true,
+			"/interfaces/interface/state/mtu":                "1500",
+		},
+	}
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "no_tags",
+			cfg:  &config{},
+			want: "sub1.interfaces.interface.state.counters.in-octets 42 1714558830\n" +
+				"sub1.interfaces.interface.state.enabled 1 1714558830\n" +
+				"sub1.interfaces.interface.state.mtu 1500 1714558830\n",
+		},
+		{
+			name: "prefix_and_tags",
+			cfg: &config{
+				Prefix: "dc1.gnmic.",
+				Tags:   []string{"source", "missing", "interface_name"},
+			},
+			want: "dc1.gnmic.sub1.10_0_0_1_57400.ethernet-1_1.interfaces.interface.state.counters.in-octets 42 1714558830\n" +
+				"dc1.gnmic.sub1.10_0_0_1_57400.ethernet-1_1.interfaces.interface.state.enabled 1 1714558830\n" +
+				"dc1.gnmic.sub1.10_0_0_1_57400.ethernet-1_1.interfaces.interface.state.mtu 1500 1714558830\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestOutput(t, tt.cfg)
+			var b []byte
+			for _, m := range g.metrics(ev) {
+				b = m.plaintext(b)
+			}
+			if string(b) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := map[string]string{
+		"in-octets":                 "in-octets",
+		"10.0.0.1":                  "10_0_0_1",
+		"ethernet-1/1":              "ethernet-1_1",
+		"srl_nokia-interfaces:name": "srl_nokia-interfaces_name",
+		"a b\tcé":                   "a_b_c_",
+	}
+	for in, want := range tests {
+		if got := sanitize(in); got != want {
+			t.Errorf("sanitize(%q): got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestToFloat(t *testing.T) {
+	for _, v := range []any{int8(-3), uint32(3), float32(0.5), "1e3", false} {
+		if _, ok := toFloat(v); !ok {
+			t.Errorf("expected %v (%T) to be converted", v, v)
+		}
+	}
+	for _, v := range []any{"UP", "NaN", []any{1}, nil} {
+		if f, ok := toFloat(v); ok {
+			t.Errorf("expected %v (%T) to be skipped, got %v", v, v, f)
+		}
+	}
+}
+
+func TestPickle(t *testing.T) {
+	got := pickle([]*metric{{path: "a.b", value: 1.5, ts: 1714558830}})
+	want := []byte{
+		0, 0, 0, 30, // length header
+		0x80, 2, ']', '(',
+		'X', 3, 0, 0, 0, 'a', '.', 'b',
+		'J', 0x6e, 0x17, 0x32, 0x66,
+		'G', 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+		0x86, 0x86,
+		'e', '.',
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	ms := make([]*metric, 100)
+	for i := range ms {
+		ms[i] = &metric{path: "gnmic.sub1.router1.interfaces.interface.state.counters.in-octets", value: float64(i), ts: 1714558830}
+	}
+	g := newTestOutput(t, &config{})
+	if p := g.payloads(ms); len(p) != 1 {
+		t.Errorf("tcp: expected 1 payload, got %d", len(p))
+	}
+	g = newTestOutput(t, &config{Network: networkUDP})
+	p := g.payloads(ms)
+	if len(p) < 2 {
+		t.Fatalf("udp: expected multiple payloads, got %d", len(p))
+	}
+	var lines int
+	for _, b := range p {
+		if len(b) > maxDatagramSize {
+			t.Errorf("udp: payload of %d bytes exceeds the max datagram size", len(b))
+		}
+		lines += strings.Count(string(b), "\n")
+	}
+	if lines != len(ms) {
+		t.Errorf("udp: got %d lines, want %d", lines, len(ms))
+	}
+}
@@ -0,0 +1,368 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+const (
+	drainTimeout = 5 * time.Second
+	// max number of response body bytes included in error messages.
+	maxErrorBodySize = 1024
+)
+
+func (h *httpOutput) createHTTPClient() error {
+	c := &http.Client{
+		Timeout: h.cfg.Timeout,
+	}
+	if h.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			h.cfg.TLS.CaFile,
+			h.cfg.TLS.CertFile,
+			h.cfg.TLS.KeyFile,
+			"",
+			h.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	h.httpClient = c
+	if h.cfg.OAuth2 != nil {
+		cc := &clientcredentials.Config{
+			ClientID:       h.cfg.OAuth2.ClientID,
+			ClientSecret:   h.cfg.OAuth2.ClientSecret,
+			TokenURL:       h.cfg.OAuth2.TokenURL,
+			Scopes:         h.cfg.OAuth2.Scopes,
+			EndpointParams: url.Values{},
+		}
+		for k, v := range h.cfg.OAuth2.EndpointParams {
+			cc.EndpointParams.Set(k, v)
+		}
+		// the token requests use the same TLS config as the data requests,
+		// the context is not canceled on close so that the pending messages can be sent.
+		h.tokenSource = cc.TokenSource(context.WithValue(context.Background(), oauth2.HTTPClient, c))
+	}
+	return nil
+}
+
+func (h *httpOutput) writer(ctx context.Context) {
+	defer h.wg.Done()
+	h.logger.Printf("starting writer")
+	ticker := time.NewTicker(h.cfg.FlushInterval)
+	defer ticker.Stop()
+	msgs := make([][]byte, 0, h.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			h.drain(msgs)
+			return
+		case b := <-h.bodyCh:
+			msgs = append(msgs, b)
+			if len(msgs) < h.cfg.BatchSize {
+				continue
+			}
+			if h.cfg.Debug {
+				h.logger.Printf("batch size reached, sending request")
+			}
+			h.write(ctx, msgs)
+			msgs = msgs[:0]
+		case <-ticker.C:
+			if len(msgs) == 0 {
+				continue
+			}
+			if h.cfg.Debug {
+				h.logger.Printf("flush interval reached, sending request")
+			}
+			h.write(ctx, msgs)
+			msgs = msgs[:0]
+		}
+	}
+}
+
+// drain sends the pending messages when the output is closed.
+func (h *httpOutput) drain(msgs [][]byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for {
+		select {
+		case b := <-h.bodyCh:
+			msgs = append(msgs, b)
+			if len(msgs) >= h.cfg.BatchSize {
+				h.write(ctx, msgs)
+				msgs = msgs[:0]
+			}
+		default:
+			if len(msgs) > 0 {
+				h.write(ctx, msgs)
+			}
+			return
+		}
+	}
+}
+
+// write sends a batch of messages in a single request,
+// the request is retried if it fails with a transport error or
+// with one of the configured retry status codes.
+func (h *httpOutput) write(ctx context.Context, msgs [][]byte) {
+	body, err := h.requestBody(msgs)
+	if err != nil {
+		h.logger.Printf("failed to build request body: %v", err)
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "body_error").Add(float64(len(msgs)))
+		return
+	}
+	retries := 0
+	wait := h.cfg.Retry.Backoff
+	for {
+		start := time.Now()
+		code, retryAfter, err := h.send(ctx, body)
+		httpRequestDuration.WithLabelValues(h.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		if err == nil {
+			if code < 300 {
+				httpNumberOfSentMsgs.WithLabelValues(h.cfg.Name).Add(float64(len(msgs)))
+				return
+			}
+			if !h.retryable(code) {
+				httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, fmt.Sprintf("status_code=%d", code)).Add(float64(len(msgs)))
+				return
+			}
+		}
+		if retries >= h.cfg.Retry.MaxRetries {
+			httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "max_retries").Add(float64(len(msgs)))
+			h.logger.Printf("dropping %d message(s) after %d retries", len(msgs), retries)
+			return
+		}
+		retries++
+		httpNumberOfRetriedMsgs.WithLabelValues(h.cfg.Name).Add(float64(len(msgs)))
+		d := wait
+		if retryAfter > 0 {
+			d = retryAfter
+		}
+		if d > h.cfg.Retry.MaxBackoff {
+			d = h.cfg.Retry.MaxBackoff
+		}
+		if h.cfg.Debug {
+			h.logger.Printf("retrying %d message(s) in %s", len(msgs), d)
+		}
+		select {
+		case <-ctx.Done():
+			httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "canceled").Add(float64(len(msgs)))
+			return
+		case <-time.After(d):
+		}
+		wait *= 2
+		if wait > h.cfg.Retry.MaxBackoff {
+			wait = h.cfg.Retry.MaxBackoff
+		}
+	}
+}
+
+// writeSync sends the messages in requests of up to batch-size messages,
+// it returns an error if a request fails with a transport error or
+// with one of the configured retry status codes, other failures are dropped.
+func (h *httpOutput) writeSync(ctx context.Context, msgs [][]byte) error {
+	for len(msgs) > 0 {
+		n := min(len(msgs), h.cfg.BatchSize)
+		body, err := h.requestBody(msgs[:n])
+		if err != nil {
+			h.logger.Printf("failed to build request body: %v", err)
+			httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "body_error").Add(float64(n))
+			return nil
+		}
+		start := time.Now()
+		code, _, err := h.send(ctx, body)
+		httpRequestDuration.WithLabelValues(h.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		if err != nil {
+			return err
+		}
+		switch {
+		case code < 300:
+		case h.retryable(code):
+			return fmt.Errorf("request failed, code=%d", code)
+		default:
+			httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, fmt.Sprintf("status_code=%d", code)).Add(float64(n))
+			msgs = msgs[n:]
+			continue
+		}
+		httpNumberOfSentMsgs.WithLabelValues(h.cfg.Name).Add(float64(n))
+		msgs = msgs[n:]
+	}
+	return nil
+}
+
+// send sends a single request, it returns the response status code
+// and the delay requested by the server in the Retry-After header.
+func (h *httpOutput) send(ctx context.Context, body []byte) (int, time.Duration, error) {
+	req, err := h.makeHTTPRequest(ctx, body)
+	if err != nil {
+		h.logger.Printf("failed to create request: %v", err)
+		return 0, 0, err
+	}
+	rsp, err := h.httpClient.Do(req)
+	if err != nil {
+		h.logger.Printf("request failed: %v", err)
+		return 0, 0, err
+	}
+	defer rsp.Body.Close()
+	if h.cfg.Debug {
+		h.logger.Printf("got response: status=%s", rsp.Status)
+	}
+	if rsp.StatusCode < 300 {
+		// drain the body to allow connection reuse.
+		io.Copy(io.Discard, rsp.Body)
+		return rsp.StatusCode, 0, nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(rsp.Body, maxErrorBodySize))
+	h.logger.Printf("request failed, code=%d, body=%s", rsp.StatusCode, string(b))
+	return rsp.StatusCode, parseRetryAfter(rsp.Header.Get("Retry-After"), time.Now()), nil
+}
+
+func (h *httpOutput) retryable(code int) bool {
+	for _, c := range h.cfg.Retry.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *httpOutput) makeHTTPRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, h.cfg.Method, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if h.cfg.BatchFormat == "ndjson" && h.cfg.BatchSize > 1 {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if h.cfg.Compression == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range h.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case h.tokenSource != nil:
+		tok, err := h.tokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oauth2 token: %w", err)
+		}
+		tok.SetAuthHeader(req)
+	case h.cfg.Authorization != nil && h.cfg.Authorization.Type != "":
+		req.Header.Set("Authorization", fmt.Sprintf("%s %s", h.cfg.Authorization.Type, h.cfg.Authorization.Credentials))
+	case h.cfg.Authentication != nil:
+		req.SetBasicAuth(h.cfg.Authentication.Username, h.cfg.Authentication.Password)
+	}
+	return req, nil
+}
+
+// requestBody combines the messages and compresses the result if configured.
+func (h *httpOutput) requestBody(msgs [][]byte) ([]byte, error) {
+	var body []byte
+	if h.cfg.BatchSize == 1 && len(msgs) == 1 {
+		body = msgs[0]
+	} else {
+		// with the event format, each message is an array of events
+		// which are merged into a single array.
+		flatten := h.cfg.Format == "event" && h.msgTpl == nil
+		body = batchBody(msgs, h.cfg.BatchFormat, flatten)
+	}
+	if h.cfg.Compression != "gzip" {
+		return body, nil
+	}
+	b := new(bytes.Buffer)
+	zw := gzip.NewWriter(b)
+	_, err := zw.Write(body)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// batchBody builds a JSON array or a newline delimited body from the messages.
+// If flatten is true, the messages are expected to be JSON arrays and their
+// elements are added to the resulting array.
+func batchBody(msgs [][]byte, batchFormat string, flatten bool) []byte {
+	b := new(bytes.Buffer)
+	if batchFormat == "ndjson" {
+		for _, m := range msgs {
+			m = bytes.TrimSpace(m)
+			if len(m) == 0 {
+				continue
+			}
+			b.Write(m)
+			b.WriteByte('\n')
+		}
+		return b.Bytes()
+	}
+	b.WriteByte('[')
+	first := true
+	for _, m := range msgs {
+		m = bytes.TrimSpace(m)
+		if flatten && len(m) >= 2 && m[0] == '[' && m[len(m)-1] == ']' {
+			m = bytes.TrimSpace(m[1 : len(m)-1])
+		}
+		if len(m) == 0 {
+			continue
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		b.Write(m)
+	}
+	b.WriteByte(']')
+	return b.Bytes()
+}
+
+// parseRetryAfter parses a Retry-After header value,
+// given either in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s <= 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0
+	}
+	if d := t.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "http_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var httpNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_msgs_sent_success_total",
+	Help:      "Number of messages successfully sent by gnmic http output",
+}, []string{"name"})
+
+var httpNumberOfFailedMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_msgs_sent_fail_total",
+	Help:      "Number of messages gnmic http output failed to send",
+}, []string{"name", "reason"})
+
+var httpNumberOfRetriedMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_msgs_retried_total",
+	Help:      "Number of messages retried by gnmic http output",
+}, []string{"name"})
+
+var httpRequestDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "request_duration_ns",
+	Help:      "gnmic http output request duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	httpNumberOfSentMsgs.WithLabelValues(name).Add(0)
+	httpNumberOfFailedMsgs.WithLabelValues(name, "").Add(0)
+	httpNumberOfRetriedMsgs.WithLabelValues(name).Add(0)
+	httpRequestDuration.WithLabelValues(name).Set(0)
+}
+
+func (h *httpOutput) registerMetrics() error {
+	if h.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = h.reg.Register(httpNumberOfSentMsgs); err != nil {
+			h.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = h.reg.Register(httpNumberOfFailedMsgs); err != nil {
+			h.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = h.reg.Register(httpNumberOfRetriedMsgs); err != nil {
+			h.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = h.reg.Register(httpRequestDuration); err != nil {
+			h.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(h.cfg.Name)
+	return err
+}
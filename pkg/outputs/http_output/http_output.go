@@ -0,0 +1,529 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "http"
+	loggingPrefix        = "[http_output:%s] "
+	defaultMethod        = http.MethodPost
+	defaultFormat        = "json"
+	defaultBatchFormat   = "array"
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 1000
+	defaultBatchSize     = 100
+	defaultMaxRetries    = 3
+	defaultBackoff       = 100 * time.Millisecond
+	defaultMaxBackoff    = 5 * time.Second
+	defaultNumWorkers    = 1
+	userAgent            = "gNMIc http"
+)
+
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &httpOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type httpOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	httpClient  *http.Client
+	tokenSource oauth2.TokenSource
+	eventChan   chan *formatters.EventMsg
+	msgChan     chan *outputs.ProtoMsg
+	bodyCh      chan []byte
+	mo          *formatters.MarshalOptions
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	msgTpl    *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name           string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	URL            string            `mapstructure:"url,omitempty" json:"url,omitempty"`
+	Method         string            `mapstructure:"method,omitempty" json:"method,omitempty"`
+	Timeout        time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers        map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	Authentication *auth             `mapstructure:"authentication,omitempty" json:"authentication,omitempty"`
+	Authorization  *authorization    `mapstructure:"authorization,omitempty" json:"authorization,omitempty"`
+	OAuth2         *oauth2Config     `mapstructure:"oauth2,omitempty" json:"oauth2,omitempty"`
+	TLS            *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	// message format, one of `json`, `event` or `protojson`.
+	Format             string `mapstructure:"format,omitempty" json:"format,omitempty"`
+	MsgTemplate        string `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
+	OverrideTimestamps bool   `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	// how the messages of a batch are combined in a request body, `array` or `ndjson`.
+	BatchFormat     string        `mapstructure:"batch-format,omitempty" json:"batch-format,omitempty"`
+	BatchSize       int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	FlushInterval   time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	Compression     string        `mapstructure:"compression,omitempty" json:"compression,omitempty"`
+	Retry           *retryConfig  `mapstructure:"retry,omitempty" json:"retry,omitempty"`
+	AddTarget       string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics   bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+type auth struct {
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"password,omitempty"`
+}
+
+type authorization struct {
+	Type        string `mapstructure:"type,omitempty" json:"type,omitempty"`
+	Credentials string `mapstructure:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+type oauth2Config struct {
+	TokenURL       string            `mapstructure:"token-url,omitempty" json:"token-url,omitempty"`
+	ClientID       string            `mapstructure:"client-id,omitempty" json:"client-id,omitempty"`
+	ClientSecret   string            `mapstructure:"client-secret,omitempty" json:"client-secret,omitempty"`
+	Scopes         []string          `mapstructure:"scopes,omitempty" json:"scopes,omitempty"`
+	EndpointParams map[string]string `mapstructure:"endpoint-params,omitempty" json:"endpoint-params,omitempty"`
+}
+
+type retryConfig struct {
+	MaxRetries  int           `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	Backoff     time.Duration `mapstructure:"backoff,omitempty" json:"backoff,omitempty"`
+	MaxBackoff  time.Duration `mapstructure:"max-backoff,omitempty" json:"max-backoff,omitempty"`
+	StatusCodes []int         `mapstructure:"status-codes,omitempty" json:"status-codes,omitempty"`
+}
+
+func (h *httpOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, h.cfg)
+	if err != nil {
+		return err
+	}
+	if h.cfg.Name == "" {
+		h.cfg.Name = name
+	}
+	h.logger.SetPrefix(fmt.Sprintf(loggingPrefix, h.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return err
+		}
+	}
+	err = h.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = h.registerMetrics()
+	if err != nil {
+		return err
+	}
+	h.mo = &formatters.MarshalOptions{
+		Format:     h.cfg.Format,
+		OverrideTS: h.cfg.OverrideTimestamps,
+	}
+
+	if h.cfg.TargetTemplate == "" {
+		h.targetTpl = outputs.DefaultTargetTemplate
+	} else if h.cfg.AddTarget != "" {
+		h.targetTpl, err = gtemplate.CreateTemplate("target-template", h.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		h.targetTpl = h.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	if h.cfg.MsgTemplate != "" {
+		h.msgTpl, err = gtemplate.CreateTemplate("msg-template", h.cfg.MsgTemplate)
+		if err != nil {
+			return err
+		}
+		h.msgTpl = h.msgTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	err = h.createHTTPClient()
+	if err != nil {
+		return err
+	}
+	h.bodyCh = make(chan []byte, h.cfg.BufferSize)
+
+	ctx, h.cfn = context.WithCancel(ctx)
+
+	for i := 0; i < h.cfg.NumWorkers; i++ {
+		go h.worker(ctx)
+	}
+	h.wg.Add(1)
+	go h.writer(ctx)
+	h.logger.Printf("initialized http output %s: %s", h.cfg.Name, h.String())
+	return nil
+}
+
+func (h *httpOutput) setDefaults() error {
+	if h.cfg.URL == "" {
+		return errors.New("missing url field")
+	}
+	_, err := url.Parse(h.cfg.URL)
+	if err != nil {
+		return err
+	}
+	if h.cfg.Method == "" {
+		h.cfg.Method = defaultMethod
+	}
+	h.cfg.Method = strings.ToUpper(h.cfg.Method)
+	switch h.cfg.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("method %q not allowed", h.cfg.Method)
+	}
+	if h.cfg.Format == "" {
+		h.cfg.Format = defaultFormat
+	}
+	switch h.cfg.Format {
+	case "json", "event", "protojson":
+	default:
+		return fmt.Errorf("unsupported format %q", h.cfg.Format)
+	}
+	if h.cfg.BatchFormat == "" {
+		h.cfg.BatchFormat = defaultBatchFormat
+	}
+	switch h.cfg.BatchFormat {
+	case "array", "ndjson":
+	default:
+		return fmt.Errorf("unknown batch-format value %q", h.cfg.BatchFormat)
+	}
+	switch h.cfg.Compression {
+	case "", "gzip":
+	default:
+		return fmt.Errorf("unsupported compression %q", h.cfg.Compression)
+	}
+	if h.cfg.OAuth2 != nil {
+		if h.cfg.OAuth2.TokenURL == "" {
+			return errors.New("missing oauth2 token-url field")
+		}
+		if h.cfg.OAuth2.ClientID == "" {
+			return errors.New("missing oauth2 client-id field")
+		}
+	}
+	if h.cfg.Timeout <= 0 {
+		h.cfg.Timeout = defaultTimeout
+	}
+	if h.cfg.FlushInterval <= 0 {
+		h.cfg.FlushInterval = defaultFlushInterval
+	}
+	if h.cfg.BufferSize <= 0 {
+		h.cfg.BufferSize = defaultBufferSize
+	}
+	if h.cfg.BatchSize <= 0 {
+		h.cfg.BatchSize = defaultBatchSize
+	}
+	if h.cfg.Retry == nil {
+		h.cfg.Retry = &retryConfig{}
+	}
+	if h.cfg.Retry.MaxRetries < 0 {
+		h.cfg.Retry.MaxRetries = 0
+	} else if h.cfg.Retry.MaxRetries == 0 {
+		h.cfg.Retry.MaxRetries = defaultMaxRetries
+	}
+	if h.cfg.Retry.Backoff <= 0 {
+		h.cfg.Retry.Backoff = defaultBackoff
+	}
+	if h.cfg.Retry.MaxBackoff <= 0 {
+		h.cfg.Retry.MaxBackoff = defaultMaxBackoff
+	}
+	if h.cfg.Retry.MaxBackoff < h.cfg.Retry.Backoff {
+		h.cfg.Retry.MaxBackoff = h.cfg.Retry.Backoff
+	}
+	if len(h.cfg.Retry.StatusCodes) == 0 {
+		h.cfg.Retry.StatusCodes = defaultRetryStatusCodes
+	}
+	if h.cfg.NumWorkers <= 0 {
+		h.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
+
+func (h *httpOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case h.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if h.cfg.Debug {
+			h.logger.Printf("writing expired after %s", h.cfg.Timeout)
+		}
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (h *httpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	// events can only be sent using the event format.
+	if h.cfg.Format != "event" {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range h.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case h.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync sends a message in its own request, without retries,
+// it returns once the server answered with a success status code.
+func (h *httpOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	b, err := h.marshalProto(rsp, meta)
+	if err != nil {
+		h.logger.Printf("%v", err)
+		return nil
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return h.writeSync(ctx, [][]byte{b})
+}
+
+// WriteEventSync sends an event in its own request, without retries,
+// it returns once the server answered with a success status code.
+func (h *httpOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	if h.cfg.Format != "event" {
+		return nil
+	}
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range h.evps {
+		evs = proc.Apply(evs...)
+	}
+	msgs := make([][]byte, 0, len(evs))
+	for _, pev := range evs {
+		b, err := h.marshalEvent(pev)
+		if err != nil {
+			h.logger.Printf("%v", err)
+			continue
+		}
+		msgs = append(msgs, b)
+	}
+	return h.writeSync(ctx, msgs)
+}
+
+func (h *httpOutput) Close() error {
+	if h.cfn == nil {
+		return nil
+	}
+	h.cfn()
+	// wait for the buffered messages to be flushed
+	h.wg.Wait()
+	return nil
+}
+
+func (h *httpOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !h.cfg.EnableMetrics {
+		return
+	}
+	h.reg = reg
+}
+
+func (h *httpOutput) String() string {
+	cfg := *h.cfg
+	if cfg.Authentication != nil {
+		cfg.Authentication = &auth{Username: cfg.Authentication.Username, Password: "****"}
+	}
+	if cfg.Authorization != nil {
+		cfg.Authorization = &authorization{Type: cfg.Authorization.Type, Credentials: "****"}
+	}
+	if cfg.OAuth2 != nil {
+		oc := *cfg.OAuth2
+		oc.ClientSecret = "****"
+		cfg.OAuth2 = &oc
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (h *httpOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && h.logger != nil {
+		h.logger.SetOutput(logger.Writer())
+		h.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (h *httpOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	h.evps, err = formatters.MakeEventProcessors(
+		logger,
+		h.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *httpOutput) SetName(name string) {
+	if h.cfg.Name == "" {
+		h.cfg.Name = name
+	}
+}
+
+func (h *httpOutput) SetClusterName(_ string) {}
+
+func (h *httpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (h *httpOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-h.eventChan:
+			b, err := h.marshalEvent(ev)
+			if err != nil {
+				h.logger.Printf("%v", err)
+				continue
+			}
+			h.buffer(ctx, b)
+		case m := <-h.msgChan:
+			b, err := h.marshalProto(m.GetMsg(), m.GetMeta())
+			if err != nil {
+				if h.cfg.Debug {
+					h.logger.Printf("%v", err)
+				}
+				continue
+			}
+			if len(b) == 0 {
+				continue
+			}
+			h.buffer(ctx, b)
+		}
+	}
+}
+
+func (h *httpOutput) marshalEvent(ev *formatters.EventMsg) ([]byte, error) {
+	b, err := json.Marshal([]*formatters.EventMsg{ev})
+	if err != nil {
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "marshal_error").Inc()
+		return nil, fmt.Errorf("failed to marshal event: %v", err)
+	}
+	return h.applyTemplate(b)
+}
+
+func (h *httpOutput) marshalProto(m proto.Message, meta outputs.Meta) ([]byte, error) {
+	pmsg, err := outputs.AddSubscriptionTarget(m, meta, h.cfg.AddTarget, h.targetTpl)
+	if err != nil {
+		h.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if pmsg != nil {
+		m = pmsg
+	}
+	b, err := h.mo.Marshal(m, meta, h.evps...)
+	if err != nil {
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "marshal_error").Inc()
+		return nil, fmt.Errorf("failed marshaling proto msg: %v", err)
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+	return h.applyTemplate(b)
+}
+
+// applyTemplate executes the msg template, if any, on b.
+func (h *httpOutput) applyTemplate(b []byte) ([]byte, error) {
+	if h.msgTpl == nil {
+		return b, nil
+	}
+	b, err := outputs.ExecTemplate(b, h.msgTpl)
+	if err != nil {
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "template_error").Inc()
+		return nil, fmt.Errorf("failed to execute template: %v", err)
+	}
+	return b, nil
+}
+
+// buffer queues the message for the writer.
+func (h *httpOutput) buffer(ctx context.Context, b []byte) {
+	if h.cfg.Debug {
+		h.logger.Printf("buffering message: %s", b)
+	}
+	select {
+	case <-ctx.Done():
+	case h.bodyCh <- b:
+	}
+}
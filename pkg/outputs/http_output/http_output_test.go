@@ -0,0 +1,155 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestBatchBody(t *testing.T) {
+	tests := map[string]struct {
+		msgs        []string
+		batchFormat string
+		flatten     bool
+		want        string
+	}{
+		"array": {
+			msgs:        []string{`{"a":1}`, `{"b":2}`},
+			batchFormat: "array",
+			want:        `[{"a":1},{"b":2}]`,
+		},
+		"array_no_flatten": {
+			msgs:        []string{`[{"a":1}]`, `[{"b":2}]`},
+			batchFormat: "array",
+			want:        `[[{"a":1}],[{"b":2}]]`,
+		},
+		"array_flatten": {
+			msgs:        []string{`[{"a":1},{"c":3}]`, `[]`, ` [{"b":2}] `},
+			batchFormat: "array",
+			flatten:     true,
+			want:        `[{"a":1},{"c":3},{"b":2}]`,
+		},
+		"ndjson": {
+			msgs:        []string{`{"a":1}`, "", `{"b":2}`},
+			batchFormat: "ndjson",
+			want:        "{\"a\":1}\n{\"b\":2}\n",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			msgs := make([][]byte, 0, len(tt.msgs))
+			for _, m := range tt.msgs {
+				msgs = append(msgs, []byte(m))
+			}
+			got := string(batchBody(msgs, tt.batchFormat, tt.flatten))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value string
+		want  time.Duration
+	}{
+		"empty": {
+			value: "",
+			want:  0,
+		},
+		"seconds": {
+			value: "3",
+			want:  3 * time.Second,
+		},
+		"negative": {
+			value: "-1",
+			want:  0,
+		},
+		"date": {
+			value: now.Add(10 * time.Second).Format(http.TimeFormat),
+			want:  10 * time.Second,
+		},
+		"past_date": {
+			value: now.Add(-10 * time.Second).Format(http.TimeFormat),
+			want:  0,
+		},
+		"invalid": {
+			value: "soon",
+			want:  0,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value, now)
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEventSync(t *testing.T) {
+	tests := map[string]struct {
+		code    int
+		wantErr bool
+	}{
+		"accepted": {
+			code: http.StatusOK,
+		},
+		"retryable": {
+			code:    http.StatusServiceUnavailable,
+			wantErr: true,
+		},
+		"rejected": {
+			code: http.StatusBadRequest,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var reqs atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reqs.Add(1)
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+
+			o := outputs.Outputs[outputType]()
+			err := o.Init(context.Background(), name, map[string]interface{}{
+				"url":    srv.URL,
+				"format": "event",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer o.Close()
+
+			err = o.(outputs.DeliveryWriter).WriteEventSync(context.Background(), &formatters.EventMsg{
+				Name:   "sub1",
+				Values: map[string]interface{}{"counter": 1},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			// WriteEventSync does not retry.
+			if n := reqs.Load(); n != 1 {
+				t.Errorf("got %d requests, want 1", n)
+			}
+		})
+	}
+}
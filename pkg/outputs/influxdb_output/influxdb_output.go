@@ -23,6 +23,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -267,6 +268,52 @@ func (i *influxDBOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg
 	}
 }
 
+// WriteSync writes a message to InfluxDB using the blocking write API,
+// bypassing the cache, it returns once InfluxDB accepted the points.
+func (i *influxDBOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	sub, err := outputs.AddSubscriptionTarget(rsp, meta, i.Cfg.AddTarget, i.targetTpl)
+	if err != nil {
+		i.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if sub == nil {
+		return nil
+	}
+	measName := "default"
+	if subName, ok := meta["subscription-name"]; ok {
+		measName = subName
+	}
+	events, err := formatters.ResponseToEventMsgs(measName, sub, meta, i.evps...)
+	if err != nil {
+		i.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return i.writeBlocking(ctx, events)
+}
+
+// WriteEventSync writes an event to InfluxDB using the blocking write API,
+// it returns once InfluxDB accepted the points.
+func (i *influxDBOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range i.evps {
+		evs = proc.Apply(evs...)
+	}
+	return i.writeBlocking(ctx, evs)
+}
+
+func (i *influxDBOutput) writeBlocking(ctx context.Context, evs []*formatters.EventMsg) error {
+	points := make([]*write.Point, 0, len(evs))
+	for _, ev := range evs {
+		points = append(points, i.points(ev)...)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return i.client.WriteAPIBlocking(i.Cfg.Org, i.Cfg.Bucket).WritePoint(ctx, points...)
+}
+
 func (i *influxDBOutput) Close() error {
 	i.logger.Printf("closing client...")
 	if i.Cfg.CacheConfig != nil {
@@ -346,43 +393,8 @@ START:
 			i.logger.Printf("worker-%d terminating...", idx)
 			return
 		case ev := <-i.eventChan:
-			if len(ev.Values) == 0 && len(ev.Deletes) == 0 {
-				continue
-			}
-			if len(ev.Values) == 0 && i.Cfg.DeleteTag == "" {
-				continue
-			}
-			for n, v := range ev.Values {
-				switch v := v.(type) {
-				//lint:ignore SA1019 still need DecimalVal for backward compatibility
-				case *gnmi.Decimal64:
-					ev.Values[n] = float64(v.Digits) / math.Pow10(int(v.Precision))
-				}
-			}
-			if ev.Timestamp == 0 || i.Cfg.OverrideTimestamps {
-				ev.Timestamp = time.Now().UnixNano()
-			}
-			if subscriptionName, ok := ev.Tags["subscription-name"]; ok {
-				ev.Name = subscriptionName
-				delete(ev.Tags, "subscription-name")
-			}
-
-			if len(ev.Values) > 0 {
-				i.convertUints(ev)
-				writer.WritePoint(influxdb2.NewPoint(ev.Name, ev.Tags, ev.Values, time.Unix(0, ev.Timestamp)))
-			}
-
-			if len(ev.Deletes) > 0 && i.Cfg.DeleteTag != "" {
-				tags := make(map[string]string, len(ev.Tags))
-				for k, v := range ev.Tags {
-					tags[k] = v
-				}
-				tags[i.Cfg.DeleteTag] = deleteTagValue
-				values := make(map[string]any, len(ev.Deletes))
-				for _, del := range ev.Deletes {
-					values[del] = ""
-				}
-				writer.WritePoint(influxdb2.NewPoint(ev.Name, tags, values, time.Unix(0, ev.Timestamp)))
+			for _, p := range i.points(ev) {
+				writer.WritePoint(p)
 			}
 		case <-i.reset:
 			firstStart = false
@@ -394,6 +406,48 @@ START:
 	}
 }
 
+// points converts an event to the points written to InfluxDB.
+func (i *influxDBOutput) points(ev *formatters.EventMsg) []*write.Point {
+	if len(ev.Values) == 0 && len(ev.Deletes) == 0 {
+		return nil
+	}
+	if len(ev.Values) == 0 && i.Cfg.DeleteTag == "" {
+		return nil
+	}
+	for n, v := range ev.Values {
+		switch v := v.(type) {
+		//lint:ignore SA1019 still need DecimalVal for backward compatibility
+		case *gnmi.Decimal64:
+			ev.Values[n] = float64(v.Digits) / math.Pow10(int(v.Precision))
+		}
+	}
+	if ev.Timestamp == 0 || i.Cfg.OverrideTimestamps {
+		ev.Timestamp = time.Now().UnixNano()
+	}
+	if subscriptionName, ok := ev.Tags["subscription-name"]; ok {
+		ev.Name = subscriptionName
+		delete(ev.Tags, "subscription-name")
+	}
+	points := make([]*write.Point, 0, 2)
+	if len(ev.Values) > 0 {
+		i.convertUints(ev)
+		points = append(points, influxdb2.NewPoint(ev.Name, ev.Tags, ev.Values, time.Unix(0, ev.Timestamp)))
+	}
+	if len(ev.Deletes) > 0 && i.Cfg.DeleteTag != "" {
+		tags := make(map[string]string, len(ev.Tags))
+		for k, v := range ev.Tags {
+			tags[k] = v
+		}
+		tags[i.Cfg.DeleteTag] = deleteTagValue
+		values := make(map[string]any, len(ev.Deletes))
+		for _, del := range ev.Deletes {
+			values[del] = ""
+		}
+		points = append(points, influxdb2.NewPoint(ev.Name, tags, values, time.Unix(0, ev.Timestamp)))
+	}
+	return points
+}
+
 func (i *influxDBOutput) SetName(name string)                             {}
 func (i *influxDBOutput) SetClusterName(name string)                      {}
 func (i *influxDBOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
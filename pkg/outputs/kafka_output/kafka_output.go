@@ -74,6 +74,10 @@ type kafkaOutput struct {
 	msgTpl    *template.Template
 
 	reg *prometheus.Registry
+
+	m            sync.Mutex
+	config       *sarama.Config
+	syncProducer sarama.SyncProducer // used by WriteSync, created on first use.
 }
 
 // config //
@@ -189,6 +193,7 @@ func (k *kafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 	if err != nil {
 		return err
 	}
+	k.config = config
 	ctx, k.cancelFn = context.WithCancel(ctx)
 	k.wg.Add(k.cfg.NumWorkers)
 	for i := 0; i < k.cfg.NumWorkers; i++ {
@@ -282,10 +287,75 @@ func (k *kafkaOutput) Write(ctx context.Context, rsp proto.Message, meta outputs
 
 func (k *kafkaOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
+// WriteSync sends a message using a sync producer,
+// it returns once the message was acknowledged according to `required-acks`.
+func (k *kafkaOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	pmsg, err := outputs.AddSubscriptionTarget(rsp, meta, k.cfg.AddTarget, k.targetTpl)
+	if err != nil {
+		k.logger.Printf("failed to add target to the response: %v", err)
+	}
+	bb, err := outputs.Marshal(pmsg, meta, k.mo, k.cfg.SplitEvents, k.evps...)
+	if err != nil {
+		if k.cfg.Debug {
+			k.logger.Printf("failed marshaling proto msg: %v", err)
+		}
+		return nil
+	}
+	msgs := make([]*sarama.ProducerMessage, 0, len(bb))
+	for _, b := range bb {
+		if k.msgTpl != nil {
+			b, err = outputs.ExecTemplate(b, k.msgTpl)
+			if err != nil {
+				if k.cfg.Debug {
+					k.logger.Printf("failed to execute template: %v", err)
+				}
+				continue
+			}
+		}
+		msg := &sarama.ProducerMessage{
+			Topic: k.selectTopic(meta),
+			Value: sarama.ByteEncoder(b),
+		}
+		if k.cfg.InsertKey {
+			msg.Key = sarama.ByteEncoder(k.partitionKey(meta))
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	k.m.Lock()
+	defer k.m.Unlock()
+	if k.syncProducer == nil {
+		k.syncProducer, err = sarama.NewSyncProducer(strings.Split(k.cfg.Address, ","), k.config)
+		if err != nil {
+			return err
+		}
+	}
+	err = k.syncProducer.SendMessages(msgs)
+	if err != nil {
+		k.syncProducer.Close()
+		k.syncProducer = nil
+		return err
+	}
+	return nil
+}
+
+func (k *kafkaOutput) WriteEventSync(context.Context, *formatters.EventMsg) error { return nil }
+
 // Close //
 func (k *kafkaOutput) Close() error {
 	k.cancelFn()
 	k.wg.Wait()
+	k.m.Lock()
+	if k.syncProducer != nil {
+		k.syncProducer.Close()
+		k.syncProducer = nil
+	}
+	k.m.Unlock()
 	return nil
 }
 
@@ -0,0 +1,308 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	backoff      = 100 * time.Millisecond
+	maxBackoff   = 5 * time.Second
+	drainTimeout = 5 * time.Second
+	// max number of response body bytes included in error messages.
+	maxErrorBodySize = 1024
+)
+
+// batch groups the pending entries per stream.
+type batch struct {
+	streams map[string]*stream
+	// stream keys in arrival order
+	order   []string
+	entries int
+	bytes   int
+}
+
+type stream struct {
+	labels  map[string]string
+	entries []*entry
+}
+
+type pushRequest struct {
+	Streams []*pushStream `json:"streams"`
+}
+
+type pushStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func newBatch() *batch {
+	return &batch{streams: make(map[string]*stream)}
+}
+
+func (b *batch) add(e *entry) {
+	s, ok := b.streams[e.key]
+	if !ok {
+		s = &stream{labels: e.labels}
+		b.streams[e.key] = s
+		b.order = append(b.order, e.key)
+	}
+	s.entries = append(s.entries, e)
+	b.entries++
+	b.bytes += len(e.line)
+}
+
+func (b *batch) empty() bool {
+	return b.entries == 0
+}
+
+// pushBody builds the push API request body,
+// the entries of each stream are sorted by timestamp.
+func (b *batch) pushBody() ([]byte, error) {
+	req := &pushRequest{
+		Streams: make([]*pushStream, 0, len(b.order)),
+	}
+	for _, k := range b.order {
+		s := b.streams[k]
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].ts < s.entries[j].ts
+		})
+		ps := &pushStream{
+			Stream: s.labels,
+			Values: make([][2]string, 0, len(s.entries)),
+		}
+		for _, e := range s.entries {
+			ps.Values = append(ps.Values, [2]string{strconv.FormatInt(e.ts, 10), e.line})
+		}
+		req.Streams = append(req.Streams, ps)
+	}
+	return json.Marshal(req)
+}
+
+func (l *lokiOutput) createHTTPClient() error {
+	c := &http.Client{
+		Timeout: l.cfg.Timeout,
+	}
+	if l.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			l.cfg.TLS.CaFile,
+			l.cfg.TLS.CertFile,
+			l.cfg.TLS.KeyFile,
+			"",
+			l.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	l.httpClient = c
+	return nil
+}
+
+func (l *lokiOutput) writer(ctx context.Context) {
+	defer l.wg.Done()
+	l.logger.Printf("starting writer")
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+	b := newBatch()
+	for {
+		select {
+		case <-ctx.Done():
+			l.drain(b)
+			return
+		case e := <-l.entryCh:
+			b.add(e)
+			if !l.batchFull(b) {
+				continue
+			}
+			if l.cfg.Debug {
+				l.logger.Printf("batch size reached, pushing to loki")
+			}
+			l.write(ctx, b)
+			b = newBatch()
+		case <-ticker.C:
+			if b.empty() {
+				continue
+			}
+			if l.cfg.Debug {
+				l.logger.Printf("flush interval reached, pushing to loki")
+			}
+			l.write(ctx, b)
+			b = newBatch()
+		}
+	}
+}
+
+func (l *lokiOutput) batchFull(b *batch) bool {
+	return b.entries >= l.cfg.BatchSize || b.bytes >= l.cfg.BatchBytes
+}
+
+// drain pushes the pending entries when the output is closed.
+func (l *lokiOutput) drain(b *batch) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for {
+		select {
+		case e := <-l.entryCh:
+			b.add(e)
+			if l.batchFull(b) {
+				l.write(ctx, b)
+				b = newBatch()
+			}
+		default:
+			if !b.empty() {
+				l.write(ctx, b)
+			}
+			return
+		}
+	}
+}
+
+// write pushes the batch, retrying on transport errors,
+// 429 and 5xx responses.
+func (l *lokiOutput) write(ctx context.Context, b *batch) {
+	body, err := b.pushBody()
+	if err != nil {
+		l.logger.Printf("failed to build push request: %v", err)
+		lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "marshal_error").Add(float64(b.entries))
+		return
+	}
+	retries := 0
+	wait := backoff
+	for {
+		start := time.Now()
+		retry, err := l.push(ctx, body)
+		lokiPushDuration.WithLabelValues(l.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		if err == nil {
+			lokiNumberOfSentEntries.WithLabelValues(l.cfg.Name).Add(float64(b.entries))
+			lokiNumberOfSentStreams.WithLabelValues(l.cfg.Name).Add(float64(len(b.order)))
+			return
+		}
+		l.logger.Printf("push failed: %v", err)
+		if !retry {
+			lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "rejected").Add(float64(b.entries))
+			return
+		}
+		if retries >= l.cfg.MaxRetries {
+			lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "max_retries").Add(float64(b.entries))
+			l.logger.Printf("dropping %d entries after %d retries", b.entries, retries)
+			return
+		}
+		retries++
+		if l.cfg.Debug {
+			l.logger.Printf("retrying push in %s", wait)
+		}
+		select {
+		case <-ctx.Done():
+			lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "canceled").Add(float64(b.entries))
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+	}
+}
+
+// writeSync pushes the entries built from evs in a single request without retries,
+// it returns an error if the request should be retried, rejected entries are dropped.
+func (l *lokiOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	b := newBatch()
+	for _, ev := range evs {
+		e, err := l.newEntry(ev)
+		if err != nil {
+			l.logger.Printf("failed to build log entry: %v", err)
+			lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "marshal_error").Inc()
+			continue
+		}
+		b.add(e)
+	}
+	if b.empty() {
+		return nil
+	}
+	body, err := b.pushBody()
+	if err != nil {
+		l.logger.Printf("failed to build push request: %v", err)
+		lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "marshal_error").Add(float64(b.entries))
+		return nil
+	}
+	start := time.Now()
+	retry, err := l.push(ctx, body)
+	lokiPushDuration.WithLabelValues(l.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	if err != nil {
+		if retry {
+			return err
+		}
+		l.logger.Printf("push failed: %v", err)
+		lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "rejected").Add(float64(b.entries))
+		return nil
+	}
+	lokiNumberOfSentEntries.WithLabelValues(l.cfg.Name).Add(float64(b.entries))
+	lokiNumberOfSentStreams.WithLabelValues(l.cfg.Name).Add(float64(len(b.order)))
+	return nil
+}
+
+// push sends a push request, it returns true along with the error
+// if the request should be retried.
+func (l *lokiOutput) push(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if l.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.cfg.TenantID)
+	}
+	for k, v := range l.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case l.cfg.Authorization != nil && l.cfg.Authorization.Type != "":
+		req.Header.Set("Authorization", fmt.Sprintf("%s %s", l.cfg.Authorization.Type, l.cfg.Authorization.Credentials))
+	case l.cfg.Authentication != nil:
+		req.SetBasicAuth(l.cfg.Authentication.Username, l.cfg.Authentication.Password)
+	}
+	rsp, err := l.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer rsp.Body.Close()
+	if l.cfg.Debug {
+		l.logger.Printf("got response from loki: status=%s", rsp.Status)
+	}
+	if rsp.StatusCode < 300 {
+		io.Copy(io.Discard, rsp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(rsp.Body, maxErrorBodySize))
+	err = fmt.Errorf("push response failed, code=%d, body=%s", rsp.StatusCode, string(msg))
+	if rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= 500 {
+		return true, err
+	}
+	// the request is rejected, retrying won't help.
+	return false, err
+}
@@ -0,0 +1,186 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const defaultStreamLabel = "job"
+
+// entry is a single log line pending push.
+type entry struct {
+	// stream labels
+	labels map[string]string
+	// stream identifier, built from the sorted labels
+	key  string
+	ts   int64
+	line string
+}
+
+// lineEvent is the json log line, it holds the event fields
+// not used as stream labels.
+type lineEvent struct {
+	Name    string                 `json:"name,omitempty"`
+	Tags    map[string]string      `json:"tags,omitempty"`
+	Values  map[string]interface{} `json:"values,omitempty"`
+	Deletes []string               `json:"deletes,omitempty"`
+}
+
+func (l *lokiOutput) newEntry(ev *formatters.EventMsg) (*entry, error) {
+	labels := make(map[string]string, len(l.cfg.Labels)+len(l.cfg.StaticLabels))
+	for k, v := range l.cfg.StaticLabels {
+		labels[k] = v
+	}
+	tags := make(map[string]string, len(ev.Tags))
+	for k, v := range ev.Tags {
+		if _, ok := l.labels[k]; ok && v != "" {
+			labels[labelName(k)] = v
+			continue
+		}
+		tags[k] = v
+	}
+	// Loki rejects streams without labels.
+	if len(labels) == 0 {
+		labels[defaultStreamLabel] = "gnmic"
+	}
+	le := &lineEvent{
+		Name:    ev.Name,
+		Tags:    tags,
+		Values:  ev.Values,
+		Deletes: ev.Deletes,
+	}
+	var line string
+	var err error
+	switch l.cfg.LineFormat {
+	case "logfmt":
+		line, err = logfmtLine(le)
+	default:
+		var b []byte
+		b, err = json.Marshal(le)
+		line = string(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ts := ev.Timestamp
+	if ts <= 0 {
+		ts = time.Now().UnixNano()
+	}
+	return &entry{
+		labels: labels,
+		key:    streamKey(labels),
+		ts:     ts,
+		line:   line,
+	}, nil
+}
+
+// labelName converts a tag name into a valid Loki label name,
+// invalid characters are replaced with `_`.
+func labelName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// streamKey builds a unique stream identifier from its labels.
+func streamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sb := new(strings.Builder)
+	sb.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(strconv.Quote(labels[k]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// logfmtLine writes the event as `key=value` pairs,
+// the name first, then the sorted tags, values and deletes.
+func logfmtLine(le *lineEvent) (string, error) {
+	sb := new(strings.Builder)
+	writePair := func(k, v string) {
+		if sb.Len() > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(logfmtValue(k))
+		sb.WriteString("=")
+		sb.WriteString(logfmtValue(v))
+	}
+	if le.Name != "" {
+		writePair("name", le.Name)
+	}
+	for _, k := range sortedKeys(le.Tags) {
+		writePair(k, le.Tags[k])
+	}
+	for _, k := range sortedKeys(le.Values) {
+		switch v := le.Values[k].(type) {
+		case string:
+			writePair(k, v)
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			writePair(k, string(b))
+		default:
+			writePair(k, fmt.Sprint(v))
+		}
+	}
+	for _, d := range le.Deletes {
+		writePair("delete", d)
+	}
+	return sb.String(), nil
+}
+
+// logfmtValue quotes s if it is empty or contains
+// spaces, quotes, `=` or control characters.
+func logfmtValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
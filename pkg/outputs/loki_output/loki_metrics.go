@@ -0,0 +1,84 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "loki_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var lokiNumberOfSentEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_entries_sent_success_total",
+	Help:      "Number of log entries successfully pushed by gnmic loki output",
+}, []string{"name"})
+
+var lokiNumberOfSentStreams = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_streams_sent_success_total",
+	Help:      "Number of streams successfully pushed by gnmic loki output",
+}, []string{"name"})
+
+var lokiNumberOfFailedEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_entries_sent_fail_total",
+	Help:      "Number of log entries gnmic loki output failed to push",
+}, []string{"name", "reason"})
+
+var lokiPushDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "push_request_duration_ns",
+	Help:      "gnmic loki output push request duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	lokiNumberOfSentEntries.WithLabelValues(name).Add(0)
+	lokiNumberOfSentStreams.WithLabelValues(name).Add(0)
+	lokiNumberOfFailedEntries.WithLabelValues(name, "").Add(0)
+	lokiPushDuration.WithLabelValues(name).Set(0)
+}
+
+func (l *lokiOutput) registerMetrics() error {
+	if l.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = l.reg.Register(lokiNumberOfSentEntries); err != nil {
+			l.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = l.reg.Register(lokiNumberOfSentStreams); err != nil {
+			l.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = l.reg.Register(lokiNumberOfFailedEntries); err != nil {
+			l.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = l.reg.Register(lokiPushDuration); err != nil {
+			l.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(l.cfg.Name)
+	return err
+}
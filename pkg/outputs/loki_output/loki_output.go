@@ -0,0 +1,427 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "loki"
+	loggingPrefix        = "[loki_output:%s] "
+	pushPath             = "/loki/api/v1/push"
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 1000
+	defaultBatchSize     = 1000
+	defaultBatchBytes    = 1024 * 1024
+	defaultMaxRetries    = 3
+	defaultNumWorkers    = 1
+	defaultLineFormat    = "json"
+	userAgent            = "gNMIc loki"
+)
+
+var defaultLabels = []string{"source", "subscription-name"}
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &lokiOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type lokiOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	httpClient *http.Client
+	eventChan  chan *formatters.EventMsg
+	msgChan    chan *outputs.ProtoMsg
+	entryCh    chan *entry
+	// tag names used as stream labels
+	labels map[string]struct{}
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name           string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	URL            string            `mapstructure:"url,omitempty" json:"url,omitempty"`
+	Timeout        time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers        map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	TenantID       string            `mapstructure:"tenant-id,omitempty" json:"tenant-id,omitempty"`
+	Authentication *auth             `mapstructure:"authentication,omitempty" json:"authentication,omitempty"`
+	Authorization  *authorization    `mapstructure:"authorization,omitempty" json:"authorization,omitempty"`
+	TLS            *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	// event tags used as stream labels
+	Labels       []string          `mapstructure:"labels,omitempty" json:"labels,omitempty"`
+	StaticLabels map[string]string `mapstructure:"static-labels,omitempty" json:"static-labels,omitempty"`
+	// log line format, `json` or `logfmt`.
+	LineFormat      string        `mapstructure:"line-format,omitempty" json:"line-format,omitempty"`
+	FlushInterval   time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	BatchSize       int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BatchBytes      int           `mapstructure:"batch-bytes,omitempty" json:"batch-bytes,omitempty"`
+	MaxRetries      int           `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	AddTarget       string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics   bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+type auth struct {
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"password,omitempty"`
+}
+
+type authorization struct {
+	Type        string `mapstructure:"type,omitempty" json:"type,omitempty"`
+	Credentials string `mapstructure:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+func (l *lokiOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, l.cfg)
+	if err != nil {
+		return err
+	}
+	if l.cfg.Name == "" {
+		l.cfg.Name = name
+	}
+	l.logger.SetPrefix(fmt.Sprintf(loggingPrefix, l.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return err
+		}
+	}
+	err = l.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = l.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if l.cfg.TargetTemplate == "" {
+		l.targetTpl = outputs.DefaultTargetTemplate
+	} else if l.cfg.AddTarget != "" {
+		l.targetTpl, err = gtemplate.CreateTemplate("target-template", l.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		l.targetTpl = l.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	l.labels = make(map[string]struct{}, len(l.cfg.Labels))
+	for _, lb := range l.cfg.Labels {
+		l.labels[lb] = struct{}{}
+	}
+
+	err = l.createHTTPClient()
+	if err != nil {
+		return err
+	}
+	l.entryCh = make(chan *entry, l.cfg.BufferSize)
+
+	ctx, l.cfn = context.WithCancel(ctx)
+	for i := 0; i < l.cfg.NumWorkers; i++ {
+		go l.worker(ctx)
+	}
+	l.wg.Add(1)
+	go l.writer(ctx)
+	l.logger.Printf("initialized loki output %s: %s", l.cfg.Name, l.String())
+	return nil
+}
+
+func (l *lokiOutput) setDefaults() error {
+	if l.cfg.URL == "" {
+		return errors.New("missing url field")
+	}
+	if !strings.HasPrefix(l.cfg.URL, "http://") && !strings.HasPrefix(l.cfg.URL, "https://") {
+		if l.cfg.TLS != nil {
+			l.cfg.URL = "https://" + l.cfg.URL
+		} else {
+			l.cfg.URL = "http://" + l.cfg.URL
+		}
+	}
+	u, err := url.Parse(l.cfg.URL)
+	if err != nil {
+		return err
+	}
+	// allow setting the Loki base URL only.
+	if u.Path == "" || u.Path == "/" {
+		u.Path = pushPath
+		l.cfg.URL = u.String()
+	}
+	switch l.cfg.LineFormat {
+	case "":
+		l.cfg.LineFormat = defaultLineFormat
+	case "json", "logfmt":
+	default:
+		return fmt.Errorf("unknown line-format value %q", l.cfg.LineFormat)
+	}
+	if len(l.cfg.Labels) == 0 {
+		l.cfg.Labels = defaultLabels
+	}
+	for k := range l.cfg.StaticLabels {
+		if labelName(k) != k {
+			return fmt.Errorf("invalid static label name %q", k)
+		}
+	}
+	if l.cfg.Timeout <= 0 {
+		l.cfg.Timeout = defaultTimeout
+	}
+	if l.cfg.FlushInterval <= 0 {
+		l.cfg.FlushInterval = defaultFlushInterval
+	}
+	if l.cfg.BufferSize <= 0 {
+		l.cfg.BufferSize = defaultBufferSize
+	}
+	if l.cfg.BatchSize <= 0 {
+		l.cfg.BatchSize = defaultBatchSize
+	}
+	if l.cfg.BatchBytes <= 0 {
+		l.cfg.BatchBytes = defaultBatchBytes
+	}
+	if l.cfg.MaxRetries < 0 {
+		l.cfg.MaxRetries = 0
+	} else if l.cfg.MaxRetries == 0 {
+		l.cfg.MaxRetries = defaultMaxRetries
+	}
+	if l.cfg.NumWorkers <= 0 {
+		l.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
+
+func (l *lokiOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, l.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case l.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if l.cfg.Debug {
+			l.logger.Printf("writing expired after %s", l.cfg.Timeout)
+		}
+		lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (l *lokiOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range l.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case l.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync pushes the entries built from a message in a single request,
+// it returns once loki accepted them.
+func (l *lokiOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := l.protoEvents(rsp, meta)
+	if err != nil {
+		l.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return l.writeSync(ctx, events)
+}
+
+// WriteEventSync pushes the entries built from an event in a single request,
+// it returns once loki accepted them.
+func (l *lokiOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range l.evps {
+		evs = proc.Apply(evs...)
+	}
+	return l.writeSync(ctx, evs)
+}
+
+func (l *lokiOutput) Close() error {
+	if l.cfn == nil {
+		return nil
+	}
+	l.cfn()
+	// wait for the buffered entries to be pushed
+	l.wg.Wait()
+	return nil
+}
+
+func (l *lokiOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !l.cfg.EnableMetrics {
+		return
+	}
+	l.reg = reg
+}
+
+func (l *lokiOutput) String() string {
+	cfg := *l.cfg
+	if cfg.Authentication != nil {
+		cfg.Authentication = &auth{Username: cfg.Authentication.Username, Password: "****"}
+	}
+	if cfg.Authorization != nil {
+		cfg.Authorization = &authorization{Type: cfg.Authorization.Type, Credentials: "****"}
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (l *lokiOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && l.logger != nil {
+		l.logger.SetOutput(logger.Writer())
+		l.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (l *lokiOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	l.evps, err = formatters.MakeEventProcessors(
+		logger,
+		l.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *lokiOutput) SetName(name string) {
+	if l.cfg.Name == "" {
+		l.cfg.Name = name
+	}
+}
+
+func (l *lokiOutput) SetClusterName(_ string) {}
+
+func (l *lokiOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (l *lokiOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-l.eventChan:
+			l.workerHandleEvent(ctx, ev)
+		case m := <-l.msgChan:
+			l.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (l *lokiOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	events, err := l.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		l.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		l.workerHandleEvent(ctx, ev)
+	}
+}
+
+func (l *lokiOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	rsp, err := outputs.AddSubscriptionTarget(pmsg, meta, l.cfg.AddTarget, l.targetTpl)
+	if err != nil {
+		l.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if rsp != nil {
+		pmsg = rsp
+	}
+	return formatters.ResponseToEventMsgs(subName, pmsg, meta, l.evps...)
+}
+
+func (l *lokiOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	e, err := l.newEntry(ev)
+	if err != nil {
+		l.logger.Printf("failed to build log entry: %v", err)
+		lokiNumberOfFailedEntries.WithLabelValues(l.cfg.Name, "marshal_error").Inc()
+		return
+	}
+	if l.cfg.Debug {
+		l.logger.Printf("buffering entry for stream %s: %s", e.key, e.line)
+	}
+	select {
+	case <-ctx.Done():
+	case l.entryCh <- e:
+	}
+}
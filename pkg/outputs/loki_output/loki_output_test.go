@@ -0,0 +1,142 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func TestLabelName(t *testing.T) {
+	tests := map[string]string{
+		"source":            "source",
+		"subscription-name": "subscription_name",
+		"interface_name":    "interface_name",
+		"1abc":              "_abc",
+		"a1.b":              "a1_b",
+	}
+	for in, want := range tests {
+		if got := labelName(in); got != want {
+			t.Errorf("labelName(%q): got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 42,
+		Tags: map[string]string{
+			"source":            "r1",
+			"subscription-name": "sub1",
+			"interface_name":    "ethernet-1/1",
+		},
+		Values: map[string]interface{}{
+			"/interface/oper-state": "down",
+		},
+	}
+	tests := map[string]struct {
+		labels       []string
+		staticLabels map[string]string
+		lineFormat   string
+		wantKey      string
+		wantLine     string
+	}{
+		"json": {
+			labels:     []string{"source", "subscription-name"},
+			lineFormat: "json",
+			wantKey:    `{source="r1",subscription_name="sub1"}`,
+			wantLine:   `{"name":"sub1","tags":{"interface_name":"ethernet-1/1"},"values":{"/interface/oper-state":"down"}}`,
+		},
+		"logfmt": {
+			labels:     []string{"source"},
+			lineFormat: "logfmt",
+			wantKey:    `{source="r1"}`,
+			wantLine:   `name=sub1 interface_name=ethernet-1/1 subscription-name=sub1 /interface/oper-state=down`,
+		},
+		"static_labels": {
+			labels:       []string{"source"},
+			staticLabels: map[string]string{"env": "lab"},
+			lineFormat:   "logfmt",
+			wantKey:      `{env="lab",source="r1"}`,
+			wantLine:     `name=sub1 interface_name=ethernet-1/1 subscription-name=sub1 /interface/oper-state=down`,
+		},
+		"no_labels": {
+			labels:     []string{"unknown"},
+			lineFormat: "logfmt",
+			wantKey:    `{job="gnmic"}`,
+			wantLine:   `name=sub1 interface_name=ethernet-1/1 source=r1 subscription-name=sub1 /interface/oper-state=down`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			l := &lokiOutput{
+				cfg: &config{
+					Labels:       tt.labels,
+					StaticLabels: tt.staticLabels,
+					LineFormat:   tt.lineFormat,
+				},
+				labels: make(map[string]struct{}),
+			}
+			for _, lb := range tt.labels {
+				l.labels[lb] = struct{}{}
+			}
+			e, err := l.newEntry(ev)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if e.key != tt.wantKey {
+				t.Errorf("key: got %s, want %s", e.key, tt.wantKey)
+			}
+			if e.line != tt.wantLine {
+				t.Errorf("line: got %s, want %s", e.line, tt.wantLine)
+			}
+			if e.ts != 42 {
+				t.Errorf("timestamp: got %d, want 42", e.ts)
+			}
+		})
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	tests := map[string]string{
+		"":          `""`,
+		"up":        "up",
+		"a b":       `"a b"`,
+		"k=v":       `"k=v"`,
+		`say "hi"`:  `"say \"hi\""`,
+		"line\nbrk": `"line\nbrk"`,
+	}
+	for in, want := range tests {
+		if got := logfmtValue(in); got != want {
+			t.Errorf("logfmtValue(%q): got %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestPushBody(t *testing.T) {
+	b := newBatch()
+	s1 := map[string]string{"source": "r1"}
+	s2 := map[string]string{"source": "r2"}
+	b.add(&entry{labels: s1, key: streamKey(s1), ts: 3, line: "c"})
+	b.add(&entry{labels: s2, key: streamKey(s2), ts: 1, line: "x"})
+	b.add(&entry{labels: s1, key: streamKey(s1), ts: 1, line: "a"})
+	if b.entries != 3 || b.bytes != 3 {
+		t.Fatalf("unexpected batch size: entries=%d, bytes=%d", b.entries, b.bytes)
+	}
+	body, err := b.pushBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"streams":[{"stream":{"source":"r1"},"values":[["1","a"],["3","c"]]},{"stream":{"source":"r2"},"values":[["1","x"]]}]}`
+	if string(body) != want {
+		t.Errorf("got %s, want %s", body, want)
+	}
+}
@@ -0,0 +1,223 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_output
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+// publisher abstracts the MQTT 3.1.1 and MQTT 5 clients.
+type publisher interface {
+	publish(ctx context.Context, topic string, payload []byte, props map[string]string) error
+	close(ctx context.Context) error
+}
+
+func (m *mqttOutput) createPublisher(ctx context.Context) (publisher, error) {
+	var tlsCfg *tls.Config
+	var err error
+	if m.cfg.TLS != nil {
+		tlsCfg, err = utils.NewTLSConfig(
+			m.cfg.TLS.CaFile,
+			m.cfg.TLS.CertFile,
+			m.cfg.TLS.KeyFile,
+			"",
+			m.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch m.cfg.ProtocolVersion {
+	case protocolVersion5:
+		return m.newPublisherV5(ctx, tlsCfg)
+	default:
+		return m.newPublisherV3(tlsCfg)
+	}
+}
+
+// brokerURL adds a scheme to the address if it doesn't have one.
+func brokerURL(address string, secure bool) string {
+	if strings.Contains(address, "://") {
+		return address
+	}
+	if secure {
+		return "ssl://" + address
+	}
+	return "tcp://" + address
+}
+
+// MQTT 3.1.1
+
+type publisherV3 struct {
+	client mqtt.Client
+	qos    byte
+	retain bool
+}
+
+func (m *mqttOutput) newPublisherV3(tlsCfg *tls.Config) (publisher, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(brokerURL(m.cfg.Address, tlsCfg != nil)).
+		SetClientID(m.cfg.ClientID).
+		SetCleanSession(!m.cfg.PersistentSession).
+		SetKeepAlive(m.cfg.KeepAlive).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(m.cfg.ConnectTimeWait).
+		SetMaxReconnectInterval(m.cfg.ConnectTimeWait).
+		SetOnConnectHandler(func(mqtt.Client) {
+			m.logger.Printf("connected to MQTT broker %s", m.cfg.Address)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Printf("connection to MQTT broker lost: %v", err)
+		})
+	if m.cfg.Username != "" {
+		opts.SetUsername(m.cfg.Username)
+		opts.SetPassword(m.cfg.Password)
+	}
+	if tlsCfg != nil {
+		opts.SetTLSConfig(tlsCfg)
+	}
+	client := mqtt.NewClient(opts)
+	// with ConnectRetry enabled the token completes once connected,
+	// messages published in the meantime are queued.
+	client.Connect()
+	return &publisherV3{
+		client: client,
+		qos:    m.cfg.QoS,
+		retain: m.cfg.Retain,
+	}, nil
+}
+
+func (p *publisherV3) publish(ctx context.Context, topic string, payload []byte, _ map[string]string) error {
+	t := p.client.Publish(topic, p.qos, p.retain, payload)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.Done():
+		return t.Error()
+	}
+}
+
+func (p *publisherV3) close(ctx context.Context) error {
+	quiesce := uint(250)
+	if dl, ok := ctx.Deadline(); ok {
+		if ms := time.Until(dl).Milliseconds(); ms > 0 {
+			quiesce = uint(ms)
+		}
+	}
+	p.client.Disconnect(quiesce)
+	return nil
+}
+
+// MQTT 5
+
+type publisherV5 struct {
+	cm          *autopaho.ConnectionManager
+	qos         byte
+	retain      bool
+	contentType string
+}
+
+func (m *mqttOutput) newPublisherV5(ctx context.Context, tlsCfg *tls.Config) (publisher, error) {
+	u, err := url.Parse(brokerURL(m.cfg.Address, tlsCfg != nil))
+	if err != nil {
+		return nil, err
+	}
+	cfg := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{u},
+		TlsCfg:                        tlsCfg,
+		KeepAlive:                     uint16(m.cfg.KeepAlive.Seconds()),
+		CleanStartOnInitialConnection: !m.cfg.PersistentSession,
+		ConnectRetryDelay:             m.cfg.ConnectTimeWait,
+		ConnectTimeout:                m.cfg.ConnectTimeWait,
+		ConnectUsername:               m.cfg.Username,
+		ConnectPassword:               []byte(m.cfg.Password),
+		OnConnectionUp: func(*autopaho.ConnectionManager, *paho.Connack) {
+			m.logger.Printf("connected to MQTT broker %s", m.cfg.Address)
+		},
+		OnConnectError: func(err error) {
+			m.logger.Printf("failed to connect to MQTT broker %s: %v", m.cfg.Address, err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: m.cfg.ClientID,
+			OnClientError: func(err error) {
+				m.logger.Printf("MQTT client error: %v", err)
+			},
+		},
+	}
+	if m.cfg.PersistentSession {
+		cfg.SessionExpiryInterval = sessionExpiryInterval
+	}
+	cm, err := autopaho.NewConnection(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	p := &publisherV5{
+		cm:     cm,
+		qos:    m.cfg.QoS,
+		retain: m.cfg.Retain,
+	}
+	switch m.cfg.Format {
+	case "proto":
+		p.contentType = "application/x-protobuf"
+	default:
+		p.contentType = "application/json"
+	}
+	return p, nil
+}
+
+func (p *publisherV5) publish(ctx context.Context, topic string, payload []byte, props map[string]string) error {
+	err := p.cm.AwaitConnection(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = p.cm.Publish(ctx, &paho.Publish{
+		QoS:     p.qos,
+		Retain:  p.retain,
+		Topic:   topic,
+		Payload: payload,
+		Properties: &paho.PublishProperties{
+			ContentType: p.contentType,
+			User:        userProperties(props),
+		},
+	})
+	return err
+}
+
+func (p *publisherV5) close(ctx context.Context) error {
+	return p.cm.Disconnect(ctx)
+}
+
+func userProperties(props map[string]string) paho.UserProperties {
+	if len(props) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ups := make(paho.UserProperties, 0, len(keys))
+	for _, k := range keys {
+		ups = append(ups, paho.UserProperty{Key: k, Value: props[k]})
+	}
+	return ups
+}
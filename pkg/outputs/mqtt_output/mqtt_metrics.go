@@ -0,0 +1,84 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "mqtt_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var mqttNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_mqtt_msgs_sent_success_total",
+	Help:      "Number of msgs successfully sent by gnmic mqtt output",
+}, []string{"name"})
+
+var mqttNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_written_mqtt_bytes_total",
+	Help:      "Number of bytes written by gnmic mqtt output",
+}, []string{"name"})
+
+var mqttNumberOfFailSendMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_mqtt_msgs_sent_fail_total",
+	Help:      "Number of failed msgs sent by gnmic mqtt output",
+}, []string{"name", "reason"})
+
+var mqttSendDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "msg_send_duration_ns",
+	Help:      "gnmic mqtt output send duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	mqttNumberOfSentMsgs.WithLabelValues(name).Add(0)
+	mqttNumberOfSentBytes.WithLabelValues(name).Add(0)
+	mqttNumberOfFailSendMsgs.WithLabelValues(name, "").Add(0)
+	mqttSendDuration.WithLabelValues(name).Set(0)
+}
+
+func (m *mqttOutput) registerMetrics() error {
+	if m.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = m.reg.Register(mqttNumberOfSentMsgs); err != nil {
+			m.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = m.reg.Register(mqttNumberOfSentBytes); err != nil {
+			m.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = m.reg.Register(mqttNumberOfFailSendMsgs); err != nil {
+			m.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = m.reg.Register(mqttSendDuration); err != nil {
+			m.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(m.cfg.Name)
+	return err
+}
@@ -0,0 +1,493 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType             = "mqtt"
+	loggingPrefix          = "[mqtt_output:%s] "
+	defaultAddress         = "localhost:1883"
+	defaultTopic           = `telemetry/{{ .Name }}/{{ index .Tags "source" }}`
+	defaultFormat          = "event"
+	defaultNumWorkers      = 1
+	defaultWriteTimeout    = 5 * time.Second
+	defaultConnectTimeWait = 2 * time.Second
+	defaultKeepAlive       = 30 * time.Second
+	closeTimeout           = 2 * time.Second
+	sessionExpiryInterval  = 24 * 60 * 60 // seconds
+
+	protocolVersion311 = "3.1.1"
+	protocolVersion5   = "5"
+)
+
+// replaces the MQTT wildcard characters which are not allowed in topic names.
+var topicReplacer = strings.NewReplacer("+", "_", "#", "_")
+
+func init() {
+	outputs.Register(outputType, func() outputs.Output {
+		return &mqttOutput{
+			cfg:    &config{},
+			wg:     new(sync.WaitGroup),
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type mqttOutput struct {
+	cfg    *config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	pub      publisher
+	msgChan  chan *outputs.ProtoMsg
+	evChan   chan *formatters.EventMsg
+	wg       *sync.WaitGroup
+	mo       *formatters.MarshalOptions
+	evps     []formatters.EventProcessor
+	topicTpl *template.Template
+
+	targetTpl *template.Template
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name               string           `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address            string           `mapstructure:"address,omitempty" json:"address,omitempty"`
+	ProtocolVersion    string           `mapstructure:"protocol-version,omitempty" json:"protocol-version,omitempty"`
+	ClientID           string           `mapstructure:"client-id,omitempty" json:"client-id,omitempty"`
+	Username           string           `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password           string           `mapstructure:"password,omitempty" json:"password,omitempty"`
+	TLS                *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	PersistentSession  bool             `mapstructure:"persistent-session,omitempty" json:"persistent-session,omitempty"`
+	KeepAlive          time.Duration    `mapstructure:"keep-alive,omitempty" json:"keep-alive,omitempty"`
+	ConnectTimeWait    time.Duration    `mapstructure:"connect-time-wait,omitempty" json:"connect-time-wait,omitempty"`
+	Topic              string           `mapstructure:"topic,omitempty" json:"topic,omitempty"`
+	QoS                byte             `mapstructure:"qos,omitempty" json:"qos,omitempty"`
+	Retain             bool             `mapstructure:"retain,omitempty" json:"retain,omitempty"`
+	Format             string           `mapstructure:"format,omitempty" json:"format,omitempty"`
+	SplitEvents        bool             `mapstructure:"split-events,omitempty" json:"split-events,omitempty"`
+	AddTarget          string           `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string           `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	OverrideTimestamps bool             `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	NumWorkers         int              `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	WriteTimeout       time.Duration    `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	BufferSize         uint             `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	Debug              bool             `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics      bool             `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	EventProcessors    []string         `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+}
+
+func (m *mqttOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	// allow setting the protocol version as a number, e.g: `protocol-version: 5`
+	if v, ok := cfg["protocol-version"]; ok && v != nil {
+		cfg["protocol-version"] = fmt.Sprint(v)
+	}
+	err := outputs.DecodeConfig(cfg, m.cfg)
+	if err != nil {
+		return err
+	}
+	if m.cfg.Name == "" {
+		m.cfg.Name = name
+	}
+	m.logger.SetPrefix(fmt.Sprintf(loggingPrefix, m.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return err
+		}
+	}
+	err = m.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = m.registerMetrics()
+	if err != nil {
+		return err
+	}
+	m.msgChan = make(chan *outputs.ProtoMsg, m.cfg.BufferSize)
+	m.evChan = make(chan *formatters.EventMsg, m.cfg.BufferSize)
+	m.mo = &formatters.MarshalOptions{
+		Format:     m.cfg.Format,
+		OverrideTS: m.cfg.OverrideTimestamps,
+	}
+	if m.cfg.TargetTemplate == "" {
+		m.targetTpl = outputs.DefaultTargetTemplate
+	} else if m.cfg.AddTarget != "" {
+		m.targetTpl, err = gtemplate.CreateTemplate("target-template", m.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		m.targetTpl = m.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	m.topicTpl, err = gtemplate.CreateTemplate("topic", m.cfg.Topic)
+	if err != nil {
+		return fmt.Errorf("failed to parse topic template: %v", err)
+	}
+	m.topicTpl = m.topicTpl.Funcs(outputs.TemplateFuncs)
+
+	// the connection outlives the output context, it is closed by Close().
+	m.pub, err = m.createPublisher(context.Background())
+	if err != nil {
+		return err
+	}
+
+	ctx, m.cfn = context.WithCancel(ctx)
+	m.wg.Add(m.cfg.NumWorkers)
+	for i := 0; i < m.cfg.NumWorkers; i++ {
+		go m.worker(ctx, i)
+	}
+	m.logger.Printf("initialized mqtt output %s: %s", m.cfg.Name, m.String())
+	return nil
+}
+
+func (m *mqttOutput) setDefaults() error {
+	if m.cfg.Format == "" {
+		m.cfg.Format = defaultFormat
+	}
+	switch m.cfg.Format {
+	case "event", "json", "protojson", "proto":
+	default:
+		return fmt.Errorf("unsupported output format '%s' for output type MQTT", m.cfg.Format)
+	}
+	switch m.cfg.ProtocolVersion {
+	case "", "3", "4", protocolVersion311:
+		m.cfg.ProtocolVersion = protocolVersion311
+	case protocolVersion5, "5.0":
+		m.cfg.ProtocolVersion = protocolVersion5
+	default:
+		return fmt.Errorf("unsupported MQTT protocol version %q", m.cfg.ProtocolVersion)
+	}
+	if m.cfg.QoS > 2 {
+		return fmt.Errorf("invalid qos value %d", m.cfg.QoS)
+	}
+	if m.cfg.Address == "" {
+		m.cfg.Address = defaultAddress
+	}
+	if m.cfg.Topic == "" {
+		m.cfg.Topic = defaultTopic
+	}
+	if m.cfg.ClientID == "" {
+		m.cfg.ClientID = "gnmic-" + m.cfg.Name + "-" + uuid.New().String()[:8]
+	}
+	if m.cfg.KeepAlive <= 0 {
+		m.cfg.KeepAlive = defaultKeepAlive
+	}
+	if m.cfg.ConnectTimeWait <= 0 {
+		m.cfg.ConnectTimeWait = defaultConnectTimeWait
+	}
+	if m.cfg.NumWorkers <= 0 {
+		m.cfg.NumWorkers = defaultNumWorkers
+	}
+	if m.cfg.WriteTimeout <= 0 {
+		m.cfg.WriteTimeout = defaultWriteTimeout
+	}
+	return nil
+}
+
+func (m *mqttOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil || m.mo == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, m.cfg.WriteTimeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case m.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if m.cfg.Debug {
+			m.logger.Printf("writing expired after %s, MQTT output might not be initialized", m.cfg.WriteTimeout)
+		}
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+// WriteEvent publishes events when the output format is `event`.
+func (m *mqttOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if m.mo == nil || m.cfg.Format != "event" {
+		return
+	}
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range m.evps {
+		evs = proc.Apply(evs...)
+	}
+	for _, pev := range evs {
+		select {
+		case <-ctx.Done():
+			return
+		case m.evChan <- pev:
+		}
+	}
+}
+
+// WriteSync publishes a message, it returns once the broker acknowledged it,
+// or once it was sent with qos 0.
+func (m *mqttOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil || m.mo == nil {
+		return nil
+	}
+	return m.handleProtoMsg(ctx, rsp, meta)
+}
+
+// WriteEventSync publishes an event when the output format is `event`,
+// it returns once the broker acknowledged it, or once it was sent with qos 0.
+func (m *mqttOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	if m.mo == nil || m.cfg.Format != "event" {
+		return nil
+	}
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range m.evps {
+		evs = proc.Apply(evs...)
+	}
+	return m.publishEvents(ctx, evs)
+}
+
+func (m *mqttOutput) Close() error {
+	if m.cfn == nil {
+		return nil
+	}
+	m.cfn()
+	m.wg.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	return m.pub.close(ctx)
+}
+
+func (m *mqttOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !m.cfg.EnableMetrics {
+		return
+	}
+	m.reg = reg
+}
+
+func (m *mqttOutput) String() string {
+	cfg := *m.cfg
+	if cfg.Password != "" {
+		cfg.Password = "****"
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (m *mqttOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && m.logger != nil {
+		m.logger.SetOutput(logger.Writer())
+		m.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (m *mqttOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	m.evps, err = formatters.MakeEventProcessors(
+		logger,
+		m.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *mqttOutput) SetName(name string) {
+	if m.cfg.Name == "" {
+		m.cfg.Name = name
+	}
+}
+
+func (m *mqttOutput) SetClusterName(_ string) {}
+
+func (m *mqttOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (m *mqttOutput) worker(ctx context.Context, i int) {
+	defer m.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", i)
+	m.logger.Printf("%s starting", workerLogPrefix)
+	defer m.logger.Printf("%s exited", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-m.evChan:
+			m.publishEvents(ctx, []*formatters.EventMsg{ev})
+		case msg := <-m.msgChan:
+			m.handleProtoMsg(ctx, msg.GetMsg(), msg.GetMeta())
+		}
+	}
+}
+
+// handleProtoMsg publishes a message, it returns an error if it could not be published.
+func (m *mqttOutput) handleProtoMsg(ctx context.Context, pmsg proto.Message, meta outputs.Meta) error {
+	pmsg, err := outputs.AddSubscriptionTarget(pmsg, meta, m.cfg.AddTarget, m.targetTpl)
+	if err != nil {
+		m.logger.Printf("failed to add target to the response: %v", err)
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	if m.cfg.Format == "event" {
+		rsp, ok := m.mo.OverrideTimestamp(pmsg).(*gnmi.SubscribeResponse)
+		if !ok {
+			return nil
+		}
+		evs, err := formatters.ResponseToEventMsgs(subName, rsp, meta, m.evps...)
+		if err != nil {
+			if m.cfg.Debug {
+				m.logger.Printf("failed to convert message to events: %v", err)
+			}
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "marshal_error").Inc()
+			return nil
+		}
+		return m.publishEvents(ctx, evs)
+	}
+	b, err := m.mo.Marshal(pmsg, meta, m.evps...)
+	if err != nil {
+		if m.cfg.Debug {
+			m.logger.Printf("failed marshaling proto msg: %v", err)
+		}
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "marshal_error").Inc()
+		return nil
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	topic, err := m.topic(&formatters.EventMsg{Name: subName, Tags: meta})
+	if err != nil {
+		m.logger.Printf("failed to render topic: %v", err)
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "topic_error").Inc()
+		return nil
+	}
+	return m.publish(ctx, topic, b, meta)
+}
+
+// publishEvents publishes the events to their topic,
+// the events sharing a topic are published as a single JSON array unless split-events is set.
+// It returns the first publish error.
+func (m *mqttOutput) publishEvents(ctx context.Context, evs []*formatters.EventMsg) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	topics := make([]string, 0, 1)
+	byTopic := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		topic, err := m.topic(ev)
+		if err != nil {
+			m.logger.Printf("failed to render topic: %v", err)
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "topic_error").Inc()
+			continue
+		}
+		if _, ok := byTopic[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		byTopic[topic] = append(byTopic[topic], ev)
+	}
+	var perr error
+	for _, topic := range topics {
+		tevs := byTopic[topic]
+		if m.cfg.SplitEvents {
+			for _, ev := range tevs {
+				b, err := json.Marshal(ev)
+				if err != nil {
+					mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "marshal_error").Inc()
+					continue
+				}
+				err = m.publish(ctx, topic, b, ev.Tags)
+				if err != nil && perr == nil {
+					perr = err
+				}
+			}
+			continue
+		}
+		b, err := json.Marshal(tevs)
+		if err != nil {
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "marshal_error").Inc()
+			continue
+		}
+		err = m.publish(ctx, topic, b, nil)
+		if err != nil && perr == nil {
+			perr = err
+		}
+	}
+	return perr
+}
+
+func (m *mqttOutput) publish(ctx context.Context, topic string, b []byte, props map[string]string) error {
+	if m.cfg.Debug {
+		if m.cfg.Format == "proto" {
+			m.logger.Printf("publishing %d bytes to topic %q", len(b), topic)
+		} else {
+			m.logger.Printf("publishing to topic %q: %s", topic, string(b))
+		}
+	}
+	start := time.Now()
+	wctx, cancel := context.WithTimeout(ctx, m.cfg.WriteTimeout)
+	defer cancel()
+	err := m.pub.publish(wctx, topic, b, props)
+	if err != nil {
+		m.logger.Printf("failed to publish to topic %q: %v", topic, err)
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "publish_error").Inc()
+		return err
+	}
+	mqttSendDuration.WithLabelValues(m.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	mqttNumberOfSentMsgs.WithLabelValues(m.cfg.Name).Inc()
+	mqttNumberOfSentBytes.WithLabelValues(m.cfg.Name).Add(float64(len(b)))
+	return nil
+}
+
+func (m *mqttOutput) topic(ev *formatters.EventMsg) (string, error) {
+	buf := new(bytes.Buffer)
+	err := m.topicTpl.Execute(buf, ev)
+	if err != nil {
+		return "", err
+	}
+	topic := topicReplacer.Replace(strings.TrimSpace(buf.String()))
+	if topic == "" {
+		return "", errors.New("topic template rendered an empty topic")
+	}
+	return topic, nil
+}
@@ -68,6 +68,9 @@ type NatsOutput struct {
 	msgTpl    *template.Template
 
 	reg *prometheus.Registry
+
+	m        sync.Mutex
+	syncConn *nats.Conn // used by WriteSync, created on first use.
 }
 
 // Config //
@@ -243,11 +246,77 @@ func (n *NatsOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.
 
 func (n *NatsOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
+// WriteSync publishes a message on a dedicated connection,
+// it returns once the NATS server acknowledged receiving it.
+func (n *NatsOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil || n.mo == nil {
+		return nil
+	}
+	pmsg, err := outputs.AddSubscriptionTarget(rsp, meta, n.Cfg.AddTarget, n.targetTpl)
+	if err != nil {
+		n.logger.Printf("failed to add target to the response: %v", err)
+	}
+	bb, err := outputs.Marshal(pmsg, meta, n.mo, n.Cfg.SplitEvents, n.evps...)
+	if err != nil {
+		if n.Cfg.Debug {
+			n.logger.Printf("failed marshaling proto msg: %v", err)
+		}
+		return nil
+	}
+	n.m.Lock()
+	defer n.m.Unlock()
+	if n.syncConn == nil {
+		opts, err := n.connOptions(n.Cfg)
+		if err != nil {
+			return err
+		}
+		// fail publishing instead of buffering messages while disconnected.
+		opts = append(opts, nats.ReconnectBufSize(-1))
+		n.syncConn, err = nats.Connect(n.Cfg.Address, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	subject := n.subjectName(n.Cfg, meta)
+	for _, b := range bb {
+		if n.msgTpl != nil {
+			b, err = outputs.ExecTemplate(b, n.msgTpl)
+			if err != nil {
+				if n.Cfg.Debug {
+					n.logger.Printf("failed to execute template: %v", err)
+				}
+				continue
+			}
+		}
+		err = n.syncConn.Publish(subject, b)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = n.syncConn.FlushTimeout(n.Cfg.WriteTimeout)
+	}
+	if err != nil {
+		n.syncConn.Close()
+		n.syncConn = nil
+		return err
+	}
+	return nil
+}
+
+func (n *NatsOutput) WriteEventSync(context.Context, *formatters.EventMsg) error { return nil }
+
 // Close //
 func (n *NatsOutput) Close() error {
 	//	n.conn.Close()
 	n.cancelFn()
 	n.wg.Wait()
+	n.m.Lock()
+	if n.syncConn != nil {
+		n.syncConn.Close()
+		n.syncConn = nil
+	}
+	n.m.Unlock()
 	return nil
 }
 
@@ -260,9 +329,21 @@ func (n *NatsOutput) RegisterMetrics(reg *prometheus.Registry) {
 }
 
 func (n *NatsOutput) createNATSConn(c *Config) (*nats.Conn, error) {
+	opts, err := n.connOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, nats.SetCustomDialer(n))
+	nc, err := nats.Connect(c.Address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return nc, nil
+}
+
+func (n *NatsOutput) connOptions(c *Config) ([]nats.Option, error) {
 	opts := []nats.Option{
 		nats.Name(c.Name),
-		nats.SetCustomDialer(n),
 		nats.ReconnectWait(n.Cfg.ConnectTimeWait),
 		nats.ReconnectBufSize(natsReconnectBufferSize),
 		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
@@ -293,11 +374,7 @@ func (n *NatsOutput) createNATSConn(c *Config) (*nats.Conn, error) {
 			opts = append(opts, nats.Secure(tlsConfig))
 		}
 	}
-	nc, err := nats.Connect(c.Address, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return nc, nil
+	return opts, nil
 }
 
 // Dial //
@@ -0,0 +1,352 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp_output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+var backoff = 100 * time.Millisecond
+
+// exporter sends an ExportMetricsServiceRequest to an OTLP receiver.
+// it returns a boolean indicating if the request can be retried.
+type exporter interface {
+	export(context.Context, *colmetricspb.ExportMetricsServiceRequest) (bool, error)
+	close() error
+}
+
+func (o *otlpOutput) newExporter(ctx context.Context) (exporter, error) {
+	switch o.cfg.Protocol {
+	case "http":
+		return o.newHTTPExporter()
+	default:
+		return o.newGRPCExporter(ctx)
+	}
+}
+
+// gRPC
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	client  colmetricspb.MetricsServiceClient
+	md      metadata.MD
+	timeout time.Duration
+	gzip    bool
+}
+
+func (o *otlpOutput) newGRPCExporter(_ context.Context) (*grpcExporter, error) {
+	opts := []grpc.DialOption{
+		grpc.WithUserAgent(userAgent),
+	}
+	if o.cfg.TLS == nil {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		tlsCfg, err := utils.NewTLSConfig(
+			o.cfg.TLS.CaFile,
+			o.cfg.TLS.CertFile,
+			o.cfg.TLS.KeyFile,
+			"",
+			o.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	}
+	conn, err := grpc.NewClient(o.cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %v", err)
+	}
+	return &grpcExporter{
+		conn:    conn,
+		client:  colmetricspb.NewMetricsServiceClient(conn),
+		md:      metadata.New(o.cfg.Headers),
+		timeout: o.cfg.Timeout,
+		gzip:    o.cfg.Gzip,
+	}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if len(e.md) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.md)
+	}
+	var callOpts []grpc.CallOption
+	if e.gzip {
+		callOpts = append(callOpts, grpc.UseCompressor(grpcgzip.Name))
+	}
+	rsp, err := e.client.Export(ctx, req, callOpts...)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Canceled,
+			codes.DeadlineExceeded,
+			codes.Aborted,
+			codes.OutOfRange,
+			codes.Unavailable,
+			codes.DataLoss,
+			codes.ResourceExhausted:
+			return true, err
+		}
+		return false, err
+	}
+	if ps := rsp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		return false, fmt.Errorf("partial success: %d data points rejected: %s",
+			ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+	}
+	return false, nil
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+// HTTP
+
+type httpExporter struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	gzip    bool
+}
+
+func (o *otlpOutput) newHTTPExporter() (*httpExporter, error) {
+	c := &http.Client{
+		Timeout: o.cfg.Timeout,
+	}
+	if o.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			o.cfg.TLS.CaFile,
+			o.cfg.TLS.CertFile,
+			o.cfg.TLS.KeyFile,
+			"",
+			o.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	url := o.cfg.Endpoint
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		if o.cfg.TLS != nil {
+			url = "https://" + url
+		} else {
+			url = "http://" + url
+		}
+	}
+	if !strings.HasSuffix(url, defaultHTTPPath) {
+		url = strings.TrimRight(url, "/") + defaultHTTPPath
+	}
+	return &httpExporter{
+		client:  c,
+		url:     url,
+		headers: o.cfg.Headers,
+		gzip:    o.cfg.Gzip,
+	}, nil
+}
+
+func (e *httpExporter) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (bool, error) {
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("marshal error: %w", err)
+	}
+	if e.gzip {
+		buf := new(bytes.Buffer)
+		gw := gzip.NewWriter(buf)
+		if _, err = gw.Write(b); err != nil {
+			return false, err
+		}
+		if err = gw.Close(); err != nil {
+			return false, err
+		}
+		b = buf.Bytes()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewBuffer(b))
+	if err != nil {
+		return false, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", userAgent)
+	if e.gzip {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+	rsp, err := e.client.Do(httpReq)
+	if err != nil {
+		return true, err
+	}
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return true, err
+	}
+	switch {
+	case rsp.StatusCode < 300:
+		ersp := new(colmetricspb.ExportMetricsServiceResponse)
+		if len(body) > 0 && proto.Unmarshal(body, ersp) == nil {
+			if ps := ersp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+				return false, fmt.Errorf("partial success: %d data points rejected: %s",
+					ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+			}
+		}
+		return false, nil
+	case rsp.StatusCode == http.StatusTooManyRequests,
+		rsp.StatusCode == http.StatusBadGateway,
+		rsp.StatusCode == http.StatusServiceUnavailable,
+		rsp.StatusCode == http.StatusGatewayTimeout:
+		return true, fmt.Errorf("export failed, code=%d, body=%s", rsp.StatusCode, string(body))
+	default:
+		return false, fmt.Errorf("export failed, code=%d, body=%s", rsp.StatusCode, string(body))
+	}
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// writer
+
+func (o *otlpOutput) writer(ctx context.Context) {
+	o.logger.Printf("starting writer")
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if o.cfg.Debug {
+				o.logger.Printf("write interval reached, writing to remote")
+			}
+			o.write(ctx)
+		case <-o.buffDrainCh:
+			if o.cfg.Debug {
+				o.logger.Printf("batch size reached, writing to remote")
+			}
+			o.write(ctx)
+		}
+	}
+}
+
+// write drains the data points buffer and sends them
+// in chunks of at most `batch-size` data points.
+func (o *otlpOutput) write(ctx context.Context) {
+	for {
+		buffSize := len(o.dataPointCh)
+		if buffSize == 0 {
+			return
+		}
+		if buffSize > o.cfg.BatchSize {
+			buffSize = o.cfg.BatchSize
+		}
+		dps := make([]*dataPoint, 0, buffSize)
+	READ:
+		for len(dps) < buffSize {
+			select {
+			case dp := <-o.dataPointCh:
+				dps = append(dps, dp)
+			default:
+				break READ
+			}
+		}
+		if len(dps) == 0 {
+			return
+		}
+		if o.cfg.Debug {
+			o.logger.Printf("writing a %d data points batch", len(dps))
+		}
+		start := time.Now()
+		err := o.exportWithRetries(ctx, buildRequest(dps))
+		if err != nil {
+			o.logger.Printf("failed to export %d data points: %v", len(dps), err)
+			otlpNumberOfFailedDataPoints.WithLabelValues(o.cfg.Name).Add(float64(len(dps)))
+			return
+		}
+		otlpSendDuration.WithLabelValues(o.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		otlpNumberOfSentDataPoints.WithLabelValues(o.cfg.Name).Add(float64(len(dps)))
+	}
+}
+
+// writeSync exports the data points built from evs in a single request without retries,
+// it returns an error if the export should be retried, rejected data points are dropped.
+func (o *otlpOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	var dps []*dataPoint
+	for _, ev := range evs {
+		dps = append(dps, o.mb.dataPointsFromEvent(ev)...)
+	}
+	if len(dps) == 0 {
+		return nil
+	}
+	start := time.Now()
+	retry, err := o.client.export(ctx, buildRequest(dps))
+	if err != nil {
+		if retry {
+			return err
+		}
+		o.logger.Printf("failed to export %d data points: %v", len(dps), err)
+		otlpNumberOfFailedDataPoints.WithLabelValues(o.cfg.Name).Add(float64(len(dps)))
+		return nil
+	}
+	otlpSendDuration.WithLabelValues(o.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	otlpNumberOfSentDataPoints.WithLabelValues(o.cfg.Name).Add(float64(len(dps)))
+	return nil
+}
+
+func (o *otlpOutput) exportWithRetries(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	wait := backoff
+	var err error
+	var retry bool
+	for attempt := 0; attempt <= o.cfg.MaxRetries; attempt++ {
+		retry, err = o.client.export(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		if o.cfg.Debug {
+			o.logger.Printf("export attempt %d failed: %v", attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+			wait *= 2
+		}
+	}
+	return err
+}
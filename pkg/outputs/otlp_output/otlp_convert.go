@@ -0,0 +1,256 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp_output
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+var metricNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_./-]+`)
+
+// dataPoint is a single OTLP number data point along with
+// the metric and resource it belongs to.
+type dataPoint struct {
+	name     string
+	counter  bool
+	resource []*commonpb.KeyValue
+	point    *metricspb.NumberDataPoint
+}
+
+type metricBuilder struct {
+	prefix                 string
+	appendSubscriptionName bool
+	stringsAsAttributes    bool
+	resourceTagKeys        map[string]struct{}
+	resourceAttributes     map[string]string
+	counterPatterns        []*regexp.Regexp
+	startTime              time.Time
+}
+
+// dataPointsFromEvent converts an event message into a list of data points,
+// one per numeric value. Tags listed in resourceTagKeys are set as resource attributes,
+// the remaining ones are set as data point attributes.
+func (mb *metricBuilder) dataPointsFromEvent(ev *formatters.EventMsg) []*dataPoint {
+	resAttrs := make([]*commonpb.KeyValue, 0, len(mb.resourceAttributes)+len(mb.resourceTagKeys))
+	for k, v := range mb.resourceAttributes {
+		resAttrs = append(resAttrs, stringKeyValue(k, v))
+	}
+	attrs := make([]*commonpb.KeyValue, 0, len(ev.Tags))
+	for k, v := range ev.Tags {
+		if _, ok := mb.resourceTagKeys[k]; ok {
+			resAttrs = append(resAttrs, stringKeyValue(k, v))
+			continue
+		}
+		attrs = append(attrs, stringKeyValue(k, v))
+	}
+	if mb.stringsAsAttributes {
+		for k, v := range ev.Values {
+			vs, ok := v.(string)
+			if !ok {
+				continue
+			}
+			if _, err := strconv.ParseFloat(vs, 64); err == nil {
+				continue
+			}
+			attrs = append(attrs, stringKeyValue(k, vs))
+		}
+	}
+	sortKeyValues(resAttrs)
+	sortKeyValues(attrs)
+
+	dps := make([]*dataPoint, 0, len(ev.Values))
+	for k, v := range ev.Values {
+		ndp := &metricspb.NumberDataPoint{
+			Attributes:   attrs,
+			TimeUnixNano: uint64(ev.Timestamp),
+		}
+		if !setValue(ndp, v) {
+			continue
+		}
+		name := mb.metricName(ev.Name, k)
+		dp := &dataPoint{
+			name:     name,
+			counter:  mb.isCounter(name),
+			resource: resAttrs,
+			point:    ndp,
+		}
+		if dp.counter {
+			ndp.StartTimeUnixNano = uint64(mb.startTime.UnixNano())
+		}
+		dps = append(dps, dp)
+	}
+	return dps
+}
+
+// metricName builds the OTLP metric name from the configured prefix,
+// the subscription name and the value name.
+func (mb *metricBuilder) metricName(measName, valueName string) string {
+	sb := strings.Builder{}
+	if mb.prefix != "" {
+		sb.WriteString(metricNameRegex.ReplaceAllString(mb.prefix, "_"))
+		sb.WriteString("_")
+	}
+	if mb.appendSubscriptionName {
+		sb.WriteString(strings.TrimRight(metricNameRegex.ReplaceAllString(measName, "_"), "_"))
+		sb.WriteString("_")
+	}
+	sb.WriteString(strings.TrimLeft(metricNameRegex.ReplaceAllString(valueName, "_"), "_/"))
+	return sb.String()
+}
+
+func (mb *metricBuilder) isCounter(name string) bool {
+	for _, re := range mb.counterPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// setValue sets the NumberDataPoint value from v.
+// It returns false if v cannot be represented as a number.
+func setValue(ndp *metricspb.NumberDataPoint, v interface{}) bool {
+	switch v := v.(type) {
+	case int:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int8:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int16:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int32:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int64:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+	case uint:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint8:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint16:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint32:
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint64:
+		if v > math.MaxInt64 {
+			ndp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+			return true
+		}
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case float32:
+		ndp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+	case float64:
+		ndp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+	case bool:
+		if v {
+			ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: 1}
+			return true
+		}
+		ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: 0}
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			ndp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: i}
+			return true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		ndp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: f}
+	case *gnmi.Decimal64:
+		//lint:ignore SA1019 still need DecimalVal for backward compatibility
+		ndp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v.Digits) / math.Pow10(int(v.Precision))}
+	default:
+		return false
+	}
+	return true
+}
+
+// buildRequest groups the data points by resource and metric name
+// and returns an ExportMetricsServiceRequest.
+func buildRequest(dps []*dataPoint) *colmetricspb.ExportMetricsServiceRequest {
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	resources := make(map[string]*metricspb.ResourceMetrics)
+	metrics := make(map[string]map[string]*metricspb.Metric)
+	for _, dp := range dps {
+		rk := keyValuesKey(dp.resource)
+		rm, ok := resources[rk]
+		if !ok {
+			rm = &metricspb.ResourceMetrics{
+				Resource: &resourcepb.Resource{Attributes: dp.resource},
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{Scope: &commonpb.InstrumentationScope{Name: defaultScopeName}},
+				},
+			}
+			resources[rk] = rm
+			metrics[rk] = make(map[string]*metricspb.Metric)
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+		}
+		m, ok := metrics[rk][dp.name]
+		if !ok {
+			m = &metricspb.Metric{Name: dp.name}
+			if dp.counter {
+				m.Data = &metricspb.Metric_Sum{
+					Sum: &metricspb.Sum{
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						IsMonotonic:            true,
+					},
+				}
+			} else {
+				m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+			}
+			metrics[rk][dp.name] = m
+			rm.ScopeMetrics[0].Metrics = append(rm.ScopeMetrics[0].Metrics, m)
+		}
+		switch d := m.Data.(type) {
+		case *metricspb.Metric_Sum:
+			d.Sum.DataPoints = append(d.Sum.DataPoints, dp.point)
+		case *metricspb.Metric_Gauge:
+			d.Gauge.DataPoints = append(d.Gauge.DataPoints, dp.point)
+		}
+	}
+	return req
+}
+
+func stringKeyValue(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   k,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+	}
+}
+
+func sortKeyValues(kvs []*commonpb.KeyValue) {
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+}
+
+// keyValuesKey returns a string that uniquely identifies a sorted
+// list of string KeyValues.
+func keyValuesKey(kvs []*commonpb.KeyValue) string {
+	sb := strings.Builder{}
+	for _, kv := range kvs {
+		sb.WriteString(kv.GetKey())
+		sb.WriteString("=")
+		sb.WriteString(kv.GetValue().GetStringValue())
+		sb.WriteString(",")
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp_output
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+var metricNameSet = map[string]struct {
+	mb        *metricBuilder
+	measName  string
+	valueName string
+	want      string
+}{
+	"no_prefix_no_subscription": {
+		mb:        &metricBuilder{},
+		measName:  "sub1",
+		valueName: "/interface/statistics/in-octets",
+		want:      "interface/statistics/in-octets",
+	},
+	"with_prefix": {
+		mb:        &metricBuilder{prefix: "gnmic"},
+		measName:  "sub1",
+		valueName: "/interface/statistics/in-octets",
+		want:      "gnmic_interface/statistics/in-octets",
+	},
+	"with_prefix_and_subscription": {
+		mb:        &metricBuilder{prefix: "gnmic", appendSubscriptionName: true},
+		measName:  "sub1",
+		valueName: "/interface[name=ethernet-1/1]/oper-state",
+		want:      "gnmic_sub1_interface_name_ethernet-1/1_/oper-state",
+	},
+}
+
+func TestMetricName(t *testing.T) {
+	for name, tc := range metricNameSet {
+		t.Run(name, func(t *testing.T) {
+			got := tc.mb.metricName(tc.measName, tc.valueName)
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	mb := &metricBuilder{
+		resourceTagKeys: map[string]struct{}{"source": {}},
+		counterPatterns: []*regexp.Regexp{regexp.MustCompile("octets$")},
+		startTime:       time.Unix(0, 1),
+	}
+	evs := []*formatters.EventMsg{
+		{
+			Name:      "sub1",
+			Timestamp: 42,
+			Tags:      map[string]string{"source": "r1", "interface_name": "e1"},
+			Values:    map[string]interface{}{"in-octets": uint64(100), "oper-state": "up"},
+		},
+		{
+			Name:      "sub1",
+			Timestamp: 43,
+			Tags:      map[string]string{"source": "r1", "interface_name": "e2"},
+			Values:    map[string]interface{}{"in-octets": "200", "cpu": 1.5},
+		},
+		{
+			Name:      "sub1",
+			Timestamp: 44,
+			Tags:      map[string]string{"source": "r2", "interface_name": "e1"},
+			Values:    map[string]interface{}{"in-octets": int64(300)},
+		},
+	}
+	dps := make([]*dataPoint, 0)
+	for _, ev := range evs {
+		dps = append(dps, mb.dataPointsFromEvent(ev)...)
+	}
+	if len(dps) != 4 {
+		t.Fatalf("expected 4 data points, got %d", len(dps))
+	}
+	req := buildRequest(dps)
+	if len(req.GetResourceMetrics()) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(req.GetResourceMetrics()))
+	}
+	for _, rm := range req.GetResourceMetrics() {
+		attrs := rm.GetResource().GetAttributes()
+		if len(attrs) != 1 || attrs[0].GetKey() != "source" {
+			t.Errorf("unexpected resource attributes: %v", attrs)
+		}
+		for _, m := range rm.GetScopeMetrics()[0].GetMetrics() {
+			switch m.GetName() {
+			case "in-octets":
+				sum, ok := m.GetData().(*metricspb.Metric_Sum)
+				if !ok {
+					t.Errorf("expected metric %q to be a sum, got %T", m.GetName(), m.GetData())
+					continue
+				}
+				if !sum.Sum.GetIsMonotonic() {
+					t.Errorf("expected metric %q to be monotonic", m.GetName())
+				}
+				for _, dp := range sum.Sum.GetDataPoints() {
+					if dp.GetStartTimeUnixNano() != 1 {
+						t.Errorf("unexpected start time: %d", dp.GetStartTimeUnixNano())
+					}
+				}
+			case "cpu":
+				g, ok := m.GetData().(*metricspb.Metric_Gauge)
+				if !ok {
+					t.Errorf("expected metric %q to be a gauge, got %T", m.GetName(), m.GetData())
+					continue
+				}
+				if v := g.Gauge.GetDataPoints()[0].GetAsDouble(); v != 1.5 {
+					t.Errorf("unexpected value %v", v)
+				}
+			default:
+				t.Errorf("unexpected metric %q", m.GetName())
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "otlp_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var otlpNumberOfSentDataPoints = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_data_points_sent_success_total",
+	Help:      "Number of data points successfully sent by gnmic otlp output",
+}, []string{"name"})
+
+var otlpNumberOfFailedDataPoints = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_data_points_sent_fail_total",
+	Help:      "Number of data points that failed to be sent by gnmic otlp output",
+}, []string{"name"})
+
+var otlpSendDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "export_duration_ns",
+	Help:      "gnmic otlp output export duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	otlpNumberOfSentDataPoints.WithLabelValues(name).Add(0)
+	otlpNumberOfFailedDataPoints.WithLabelValues(name).Add(0)
+	otlpSendDuration.WithLabelValues(name).Set(0)
+}
+
+func (o *otlpOutput) registerMetrics() error {
+	if o.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = o.reg.Register(otlpNumberOfSentDataPoints); err != nil {
+			o.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = o.reg.Register(otlpNumberOfFailedDataPoints); err != nil {
+			o.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = o.reg.Register(otlpSendDuration); err != nil {
+			o.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(o.cfg.Name)
+	return err
+}
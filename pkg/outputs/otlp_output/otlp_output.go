@@ -0,0 +1,401 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "otlp"
+	loggingPrefix        = "[otlp_output:%s] "
+	defaultProtocol      = "grpc"
+	defaultTimeout       = 10 * time.Second
+	defaultWriteInterval = 10 * time.Second
+	defaultBufferSize    = 1000
+	defaultBatchSize     = 500
+	defaultMaxRetries    = 3
+	defaultNumWorkers    = 1
+	defaultNumWriters    = 1
+	defaultScopeName     = "gnmic"
+	defaultHTTPPath      = "/v1/metrics"
+	userAgent            = "gNMIc otlp"
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &otlpOutput{
+				cfg:         &config{},
+				logger:      log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan:   make(chan *formatters.EventMsg),
+				msgChan:     make(chan *outputs.ProtoMsg),
+				buffDrainCh: make(chan struct{}),
+			}
+		})
+}
+
+type otlpOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	client      exporter
+	eventChan   chan *formatters.EventMsg
+	msgChan     chan *outputs.ProtoMsg
+	dataPointCh chan *dataPoint
+	buffDrainCh chan struct{}
+	mb          *metricBuilder
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name       string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Endpoint   string            `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Protocol   string            `mapstructure:"protocol,omitempty" json:"protocol,omitempty"`
+	Timeout    time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers    map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	TLS        *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Gzip       bool              `mapstructure:"gzip,omitempty" json:"gzip,omitempty"`
+	Interval   time.Duration     `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	BufferSize int               `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	BatchSize  int               `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	MaxRetries int               `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	Debug      bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	//
+	MetricPrefix           string            `mapstructure:"metric-prefix,omitempty" json:"metric-prefix,omitempty"`
+	AppendSubscriptionName bool              `mapstructure:"append-subscription-name,omitempty" json:"append-subscription-name,omitempty"`
+	StringsAsAttributes    bool              `mapstructure:"strings-as-attributes,omitempty" json:"strings-as-attributes,omitempty"`
+	ResourceTagKeys        []string          `mapstructure:"resource-tag-keys,omitempty" json:"resource-tag-keys,omitempty"`
+	ResourceAttributes     map[string]string `mapstructure:"resource-attributes,omitempty" json:"resource-attributes,omitempty"`
+	CounterPatterns        []string          `mapstructure:"counter-patterns,omitempty" json:"counter-patterns,omitempty"`
+	AddTarget              string            `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate         string            `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors        []string          `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers             int               `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	NumWriters             int               `mapstructure:"num-writers,omitempty" json:"num-writers,omitempty"`
+	EnableMetrics          bool              `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+func (o *otlpOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, o.cfg)
+	if err != nil {
+		return err
+	}
+	if o.cfg.Endpoint == "" {
+		return errors.New("missing endpoint field")
+	}
+	if o.cfg.Name == "" {
+		o.cfg.Name = name
+	}
+	o.logger.SetPrefix(fmt.Sprintf(loggingPrefix, o.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return err
+		}
+	}
+
+	err = o.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if o.cfg.TargetTemplate == "" {
+		o.targetTpl = outputs.DefaultTargetTemplate
+	} else if o.cfg.AddTarget != "" {
+		o.targetTpl, err = gtemplate.CreateTemplate("target-template", o.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		o.targetTpl = o.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	err = o.setDefaults()
+	if err != nil {
+		return err
+	}
+
+	o.mb = &metricBuilder{
+		prefix:                 o.cfg.MetricPrefix,
+		appendSubscriptionName: o.cfg.AppendSubscriptionName,
+		stringsAsAttributes:    o.cfg.StringsAsAttributes,
+		resourceTagKeys:        make(map[string]struct{}, len(o.cfg.ResourceTagKeys)),
+		resourceAttributes:     o.cfg.ResourceAttributes,
+		counterPatterns:        make([]*regexp.Regexp, 0, len(o.cfg.CounterPatterns)),
+		startTime:              time.Now(),
+	}
+	for _, k := range o.cfg.ResourceTagKeys {
+		o.mb.resourceTagKeys[k] = struct{}{}
+	}
+	for _, p := range o.cfg.CounterPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("failed to compile counter pattern %q: %v", p, err)
+		}
+		o.mb.counterPatterns = append(o.mb.counterPatterns, re)
+	}
+
+	// initialize buffer chan
+	o.dataPointCh = make(chan *dataPoint, o.cfg.BufferSize)
+
+	ctx, o.cfn = context.WithCancel(ctx)
+	o.client, err = o.newExporter(ctx)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < o.cfg.NumWorkers; i++ {
+		go o.worker(ctx)
+	}
+	for i := 0; i < o.cfg.NumWriters; i++ {
+		go o.writer(ctx)
+	}
+	o.logger.Printf("initialized otlp output %s: %s", o.cfg.Name, o.String())
+	return nil
+}
+
+func (o *otlpOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, o.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case o.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if o.cfg.Debug {
+			o.logger.Printf("writing expired after %s", o.cfg.Timeout)
+		}
+		return
+	}
+}
+
+func (o *otlpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range o.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			o.eventChan <- pev
+		}
+	}
+}
+
+// WriteSync exports the data points built from a message in a single request,
+// it returns once the collector accepted them.
+func (o *otlpOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := o.protoEvents(rsp, meta)
+	if err != nil {
+		o.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return o.writeSync(ctx, events)
+}
+
+// WriteEventSync exports the data points built from an event in a single request,
+// it returns once the collector accepted them.
+func (o *otlpOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range o.evps {
+		evs = proc.Apply(evs...)
+	}
+	return o.writeSync(ctx, evs)
+}
+
+func (o *otlpOutput) Close() error {
+	if o.cfn == nil {
+		return nil
+	}
+	o.cfn()
+	if o.client != nil {
+		return o.client.close()
+	}
+	return nil
+}
+
+func (o *otlpOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !o.cfg.EnableMetrics {
+		return
+	}
+	o.reg = reg
+}
+
+func (o *otlpOutput) String() string {
+	b, err := json.Marshal(o.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (o *otlpOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && o.logger != nil {
+		o.logger.SetOutput(logger.Writer())
+		o.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (o *otlpOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	o.evps, err = formatters.MakeEventProcessors(
+		logger,
+		o.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *otlpOutput) SetName(name string) {
+	if o.cfg.Name == "" {
+		o.cfg.Name = name
+	}
+}
+
+func (o *otlpOutput) SetClusterName(_ string) {}
+
+func (o *otlpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (o *otlpOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-o.eventChan:
+			o.workerHandleEvent(ev)
+		case m := <-o.msgChan:
+			o.workerHandleProto(m)
+		}
+	}
+}
+
+func (o *otlpOutput) workerHandleProto(m *outputs.ProtoMsg) {
+	events, err := o.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		o.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		o.workerHandleEvent(ev)
+	}
+}
+
+func (o *otlpOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	switch pmsg := m.(type) {
+	case *gnmi.SubscribeResponse:
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		var err error
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, meta, o.cfg.AddTarget, o.targetTpl)
+		if err != nil {
+			o.logger.Printf("failed to add target to the response: %v", err)
+		}
+		return formatters.ResponseToEventMsgs(measName, pmsg, meta, o.evps...)
+	}
+	return nil, nil
+}
+
+func (o *otlpOutput) workerHandleEvent(ev *formatters.EventMsg) {
+	if o.cfg.Debug {
+		o.logger.Printf("got event to buffer: %+v", ev)
+	}
+	for _, dp := range o.mb.dataPointsFromEvent(ev) {
+		if len(o.dataPointCh) >= o.cfg.BatchSize {
+			if o.cfg.Debug {
+				o.logger.Printf("batch size reached, triggering write")
+			}
+			select {
+			case o.buffDrainCh <- struct{}{}:
+			default:
+			}
+		}
+		o.dataPointCh <- dp
+	}
+}
+
+func (o *otlpOutput) setDefaults() error {
+	o.cfg.Protocol = strings.ToLower(o.cfg.Protocol)
+	switch o.cfg.Protocol {
+	case "":
+		o.cfg.Protocol = defaultProtocol
+	case "grpc", "http":
+	default:
+		return fmt.Errorf("unknown protocol %q, must be one of: grpc, http", o.cfg.Protocol)
+	}
+	if o.cfg.Timeout <= 0 {
+		o.cfg.Timeout = defaultTimeout
+	}
+	if o.cfg.Interval <= 0 {
+		o.cfg.Interval = defaultWriteInterval
+	}
+	if o.cfg.BufferSize <= 0 {
+		o.cfg.BufferSize = defaultBufferSize
+	}
+	if o.cfg.BatchSize <= 0 {
+		o.cfg.BatchSize = defaultBatchSize
+	}
+	if o.cfg.BatchSize > o.cfg.BufferSize {
+		o.cfg.BatchSize = o.cfg.BufferSize
+	}
+	if o.cfg.MaxRetries <= 0 {
+		o.cfg.MaxRetries = defaultMaxRetries
+	}
+	if o.cfg.NumWorkers <= 0 {
+		o.cfg.NumWorkers = defaultNumWorkers
+	}
+	if o.cfg.NumWriters <= 0 {
+		o.cfg.NumWriters = defaultNumWriters
+	}
+	return nil
+}
@@ -62,6 +62,16 @@ var OutputTypes = map[string]struct{}{
 	"jetstream":        {},
 	"snmp":             {},
 	"asciigraph":       {},
+	"otlp":             {},
+	"elasticsearch":    {},
+	"clickhouse":       {},
+	"http":             {},
+	"loki":             {},
+	"postgres":         {},
+	"syslog":           {},
+	"redis":            {},
+	"graphite":         {},
+	"mqtt":             {},
 }
 
 func Register(name string, initFn Initializer) {
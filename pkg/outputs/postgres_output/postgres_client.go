@@ -0,0 +1,417 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres_output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	backoff      = 100 * time.Millisecond
+	drainTimeout = 5 * time.Second
+	tempTable    = "gnmic_copy"
+)
+
+var (
+	errEmptyTable = errors.New("table template rendered an empty table name")
+	passwordRegex = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+)
+
+func (p *postgresOutput) connect(ctx context.Context) (*pgxpool.Pool, error) {
+	// the connection settings not set in the config are
+	// taken from the standard PG* environment variables.
+	pcfg, err := pgxpool.ParseConfig(p.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	cc := pcfg.ConnConfig
+	if p.cfg.Address != "" {
+		host, port, err := net.SplitHostPort(p.cfg.Address)
+		if err != nil {
+			// no port in the address
+			host = p.cfg.Address
+			port = ""
+		}
+		cc.Host = host
+		if port != "" {
+			pn, err := strconv.ParseUint(port, 10, 16)
+			if err != nil {
+				return nil, err
+			}
+			cc.Port = uint16(pn)
+		}
+	}
+	if p.cfg.Database != "" {
+		cc.Database = p.cfg.Database
+	}
+	if p.cfg.Username != "" {
+		cc.User = p.cfg.Username
+	}
+	if p.cfg.Password != "" {
+		cc.Password = p.cfg.Password
+	}
+	if p.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			p.cfg.TLS.CaFile,
+			p.cfg.TLS.CertFile,
+			p.cfg.TLS.KeyFile,
+			"",
+			p.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if tlsCfg != nil && tlsCfg.ServerName == "" {
+			tlsCfg.ServerName = cc.Host
+		}
+		cc.TLSConfig = tlsCfg
+		cc.Fallbacks = nil
+	}
+	cc.ConnectTimeout = p.cfg.Timeout
+	if cc.RuntimeParams == nil {
+		cc.RuntimeParams = make(map[string]string)
+	}
+	cc.RuntimeParams["application_name"] = "gnmic"
+	if p.cfg.MaxConnections > 0 {
+		pcfg.MaxConns = int32(p.cfg.MaxConnections)
+	}
+	return pgxpool.NewWithConfig(ctx, pcfg)
+}
+
+func (p *postgresOutput) writer(ctx context.Context) {
+	defer p.wg.Done()
+	p.logger.Printf("starting writer")
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+	evs := make([]*formatters.EventMsg, 0, p.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			p.drain(evs)
+			return
+		case ev := <-p.rowCh:
+			evs = append(evs, ev)
+			if len(evs) < p.cfg.BatchSize {
+				continue
+			}
+			if p.cfg.Debug {
+				p.logger.Printf("batch size reached, writing to postgres")
+			}
+			p.write(ctx, evs)
+			evs = evs[:0]
+		case <-ticker.C:
+			if len(evs) == 0 {
+				continue
+			}
+			if p.cfg.Debug {
+				p.logger.Printf("flush interval reached, writing to postgres")
+			}
+			p.write(ctx, evs)
+			evs = evs[:0]
+		}
+	}
+}
+
+// drain writes the pending rows when the output is closed.
+func (p *postgresOutput) drain(evs []*formatters.EventMsg) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for {
+		select {
+		case ev := <-p.rowCh:
+			evs = append(evs, ev)
+			if len(evs) >= p.cfg.BatchSize {
+				p.write(ctx, evs)
+				evs = evs[:0]
+			}
+		default:
+			if len(evs) > 0 {
+				p.write(ctx, evs)
+			}
+			return
+		}
+	}
+}
+
+// write groups the events per table and copies each group.
+func (p *postgresOutput) write(ctx context.Context, evs []*formatters.EventMsg) {
+	tables, order := p.groupByTable(evs)
+	for _, table := range order {
+		p.writeTable(ctx, table, tables[table])
+	}
+}
+
+// writeSync groups the events per table and copies each group without retries,
+// it returns the first copy error.
+func (p *postgresOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	tables, order := p.groupByTable(evs)
+	for _, table := range order {
+		start := time.Now()
+		err := p.copy(ctx, table, tables[table])
+		if err != nil {
+			p.m.Lock()
+			delete(p.schemas, table)
+			p.m.Unlock()
+			return fmt.Errorf("failed to write %d row(s) to table %q: %v", len(tables[table]), table, err)
+		}
+		postgresCopyDuration.WithLabelValues(p.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		postgresNumberOfWrittenRows.WithLabelValues(p.cfg.Name).Add(float64(len(tables[table])))
+	}
+	return nil
+}
+
+// groupByTable groups the events with values per table name,
+// it returns the groups and the table names in order of appearance.
+func (p *postgresOutput) groupByTable(evs []*formatters.EventMsg) (map[string][]*formatters.EventMsg, []string) {
+	tables := make(map[string][]*formatters.EventMsg)
+	order := make([]string, 0)
+	for _, ev := range evs {
+		// deletes and events without values are not stored.
+		if len(ev.Values) == 0 {
+			continue
+		}
+		table, err := p.tableName(ev)
+		if err != nil {
+			p.logger.Printf("failed to render table name: %v", err)
+			postgresNumberOfFailedRows.WithLabelValues(p.cfg.Name, "table_template").Inc()
+			continue
+		}
+		if _, ok := tables[table]; !ok {
+			order = append(order, table)
+		}
+		tables[table] = append(tables[table], ev)
+	}
+	return tables, order
+}
+
+func (p *postgresOutput) tableName(ev *formatters.EventMsg) (string, error) {
+	b := new(bytes.Buffer)
+	err := p.tableTpl.Execute(b, ev)
+	if err != nil {
+		return "", err
+	}
+	table := strings.TrimSpace(b.String())
+	if table == "" {
+		return "", errEmptyTable
+	}
+	return identifier(table), nil
+}
+
+func (p *postgresOutput) writeTable(ctx context.Context, table string, evs []*formatters.EventMsg) {
+	retries := 0
+RETRY:
+	start := time.Now()
+	err := p.copy(ctx, table, evs)
+	if err != nil {
+		p.logger.Printf("failed to write %d row(s) to table %q: %v", len(evs), table, err)
+		// the table might have been changed by someone else,
+		// reload its schema before retrying.
+		p.m.Lock()
+		delete(p.schemas, table)
+		p.m.Unlock()
+		if retries < p.cfg.MaxRetries && ctx.Err() == nil {
+			retries++
+			time.Sleep(backoff)
+			goto RETRY
+		}
+		postgresNumberOfFailedRows.WithLabelValues(p.cfg.Name, "copy_error").Add(float64(len(evs)))
+		return
+	}
+	postgresCopyDuration.WithLabelValues(p.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	postgresNumberOfWrittenRows.WithLabelValues(p.cfg.Name).Add(float64(len(evs)))
+}
+
+// copy writes the events using the COPY protocol, directly into the table
+// or through a temporary table when conflicts must be handled.
+func (p *postgresOutput) copy(ctx context.Context, table string, evs []*formatters.EventMsg) error {
+	cols := tableColumns(evs, p.cfg.TagsAs, p.cfg.ValuesAs, p.conflictTags)
+	ts, err := p.ensureTable(ctx, table, cols)
+	if err != nil {
+		return err
+	}
+	// only copy the columns with a supported type.
+	copyCols := make([]column, 0, len(cols))
+	colNames := make([]string, 0, len(cols))
+	for _, col := range cols {
+		typ, ok := ts[col.name]
+		if !ok {
+			continue
+		}
+		if col.key != "" && !supportedType(typ) {
+			if p.cfg.Debug {
+				p.logger.Printf("table %q: skipping column %q with unsupported type %s", table, col.name, typ)
+			}
+			continue
+		}
+		copyCols = append(copyCols, col)
+		colNames = append(colNames, col.name)
+	}
+	rows := make([][]interface{}, 0, len(evs))
+	for _, ev := range evs {
+		row, errs := eventRow(ev, copyCols, ts)
+		for _, err := range errs {
+			postgresNumberOfFailedValues.WithLabelValues(p.cfg.Name, table).Inc()
+			if p.cfg.Debug {
+				p.logger.Printf("table %q: %v", table, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	if p.cfg.OnConflict == onConflictError {
+		_, err = p.pool.CopyFrom(ctx, pgx.Identifier{p.cfg.Schema, table}, colNames, pgx.CopyFromRows(rows))
+		return err
+	}
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	_, err = tx.Exec(ctx, createTempTableQuery(p.cfg.Schema, table, tempTable))
+	if err != nil {
+		return err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{tempTable}, colNames, pgx.CopyFromRows(rows))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, insertFromTempQuery(p.cfg.Schema, table, tempTable, colNames, p.cfg.OnConflict, p.cfg.ConflictColumns))
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ensureTable makes sure the table exists and has the given columns,
+// it returns the table schema.
+func (p *postgresOutput) ensureTable(ctx context.Context, table string, cols []column) (tableSchema, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	ts, ok := p.schemas[table]
+	if !ok {
+		var err error
+		ts, err = p.describeTable(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(ts) == 0 {
+			err = p.createTable(ctx, table, cols)
+			if err != nil {
+				return nil, err
+			}
+			p.logger.Printf("created table %q", table)
+			postgresNumberOfSchemaChanges.WithLabelValues(p.cfg.Name, "create").Inc()
+			ts, err = p.describeTable(ctx, table)
+			if err != nil {
+				return nil, err
+			}
+		}
+		p.schemas[table] = ts
+	}
+	missing := missingColumns(ts, cols)
+	if len(missing) == 0 {
+		return ts, nil
+	}
+	err := p.exec(ctx, addColumnsQuery(p.cfg.Schema, table, missing))
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.Debug {
+		p.logger.Printf("added %d column(s) to table %q", len(missing), table)
+	}
+	postgresNumberOfSchemaChanges.WithLabelValues(p.cfg.Name, "alter").Inc()
+	// reload the schema in case the columns were added concurrently with different types.
+	ts, err = p.describeTable(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	p.schemas[table] = ts
+	return ts, nil
+}
+
+func (p *postgresOutput) createTable(ctx context.Context, table string, cols []column) error {
+	queries := []string{createTableQuery(p.cfg.Schema, table, cols)}
+	if p.cfg.Hypertable {
+		queries = append(queries, createHypertableQuery(p.cfg.Schema, table, p.cfg.ChunkTimeInterval))
+	} else {
+		queries = append(queries, createIndexQuery(p.cfg.Schema, table, "_time_idx", false, []string{timeColumn}))
+	}
+	if p.cfg.OnConflict != onConflictError {
+		queries = append(queries, createIndexQuery(p.cfg.Schema, table, "_conflict_idx", true, p.cfg.ConflictColumns))
+	}
+	for _, q := range queries {
+		err := p.exec(ctx, q)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *postgresOutput) describeTable(ctx context.Context, table string) (tableSchema, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	rows, err := p.pool.Query(ctx,
+		"SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2",
+		p.cfg.Schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ts := make(tableSchema)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		ts[name] = typ
+	}
+	return ts, rows.Err()
+}
+
+func (p *postgresOutput) exec(ctx context.Context, query string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	if p.cfg.Debug {
+		p.logger.Printf("executing: %s", query)
+	}
+	_, err := p.pool.Exec(ctx, query)
+	return err
+}
+
+// redactURL hides the password of a connection string,
+// given either as a URL or as key/value pairs.
+func redactURL(s string) string {
+	if !strings.HasPrefix(s, "postgres://") && !strings.HasPrefix(s, "postgresql://") {
+		return passwordRegex.ReplaceAllString(s, "${1}xxxxx")
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,94 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "postgres_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var postgresNumberOfWrittenRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_rows_written_total",
+	Help:      "Number of rows successfully written by gnmic postgres output",
+}, []string{"name"})
+
+var postgresNumberOfFailedRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_rows_write_fail_total",
+	Help:      "Number of rows gnmic postgres output failed to write",
+}, []string{"name", "reason"})
+
+var postgresNumberOfFailedValues = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_values_conversion_fail_total",
+	Help:      "Number of values gnmic postgres output failed to convert to their column type",
+}, []string{"name", "table"})
+
+var postgresNumberOfSchemaChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_schema_changes_total",
+	Help:      "Number of tables created or altered by gnmic postgres output",
+}, []string{"name", "type"})
+
+var postgresCopyDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "copy_duration_ns",
+	Help:      "gnmic postgres output COPY duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	postgresNumberOfWrittenRows.WithLabelValues(name).Add(0)
+	postgresNumberOfFailedRows.WithLabelValues(name, "").Add(0)
+	postgresCopyDuration.WithLabelValues(name).Set(0)
+}
+
+func (p *postgresOutput) registerMetrics() error {
+	if p.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = p.reg.Register(postgresNumberOfWrittenRows); err != nil {
+			p.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = p.reg.Register(postgresNumberOfFailedRows); err != nil {
+			p.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = p.reg.Register(postgresNumberOfFailedValues); err != nil {
+			p.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = p.reg.Register(postgresNumberOfSchemaChanges); err != nil {
+			p.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = p.reg.Register(postgresCopyDuration); err != nil {
+			p.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(p.cfg.Name)
+	return err
+}
@@ -0,0 +1,450 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "postgres"
+	loggingPrefix        = "[postgres_output:%s] "
+	defaultSchema        = "public"
+	defaultTable         = "{{ .Name }}"
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 1000
+	defaultBatchSize     = 1000
+	defaultMaxRetries    = 3
+	defaultNumWorkers    = 1
+	defaultNumWriters    = 1
+
+	storeColumns = "columns"
+	storeJSONB   = "jsonb"
+
+	onConflictError  = "error"
+	onConflictIgnore = "ignore"
+	onConflictUpdate = "update"
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &postgresOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				m:         new(sync.Mutex),
+				schemas:   make(map[string]tableSchema),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type postgresOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	pool      *pgxpool.Pool
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	rowCh     chan *formatters.EventMsg
+
+	m *sync.Mutex
+	// known tables schemas
+	schemas map[string]tableSchema
+	// tags that must have a column when the table is created
+	conflictTags []string
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	tableTpl  *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name string `mapstructure:"name,omitempty" json:"name,omitempty"`
+	// connection string, the other connection fields override its values.
+	URL            string           `mapstructure:"url,omitempty" json:"url,omitempty"`
+	Address        string           `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Database       string           `mapstructure:"database,omitempty" json:"database,omitempty"`
+	Username       string           `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password       string           `mapstructure:"password,omitempty" json:"password,omitempty"`
+	TLS            *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Timeout        time.Duration    `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	MaxConnections int              `mapstructure:"max-connections,omitempty" json:"max-connections,omitempty"`
+	Schema         string           `mapstructure:"schema,omitempty" json:"schema,omitempty"`
+	// table name template
+	Table string `mapstructure:"table,omitempty" json:"table,omitempty"`
+	// how tags and values are stored, `columns` or `jsonb`.
+	TagsAs   string `mapstructure:"tags-as,omitempty" json:"tags-as,omitempty"`
+	ValuesAs string `mapstructure:"values-as,omitempty" json:"values-as,omitempty"`
+	// conflict handling, one of `error`, `ignore` or `update`.
+	OnConflict      string   `mapstructure:"on-conflict,omitempty" json:"on-conflict,omitempty"`
+	ConflictColumns []string `mapstructure:"conflict-columns,omitempty" json:"conflict-columns,omitempty"`
+	// TimescaleDB
+	Hypertable        bool          `mapstructure:"hypertable,omitempty" json:"hypertable,omitempty"`
+	ChunkTimeInterval time.Duration `mapstructure:"chunk-time-interval,omitempty" json:"chunk-time-interval,omitempty"`
+	FlushInterval     time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BufferSize        int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	BatchSize         int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	MaxRetries        int           `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	AddTarget         string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate    string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors   []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers        int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	NumWriters        int           `mapstructure:"num-writers,omitempty" json:"num-writers,omitempty"`
+	EnableMetrics     bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug             bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+func (p *postgresOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, p.cfg)
+	if err != nil {
+		return err
+	}
+	if p.cfg.Name == "" {
+		p.cfg.Name = name
+	}
+	p.logger.SetPrefix(fmt.Sprintf(loggingPrefix, p.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return err
+		}
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = p.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if p.cfg.TargetTemplate == "" {
+		p.targetTpl = outputs.DefaultTargetTemplate
+	} else if p.cfg.AddTarget != "" {
+		p.targetTpl, err = gtemplate.CreateTemplate("target-template", p.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		p.targetTpl = p.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	p.tableTpl, err = gtemplate.CreateTemplate("table", p.cfg.Table)
+	if err != nil {
+		return fmt.Errorf("failed to parse table template: %v", err)
+	}
+	p.tableTpl = p.tableTpl.Funcs(outputs.TemplateFuncs)
+
+	p.pool, err = p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	p.rowCh = make(chan *formatters.EventMsg, p.cfg.BufferSize)
+
+	ctx, p.cfn = context.WithCancel(ctx)
+	for i := 0; i < p.cfg.NumWorkers; i++ {
+		go p.worker(ctx)
+	}
+	p.wg.Add(p.cfg.NumWriters)
+	for i := 0; i < p.cfg.NumWriters; i++ {
+		go p.writer(ctx)
+	}
+	p.logger.Printf("initialized postgres output %s: %s", p.cfg.Name, p.String())
+	return nil
+}
+
+func (p *postgresOutput) setDefaults() error {
+	if p.cfg.Schema == "" {
+		p.cfg.Schema = defaultSchema
+	}
+	if p.cfg.Table == "" {
+		p.cfg.Table = defaultTable
+	}
+	switch p.cfg.TagsAs {
+	case "":
+		p.cfg.TagsAs = storeJSONB
+	case storeColumns, storeJSONB:
+	default:
+		return fmt.Errorf("unknown tags-as value %q", p.cfg.TagsAs)
+	}
+	switch p.cfg.ValuesAs {
+	case "":
+		p.cfg.ValuesAs = storeColumns
+	case storeColumns, storeJSONB:
+	default:
+		return fmt.Errorf("unknown values-as value %q", p.cfg.ValuesAs)
+	}
+	switch p.cfg.OnConflict {
+	case "":
+		p.cfg.OnConflict = onConflictError
+	case onConflictError, onConflictIgnore, onConflictUpdate:
+	default:
+		return fmt.Errorf("unknown on-conflict value %q", p.cfg.OnConflict)
+	}
+	if p.cfg.OnConflict != onConflictError {
+		if len(p.cfg.ConflictColumns) == 0 {
+			if p.cfg.TagsAs != storeJSONB {
+				return errors.New("conflict-columns must be set when on-conflict is used with tags-as columns")
+			}
+			p.cfg.ConflictColumns = []string{timeColumn, nameColumn, tagsColumn}
+		}
+		p.conflictTags = p.conflictTags[:0]
+		for _, c := range p.cfg.ConflictColumns {
+			if isReservedColumn(c, p.cfg.TagsAs, p.cfg.ValuesAs) {
+				continue
+			}
+			if p.cfg.TagsAs != storeColumns {
+				return fmt.Errorf("unknown conflict column %q", c)
+			}
+			p.conflictTags = append(p.conflictTags, c)
+		}
+	}
+	if p.cfg.Timeout <= 0 {
+		p.cfg.Timeout = defaultTimeout
+	}
+	if p.cfg.FlushInterval <= 0 {
+		p.cfg.FlushInterval = defaultFlushInterval
+	}
+	if p.cfg.BufferSize <= 0 {
+		p.cfg.BufferSize = defaultBufferSize
+	}
+	if p.cfg.BatchSize <= 0 {
+		p.cfg.BatchSize = defaultBatchSize
+	}
+	if p.cfg.MaxRetries < 0 {
+		p.cfg.MaxRetries = 0
+	} else if p.cfg.MaxRetries == 0 {
+		p.cfg.MaxRetries = defaultMaxRetries
+	}
+	if p.cfg.NumWorkers <= 0 {
+		p.cfg.NumWorkers = defaultNumWorkers
+	}
+	if p.cfg.NumWriters <= 0 {
+		p.cfg.NumWriters = defaultNumWriters
+	}
+	return nil
+}
+
+func (p *postgresOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case p.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if p.cfg.Debug {
+			p.logger.Printf("writing expired after %s", p.cfg.Timeout)
+		}
+		postgresNumberOfFailedRows.WithLabelValues(p.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (p *postgresOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range p.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case p.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync writes the rows built from a message,
+// it returns once postgres committed them.
+func (p *postgresOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := p.protoEvents(rsp, meta)
+	if err != nil {
+		p.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return p.writeSync(ctx, events)
+}
+
+// WriteEventSync writes the rows built from an event,
+// it returns once postgres committed them.
+func (p *postgresOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range p.evps {
+		evs = proc.Apply(evs...)
+	}
+	return p.writeSync(ctx, evs)
+}
+
+func (p *postgresOutput) Close() error {
+	if p.cfn == nil {
+		return nil
+	}
+	p.cfn()
+	// wait for the buffered rows to be written
+	p.wg.Wait()
+	if p.pool != nil {
+		p.pool.Close()
+	}
+	return nil
+}
+
+func (p *postgresOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !p.cfg.EnableMetrics {
+		return
+	}
+	p.reg = reg
+}
+
+func (p *postgresOutput) String() string {
+	cfg := *p.cfg
+	if cfg.Password != "" {
+		cfg.Password = "****"
+	}
+	if cfg.URL != "" {
+		cfg.URL = redactURL(cfg.URL)
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (p *postgresOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && p.logger != nil {
+		p.logger.SetOutput(logger.Writer())
+		p.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (p *postgresOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	p.evps, err = formatters.MakeEventProcessors(
+		logger,
+		p.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *postgresOutput) SetName(name string) {
+	if p.cfg.Name == "" {
+		p.cfg.Name = name
+	}
+}
+
+func (p *postgresOutput) SetClusterName(_ string) {}
+
+func (p *postgresOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (p *postgresOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-p.eventChan:
+			p.workerHandleEvent(ctx, ev)
+		case m := <-p.msgChan:
+			p.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (p *postgresOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	events, err := p.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		p.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		p.workerHandleEvent(ctx, ev)
+	}
+}
+
+func (p *postgresOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	rsp, err := outputs.AddSubscriptionTarget(pmsg, meta, p.cfg.AddTarget, p.targetTpl)
+	if err != nil {
+		p.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if rsp != nil {
+		pmsg = rsp
+	}
+	return formatters.ResponseToEventMsgs(subName, pmsg, meta, p.evps...)
+}
+
+func (p *postgresOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	// deletes and events without values are not stored.
+	if len(ev.Values) == 0 {
+		return
+	}
+	if p.cfg.Debug {
+		p.logger.Printf("buffering event: %+v", ev)
+	}
+	select {
+	case <-ctx.Done():
+	case p.rowCh <- ev:
+	}
+}
@@ -0,0 +1,577 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres_output
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	timeColumn   = "time"
+	nameColumn   = "name"
+	tagsColumn   = "tags"
+	valuesColumn = "values"
+
+	// PostgreSQL truncates identifiers longer than 63 bytes.
+	maxIdentifierLength = 63
+
+	typeTimestamp = "timestamp with time zone"
+	typeText      = "text"
+	typeBigint    = "bigint"
+	typeNumeric   = "numeric"
+	typeDouble    = "double precision"
+	typeBoolean   = "boolean"
+	typeJSONB     = "jsonb"
+)
+
+// column is a table column name and type.
+type column struct {
+	name string
+	typ  string
+	// tag or value name the column is built from.
+	key string
+}
+
+// tableSchema maps the table columns names to their data type
+// as reported by information_schema.columns.
+type tableSchema map[string]string
+
+// tableOptions are the settings used when creating a table.
+type tableOptions struct {
+	onConflict        string
+	conflictColumns   []string
+	hypertable        bool
+	chunkTimeInterval time.Duration
+}
+
+// identifier makes sure s fits in a PostgreSQL identifier,
+// longer names are truncated and suffixed with a hash of the full name
+// to keep them unique.
+func identifier(s string) string {
+	if len(s) <= maxIdentifierLength {
+		return s
+	}
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+	// keep the end of the name, which is the most specific part of a path.
+	tail := s[len(s)-(maxIdentifierLength-len(suffix)):]
+	// avoid cutting a multi-byte character.
+	for len(tail) > 0 && tail[0]&0xC0 == 0x80 {
+		tail = tail[1:]
+	}
+	return tail + suffix
+}
+
+func tableIdent(schema, table string) string {
+	if schema == "" {
+		return pgx.Identifier{table}.Sanitize()
+	}
+	return pgx.Identifier{schema, table}.Sanitize()
+}
+
+func quoteIdent(s string) string {
+	return pgx.Identifier{s}.Sanitize()
+}
+
+func quoteIdents(ss []string) string {
+	qs := make([]string, 0, len(ss))
+	for _, s := range ss {
+		qs = append(qs, quoteIdent(s))
+	}
+	return strings.Join(qs, ", ")
+}
+
+func createTableQuery(schema, table string, cols []column) string {
+	sb := new(strings.Builder)
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(tableIdent(schema, table))
+	sb.WriteString(" (")
+	for i, c := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(c.name))
+		sb.WriteString(" ")
+		sb.WriteString(c.typ)
+		if c.name == timeColumn || c.name == nameColumn {
+			sb.WriteString(" NOT NULL")
+		}
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func addColumnsQuery(schema, table string, cols []column) string {
+	sb := new(strings.Builder)
+	sb.WriteString("ALTER TABLE ")
+	sb.WriteString(tableIdent(schema, table))
+	for i, c := range cols {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(" ADD COLUMN IF NOT EXISTS ")
+		sb.WriteString(quoteIdent(c.name))
+		sb.WriteString(" ")
+		sb.WriteString(c.typ)
+	}
+	return sb.String()
+}
+
+func createIndexQuery(schema, table, suffix string, unique bool, cols []string) string {
+	sb := new(strings.Builder)
+	sb.WriteString("CREATE ")
+	if unique {
+		sb.WriteString("UNIQUE ")
+	}
+	sb.WriteString("INDEX IF NOT EXISTS ")
+	sb.WriteString(quoteIdent(identifier(table + suffix)))
+	sb.WriteString(" ON ")
+	sb.WriteString(tableIdent(schema, table))
+	sb.WriteString(" (")
+	sb.WriteString(quoteIdents(cols))
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func createHypertableQuery(schema, table string, chunkTimeInterval time.Duration) string {
+	// create_hypertable takes the table as a regclass, which is parsed from a string literal.
+	rel := strings.ReplaceAll(tableIdent(schema, table), "'", "''")
+	q := fmt.Sprintf("SELECT create_hypertable('%s', '%s', if_not_exists => TRUE", rel, timeColumn)
+	if chunkTimeInterval > 0 {
+		q += fmt.Sprintf(", chunk_time_interval => INTERVAL '%d microseconds'", chunkTimeInterval.Microseconds())
+	}
+	return q + ")"
+}
+
+func createTempTableQuery(schema, table, tmp string) string {
+	return fmt.Sprintf("CREATE TEMPORARY TABLE %s (LIKE %s) ON COMMIT DROP", quoteIdent(tmp), tableIdent(schema, table))
+}
+
+// insertFromTempQuery moves the rows copied into the temporary table
+// to the target table, applying the configured conflict handling.
+func insertFromTempQuery(schema, table, tmp string, cols []string, onConflict string, conflictCols []string) string {
+	sb := new(strings.Builder)
+	qcols := quoteIdents(cols)
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(tableIdent(schema, table))
+	sb.WriteString(" (")
+	sb.WriteString(qcols)
+	sb.WriteString(") SELECT ")
+	sb.WriteString(qcols)
+	sb.WriteString(" FROM ")
+	sb.WriteString(quoteIdent(tmp))
+	switch onConflict {
+	case onConflictIgnore:
+		sb.WriteString(" ON CONFLICT DO NOTHING")
+	case onConflictUpdate:
+		isConflictCol := make(map[string]struct{}, len(conflictCols))
+		for _, c := range conflictCols {
+			isConflictCol[c] = struct{}{}
+		}
+		sets := make([]string, 0, len(cols))
+		for _, c := range cols {
+			if _, ok := isConflictCol[c]; ok {
+				continue
+			}
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", quoteIdent(c), quoteIdent(c)))
+		}
+		sb.WriteString(" ON CONFLICT (")
+		sb.WriteString(quoteIdents(conflictCols))
+		sb.WriteString(")")
+		if len(sets) == 0 {
+			sb.WriteString(" DO NOTHING")
+		} else {
+			sb.WriteString(" DO UPDATE SET ")
+			sb.WriteString(strings.Join(sets, ", "))
+		}
+	}
+	return sb.String()
+}
+
+// tableColumns returns the columns needed to store the events.
+// Depending on the configuration, tags and values are stored
+// in dedicated columns or in a single JSONB column.
+// A value with the same name as a tag is ignored.
+func tableColumns(evs []*formatters.EventMsg, tagsAs, valuesAs string, extraTags []string) []column {
+	cols := []column{
+		{name: timeColumn, typ: typeTimestamp},
+		{name: nameColumn, typ: typeText},
+	}
+	tags := make(map[string]struct{})
+	if tagsAs == storeJSONB {
+		cols = append(cols, column{name: tagsColumn, typ: typeJSONB})
+	} else {
+		for _, k := range extraTags {
+			tags[k] = struct{}{}
+		}
+		for _, ev := range evs {
+			for k := range ev.Tags {
+				tags[k] = struct{}{}
+			}
+		}
+		for _, k := range sortedKeys(tags) {
+			name := identifier(k)
+			if isReservedColumn(name, tagsAs, valuesAs) {
+				continue
+			}
+			cols = append(cols, column{name: name, typ: typeText, key: k})
+		}
+	}
+	if valuesAs == storeJSONB {
+		cols = append(cols, column{name: valuesColumn, typ: typeJSONB})
+		return cols
+	}
+	values := make(map[string]string)
+	for _, ev := range evs {
+		for k, v := range ev.Values {
+			if _, ok := tags[k]; ok {
+				continue
+			}
+			t := valueType(v)
+			if pt, ok := values[k]; ok {
+				t = mergeValueTypes(pt, t)
+			}
+			values[k] = t
+		}
+	}
+	for _, k := range sortedKeys(values) {
+		name := identifier(k)
+		if isReservedColumn(name, tagsAs, valuesAs) {
+			continue
+		}
+		cols = append(cols, column{name: name, typ: values[k], key: k})
+	}
+	return cols
+}
+
+func isReservedColumn(name, tagsAs, valuesAs string) bool {
+	switch name {
+	case timeColumn, nameColumn:
+		return true
+	case tagsColumn:
+		return tagsAs == storeJSONB
+	case valuesColumn:
+		return valuesAs == storeJSONB
+	}
+	return false
+}
+
+// missingColumns returns the columns not present in the table schema.
+func missingColumns(ts tableSchema, cols []column) []column {
+	var missing []column
+	for _, c := range cols {
+		if _, ok := ts[c.name]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// valueType returns the PostgreSQL type used to store the value v.
+func valueType(v interface{}) string {
+	switch normalizeValue(v).(type) {
+	case int64:
+		return typeBigint
+	case uint64:
+		return typeNumeric
+	case float64:
+		return typeDouble
+	case bool:
+		return typeBoolean
+	case string:
+		return typeText
+	default:
+		return typeJSONB
+	}
+}
+
+// mergeValueTypes returns a type able to hold values of both types t1 and t2.
+func mergeValueTypes(t1, t2 string) string {
+	if t1 == t2 {
+		return t1
+	}
+	isNum := func(t string) bool { return t == typeBigint || t == typeNumeric || t == typeDouble }
+	switch {
+	case isNum(t1) && isNum(t2):
+		if t1 == typeDouble || t2 == typeDouble {
+			return typeDouble
+		}
+		return typeNumeric
+	default:
+		return typeText
+	}
+}
+
+// normalizeValue converts v to one of int64, uint64, float64, bool or string,
+// maps and slices are returned as is.
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case uint64:
+		return v
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		return v
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// convertValue converts v to a Go type the driver can encode for the column data type.
+func convertValue(v interface{}, typ string) (interface{}, error) {
+	v = normalizeValue(v)
+	switch typ {
+	case typeText, "character varying", "character":
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case typeJSONB, "json":
+		return v, nil
+	case typeBoolean:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case uint64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case typeDouble, "real":
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		if typ == "real" {
+			return float32(f), nil
+		}
+		return f, nil
+	case typeNumeric:
+		switch v := v.(type) {
+		case int64, uint64, float64:
+			return v, nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i, nil
+			}
+			if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+				return u, nil
+			}
+			return strconv.ParseFloat(v, 64)
+		}
+	case typeBigint, "integer", "smallint":
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "integer":
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return nil, errOutOfRange(v, typ)
+			}
+			return int32(i), nil
+		case "smallint":
+			if i < math.MinInt16 || i > math.MaxInt16 {
+				return nil, errOutOfRange(v, typ)
+			}
+			return int16(i), nil
+		}
+		return i, nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %q", typ)
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", v, typ)
+}
+
+// supportedType reports whether values can be converted to the column data type.
+func supportedType(typ string) bool {
+	switch typ {
+	case typeText, "character varying", "character", typeJSONB, "json", typeBoolean,
+		typeDouble, "real", typeNumeric, typeBigint, "integer", "smallint":
+		return true
+	}
+	return false
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to %s", v, typeDouble)
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, errOutOfRange(v, typeBigint)
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, errOutOfRange(v, typeBigint)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to %s", v, typeBigint)
+}
+
+func errOutOfRange(v interface{}, t string) error {
+	return fmt.Errorf("value %v out of %s range", v, t)
+}
+
+func eventTime(ev *formatters.EventMsg) time.Time {
+	if ev.Timestamp > 0 {
+		return time.Unix(0, ev.Timestamp).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// eventRow builds the row of an event for the given columns.
+// Values that cannot be converted to their column type are set to NULL and returned as errors.
+func eventRow(ev *formatters.EventMsg, cols []column, ts tableSchema) ([]interface{}, []error) {
+	row := make([]interface{}, 0, len(cols))
+	var errs []error
+	for _, c := range cols {
+		switch {
+		case c.key == "" && c.name == timeColumn:
+			row = append(row, eventTime(ev))
+			continue
+		case c.key == "" && c.name == nameColumn:
+			row = append(row, ev.Name)
+			continue
+		case c.key == "" && c.name == tagsColumn:
+			tags := ev.Tags
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			row = append(row, tags)
+			continue
+		case c.key == "" && c.name == valuesColumn:
+			values := ev.Values
+			if values == nil {
+				values = map[string]interface{}{}
+			}
+			row = append(row, values)
+			continue
+		}
+		var v interface{}
+		var ok bool
+		if v, ok = ev.Tags[c.key]; !ok {
+			v, ok = ev.Values[c.key]
+		}
+		if !ok {
+			row = append(row, nil)
+			continue
+		}
+		cv, err := convertValue(v, ts[c.name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("column %q: %v", c.name, err))
+			row = append(row, nil)
+			continue
+		}
+		row = append(row, cv)
+	}
+	return row, errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
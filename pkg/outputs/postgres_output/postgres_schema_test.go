@@ -0,0 +1,241 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres_output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func TestIdentifier(t *testing.T) {
+	short := "/interface/statistics/in-octets"
+	if got := identifier(short); got != short {
+		t.Errorf("got %q, want %q", got, short)
+	}
+	long := "/network-instance/protocols/bgp/neighbor/afi-safi/ipv4-unicast/prefix-limit/max-prefixes"
+	got := identifier(long)
+	if len(got) > maxIdentifierLength {
+		t.Errorf("identifier too long: %d", len(got))
+	}
+	// the name is truncated from the start and suffixed with `_` and an 8 characters hash.
+	if !strings.HasSuffix(long, got[:len(got)-9]) {
+		t.Errorf("identifier %q does not keep the end of %q", got, long)
+	}
+	if identifier(long) != got {
+		t.Errorf("identifier is not deterministic")
+	}
+	other := "/network-instance/protocols/bgp/neighbor/afi-safi/ipv6-unicast/prefix-limit/max-prefixes"
+	if identifier(other) == got {
+		t.Errorf("identifiers of different names collide")
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	evs := []*formatters.EventMsg{
+		{
+			Name: "sub1",
+			Tags: map[string]string{"source": "r1", "interface_name": "e1"},
+			Values: map[string]interface{}{
+				"in-octets":  uint64(1),
+				"oper-state": "up",
+				"rate":       int64(1),
+			},
+		},
+		{
+			Name: "sub1",
+			Tags: map[string]string{"source": "r1"},
+			Values: map[string]interface{}{
+				"rate":    1.5,
+				"enabled": true,
+				"source":  "ignored",
+			},
+		},
+	}
+	tests := map[string]struct {
+		tagsAs    string
+		valuesAs  string
+		extraTags []string
+		want      []column
+	}{
+		"jsonb_tags": {
+			tagsAs:   storeJSONB,
+			valuesAs: storeColumns,
+			want: []column{
+				{name: "time", typ: typeTimestamp},
+				{name: "name", typ: typeText},
+				{name: "tags", typ: typeJSONB},
+				{name: "enabled", typ: typeBoolean, key: "enabled"},
+				{name: "in-octets", typ: typeNumeric, key: "in-octets"},
+				{name: "oper-state", typ: typeText, key: "oper-state"},
+				{name: "rate", typ: typeDouble, key: "rate"},
+				{name: "source", typ: typeText, key: "source"},
+			},
+		},
+		"tag_columns": {
+			tagsAs:    storeColumns,
+			valuesAs:  storeJSONB,
+			extraTags: []string{"site"},
+			want: []column{
+				{name: "time", typ: typeTimestamp},
+				{name: "name", typ: typeText},
+				{name: "interface_name", typ: typeText, key: "interface_name"},
+				{name: "site", typ: typeText, key: "site"},
+				{name: "source", typ: typeText, key: "source"},
+				{name: "values", typ: typeJSONB},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tableColumns(evs, tt.tagsAs, tt.valuesAs, tt.extraTags)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueries(t *testing.T) {
+	cols := []column{
+		{name: "time", typ: typeTimestamp},
+		{name: "name", typ: typeText},
+		{name: "tags", typ: typeJSONB},
+		{name: "in-octets", typ: typeNumeric, key: "in-octets"},
+	}
+	tests := map[string]struct {
+		got  string
+		want string
+	}{
+		"create_table": {
+			got:  createTableQuery("public", "sub1", cols),
+			want: `CREATE TABLE IF NOT EXISTS "public"."sub1" ("time" timestamp with time zone NOT NULL, "name" text NOT NULL, "tags" jsonb, "in-octets" numeric)`,
+		},
+		"add_columns": {
+			got:  addColumnsQuery("public", "sub1", cols[3:]),
+			want: `ALTER TABLE "public"."sub1" ADD COLUMN IF NOT EXISTS "in-octets" numeric`,
+		},
+		"unique_index": {
+			got:  createIndexQuery("public", "sub1", "_conflict_idx", true, []string{"time", "name", "tags"}),
+			want: `CREATE UNIQUE INDEX IF NOT EXISTS "sub1_conflict_idx" ON "public"."sub1" ("time", "name", "tags")`,
+		},
+		"hypertable": {
+			got:  createHypertableQuery("public", "sub1", 24*time.Hour),
+			want: `SELECT create_hypertable('"public"."sub1"', 'time', if_not_exists => TRUE, chunk_time_interval => INTERVAL '86400000000 microseconds')`,
+		},
+		"insert_error": {
+			got:  insertFromTempQuery("public", "sub1", "tmp", []string{"time", "name", "v"}, onConflictError, nil),
+			want: `INSERT INTO "public"."sub1" ("time", "name", "v") SELECT "time", "name", "v" FROM "tmp"`,
+		},
+		"insert_ignore": {
+			got:  insertFromTempQuery("public", "sub1", "tmp", []string{"time", "name", "v"}, onConflictIgnore, []string{"time", "name"}),
+			want: `INSERT INTO "public"."sub1" ("time", "name", "v") SELECT "time", "name", "v" FROM "tmp" ON CONFLICT DO NOTHING`,
+		},
+		"insert_update": {
+			got:  insertFromTempQuery("public", "sub1", "tmp", []string{"time", "name", "v"}, onConflictUpdate, []string{"time", "name"}),
+			want: `INSERT INTO "public"."sub1" ("time", "name", "v") SELECT "time", "name", "v" FROM "tmp" ON CONFLICT ("time", "name") DO UPDATE SET "v" = EXCLUDED."v"`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		v       interface{}
+		typ     string
+		want    interface{}
+		wantErr bool
+	}{
+		{v: uint32(5), typ: typeBigint, want: int64(5)},
+		{v: uint64(1 << 63), typ: typeBigint, wantErr: true},
+		{v: uint64(1 << 63), typ: typeNumeric, want: uint64(1 << 63)},
+		{v: "42", typ: typeNumeric, want: int64(42)},
+		{v: int64(1 << 40), typ: "integer", wantErr: true},
+		{v: float32(1.5), typ: typeDouble, want: 1.5},
+		{v: "true", typ: typeBoolean, want: true},
+		{v: int64(3), typ: typeText, want: "3"},
+		{v: map[string]interface{}{"a": 1}, typ: typeText, want: `{"a":1}`},
+		{v: []interface{}{"a"}, typ: typeJSONB, want: []interface{}{"a"}},
+		{v: "x", typ: "inet", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := convertValue(tt.v, tt.typ)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("convertValue(%v, %s): expected an error, got %v", tt.v, tt.typ, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertValue(%v, %s): unexpected error: %v", tt.v, tt.typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("convertValue(%v, %s): got %#v, want %#v", tt.v, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestEventRow(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1710000000000000000,
+		Tags:      map[string]string{"source": "r1"},
+		Values: map[string]interface{}{
+			"counter": "not-a-number",
+			"state":   "up",
+		},
+	}
+	cols := []column{
+		{name: "time", typ: typeTimestamp},
+		{name: "name", typ: typeText},
+		{name: "source", typ: typeText, key: "source"},
+		{name: "counter", typ: typeBigint, key: "counter"},
+		{name: "state", typ: typeText, key: "state"},
+		{name: "missing", typ: typeText, key: "missing"},
+		{name: "values", typ: typeJSONB},
+	}
+	ts := tableSchema{
+		"time": typeTimestamp, "name": typeText, "source": typeText,
+		"counter": typeBigint, "state": typeText, "missing": typeText, "values": typeJSONB,
+	}
+	row, errs := eventRow(ev, cols, ts)
+	want := []interface{}{
+		time.Unix(0, ev.Timestamp).UTC(), "sub1", "r1", nil, "up", nil, ev.Values,
+	}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("got %#v, want %#v", row, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 conversion error, got %v", errs)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := map[string]string{
+		"postgres://user:secret@db:5432/gnmic?sslmode=disable": "postgres://user:xxxxx@db:5432/gnmic?sslmode=disable",
+		"postgres://db:5432/gnmic":                             "postgres://db:5432/gnmic",
+		"host=db password=secret dbname=gnmic":                 "host=db password=xxxxx dbname=gnmic",
+		"host=db password='se cret' dbname=gnmic":              "host=db password=xxxxx dbname=gnmic",
+	}
+	for in, want := range tests {
+		if got := redactURL(in); got != want {
+			t.Errorf("redactURL(%q): got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -232,6 +232,25 @@ func (p *promWriteOutput) WriteEvent(ctx context.Context, ev *formatters.EventMs
 	}
 }
 
+// WriteSync sends a message to the remote,
+// it returns once the remote accepted it.
+func (p *promWriteOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	return p.writeSync(ctx, p.responseEvents(rsp, meta))
+}
+
+// WriteEventSync sends an event to the remote,
+// it returns once the remote accepted it.
+func (p *promWriteOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range p.evps {
+		evs = proc.Apply(evs...)
+	}
+	return p.writeSync(ctx, evs)
+}
+
 func (p *promWriteOutput) Close() error {
 	if p.cfn == nil {
 		return nil
@@ -306,28 +325,32 @@ func (p *promWriteOutput) worker(ctx context.Context) {
 }
 
 func (p *promWriteOutput) workerHandleProto(_ context.Context, m *outputs.ProtoMsg) {
-	pmsg := m.GetMsg()
-	switch pmsg := pmsg.(type) {
-	case *gnmi.SubscribeResponse:
-		meta := m.GetMeta()
-		measName := "default"
-		if subName, ok := meta["subscription-name"]; ok {
-			measName = subName
-		}
-		var err error
-		pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), p.cfg.AddTarget, p.targetTpl)
-		if err != nil {
-			p.logger.Printf("failed to add target to the response: %v", err)
-		}
-		events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, p.evps...)
-		if err != nil {
-			p.logger.Printf("failed to convert message to event: %v", err)
-			return
-		}
-		for _, ev := range events {
-			p.workerHandleEvent(ev)
-		}
+	for _, ev := range p.responseEvents(m.GetMsg(), m.GetMeta()) {
+		p.workerHandleEvent(ev)
+	}
+}
+
+// responseEvents converts a gNMI SubscribeResponse to events.
+func (p *promWriteOutput) responseEvents(m proto.Message, meta outputs.Meta) []*formatters.EventMsg {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil
+	}
+	measName := "default"
+	if subName, ok := meta["subscription-name"]; ok {
+		measName = subName
+	}
+	var err error
+	pmsg, err = outputs.AddSubscriptionTarget(pmsg, meta, p.cfg.AddTarget, p.targetTpl)
+	if err != nil {
+		p.logger.Printf("failed to add target to the response: %v", err)
+	}
+	events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, p.evps...)
+	if err != nil {
+		p.logger.Printf("failed to convert message to event: %v", err)
+		return nil
 	}
+	return events
 }
 
 func (p *promWriteOutput) workerHandleEvent(ev *formatters.EventMsg) {
@@ -341,17 +364,7 @@ func (p *promWriteOutput) workerHandleEvent(ev *formatters.EventMsg) {
 			}
 			p.buffDrainCh <- struct{}{}
 		}
-		// populate metadata cache
-		p.m.Lock()
-		if p.cfg.Debug {
-			p.logger.Printf("saving metrics metadata")
-		}
-		p.metadataCache[pts.Name] = prompb.MetricMetadata{
-			Type:             prompb.MetricMetadata_COUNTER,
-			MetricFamilyName: pts.Name,
-			Help:             defaultMetricHelp,
-		}
-		p.m.Unlock()
+		p.cacheMetadata(pts.Name)
 		// write time series to buffer
 		if p.cfg.Debug {
 			p.logger.Printf("writing TimeSeries to buffer")
@@ -360,6 +373,42 @@ func (p *promWriteOutput) workerHandleEvent(ev *formatters.EventMsg) {
 	}
 }
 
+// cacheMetadata populates the metadata cache.
+func (p *promWriteOutput) cacheMetadata(name string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.cfg.Debug {
+		p.logger.Printf("saving metrics metadata")
+	}
+	p.metadataCache[name] = prompb.MetricMetadata{
+		Type:             prompb.MetricMetadata_COUNTER,
+		MetricFamilyName: name,
+		Help:             defaultMetricHelp,
+	}
+}
+
+// writeSync converts the events to time series and sends them to the remote,
+// it returns once the remote accepted all of them.
+func (p *promWriteOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	pts := make([]prompb.TimeSeries, 0, len(evs))
+	for _, ev := range evs {
+		for _, pt := range p.mb.TimeSeriesFromEvent(ev) {
+			p.cacheMetadata(pt.Name)
+			pts = append(pts, *pt.TS)
+		}
+	}
+	for len(pts) > 0 {
+		n := min(len(pts), p.cfg.MaxTimeSeriesPerWrite)
+		err := p.writeRequest(ctx, &prompb.WriteRequest{Timeseries: pts[:n]})
+		if err != nil {
+			return err
+		}
+		prometheusWriteNumberOfSentMsgs.WithLabelValues(p.cfg.Name).Add(float64(n))
+		pts = pts[n:]
+	}
+	return nil
+}
+
 func (p *promWriteOutput) setDefaults() error {
 	if p.cfg.Timeout <= 0 {
 		p.cfg.Timeout = defaultTimeout
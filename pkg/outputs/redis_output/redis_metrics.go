@@ -0,0 +1,84 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redis_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "redis_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var redisNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_redis_msgs_sent_success_total",
+	Help:      "Number of msgs successfully sent by gnmic redis output",
+}, []string{"name"})
+
+var redisNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_written_redis_bytes_total",
+	Help:      "Number of bytes written by gnmic redis output",
+}, []string{"name"})
+
+var redisNumberOfFailSendMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_redis_msgs_sent_fail_total",
+	Help:      "Number of failed msgs sent by gnmic redis output",
+}, []string{"name", "reason"})
+
+var redisSendDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "msg_send_duration_ns",
+	Help:      "gnmic redis output send duration in ns",
+}, []string{"name"})
+
+func initMetrics(name string) {
+	redisNumberOfSentMsgs.WithLabelValues(name).Add(0)
+	redisNumberOfSentBytes.WithLabelValues(name).Add(0)
+	redisNumberOfFailSendMsgs.WithLabelValues(name, "").Add(0)
+	redisSendDuration.WithLabelValues(name).Set(0)
+}
+
+func (r *redisOutput) registerMetrics() error {
+	if r.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = r.reg.Register(redisNumberOfSentMsgs); err != nil {
+			r.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = r.reg.Register(redisNumberOfSentBytes); err != nil {
+			r.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = r.reg.Register(redisNumberOfFailSendMsgs); err != nil {
+			r.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = r.reg.Register(redisSendDuration); err != nil {
+			r.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(r.cfg.Name)
+	return err
+}
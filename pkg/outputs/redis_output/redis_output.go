@@ -0,0 +1,528 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redis_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType          = "redis"
+	loggingPrefix       = "[redis_output:%s] "
+	defaultAddress      = "127.0.0.1:6379"
+	defaultStream       = `gnmic:{{ .Name }}`
+	defaultFormat       = "event"
+	defaultNumWorkers   = 1
+	defaultBufferSize   = 1000
+	defaultWriteTimeout = 5 * time.Second
+	// name of the stream entry field holding the message.
+	dataField = "data"
+)
+
+func init() {
+	outputs.Register(outputType, func() outputs.Output {
+		return &redisOutput{
+			cfg:    &config{},
+			wg:     new(sync.WaitGroup),
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type redisOutput struct {
+	cfg    *config
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	client    *redis.Client
+	msgChan   chan *outputs.ProtoMsg
+	evChan    chan *formatters.EventMsg
+	wg        *sync.WaitGroup
+	mo        *formatters.MarshalOptions
+	evps      []formatters.EventProcessor
+	streamTpl *template.Template
+
+	targetTpl *template.Template
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name     string           `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address  string           `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Username string           `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string           `mapstructure:"password,omitempty" json:"password,omitempty"`
+	DB       int              `mapstructure:"db,omitempty" json:"db,omitempty"`
+	TLS      *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	// Go template of the stream name.
+	Stream string `mapstructure:"stream,omitempty" json:"stream,omitempty"`
+	// max number of entries kept in a stream, 0 disables trimming.
+	MaxLen int64 `mapstructure:"max-len,omitempty" json:"max-len,omitempty"`
+	// trim the streams to exactly max-len entries instead of approximately.
+	ExactTrim          bool          `mapstructure:"exact-trim,omitempty" json:"exact-trim,omitempty"`
+	Format             string        `mapstructure:"format,omitempty" json:"format,omitempty"`
+	SplitEvents        bool          `mapstructure:"split-events,omitempty" json:"split-events,omitempty"`
+	AddTarget          string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	NumWorkers         int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	WriteTimeout       time.Duration `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	BufferSize         int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	Debug              bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	EventProcessors    []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+}
+
+func (r *redisOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, r.cfg)
+	if err != nil {
+		return err
+	}
+	if r.cfg.Name == "" {
+		r.cfg.Name = name
+	}
+	r.logger.SetPrefix(fmt.Sprintf(loggingPrefix, r.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return err
+		}
+	}
+	err = r.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = r.registerMetrics()
+	if err != nil {
+		return err
+	}
+	r.msgChan = make(chan *outputs.ProtoMsg, r.cfg.BufferSize)
+	r.evChan = make(chan *formatters.EventMsg, r.cfg.BufferSize)
+	r.mo = &formatters.MarshalOptions{
+		Format:     r.cfg.Format,
+		OverrideTS: r.cfg.OverrideTimestamps,
+	}
+	if r.cfg.TargetTemplate == "" {
+		r.targetTpl = outputs.DefaultTargetTemplate
+	} else if r.cfg.AddTarget != "" {
+		r.targetTpl, err = gtemplate.CreateTemplate("target-template", r.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		r.targetTpl = r.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	r.streamTpl, err = gtemplate.CreateTemplate("stream", r.cfg.Stream)
+	if err != nil {
+		return fmt.Errorf("failed to parse stream template: %v", err)
+	}
+	r.streamTpl = r.streamTpl.Funcs(outputs.TemplateFuncs)
+
+	r.client, err = r.createClient()
+	if err != nil {
+		return err
+	}
+	// the client reconnects on its own, a failed ping is not fatal.
+	pctx, cancel := context.WithTimeout(ctx, r.cfg.WriteTimeout)
+	defer cancel()
+	if err := r.client.Ping(pctx).Err(); err != nil {
+		r.logger.Printf("failed to ping redis server %s: %v", r.cfg.Address, err)
+	}
+
+	ctx, r.cfn = context.WithCancel(ctx)
+	r.wg.Add(r.cfg.NumWorkers)
+	for i := 0; i < r.cfg.NumWorkers; i++ {
+		go r.worker(ctx, i)
+	}
+	r.logger.Printf("initialized redis output %s: %s", r.cfg.Name, r.String())
+	return nil
+}
+
+func (r *redisOutput) setDefaults() error {
+	if r.cfg.Format == "" {
+		r.cfg.Format = defaultFormat
+	}
+	switch r.cfg.Format {
+	case "event", "json", "protojson", "proto":
+	default:
+		return fmt.Errorf("unsupported output format '%s' for output type redis", r.cfg.Format)
+	}
+	if r.cfg.MaxLen < 0 {
+		return fmt.Errorf("invalid max-len value %d", r.cfg.MaxLen)
+	}
+	if r.cfg.Address == "" {
+		r.cfg.Address = defaultAddress
+	}
+	if r.cfg.Stream == "" {
+		r.cfg.Stream = defaultStream
+	}
+	if r.cfg.NumWorkers <= 0 {
+		r.cfg.NumWorkers = defaultNumWorkers
+	}
+	if r.cfg.BufferSize <= 0 {
+		r.cfg.BufferSize = defaultBufferSize
+	}
+	if r.cfg.WriteTimeout <= 0 {
+		r.cfg.WriteTimeout = defaultWriteTimeout
+	}
+	return nil
+}
+
+func (r *redisOutput) createClient() (*redis.Client, error) {
+	opts := &redis.Options{
+		Addr:         r.cfg.Address,
+		Username:     r.cfg.Username,
+		Password:     r.cfg.Password,
+		DB:           r.cfg.DB,
+		WriteTimeout: r.cfg.WriteTimeout,
+		PoolSize:     r.cfg.NumWorkers,
+	}
+	if r.cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			r.cfg.TLS.CaFile,
+			r.cfg.TLS.CertFile,
+			r.cfg.TLS.KeyFile,
+			"",
+			r.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsCfg
+	}
+	return redis.NewClient(opts), nil
+}
+
+func (r *redisOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil || r.mo == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, r.cfg.WriteTimeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case r.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if r.cfg.Debug {
+			r.logger.Printf("writing expired after %s, redis output might not be initialized", r.cfg.WriteTimeout)
+		}
+		redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+// WriteEvent adds events to the streams when the output format is `event`.
+func (r *redisOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if r.cfg.Format != "event" {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range r.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case r.evChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync adds a message to its stream, it returns once redis added the entry.
+func (r *redisOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil || r.mo == nil {
+		return nil
+	}
+	return r.handleProtoMsg(ctx, rsp, meta)
+}
+
+// WriteEventSync adds an event to its stream when the output format is `event`,
+// it returns once redis added the entry.
+func (r *redisOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	if r.mo == nil || r.cfg.Format != "event" {
+		return nil
+	}
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range r.evps {
+		evs = proc.Apply(evs...)
+	}
+	return r.addEvents(ctx, evs)
+}
+
+func (r *redisOutput) Close() error {
+	if r.cfn == nil {
+		return nil
+	}
+	r.cfn()
+	r.wg.Wait()
+	if r.client != nil {
+		return r.client.Close()
+	}
+	return nil
+}
+
+func (r *redisOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !r.cfg.EnableMetrics {
+		return
+	}
+	r.reg = reg
+}
+
+func (r *redisOutput) String() string {
+	cfg := *r.cfg
+	if cfg.Password != "" {
+		cfg.Password = "****"
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (r *redisOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && r.logger != nil {
+		r.logger.SetOutput(logger.Writer())
+		r.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (r *redisOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	r.evps, err = formatters.MakeEventProcessors(
+		logger,
+		r.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *redisOutput) SetName(name string) {
+	if r.cfg.Name == "" {
+		r.cfg.Name = name
+	}
+}
+
+func (r *redisOutput) SetClusterName(_ string) {}
+
+func (r *redisOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (r *redisOutput) worker(ctx context.Context, i int) {
+	defer r.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", i)
+	r.logger.Printf("%s starting", workerLogPrefix)
+	defer r.logger.Printf("%s exited", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-r.evChan:
+			r.addEvents(ctx, []*formatters.EventMsg{ev})
+		case msg := <-r.msgChan:
+			r.handleProtoMsg(ctx, msg.GetMsg(), msg.GetMeta())
+		}
+	}
+}
+
+// handleProtoMsg adds a message to its stream, it returns an error if it could not be added.
+func (r *redisOutput) handleProtoMsg(ctx context.Context, pmsg proto.Message, meta outputs.Meta) error {
+	pmsg, err := outputs.AddSubscriptionTarget(pmsg, meta, r.cfg.AddTarget, r.targetTpl)
+	if err != nil {
+		r.logger.Printf("failed to add target to the response: %v", err)
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	if r.cfg.Format == "event" {
+		rsp, ok := r.mo.OverrideTimestamp(pmsg).(*gnmi.SubscribeResponse)
+		if !ok {
+			return nil
+		}
+		evs, err := formatters.ResponseToEventMsgs(subName, rsp, meta, r.evps...)
+		if err != nil {
+			if r.cfg.Debug {
+				r.logger.Printf("failed to convert message to events: %v", err)
+			}
+			redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "marshal_error").Inc()
+			return nil
+		}
+		return r.addEvents(ctx, evs)
+	}
+	b, err := r.mo.Marshal(pmsg, meta, r.evps...)
+	if err != nil {
+		if r.cfg.Debug {
+			r.logger.Printf("failed marshaling proto msg: %v", err)
+		}
+		redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "marshal_error").Inc()
+		return nil
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	stream, err := r.stream(&formatters.EventMsg{Name: subName, Tags: meta})
+	if err != nil {
+		r.logger.Printf("failed to render stream name: %v", err)
+		redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "stream_error").Inc()
+		return nil
+	}
+	return r.add(ctx, stream, entryValues(b, meta))
+}
+
+// addEvents adds the events to their stream,
+// the events sharing a stream are added as a single JSON array unless split-events is set.
+// It returns the first XADD error.
+func (r *redisOutput) addEvents(ctx context.Context, evs []*formatters.EventMsg) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	streams := make([]string, 0, 1)
+	byStream := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		stream, err := r.stream(ev)
+		if err != nil {
+			r.logger.Printf("failed to render stream name: %v", err)
+			redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "stream_error").Inc()
+			continue
+		}
+		if _, ok := byStream[stream]; !ok {
+			streams = append(streams, stream)
+		}
+		byStream[stream] = append(byStream[stream], ev)
+	}
+	var aerr error
+	for _, stream := range streams {
+		sevs := byStream[stream]
+		if r.cfg.SplitEvents {
+			for _, ev := range sevs {
+				b, err := json.Marshal(ev)
+				if err != nil {
+					redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "marshal_error").Inc()
+					continue
+				}
+				err = r.add(ctx, stream, entryValues(b, nil))
+				if err != nil && aerr == nil {
+					aerr = err
+				}
+			}
+			continue
+		}
+		b, err := json.Marshal(sevs)
+		if err != nil {
+			redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "marshal_error").Inc()
+			continue
+		}
+		err = r.add(ctx, stream, entryValues(b, nil))
+		if err != nil && aerr == nil {
+			aerr = err
+		}
+	}
+	return aerr
+}
+
+func (r *redisOutput) add(ctx context.Context, stream string, values []any) error {
+	if r.cfg.Debug {
+		if r.cfg.Format == "proto" {
+			r.logger.Printf("adding %d bytes entry to stream %q", len(values[1].([]byte)), stream)
+		} else {
+			r.logger.Printf("adding entry to stream %q: %s", stream, values[1])
+		}
+	}
+	start := time.Now()
+	wctx, cancel := context.WithTimeout(ctx, r.cfg.WriteTimeout)
+	defer cancel()
+	err := r.client.XAdd(wctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: r.cfg.MaxLen,
+		Approx: !r.cfg.ExactTrim,
+		Values: values,
+	}).Err()
+	if err != nil {
+		r.logger.Printf("failed to add entry to stream %q: %v", stream, err)
+		redisNumberOfFailSendMsgs.WithLabelValues(r.cfg.Name, "xadd_error").Inc()
+		return err
+	}
+	redisSendDuration.WithLabelValues(r.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	redisNumberOfSentMsgs.WithLabelValues(r.cfg.Name).Inc()
+	redisNumberOfSentBytes.WithLabelValues(r.cfg.Name).Add(float64(len(values[1].([]byte))))
+	return nil
+}
+
+// entryValues returns the stream entry field-value pairs:
+// the message under the `data` field followed by the message metadata, sorted by name.
+func entryValues(b []byte, meta outputs.Meta) []any {
+	values := make([]any, 0, 2+2*len(meta))
+	values = append(values, dataField, b)
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		if k == dataField {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		values = append(values, k, meta[k])
+	}
+	return values
+}
+
+func (r *redisOutput) stream(ev *formatters.EventMsg) (string, error) {
+	buf := new(bytes.Buffer)
+	err := r.streamTpl.Execute(buf, ev)
+	if err != nil {
+		return "", err
+	}
+	stream := strings.TrimSpace(buf.String())
+	if stream == "" {
+		return "", errors.New("stream template rendered an empty stream name")
+	}
+	return stream, nil
+}
@@ -0,0 +1,106 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redis_output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestSetDefaults(t *testing.T) {
+	r := &redisOutput{cfg: &config{}}
+	if err := r.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if r.cfg.Address != defaultAddress || r.cfg.Stream != defaultStream || r.cfg.Format != defaultFormat {
+		t.Errorf("unexpected defaults: %+v", r.cfg)
+	}
+	for _, cfg := range []*config{
+		{Format: "prototext"},
+		{MaxLen: -1},
+	} {
+		r := &redisOutput{cfg: cfg}
+		if err := r.setDefaults(); err == nil {
+			t.Errorf("expected an error for config %+v", cfg)
+		}
+	}
+}
+
+func TestEntryValues(t *testing.T) {
+	b := []byte(`{"name":"sub1"}`)
+	got := entryValues(b, outputs.Meta{
+		"subscription-name": "sub1",
+		"source":            "router1:57400",
+		"data":              "ignored",
+	})
+	want := []any{"data", b, "source", "router1:57400", "subscription-name", "sub1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got = entryValues(b, nil)
+	want = []any{"data", b}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		tpl     string
+		ev      *formatters.EventMsg
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default",
+			tpl:  defaultStream,
+			ev:   &formatters.EventMsg{Name: "sub1"},
+			want: "gnmic:sub1",
+		},
+		{
+			name: "tag",
+			tpl:  `telemetry:{{ index .Tags "source" }}`,
+			ev:   &formatters.EventMsg{Name: "sub1", Tags: map[string]string{"source": "router1"}},
+			want: "telemetry:router1",
+		},
+		{
+			name:    "empty",
+			tpl:     `{{ index .Tags "source" }}`,
+			ev:      &formatters.EventMsg{Name: "sub1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := gtemplate.CreateTemplate("stream", tt.tpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &redisOutput{cfg: &config{}, streamTpl: tpl}
+			got, err := r.stream(tt.ev)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package syslog_output
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func (s *syslogOutput) dial(ctx context.Context) (net.Conn, error) {
+	d := &net.Dialer{Timeout: s.cfg.Timeout}
+	switch s.cfg.Network {
+	case networkTLS:
+		tlsCfg, err := utils.NewTLSConfig(
+			s.cfg.TLS.CaFile,
+			s.cfg.TLS.CertFile,
+			s.cfg.TLS.KeyFile,
+			"",
+			s.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if tlsCfg == nil {
+			tlsCfg = new(tls.Config)
+		}
+		if tlsCfg.ServerName == "" {
+			tlsCfg.ServerName, _, _ = net.SplitHostPort(s.cfg.Address)
+		}
+		td := &tls.Dialer{NetDialer: d, Config: tlsCfg}
+		return td.DialContext(ctx, "tcp", s.cfg.Address)
+	default:
+		return d.DialContext(ctx, s.cfg.Network, s.cfg.Address)
+	}
+}
+
+// writer sends the buffered messages over a single connection.
+// Stream connections are re-established after a failure and the failed message is sent again,
+// UDP messages that fail to be sent are dropped.
+func (s *syslogOutput) writer(ctx context.Context) {
+	defer s.wg.Done()
+	s.logger.Printf("starting writer")
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b := <-s.buffer:
+			for {
+				if conn == nil {
+					var err error
+					conn, err = s.dial(ctx)
+					if err != nil {
+						s.logger.Printf("failed to connect to %s: %v", s.cfg.Address, err)
+						syslogNumberOfFailedMsgs.WithLabelValues(s.cfg.Name, "connect_error").Inc()
+						if !s.wait(ctx) {
+							return
+						}
+						continue
+					}
+					s.logger.Printf("connected to %s over %s", s.cfg.Address, s.cfg.Network)
+				}
+				err := s.send(conn, b)
+				if err == nil {
+					break
+				}
+				s.logger.Printf("failed to send message: %v", err)
+				syslogNumberOfFailedMsgs.WithLabelValues(s.cfg.Name, "write_error").Inc()
+				if s.cfg.Network == networkUDP {
+					break
+				}
+				conn.Close()
+				conn = nil
+				if !s.wait(ctx) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeSync sends the messages built from evs on the WriteSync connection,
+// the connection is closed after a failure and dialed again on the next call.
+func (s *syslogOutput) writeSync(ctx context.Context, evs []*formatters.EventMsg) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for _, ev := range evs {
+		b, err := s.framedMessage(ev)
+		if err != nil {
+			s.logger.Printf("%v", err)
+			continue
+		}
+		if s.syncConn == nil {
+			s.syncConn, err = s.dial(ctx)
+			if err != nil {
+				syslogNumberOfFailedMsgs.WithLabelValues(s.cfg.Name, "connect_error").Inc()
+				return err
+			}
+		}
+		err = s.send(s.syncConn, b)
+		if err != nil {
+			syslogNumberOfFailedMsgs.WithLabelValues(s.cfg.Name, "write_error").Inc()
+			s.syncConn.Close()
+			s.syncConn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogOutput) send(conn net.Conn, b []byte) error {
+	err := conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout))
+	if err != nil {
+		return err
+	}
+	n, err := conn.Write(b)
+	if err != nil {
+		return err
+	}
+	syslogNumberOfSentMsgs.WithLabelValues(s.cfg.Name).Inc()
+	syslogNumberOfSentBytes.WithLabelValues(s.cfg.Name).Add(float64(n))
+	return nil
+}
+
+// wait sleeps for the retry interval, it returns false if the context is done.
+func (s *syslogOutput) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(s.cfg.RetryInterval):
+		return true
+	}
+}
@@ -0,0 +1,309 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package syslog_output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	nilValue = "-"
+	// RFC 5424 header fields max lengths
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxMsgIDLen    = 32
+	maxSDNameLen   = 32
+	// RFC 3164 TAG max length
+	maxTagLen = 32
+
+	rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+	rfc3164TimeFormat = time.Stamp
+)
+
+var severities = map[string]int{
+	"emerg":         0,
+	"emergency":     0,
+	"alert":         1,
+	"crit":          2,
+	"critical":      2,
+	"err":           3,
+	"error":         3,
+	"warning":       4,
+	"warn":          4,
+	"notice":        5,
+	"info":          6,
+	"informational": 6,
+	"debug":         7,
+}
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"ntp":      12,
+	"security": 13,
+	"console":  14,
+	"clock":    15,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// parseSeverity returns the code of a severity given as a name or a number.
+func parseSeverity(s string) (int, error) {
+	return parseCode(s, severities, 7)
+}
+
+// parseFacility returns the code of a facility given as a name or a number.
+func parseFacility(s string) (int, error) {
+	return parseCode(s, facilities, 23)
+}
+
+func parseCode(s string, names map[string]int, max int) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := names[s]; ok {
+		return c, nil
+	}
+	c, err := strconv.Atoi(s)
+	if err != nil || c < 0 || c > max {
+		return 0, fmt.Errorf("unknown value %q", s)
+	}
+	return c, nil
+}
+
+// mapping sets a message facility or severity based on an event tag or value.
+type mapping struct {
+	// Field is the name of the tag or value to map.
+	Field string `mapstructure:"field,omitempty" json:"field,omitempty"`
+	// Values maps the field values to a facility or severity name or code.
+	Values map[string]string `mapstructure:"values,omitempty" json:"values,omitempty"`
+
+	codes map[string]int
+}
+
+func (m *mapping) init(parse func(string) (int, error)) error {
+	if m.Field == "" {
+		return fmt.Errorf("missing mapping field")
+	}
+	m.codes = make(map[string]int, len(m.Values))
+	for k, v := range m.Values {
+		c, err := parse(v)
+		if err != nil {
+			return fmt.Errorf("mapping %q: %v", k, err)
+		}
+		// config keys are case insensitive.
+		m.codes[strings.ToLower(k)] = c
+	}
+	return nil
+}
+
+// code returns the facility or severity of an event.
+// The field value is looked up in the mapping values first,
+// then parsed as a name or code, otherwise the default code is returned.
+func (m *mapping) code(ev *formatters.EventMsg, parse func(string) (int, error), def int) int {
+	if m == nil {
+		return def
+	}
+	var fv string
+	if v, ok := ev.Tags[m.Field]; ok {
+		fv = v
+	} else if v, ok := ev.Values[m.Field]; ok {
+		fv = fmt.Sprint(v)
+	} else {
+		return def
+	}
+	if c, ok := m.codes[strings.ToLower(fv)]; ok {
+		return c
+	}
+	if c, err := parse(fv); err == nil {
+		return c
+	}
+	return def
+}
+
+// message builds the syslog message of an event, without framing.
+func (s *syslogOutput) message(ev *formatters.EventMsg) ([]byte, error) {
+	msg, err := s.body(ev)
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now()
+	if ev.Timestamp > 0 {
+		ts = time.Unix(0, ev.Timestamp)
+	}
+	pri := s.facilityMapping.code(ev, parseFacility, s.facility)*8 +
+		s.severityMapping.code(ev, parseSeverity, s.severity)
+
+	buf := new(bytes.Buffer)
+	switch s.cfg.Format {
+	case formatRFC3164:
+		fmt.Fprintf(buf, "<%d>%s %s %s: ",
+			pri,
+			ts.Local().Format(rfc3164TimeFormat),
+			headerField(s.hostname(ev), maxHostnameLen, s.localHostname),
+			headerField(s.cfg.AppName, maxTagLen, defaultAppName),
+		)
+	default: // rfc5424
+		msgID := s.cfg.MsgID
+		if msgID == "" {
+			msgID = ev.Name
+		}
+		fmt.Fprintf(buf, "<%d>1 %s %s %s %s %s %s",
+			pri,
+			ts.UTC().Format(rfc5424TimeFormat),
+			headerField(s.hostname(ev), maxHostnameLen, nilValue),
+			headerField(s.cfg.AppName, maxAppNameLen, nilValue),
+			nilValue, // PROCID
+			headerField(msgID, maxMsgIDLen, nilValue),
+			structuredData(s.cfg.StructuredDataID, ev.Tags),
+		)
+		if len(msg) > 0 {
+			buf.WriteByte(' ')
+		}
+	}
+	buf.Write(msg)
+	return buf.Bytes(), nil
+}
+
+// body returns the message body, either the event as JSON or
+// the result of the msg-template executed against it.
+func (s *syslogOutput) body(ev *formatters.EventMsg) ([]byte, error) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	if s.msgTpl != nil {
+		b, err = outputs.ExecTemplate(b, s.msgTpl)
+		if err != nil {
+			return nil, err
+		}
+		b = bytes.TrimSpace(b)
+	}
+	if s.cfg.Framing == framingNonTransparent {
+		// a new line would end the message.
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte(" "))
+	}
+	return b, nil
+}
+
+// hostname returns the HOSTNAME of an event message:
+// the configured hostname, or the event source (without port) if present.
+func (s *syslogOutput) hostname(ev *formatters.EventMsg) string {
+	if s.cfg.Hostname != "" {
+		return s.cfg.Hostname
+	}
+	if src, ok := ev.Tags["source"]; ok && src != "" {
+		return utils.GetHost(src)
+	}
+	return s.localHostname
+}
+
+// headerField replaces the characters not allowed in a header field
+// and truncates it to max bytes.
+func headerField(s string, max int, def string) string {
+	s = strings.Map(func(r rune) rune {
+		// printable US-ASCII, without space.
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// structuredData returns the RFC 5424 STRUCTURED-DATA element
+// holding the event tags as parameters.
+func structuredData(id string, tags map[string]string) string {
+	if id == "" || len(tags) == 0 {
+		return nilValue
+	}
+	names := make([]string, 0, len(tags))
+	for k := range tags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	sb := new(strings.Builder)
+	sb.WriteByte('[')
+	sb.WriteString(sdName(id))
+	for _, k := range names {
+		sb.WriteByte(' ')
+		sb.WriteString(sdName(k))
+		sb.WriteString(`="`)
+		sb.WriteString(sdValue(tags[k]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// sdName replaces the characters not allowed in SD-IDs and PARAM-NAMEs.
+func sdName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r < 33 || r > 126, r == '=', r == ']', r == '"':
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > maxSDNameLen {
+		s = s[:maxSDNameLen]
+	}
+	return s
+}
+
+// sdValue escapes the characters '"', '\' and ']' in PARAM-VALUEs.
+func sdValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// frame adds the transport framing to a message.
+// UDP messages are sent as is, TCP and TLS messages use octet counting (RFC 6587 and RFC 5425)
+// or are terminated by a new line when using the non-transparent framing.
+func frame(msg []byte, network, framing string) []byte {
+	if network == networkUDP {
+		return msg
+	}
+	if framing == framingNonTransparent {
+		return append(msg, '\n')
+	}
+	b := make([]byte, 0, len(msg)+8)
+	b = strconv.AppendInt(b, int64(len(msg)), 10)
+	b = append(b, ' ')
+	return append(b, msg...)
+}
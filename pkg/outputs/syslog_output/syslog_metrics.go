@@ -0,0 +1,72 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package syslog_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "gnmic"
+	subsystem = "syslog_output"
+)
+
+var registerMetricsOnce sync.Once
+
+var syslogNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_messages_sent_success_total",
+	Help:      "Number of messages successfully sent by gnmic syslog output",
+}, []string{"name"})
+
+var syslogNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_bytes_sent_total",
+	Help:      "Number of bytes sent by gnmic syslog output",
+}, []string{"name"})
+
+var syslogNumberOfFailedMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_messages_sent_fail_total",
+	Help:      "Number of messages gnmic syslog output failed to send",
+}, []string{"name", "reason"})
+
+func initMetrics(name string) {
+	syslogNumberOfSentMsgs.WithLabelValues(name).Add(0)
+	syslogNumberOfSentBytes.WithLabelValues(name).Add(0)
+	syslogNumberOfFailedMsgs.WithLabelValues(name, "").Add(0)
+}
+
+func (s *syslogOutput) registerMetrics() error {
+	if s.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = s.reg.Register(syslogNumberOfSentMsgs); err != nil {
+			s.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = s.reg.Register(syslogNumberOfSentBytes); err != nil {
+			s.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+		if err = s.reg.Register(syslogNumberOfFailedMsgs); err != nil {
+			s.logger.Printf("failed to register metric: %v", err)
+			return
+		}
+	})
+	initMetrics(s.cfg.Name)
+	return err
+}
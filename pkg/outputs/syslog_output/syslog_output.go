@@ -0,0 +1,468 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package syslog_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType    = "syslog"
+	loggingPrefix = "[syslog_output:%s] "
+
+	networkUDP = "udp"
+	networkTCP = "tcp"
+	networkTLS = "tls"
+
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+
+	framingOctetCounting  = "octet-counting"
+	framingNonTransparent = "non-transparent"
+
+	defaultPort          = "514"
+	defaultTLSPort       = "6514"
+	defaultAppName       = "gnmic"
+	defaultFacility      = "local0"
+	defaultSeverity      = "informational"
+	defaultTimeout       = 10 * time.Second
+	defaultRetryInterval = 2 * time.Second
+	defaultBufferSize    = 1000
+	defaultNumWorkers    = 1
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &syslogOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				wg:        new(sync.WaitGroup),
+			}
+		})
+}
+
+type syslogOutput struct {
+	cfg    *config
+	logger *log.Logger
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	// framed messages waiting to be sent
+	buffer chan []byte
+
+	m sync.Mutex
+	// connection used by WriteSync, dialed on first use.
+	syncConn net.Conn
+
+	facility        int
+	severity        int
+	facilityMapping *mapping
+	severityMapping *mapping
+	localHostname   string
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	msgTpl    *template.Template
+	cfn       context.CancelFunc
+	wg        *sync.WaitGroup
+
+	reg *prometheus.Registry
+}
+
+type config struct {
+	Name    string           `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address string           `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Network string           `mapstructure:"network,omitempty" json:"network,omitempty"`
+	TLS     *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Timeout time.Duration    `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	// wait time before reconnecting after a failure.
+	RetryInterval time.Duration `mapstructure:"retry-interval,omitempty" json:"retry-interval,omitempty"`
+	// message format, `rfc5424` or `rfc3164`.
+	Format string `mapstructure:"format,omitempty" json:"format,omitempty"`
+	// TCP and TLS framing, `octet-counting` or `non-transparent`.
+	Framing          string   `mapstructure:"framing,omitempty" json:"framing,omitempty"`
+	Hostname         string   `mapstructure:"hostname,omitempty" json:"hostname,omitempty"`
+	AppName          string   `mapstructure:"app-name,omitempty" json:"app-name,omitempty"`
+	MsgID            string   `mapstructure:"msg-id,omitempty" json:"msg-id,omitempty"`
+	Facility         string   `mapstructure:"facility,omitempty" json:"facility,omitempty"`
+	Severity         string   `mapstructure:"severity,omitempty" json:"severity,omitempty"`
+	FacilityMapping  *mapping `mapstructure:"facility-mapping,omitempty" json:"facility-mapping,omitempty"`
+	SeverityMapping  *mapping `mapstructure:"severity-mapping,omitempty" json:"severity-mapping,omitempty"`
+	StructuredDataID string   `mapstructure:"structured-data-id,omitempty" json:"structured-data-id,omitempty"`
+	MsgTemplate      string   `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
+	BufferSize       int      `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	AddTarget        string   `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate   string   `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors  []string `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers       int      `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics    bool     `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug            bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+func (s *syslogOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, s.cfg)
+	if err != nil {
+		return err
+	}
+	if s.cfg.Name == "" {
+		s.cfg.Name = name
+	}
+	s.logger.SetPrefix(fmt.Sprintf(loggingPrefix, s.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return err
+		}
+	}
+	err = s.setDefaults()
+	if err != nil {
+		return err
+	}
+	err = s.registerMetrics()
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.TargetTemplate == "" {
+		s.targetTpl = outputs.DefaultTargetTemplate
+	} else if s.cfg.AddTarget != "" {
+		s.targetTpl, err = gtemplate.CreateTemplate("target-template", s.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		s.targetTpl = s.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	if s.cfg.MsgTemplate != "" {
+		s.msgTpl, err = gtemplate.CreateTemplate(fmt.Sprintf("%s-msg-template", s.cfg.Name), s.cfg.MsgTemplate)
+		if err != nil {
+			return err
+		}
+		s.msgTpl = s.msgTpl.Funcs(outputs.TemplateFuncs)
+	}
+	s.localHostname, err = os.Hostname()
+	if err != nil {
+		s.localHostname = nilValue
+	}
+	s.buffer = make(chan []byte, s.cfg.BufferSize)
+
+	ctx, s.cfn = context.WithCancel(ctx)
+	for i := 0; i < s.cfg.NumWorkers; i++ {
+		go s.worker(ctx)
+	}
+	s.wg.Add(1)
+	go s.writer(ctx)
+	s.logger.Printf("initialized syslog output %s: %s", s.cfg.Name, s.String())
+	return nil
+}
+
+func (s *syslogOutput) setDefaults() error {
+	if s.cfg.Address == "" {
+		return errors.New("missing address field")
+	}
+	switch s.cfg.Network {
+	case "":
+		s.cfg.Network = networkUDP
+		if s.cfg.TLS != nil {
+			s.cfg.Network = networkTLS
+		}
+	case networkUDP, networkTCP, networkTLS:
+	default:
+		return fmt.Errorf("unknown network %q", s.cfg.Network)
+	}
+	if s.cfg.Network == networkTLS && s.cfg.TLS == nil {
+		s.cfg.TLS = new(types.TLSConfig)
+	}
+	if _, _, err := net.SplitHostPort(s.cfg.Address); err != nil {
+		port := defaultPort
+		if s.cfg.Network == networkTLS {
+			port = defaultTLSPort
+		}
+		s.cfg.Address = net.JoinHostPort(s.cfg.Address, port)
+	}
+	switch s.cfg.Format {
+	case "":
+		s.cfg.Format = formatRFC5424
+	case formatRFC5424, formatRFC3164:
+	default:
+		return fmt.Errorf("unknown format %q", s.cfg.Format)
+	}
+	switch s.cfg.Framing {
+	case "":
+		s.cfg.Framing = framingOctetCounting
+	case framingOctetCounting, framingNonTransparent:
+	default:
+		return fmt.Errorf("unknown framing %q", s.cfg.Framing)
+	}
+	if s.cfg.AppName == "" {
+		s.cfg.AppName = defaultAppName
+	}
+	if s.cfg.Facility == "" {
+		s.cfg.Facility = defaultFacility
+	}
+	var err error
+	s.facility, err = parseFacility(s.cfg.Facility)
+	if err != nil {
+		return fmt.Errorf("facility: %v", err)
+	}
+	if s.cfg.Severity == "" {
+		s.cfg.Severity = defaultSeverity
+	}
+	s.severity, err = parseSeverity(s.cfg.Severity)
+	if err != nil {
+		return fmt.Errorf("severity: %v", err)
+	}
+	if s.cfg.FacilityMapping != nil {
+		if err = s.cfg.FacilityMapping.init(parseFacility); err != nil {
+			return fmt.Errorf("facility-mapping: %v", err)
+		}
+		s.facilityMapping = s.cfg.FacilityMapping
+	}
+	if s.cfg.SeverityMapping != nil {
+		if err = s.cfg.SeverityMapping.init(parseSeverity); err != nil {
+			return fmt.Errorf("severity-mapping: %v", err)
+		}
+		s.severityMapping = s.cfg.SeverityMapping
+	}
+	if s.cfg.Timeout <= 0 {
+		s.cfg.Timeout = defaultTimeout
+	}
+	if s.cfg.RetryInterval <= 0 {
+		s.cfg.RetryInterval = defaultRetryInterval
+	}
+	if s.cfg.BufferSize <= 0 {
+		s.cfg.BufferSize = defaultBufferSize
+	}
+	if s.cfg.NumWorkers <= 0 {
+		s.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
+
+func (s *syslogOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case s.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if s.cfg.Debug {
+			s.logger.Printf("writing expired after %s", s.cfg.Timeout)
+		}
+		syslogNumberOfFailedMsgs.WithLabelValues(s.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (s *syslogOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range s.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case s.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync sends the messages built from a gNMI message on a dedicated connection,
+// it returns once they were written to the connection.
+func (s *syslogOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	events, err := s.protoEvents(rsp, meta)
+	if err != nil {
+		s.logger.Printf("failed to convert message to event: %v", err)
+		return nil
+	}
+	return s.writeSync(ctx, events)
+}
+
+// WriteEventSync sends the messages built from an event on a dedicated connection,
+// it returns once they were written to the connection.
+func (s *syslogOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	var evs = []*formatters.EventMsg{ev}
+	for _, proc := range s.evps {
+		evs = proc.Apply(evs...)
+	}
+	return s.writeSync(ctx, evs)
+}
+
+func (s *syslogOutput) Close() error {
+	if s.cfn == nil {
+		return nil
+	}
+	s.cfn()
+	s.wg.Wait()
+	s.m.Lock()
+	if s.syncConn != nil {
+		s.syncConn.Close()
+		s.syncConn = nil
+	}
+	s.m.Unlock()
+	return nil
+}
+
+func (s *syslogOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !s.cfg.EnableMetrics {
+		return
+	}
+	s.reg = reg
+}
+
+func (s *syslogOutput) String() string {
+	b, err := json.Marshal(s.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (s *syslogOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && s.logger != nil {
+		s.logger.SetOutput(logger.Writer())
+		s.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (s *syslogOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	s.evps, err = formatters.MakeEventProcessors(
+		logger,
+		s.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *syslogOutput) SetName(name string) {
+	if s.cfg.Name == "" {
+		s.cfg.Name = name
+	}
+}
+
+func (s *syslogOutput) SetClusterName(_ string) {}
+
+func (s *syslogOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (s *syslogOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.eventChan:
+			s.workerHandleEvent(ctx, ev)
+		case m := <-s.msgChan:
+			s.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (s *syslogOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	events, err := s.protoEvents(m.GetMsg(), m.GetMeta())
+	if err != nil {
+		s.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		s.workerHandleEvent(ctx, ev)
+	}
+}
+
+func (s *syslogOutput) protoEvents(m proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	pmsg, ok := m.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	rsp, err := outputs.AddSubscriptionTarget(pmsg, meta, s.cfg.AddTarget, s.targetTpl)
+	if err != nil {
+		s.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if rsp != nil {
+		pmsg = rsp
+	}
+	return formatters.ResponseToEventMsgs(subName, pmsg, meta, s.evps...)
+}
+
+func (s *syslogOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	b, err := s.framedMessage(ev)
+	if err != nil {
+		if s.cfg.Debug {
+			s.logger.Printf("%v", err)
+		}
+		return
+	}
+	if s.cfg.Debug {
+		s.logger.Printf("buffering message: %s", b)
+	}
+	select {
+	case <-ctx.Done():
+	case s.buffer <- b:
+	}
+}
+
+func (s *syslogOutput) framedMessage(ev *formatters.EventMsg) ([]byte, error) {
+	msg, err := s.message(ev)
+	if err != nil {
+		syslogNumberOfFailedMsgs.WithLabelValues(s.cfg.Name, "marshal_error").Inc()
+		return nil, fmt.Errorf("failed to build syslog message: %v", err)
+	}
+	return frame(msg, s.cfg.Network, s.cfg.Framing), nil
+}
@@ -0,0 +1,198 @@
+// © 2024 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package syslog_output
+
+import (
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+var testTS = time.Date(2024, 5, 1, 10, 20, 30, 123456789, time.UTC).UnixNano()
+
+func newTestOutput(t *testing.T, cfg *config) *syslogOutput {
+	t.Helper()
+	if cfg.Address == "" {
+		cfg.Address = "localhost"
+	}
+	s := &syslogOutput{cfg: cfg, localHostname: "collector"}
+	err := s.setDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MsgTemplate != "" {
+		s.msgTpl = template.Must(template.New("msg").Parse(cfg.MsgTemplate))
+	}
+	return s
+}
+
+func TestSetDefaults(t *testing.T) {
+	s := newTestOutput(t, &config{Address: "10.0.0.1"})
+	if s.cfg.Address != "10.0.0.1:514" || s.cfg.Network != networkUDP {
+		t.Errorf("unexpected address/network: %s/%s", s.cfg.Address, s.cfg.Network)
+	}
+	if s.facility != 16 || s.severity != 6 {
+		t.Errorf("unexpected facility/severity: %d/%d", s.facility, s.severity)
+	}
+	s = newTestOutput(t, &config{Address: "syslog.example.com", Network: networkTLS})
+	if s.cfg.Address != "syslog.example.com:6514" || s.cfg.TLS == nil {
+		t.Errorf("unexpected tls config: %s, %v", s.cfg.Address, s.cfg.TLS)
+	}
+
+	for _, cfg := range []*config{
+		{},
+		{Address: "a", Network: "sctp"},
+		{Address: "a", Format: "cef"},
+		{Address: "a", Facility: "local8"},
+		{Address: "a", Severity: "8"},
+		{Address: "a", SeverityMapping: &mapping{Field: "severity", Values: map[string]string{"x": "fatal"}}},
+		{Address: "a", FacilityMapping: &mapping{}},
+	} {
+		s := &syslogOutput{cfg: cfg}
+		if err := s.setDefaults(); err == nil {
+			t.Errorf("expected an error for config %+v", cfg)
+		}
+	}
+}
+
+func TestMappingCode(t *testing.T) {
+	m := &mapping{
+		Field: "/alarms/alarm/state/severity",
+		Values: map[string]string{
+			"openconfig-alarm-types:CRITICAL": "crit",
+			"openconfig-alarm-types:MAJOR":    "3",
+		},
+	}
+	if err := m.init(parseSeverity); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		ev   *formatters.EventMsg
+		want int
+	}{
+		{
+			name: "mapped_value",
+			ev:   &formatters.EventMsg{Values: map[string]any{"/alarms/alarm/state/severity": "openconfig-alarm-types:CRITICAL"}},
+			want: 2,
+		},
+		{
+			name: "case_insensitive",
+			ev:   &formatters.EventMsg{Values: map[string]any{"/alarms/alarm/state/severity": "OPENCONFIG-ALARM-TYPES:MAJOR"}},
+			want: 3,
+		},
+		{
+			name: "severity_name",
+			ev:   &formatters.EventMsg{Tags: map[string]string{"/alarms/alarm/state/severity": "Warning"}},
+			want: 4,
+		},
+		{
+			name: "unknown_value",
+			ev:   &formatters.EventMsg{Values: map[string]any{"/alarms/alarm/state/severity": "openconfig-alarm-types:MINOR"}},
+			want: 6,
+		},
+		{
+			name: "missing_field",
+			ev:   &formatters.EventMsg{Values: map[string]any{"a": 1}},
+			want: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.code(tt.ev, parseSeverity, 6); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+	var nm *mapping
+	if got := nm.code(tests[0].ev, parseSeverity, 5); got != 5 {
+		t.Errorf("nil mapping: got %d, want 5", got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "alarms",
+		Timestamp: testTS,
+		Tags:      map[string]string{"source": "router1:57400", "id": `a"b]`},
+		Values:    map[string]any{"severity": "MAJOR", "text": "link down"},
+	}
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "rfc5424_default",
+			cfg:  &config{MsgTemplate: `{{ index .values "text" }}`},
+			want: `<134>1 2024-05-01T10:20:30.123456Z router1 gnmic - alarms - link down`,
+		},
+		{
+			name: "rfc5424_structured_data",
+			cfg: &config{
+				Hostname:         "gnmic 1",
+				MsgID:            "ALARM",
+				Facility:         "local7",
+				StructuredDataID: "gnmic@32473",
+				SeverityMapping:  &mapping{Field: "severity", Values: map[string]string{"MAJOR": "err"}},
+				MsgTemplate:      `{{ index .values "text" }}`,
+			},
+			want: `<187>1 2024-05-01T10:20:30.123456Z gnmic_1 gnmic - ALARM [gnmic@32473 id="a\"b\]" source="router1:57400"] link down`,
+		},
+		{
+			name: "rfc5424_json_body",
+			cfg:  &config{AppName: "telemetry", Severity: "notice", Facility: "user"},
+			want: `<13>1 2024-05-01T10:20:30.123456Z router1 telemetry - alarms - {"name":"alarms","timestamp":1714558830123456789,"tags":{"id":"a\"b]","source":"router1:57400"},"values":{"severity":"MAJOR","text":"link down"}}`,
+		},
+		{
+			name: "rfc3164",
+			cfg:  &config{Format: formatRFC3164, MsgTemplate: "{{ .name }}:\n{{ index .values \"text\" }}\n", Framing: framingNonTransparent},
+			want: `<134>` + time.Unix(0, testTS).Local().Format(time.Stamp) + ` router1 gnmic: alarms: link down`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestOutput(t, tt.cfg)
+			got, err := s.message(ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrame(t *testing.T) {
+	msg := []byte("<14>1 - - - - - hello")
+	if got := string(frame(msg, networkUDP, framingOctetCounting)); got != string(msg) {
+		t.Errorf("udp: got %q", got)
+	}
+	if got := string(frame(msg, networkTCP, framingOctetCounting)); got != "21 "+string(msg) {
+		t.Errorf("octet-counting: got %q", got)
+	}
+	if got := string(frame(msg, networkTLS, framingNonTransparent)); got != string(msg)+"\n" {
+		t.Errorf("non-transparent: got %q", got)
+	}
+}
+
+func TestHeaderField(t *testing.T) {
+	if got := headerField("", 10, nilValue); got != nilValue {
+		t.Errorf("got %q", got)
+	}
+	if got := headerField("a b\tcé", 10, nilValue); got != "a_b_c_" {
+		t.Errorf("got %q", got)
+	}
+	if got := headerField("abcdef", 3, nilValue); got != "abc" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -15,9 +15,11 @@ import (
 	"io"
 	"log"
 	"net"
+	"sync"
 	"text/template"
 	"time"
 
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -56,6 +58,10 @@ type tcpOutput struct {
 
 	targetTpl *template.Template
 	delimiter []byte
+
+	m sync.Mutex
+	// connection used by WriteSync, dialed on first use.
+	syncConn net.Conn
 }
 
 type config struct {
@@ -68,6 +74,7 @@ type config struct {
 	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty"`
 	SplitEvents        bool          `mapstructure:"split-events,omitempty"`
 	Delimiter          string        `mapstructure:"delimiter,omitempty"`
+	LengthPrefix       bool          `mapstructure:"length-prefix,omitempty"`
 	KeepAlive          time.Duration `mapstructure:"keep-alive,omitempty"`
 	RetryInterval      time.Duration `mapstructure:"retry-interval,omitempty"`
 	NumWorkers         int           `mapstructure:"num-workers,omitempty"`
@@ -180,11 +187,57 @@ func (t *tcpOutput) Write(ctx context.Context, m proto.Message, meta outputs.Met
 
 func (t *tcpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
+// WriteSync writes a message on a dedicated connection,
+// it returns once the message was written to the connection.
+func (t *tcpOutput) WriteSync(ctx context.Context, m proto.Message, meta outputs.Meta) error {
+	if m == nil {
+		return nil
+	}
+	rsp, err := outputs.AddSubscriptionTarget(m, meta, t.cfg.AddTarget, t.targetTpl)
+	if err != nil {
+		t.logger.Printf("failed to add target to the response: %v", err)
+	}
+	bb, err := outputs.Marshal(rsp, meta, t.mo, t.cfg.SplitEvents, t.evps...)
+	if err != nil {
+		t.logger.Printf("failed marshaling proto msg: %v", err)
+		return nil
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.syncConn == nil {
+		d := net.Dialer{KeepAlive: t.cfg.KeepAlive}
+		t.syncConn, err = d.DialContext(ctx, "tcp", t.cfg.Address)
+		if err != nil {
+			return err
+		}
+	}
+	for _, b := range bb {
+		if t.limiter != nil {
+			<-t.limiter.C
+		}
+		_, err = t.syncConn.Write(t.frame(b))
+		if err != nil {
+			t.syncConn.Close()
+			t.syncConn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tcpOutput) WriteEventSync(context.Context, *formatters.EventMsg) error { return nil }
+
 func (t *tcpOutput) Close() error {
 	t.cancelFn()
 	if t.limiter != nil {
 		t.limiter.Stop()
 	}
+	t.m.Lock()
+	if t.syncConn != nil {
+		t.syncConn.Close()
+		t.syncConn = nil
+	}
+	t.m.Unlock()
 	return nil
 }
 func (t *tcpOutput) RegisterMetrics(reg *prometheus.Registry) {}
@@ -226,9 +279,7 @@ START:
 			if t.limiter != nil {
 				<-t.limiter.C
 			}
-			// append delimiter
-			b = append(b, t.delimiter...)
-			_, err = conn.Write(b)
+			_, err = conn.Write(t.frame(b))
 			if err != nil {
 				t.logger.Printf("%s failed sending tcp bytes: %v", workerLogPrefix, err)
 				conn.Close()
@@ -239,6 +290,17 @@ START:
 	}
 }
 
+// frame prefixes a message with its varint encoded length if length-prefix is set,
+// otherwise it appends the delimiter.
+func (t *tcpOutput) frame(b []byte) []byte {
+	if t.cfg.LengthPrefix {
+		lb := make([]byte, 0, protowire.SizeVarint(uint64(len(b)))+len(b))
+		lb = protowire.AppendVarint(lb, uint64(len(b)))
+		return append(lb, b...)
+	}
+	return append(b, t.delimiter...)
+}
+
 func (t *tcpOutput) SetName(name string)                             {}
 func (t *tcpOutput) SetClusterName(name string)                      {}
 func (s *tcpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}